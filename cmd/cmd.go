@@ -1,31 +1,134 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/priyanshujain/infrasync/internal/audit"
 	"github.com/priyanshujain/infrasync/internal/config"
+	"github.com/priyanshujain/infrasync/internal/daemon"
+	"github.com/priyanshujain/infrasync/internal/doctor"
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/internal/inventory"
+	"github.com/priyanshujain/infrasync/internal/keyring"
+	"github.com/priyanshujain/infrasync/internal/lock"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/server"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"github.com/priyanshujain/infrasync/internal/vcs"
+	"github.com/priyanshujain/infrasync/internal/version"
 	"github.com/priyanshujain/infrasync/pkg/infrasync"
 	"github.com/spf13/cobra"
 )
 
 var cfg config.Config
 
+// configOptional annotates commands which run without a valid config
+var configOptional = map[string]string{"config": "optional"}
+
+var verify bool
+
+var preflightOnly bool
+
+var fromInventory string
+
+var junitReport string
+
+var parallelism int
+
+var forceUnlock bool
+var force bool
+
+var checkUpdate bool
+
+var otlpEndpoint string
+
+var configLocations []string
+
+var noGit bool
+
+var initOpts infrasync.InitOptions
+
+var rollbackRun string
+
+var exportFormat string
+
+var pruneYes bool
+
+var syncOpts infrasync.SyncOptions
+
+var (
+	syncDryRun   bool
+	syncShowDiff bool
+	syncPRHost   string
+	remediateYes bool
+)
+
+var (
+	daemonInterval time.Duration
+	daemonAddr     string
+)
+
+var serveAddr string
+
 func Execute() {
+	shutdownTracing := func(context.Context) error { return nil }
+
 	rootCmd := &cobra.Command{
 		Use:   "infrasync",
 		Short: "InfraSync - Convert existing infrastructure to IaC",
 		Long:  `InfraSync is a tool for converting existing cloud infrastructure to Terraform code.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Annotations["config"] != "optional" {
+				var err error
+				cfg, err = config.LoadFrom(context.Background(), configLocations...)
+				if err != nil {
+					fmt.Println("Please format the config file as per the template.")
+					fmt.Println("Template:")
+					fmt.Print(config.Template)
+					return fmt.Errorf("error loading config file: %w", err)
+				}
+			}
+
+			var err error
+			shutdownTracing, err = telemetry.Setup(context.Background(), otlpEndpoint)
+			if err != nil {
+				return fmt.Errorf("failed to setup tracing: %w", err)
+			}
+			return nil
+		},
 	}
 
+	rootCmd.PersistentFlags().StringArrayVar(&configLocations, "config", nil, "Config file path, gs://bucket/object or https:// URL instead of ~/.config/infrasync/config.yaml; repeat to override earlier files")
+	rootCmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP endpoint to export traces to (defaults to OTEL_EXPORTER_OTLP_ENDPOINT)")
+	rootCmd.PersistentFlags().BoolVar(&forceUnlock, "force-unlock", false, "Break the repository and state locks held by another run")
+	rootCmd.PersistentFlags().IntVar(&parallelism, "parallelism", 0, "Number of resources discovered concurrently per service (overrides config)")
+
 	importCmd := &cobra.Command{
 		Use:   "import",
 		Short: "Import cloud resources and generate Terraform code",
-		RunE:  runImport,
+		RunE:  audited(runImport),
 	}
 
+	importCmd.Flags().BoolVar(&verify, "verify", false, "Run terraform plan after import and fail if it is not empty")
+	importCmd.Flags().BoolVar(&preflightOnly, "preflight", false, "Only count resources and estimate API calls and duration")
+	importCmd.Flags().StringVar(&fromInventory, "from-inventory", "", "Import exactly the resources listed in an inventory written by export, skipping discovery")
+	importCmd.Flags().BoolVar(&force, "force", false, "Overwrite generated files even if they were changed since they were generated")
+	importCmd.Flags().StringVar(&junitReport, "junit", "", "Write a JUnit XML report of the import to this path, with a test case per resource")
+	importCmd.MarkFlagsMutuallyExclusive("preflight", "from-inventory")
+
 	initCmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize a new IaC repository",
@@ -33,49 +136,686 @@ func Execute() {
 		RunE:  runInit,
 	}
 
+	initCmd.Flags().BoolVar(&noGit, "no-git", false, "Skip initializing a git repository")
+	initCmd.Flags().BoolVar(&initOpts.EncryptState, "encrypt-state", false, "Encrypt state and plans with a Cloud KMS key (OpenTofu only)")
+	initCmd.Flags().BoolVar(&initOpts.CreateBackend, "create-backend", false, "Create the state bucket if it doesn't exist and generate its config")
+	initCmd.Flags().StringVar(&initOpts.KMSKey, "kms-key", "", "Full name of the Cloud KMS key to encrypt state with, instead of the only key of the project")
+	initCmd.Flags().StringVar(&initOpts.GitHubOIDC, "github-oidc", "", "GitHub repository (owner/name) whose workflow authenticates with Workload Identity Federation instead of a key")
+
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Detect drift and update Terraform code to match cloud resources",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Dry runs change nothing worth auditing
+			if syncDryRun {
+				return runSyncDryRun(cmd, args)
+			}
+			if syncShowDiff {
+				return errors.New("--show-diff needs --dry-run")
+			}
+			return audited(runSync)(cmd, args)
+		},
+	}
+
+	addSyncFlags(syncCmd)
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Generate config in a scratch copy of the project and report what would change, without changing the repository")
+	syncCmd.Flags().BoolVar(&syncShowDiff, "show-diff", false, "With --dry-run, print unified diffs of the generated files that would change")
+	syncCmd.MarkFlagsMutuallyExclusive("dry-run", "create-pr")
+	syncCmd.MarkFlagsMutuallyExclusive("dry-run", "remediate")
+	syncCmd.PreRun = func(cmd *cobra.Command, args []string) {
+		syncOpts.PRHost = vcs.HostType(syncPRHost)
+		syncOpts.ConfirmRemediation = confirmRemediation
+	}
+
+	daemonCmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run sync on a schedule",
+		Long:  `Run sync periodically in-process, serving health and last-run status over HTTP.`,
+		RunE:  audited(runDaemon),
+	}
+
+	daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 6*time.Hour, "Interval between sync runs")
+	daemonCmd.Flags().StringVar(&daemonAddr, "listen", ":8080", "Address of the health and status endpoint (empty to disable)")
+	addSyncFlags(daemonCmd)
+	daemonCmd.PreRunE = unattendedSyncPreRun
+
 	rootCmd.AddCommand(importCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(daemonCmd)
 
-	var err error
-	cfg, err = config.Load()
-	if err != nil {
-		fmt.Printf("Error loading config file: %v\n", err)
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an HTTP API to trigger imports and syncs",
+		Long:  `Serve an HTTP API to trigger imports and syncs for a project and services, stream their progress and fetch results.`,
+		RunE:  audited(runServe),
+	}
 
-		fmt.Println("Please format the config file as per the template.")
-		fmt.Println("Template:")
-		fmt.Print(config.Template)
-		os.Exit(1)
+	serveCmd.Flags().StringVar(&serveAddr, "listen", ":8080", "Address to serve the API on")
+	addSyncFlags(serveCmd)
+	serveCmd.PreRunE = unattendedSyncPreRun
+
+	rootCmd.AddCommand(serveCmd)
+
+	driftCmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Inspect detected drift",
+	}
+
+	historyCmd := &cobra.Command{
+		Use:   "history [resource]",
+		Short: "Show how drift evolved over past sync runs",
+		Long:  `Show the drift recorded by each sync run, or the drift of a single resource address over time.`,
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runDriftHistory,
+	}
+
+	driftCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(driftCmd)
+
+	diffCmd := &cobra.Command{
+		Use:   "diff <resource-address>",
+		Short: "Show the attribute diff between a live resource and its state",
+		Long:  `Discover a single resource and print how its cloud attributes differ from Terraform state. Drift ignore rules are not applied.`,
+		Args:  cobra.ExactArgs(1),
+		RunE:  runDiff,
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	rootCmd.AddCommand(diffCmd)
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the inventory of discovered resources",
+		Long:  `Discover the resources of every configured service and print their type, name, ID and key attributes, without generating any Terraform.`,
+		RunE:  runExport,
+	}
+
+	exportCmd.Flags().StringVar(&exportFormat, "format", string(inventory.FormatJSON), "Output format (json, csv or yaml)")
+	rootCmd.AddCommand(exportCmd)
+
+	versionCmd := &cobra.Command{
+		Use:         "version",
+		Short:       "Print the version and supported resources",
+		RunE:        runVersion,
+		Annotations: configOptional,
+	}
+
+	versionCmd.Flags().BoolVar(&checkUpdate, "check", false, "Check GitHub releases for a newer version")
+	rootCmd.AddCommand(versionCmd)
+
+	doctorCmd := &cobra.Command{
+		Use:         "doctor",
+		Short:       "Check the environment for common problems",
+		Long:        `Check the config, credentials, the gcloud and terraform CLIs, the provider plugin cache, the output directory and network access to Google APIs, and suggest fixes for anything missing.`,
+		RunE:        runDoctor,
+		Annotations: configOptional,
+	}
+
+	rootCmd.AddCommand(doctorCmd)
+
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Undo an import run",
+		Long:  `Remove the resources imported by a run from Terraform state, without destroying them, and delete the .tf files it generated. Runs are identified by the run_id of the audit log.`,
+		RunE:  audited(runRollback),
+	}
+
+	rollbackCmd.Flags().StringVar(&rollbackRun, "run", "", "ID of the run to roll back, as recorded in the audit log")
+	rollbackCmd.MarkFlagRequired("run")
+	rootCmd.AddCommand(rollbackCmd)
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete generated files of resources which no longer exist",
+		Long:  `Discover the resources of every configured service and find the generated .tf files, as recorded in the manifest, whose resources no longer exist. After confirmation the files are deleted and their resources removed from Terraform state.`,
+		RunE:  audited(runPrune),
+	}
+
+	pruneCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Delete the orphaned files without asking")
+	rootCmd.AddCommand(pruneCmd)
+
+	labelsCmd := &cobra.Command{
+		Use:   "labels",
+		Short: "Manage the configured labels",
+	}
+
+	labelsApplyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Add the configured labels to cloud resources",
+		Long:  `Add the configured labels to the cloud resources of every configured service which support labels. Existing labels are left untouched.`,
+		RunE:  audited(runLabelsApply),
+	}
+
+	labelsCmd.AddCommand(labelsApplyCmd)
+	rootCmd.AddCommand(labelsCmd)
+
+	credentialsCmd := &cobra.Command{
+		Use:   "credentials",
+		Short: "Manage credentials in the keychain of the operating system",
+	}
+
+	credentialsStoreCmd := &cobra.Command{
+		Use:         "store <alias> [file]",
+		Short:       "Store credentials in the keyring under an alias",
+		Long:        `Store a service account key or user credentials, read from file or stdin, in the keychain of the operating system under alias, for the config to refer to with credentials.keyring. The file can be deleted afterwards.`,
+		Args:        cobra.RangeArgs(1, 2),
+		RunE:        runCredentialsStore,
+		Annotations: configOptional,
+	}
+
+	credentialsDeleteCmd := &cobra.Command{
+		Use:         "delete <alias>",
+		Short:       "Delete credentials from the keyring",
+		Args:        cobra.ExactArgs(1),
+		RunE:        runCredentialsDelete,
+		Annotations: configOptional,
+	}
+
+	credentialsCmd.AddCommand(credentialsStoreCmd)
+	credentialsCmd.AddCommand(credentialsDeleteCmd)
+	rootCmd.AddCommand(credentialsCmd)
+
+	err := rootCmd.Execute()
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		slog.Error("Failed to flush traces", "error", shutdownErr)
+	}
+	if err != nil {
 		fmt.Println(err)
+		if errors.Is(err, infrasync.ErrDriftDetected) || errors.Is(err, infrasync.ErrBudgetExceeded) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 }
 
+// audited records the changes made by run to the audit log. Only commands
+// which change the repository, state or cloud resources are audited.
+func audited(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		auditLog, err := audit.Open(cfg.AuditLogPath())
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		audit.SetDefault(auditLog)
+		defer func() {
+			audit.SetDefault(nil)
+			auditLog.Close()
+		}()
+
+		return run(cmd, args)
+	}
+}
+
+// newClient creates a client for c with the overrides passed as flags
+func newClient(c config.Config, opts ...infrasync.Option) (*infrasync.Client, error) {
+	return infrasync.New(append([]infrasync.Option{
+		infrasync.WithConfig(c),
+		infrasync.WithParallelism(parallelism),
+		infrasync.WithForceUnlock(forceUnlock),
+		infrasync.WithForce(force),
+	}, opts...)...)
+}
+
 func runImport(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
-	client := infrasync.NewClient(cfg)
-	
-	if err := client.Import(ctx); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if preflightOnly {
+		report, err := client.Preflight(ctx)
+		if err != nil {
+			return fmt.Errorf("preflight failed: %w", err)
+		}
+		infrasync.LogPreflight(report)
+		return nil
+	}
+
+	var items []inventory.Item
+	if fromInventory != "" {
+		items, err = inventory.ReadFile(fromInventory)
+		if err != nil {
+			return err
+		}
+	}
+
+	var result *infrasync.ImportResult
+	if fromInventory != "" {
+		result, err = client.ImportInventory(ctx, items)
+	} else {
+		result, err = client.Import(ctx)
+	}
+	if result != nil {
+		infrasync.LogImportResult(*result)
+		if junitReport != "" {
+			if reportErr := writeJUnitReport(junitReport, *result, err); reportErr != nil {
+				return reportErr
+			}
+		}
+	}
+	if err != nil {
+		if !errors.Is(err, lock.ErrLocked) {
+			fmt.Printf("To undo this run: infrasync rollback --run %s\n", audit.RunID())
+		}
 		return fmt.Errorf("import failed: %w", err)
 	}
-	
+
+	if verify {
+		if err := client.Verify(ctx); err != nil {
+			return fmt.Errorf("verification failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeJUnitReport writes the JUnit report of an import which failed with
+// runErr, if it did, to path
+func writeJUnitReport(path string, result infrasync.ImportResult, runErr error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create JUnit report: %w", err)
+	}
+	defer f.Close()
+	if err := infrasync.WriteJUnitReport(f, result, runErr); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Sync(ctx, syncOpts); err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+
+	return nil
+}
+
+func runSyncDryRun(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	diffs, err := client.SyncDryRun(ctx)
+	if syncShowDiff {
+		for _, d := range diffs {
+			fmt.Print(d.Diff)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+	return nil
+}
+
+// addSyncFlags registers the flags of sync runs, shared by sync and the daemon
+// and serve commands running it unattended
+func addSyncFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&syncOpts.CreatePR, "create-pr", false, "Commit changes to a new branch and open a pull request")
+	cmd.Flags().StringVar(&syncPRHost, "pr-host", string(vcs.HostTypeGitHub), "Pull request host (github or gitlab)")
+	cmd.Flags().StringVar(&syncOpts.BaseBranch, "base", "main", "Base branch for the pull request")
+	cmd.Flags().BoolVar(&syncOpts.AllowDestroy, "allow-destroy", false, "Open the pull request even if its plan destroys or replaces resources")
+	cmd.Flags().BoolVar(&syncOpts.Remediate, "remediate", false, "Preview reverting the drift of the resource types in drift.remediate in the cloud, and revert it once confirmed")
+	cmd.Flags().BoolVarP(&remediateYes, "yes", "y", false, "With --remediate, revert the drift without asking")
+	cmd.Flags().StringVar(&syncOpts.SARIF, "sarif", "", "Write drift and policy violations to this path as SARIF for code scanning")
+}
+
+// unattendedSyncPreRun sets up the sync runs of the daemon and serve
+// commands. Nobody is there to confirm remediations, so they need --yes.
+func unattendedSyncPreRun(cmd *cobra.Command, args []string) error {
+	if syncOpts.Remediate && !remediateYes {
+		return errors.New("--remediate needs --yes when running unattended")
+	}
+	syncOpts.PRHost = vcs.HostType(syncPRHost)
+	syncOpts.ConfirmRemediation = confirmRemediation
+	return nil
+}
+
+// confirmRemediation asks whether to make the previewed remediations, unless
+// --yes was passed
+func confirmRemediation(remediations []google.Remediation) bool {
+	if remediateYes {
+		return true
+	}
+	fmt.Printf("Revert the drift of %d resource(s) in the cloud? [y/N] ", len(remediations))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	return daemon.Run(ctx, daemonInterval, daemonAddr, func(ctx context.Context) error {
+		return client.Sync(ctx, syncOpts)
+	})
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	token := os.Getenv("INFRASYNC_API_TOKEN")
+	if token == "" {
+		return fmt.Errorf("INFRASYNC_API_TOKEN must be set to authenticate API requests")
+	}
+
+	srv := server.New(func(ctx context.Context, req server.RunRequest) (any, error) {
+		projectCfg, err := cfg.ForProject(req.Project, req.Services)
+		if err != nil {
+			return nil, err
+		}
+
+		hooks := &driftHooks{}
+		client, err := newClient(projectCfg, infrasync.WithHooks(hooks))
+		if err != nil {
+			return nil, err
+		}
+		if req.Kind == server.RunKindSync {
+			if err := client.Sync(ctx, syncOpts); err != nil {
+				return nil, err
+			}
+			return hooks.report, nil
+		}
+		return client.Import(ctx)
+	}, token)
+	slog.SetDefault(slog.New(srv.LogHandler(slog.NewTextHandler(os.Stderr, nil))))
+
+	return srv.ListenAndServe(ctx, serveAddr)
+}
+
+// driftHooks keeps the drift found by a sync
+type driftHooks struct {
+	infrasync.NopHooks
+	report *drift.Report
+}
+
+func (h *driftHooks) OnDrift(report drift.Report) {
+	h.report = &report
+}
+
+func runDriftHistory(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := cfg.DriftHistory()
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("drift history is not recorded in CI without a gcs backend")
+	}
+
+	records, err := store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read drift history: %w", err)
+	}
+	if len(records) == 0 {
+		fmt.Println("No drift history recorded yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	if len(args) == 1 {
+		fmt.Fprintln(w, "TIME\tSTATUS")
+		for _, record := range records {
+			status := record.Status(args[0])
+			if status == "" {
+				status = "in sync"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", record.Time.Format(time.RFC3339), status)
+		}
+		return nil
+	}
+
+	fmt.Fprintln(w, "TIME\tPROJECT\tUNMANAGED\tDELETED\tMODIFIED")
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", record.Time.Format(time.RFC3339), record.Project,
+			len(record.Unmanaged), len(record.Deleted), len(record.Modified))
+	}
+	return nil
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	diff, err := client.Diff(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", err)
+	}
+
+	switch {
+	case !diff.InCloud && !diff.InState:
+		return fmt.Errorf("%s exists neither in the cloud nor in state", diff.Address)
+	case !diff.InState:
+		fmt.Printf("+ %s exists in the cloud but is not managed by Terraform\n", diff.Address)
+	case !diff.InCloud:
+		fmt.Printf("- %s is in state but was deleted from the cloud\n", diff.Address)
+	case len(diff.Changes) == 0:
+		fmt.Printf("%s is in sync\n", diff.Address)
+	default:
+		fmt.Printf("~ %s\n", diff.Address)
+		for _, change := range diff.Changes {
+			fmt.Printf("    %s: %q (state) -> %q (cloud)\n", change.Attribute, change.State, change.Cloud)
+		}
+	}
+	return nil
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	format, err := inventory.ParseFormat(exportFormat)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	items, err := client.Export(ctx)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	return inventory.Write(os.Stdout, format, items)
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	v, commit := version.Info()
+	fmt.Printf("infrasync %s", v)
+	if commit != "" {
+		fmt.Printf(" (commit %s)", commit)
+	}
+	fmt.Printf(" %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	fmt.Println("\nSupported resources:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, service := range google.Services {
+		for _, resourceType := range google.ResourceTypes(service) {
+			fmt.Fprintf(w, "  google\t%s\t%s\n", service, resourceType)
+		}
+	}
+	w.Flush()
+
+	if !checkUpdate {
+		return nil
+	}
+
+	latest, err := version.Latest(context.Background())
+	if err != nil {
+		return fmt.Errorf("update check failed: %w", err)
+	}
+	if version.Newer(latest, v) {
+		fmt.Printf("\nA newer version is available: %s\n", latest)
+	} else {
+		fmt.Println("\ninfrasync is up to date")
+	}
+	return nil
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	checks := doctor.Run(context.Background(), configLocations)
+
+	for _, check := range checks {
+		fmt.Printf("[%s] %s: %s\n", check.Status, check.Name, check.Detail)
+		if check.Fix != "" && check.Status != doctor.StatusOK {
+			fmt.Printf("       %s\n", check.Fix)
+		}
+	}
+
+	if doctor.Failed(checks) {
+		return fmt.Errorf("some checks failed")
+	}
+	return nil
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Rollback(ctx, rollbackRun); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	return nil
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	orphans, err := client.Orphans(ctx)
+	if err != nil {
+		return fmt.Errorf("finding orphaned files failed: %w", err)
+	}
+	if len(orphans) == 0 {
+		slog.Info("No orphaned files")
+		return nil
+	}
+
+	for _, orphan := range orphans {
+		fmt.Printf("%s\n", orphan.Path)
+		for _, address := range orphan.Addresses {
+			fmt.Printf("  %s\n", address)
+		}
+	}
+
+	if !pruneYes {
+		fmt.Printf("Delete %d file(s) and remove their resources from state? [y/N] ", len(orphans))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			slog.Info("Nothing was deleted")
+			return nil
+		}
+	}
+
+	if err := client.Prune(ctx, orphans); err != nil {
+		return fmt.Errorf("prune failed: %w", err)
+	}
+
+	return nil
+}
+
+func runLabelsApply(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.ApplyLabels(ctx); err != nil {
+		return fmt.Errorf("applying labels failed: %w", err)
+	}
+
+	return nil
+}
+
+func runCredentialsStore(cmd *cobra.Command, args []string) error {
+	var secret []byte
+	var err error
+	if len(args) == 2 {
+		secret, err = os.ReadFile(args[1])
+	} else {
+		secret, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if !json.Valid(secret) {
+		return fmt.Errorf("credentials are not JSON")
+	}
+
+	if err := keyring.Set(args[0], strings.TrimSpace(string(secret))); err != nil {
+		return err
+	}
+	fmt.Printf("Stored credentials under %s, use them with credentials: {keyring: %s}\n", args[0], args[0])
+	return nil
+}
+
+func runCredentialsDelete(cmd *cobra.Command, args []string) error {
+	if err := keyring.Delete(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted credentials under %s\n", args[0])
 	return nil
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
-	client := infrasync.NewClient(cfg)
-	
-	if err := client.Initialize(ctx); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if noGit {
+		cfg.Git.Enabled = false
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Initialize(ctx, initOpts); err != nil {
 		return fmt.Errorf("initialization failed: %w", err)
 	}
-	
+
 	slog.Info("Next steps:")
 	slog.Info("1. Review and edit the generated files")
 	slog.Info("2. Run 'infrasync import' to import existing resources")
 	slog.Info("3. Run 'terraform init' and 'terraform apply' to apply the configuration")
-	
+
 	return nil
-}
\ No newline at end of file
+}
@@ -11,51 +11,57 @@ import (
 )
 
 func main() {
-	// Load a custom config
-	cfg := config.Config{
-		// Set your custom configuration here
-		// Or load from a file
+	// Create a client with the config at the default path
+	client, err := infrasync.New()
+	if err != nil {
+		log.Fatalf("Error creating client: %v", err)
 	}
 
-	// Or load the default config
-	var err error
-	cfg, err = config.Load()
+	// Or pass a custom config and override settings with options
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
-
-	// Create a new client
-	client := infrasync.NewClient(cfg)
-
-	// Or use the default client (loads config from default location)
-	defaultClient, err := infrasync.DefaultClient()
+	customClient, err := infrasync.New(
+		infrasync.WithConfig(cfg),
+		infrasync.WithParallelism(4),
+	)
 	if err != nil {
-		log.Fatalf("Error creating default client: %v", err)
+		log.Fatalf("Error creating client: %v", err)
 	}
 
 	// Context for operations
 	ctx := context.Background()
 
 	// Initialize a new project
-	if err := client.Initialize(ctx); err != nil {
+	if err := client.Initialize(ctx, infrasync.InitOptions{}); err != nil {
 		log.Fatalf("Error initializing project: %v", err)
 	}
 
 	// Import all configured services
-	if err := client.Import(ctx); err != nil {
+	result, err := client.Import(ctx)
+	if err != nil {
 		log.Fatalf("Error importing resources: %v", err)
 	}
+	for _, s := range result.Services {
+		fmt.Printf("%s: %d imported, %d skipped\n", s.Service, s.Imported, s.Skipped)
+	}
+
+	// Detect drift and import resources which are not codified yet
+	if err := client.Sync(ctx, infrasync.SyncOptions{}); err != nil {
+		log.Fatalf("Error syncing resources: %v", err)
+	}
 
 	// Or import specific services
-	if err := defaultClient.ImportPubSub(ctx); err != nil {
+	if _, err := customClient.ImportPubSub(ctx); err != nil {
 		log.Fatalf("Error importing PubSub resources: %v", err)
 	}
 
-	if err := defaultClient.ImportCloudSQL(ctx); err != nil {
+	if _, err := customClient.ImportCloudSQL(ctx); err != nil {
 		log.Fatalf("Error importing CloudSQL resources: %v", err)
 	}
 
-	if err := defaultClient.ImportStorage(ctx); err != nil {
+	if _, err := customClient.ImportStorage(ctx); err != nil {
 		log.Fatalf("Error importing Storage resources: %v", err)
 	}
 
@@ -63,7 +69,7 @@ func main() {
 	// Note: Individual resource import is not fully implemented yet
 	// Currently this will import all resources of the specified service
 	bucketName := "my-bucket"
-	if err := defaultClient.ImportSingleResource(ctx, "storage", "google_storage_bucket", bucketName); err != nil {
+	if _, err := customClient.ImportSingleResource(ctx, "storage", "google_storage_bucket", bucketName); err != nil {
 		log.Fatalf("Error importing specific bucket: %v", err)
 	}
 	
@@ -0,0 +1,260 @@
+// Package audit records every mutation of an import or sync run, such as files
+// written, commands run, state changed and drift remediated, to an append-only
+// JSONL log so that runs on production repositories can be reviewed
+// afterwards.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+var (
+	KindFile    Kind = "file"
+	KindCommand Kind = "command"
+	KindState   Kind = "state"
+	KindCloud   Kind = "cloud"
+)
+
+type Action string
+
+var (
+	ActionCreate Action = "create"
+	ActionModify Action = "modify"
+	ActionDelete Action = "delete"
+	ActionRun    Action = "run"
+	ActionImport Action = "import"
+	ActionLock   Action = "lock"
+	ActionUnlock Action = "unlock"
+)
+
+type Event struct {
+	Time     time.Time `json:"time"`
+	RunID    string    `json:"run_id"`
+	Kind     Kind      `json:"kind"`
+	Action   Action    `json:"action"`
+	Path     string    `json:"path,omitempty"`
+	Dir      string    `json:"dir,omitempty"`
+	Args     []string  `json:"args,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Address  string    `json:"address,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Log appends events to a JSONL file
+type Log struct {
+	mu    sync.Mutex
+	file  *os.File
+	runID string
+}
+
+var defaultLog *Log
+
+// Open opens the audit log at path for a new run, creating it if needed
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &Log{file: file, runID: newRunID()}, nil
+}
+
+func newRunID() string {
+	return time.Now().UTC().Format("20060102T150405.000Z")
+}
+
+func (l *Log) RunID() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.runID
+}
+
+// StartRun gives the events recorded from now on a new run ID and returns it.
+// Long-running processes such as the daemon start a run for every import or
+// sync, so that each can be reviewed and rolled back on its own.
+func (l *Log) StartRun() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.runID = newRunID()
+	return l.runID
+}
+
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// Record appends event to the log and syncs it to disk, so that events
+// survive a crash of the run they describe
+func (l *Log) Record(event Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Time = time.Now().UTC()
+	event.RunID = l.runID
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return nil
+}
+
+// SetDefault makes l the log the package-level functions record to. Without
+// a default log nothing is recorded.
+func SetDefault(l *Log) {
+	defaultLog = l
+}
+
+// RunID returns the ID of the run recorded by the default log, or "" if there
+// is none
+func RunID() string {
+	if defaultLog == nil {
+		return ""
+	}
+	return defaultLog.RunID()
+}
+
+// record records event to the default log. The change it describes has
+// already been made, so failures are logged rather than returned.
+// StartRun starts a new run in the default log and returns its ID, or "" if
+// there is no default log
+func StartRun() string {
+	if defaultLog == nil {
+		return ""
+	}
+	return defaultLog.StartRun()
+}
+
+func record(event Event) {
+	if defaultLog == nil {
+		return
+	}
+	if err := defaultLog.Record(event); err != nil {
+		slog.Error("Failed to record audit event", "kind", event.Kind, "action", event.Action, "error", err)
+	}
+}
+
+// WriteFile writes a file like os.WriteFile and records its creation or
+// modification
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	action := ActionModify
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		action = ActionCreate
+	}
+
+	err := os.WriteFile(path, data, perm)
+	File(action, path, err)
+	return err
+}
+
+// Remove removes a file like os.Remove and records its deletion
+func Remove(path string) error {
+	err := os.Remove(path)
+	File(ActionDelete, path, err)
+	return err
+}
+
+// File records a change to a file made without the helpers above
+func File(action Action, path string, err error) {
+	if abs, absErr := filepath.Abs(path); absErr == nil {
+		path = abs
+	}
+	record(Event{Kind: KindFile, Action: action, Path: path, Error: errorString(err)})
+}
+
+// Run runs cmd like cmd.Run and records its arguments and exit code
+func Run(cmd *exec.Cmd) error {
+	err := cmd.Run()
+	Command(cmd.Path, cmd.Dir, cmd.Args, err)
+	return err
+}
+
+// Command records a command run by a library rather than with Run. args
+// starts with the program, like exec.Cmd.Args.
+func Command(path, dir string, args []string, err error) {
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+
+	record(Event{
+		Kind:     KindCommand,
+		Action:   ActionRun,
+		Path:     path,
+		Dir:      dir,
+		Args:     args,
+		ExitCode: &exitCode,
+		Error:    errorString(err),
+	})
+}
+
+// State records a change to the state of a resource address, or of the whole
+// state if address is empty
+func State(action Action, address string, err error) {
+	record(Event{Kind: KindState, Action: action, Address: address, Error: errorString(err)})
+}
+
+// Cloud records a change made to a cloud resource through its API, such as a
+// remediation of drift
+func Cloud(action Action, address string, err error) {
+	record(Event{Kind: KindCloud, Action: action, Address: address, Error: errorString(err)})
+}
+
+// Read returns the events recorded by the run with the given ID in the log at
+// path, in the order they were recorded
+func Read(path, runID string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode audit log entry: %w", err)
+		}
+		if event.RunID == runID {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return events, nil
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,43 @@
+// Package binary resolves the external programs infrasync runs on the current
+// platform.
+package binary
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+)
+
+const (
+	Terraform = "terraform"
+	GCloud    = "gcloud"
+	OPA       = "opa"
+	Docker    = "docker"
+	SOPS      = "sops"
+	Age       = "age"
+)
+
+// Name returns the file name of program on the current platform. On Windows
+// gcloud is installed as a batch script and the others as executables, and
+// naming them explicitly keeps a same-named file without extension from
+// being picked up.
+func Name(program string) string {
+	if runtime.GOOS != "windows" {
+		return program
+	}
+	if program == GCloud {
+		return program + ".cmd"
+	}
+	return program + ".exe"
+}
+
+// LookPath returns the path of program in PATH
+func LookPath(program string) (string, error) {
+	return exec.LookPath(Name(program))
+}
+
+// CommandContext returns a command running program with args, like
+// exec.CommandContext
+func CommandContext(ctx context.Context, program string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, Name(program), args...)
+}
@@ -0,0 +1,18 @@
+package binary
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := map[string]string{Terraform: "terraform", GCloud: "gcloud", OPA: "opa"}
+	if runtime.GOOS == "windows" {
+		tests = map[string]string{Terraform: "terraform.exe", GCloud: "gcloud.cmd", OPA: "opa.exe"}
+	}
+	for program, want := range tests {
+		if got := Name(program); got != want {
+			t.Errorf("Name(%q) = %q, want %q", program, got, want)
+		}
+	}
+}
@@ -1,37 +1,280 @@
 package config
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
 
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/internal/history"
 	"github.com/priyanshujain/infrasync/internal/providers"
 	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/tfimport"
+	"github.com/priyanshujain/infrasync/pkg/resource"
 	"gopkg.in/yaml.v3"
 )
 
 type cfg struct {
-	Name      string `yaml:"name"`
-	Path      string `yaml:"path"`
-	Providers map[string]struct {
-		Projects []struct {
-			ID       string   `yaml:"id"`
-			Region   string   `yaml:"region"`
-			Services []string `yaml:"services"`
-		} `yaml:"projects"`
-		Credentials string `yaml:"credentials,omitempty"`
-	} `yaml:"providers"`
-	Backend struct {
-		Type       string `yaml:"type"`
-		BucketName string `yaml:"bucket"`
+	Name      string                 `yaml:"name"`
+	Path      string                 `yaml:"path"`
+	Providers map[string]providerCfg `yaml:"providers"`
+	Backend   struct {
+		Type          string `yaml:"type"`
+		BucketName    string `yaml:"bucket"`
+		Prefix        string `yaml:"prefix,omitempty"`
+		Hostname      string `yaml:"hostname,omitempty"`
+		Organization  string `yaml:"organization,omitempty"`
+		Workspace     string `yaml:"workspace,omitempty"`
+		Address       string `yaml:"address,omitempty"`
+		LockAddress   string `yaml:"lock_address,omitempty"`
+		UnlockAddress string `yaml:"unlock_address,omitempty"`
+		LockMethod    string `yaml:"lock_method,omitempty"`
+		UnlockMethod  string `yaml:"unlock_method,omitempty"`
 	} `yaml:"backend"`
+	Normalize []struct {
+		Type    string            `yaml:"type"`
+		Remove  []string          `yaml:"remove"`
+		Rewrite map[string]string `yaml:"rewrite"`
+	} `yaml:"normalize,omitempty"`
+	Lifecycle []struct {
+		Type           string   `yaml:"type"`
+		Name           string   `yaml:"name,omitempty"`
+		PreventDestroy bool     `yaml:"prevent_destroy"`
+		IgnoreChanges  []string `yaml:"ignore_changes"`
+	} `yaml:"lifecycle,omitempty"`
+	Git struct {
+		Enabled       *bool  `yaml:"enabled,omitempty"`
+		DefaultBranch string `yaml:"default_branch,omitempty"`
+		AuthorName    string `yaml:"author_name,omitempty"`
+		AuthorEmail   string `yaml:"author_email,omitempty"`
+		CommitImports string `yaml:"commit_imports,omitempty"`
+	} `yaml:"git,omitempty"`
+	Drift struct {
+		Ignore []struct {
+			Resource   string   `yaml:"resource"`
+			Attributes []string `yaml:"attributes,omitempty"`
+		} `yaml:"ignore,omitempty"`
+		FailOn          []string       `yaml:"fail_on,omitempty"`
+		PROn            []string       `yaml:"pr_on,omitempty"`
+		History         string         `yaml:"history,omitempty"`
+		EstimateCost    bool           `yaml:"estimate_cost,omitempty"`
+		Remediate       []string       `yaml:"remediate,omitempty"`
+		UnmanagedBudget map[string]int `yaml:"unmanaged_budget,omitempty"`
+	} `yaml:"drift,omitempty"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+	Exclude struct {
+		Defaults  *bool `yaml:"defaults,omitempty"`
+		Resources []struct {
+			Type string `yaml:"type"`
+			ID   string `yaml:"id"`
+		} `yaml:"resources,omitempty"`
+		Members []string `yaml:"members,omitempty"`
+	} `yaml:"exclude,omitempty"`
+	Dependents struct {
+		MaxDepth *int `yaml:"max_depth,omitempty"`
+		SkipIAM  bool `yaml:"skip_iam,omitempty"`
+	} `yaml:"dependents,omitempty"`
+	Templates []struct {
+		Type string `yaml:"type"`
+		Name string `yaml:"name,omitempty"`
+		Path string `yaml:"path"`
+	} `yaml:"templates,omitempty"`
+	Mappings []struct {
+		Type     string `yaml:"type,omitempty"`
+		ID       string `yaml:"id"`
+		Override struct {
+			Type string `yaml:"type,omitempty"`
+			Name string `yaml:"name,omitempty"`
+			ID   string `yaml:"id,omitempty"`
+		} `yaml:"override"`
+	} `yaml:"mappings,omitempty"`
+	Policy struct {
+		Path    string `yaml:"path"`
+		Enforce bool   `yaml:"enforce,omitempty"`
+	} `yaml:"policy,omitempty"`
+	Audit struct {
+		Path string `yaml:"path,omitempty"`
+	} `yaml:"audit,omitempty"`
+	Output struct {
+		Layout     string `yaml:"layout,omitempty"`
+		FileLayout string `yaml:"file_layout,omitempty"`
+		// ExtractVariables is how many resources a literal must repeat in
+		// to be factored into a variable, 0 to keep literals
+		ExtractVariables int `yaml:"extract_variables,omitempty"`
+	} `yaml:"output,omitempty"`
+	Runner      string `yaml:"runner,omitempty"`
+	RunnerImage string `yaml:"runner_image,omitempty"`
 }
 
+type providerCfg struct {
+	Projects    []projectCfg   `yaml:"projects"`
+	Credentials credentialsCfg `yaml:"credentials,omitempty"`
+	// APIEndpoint is the private or restricted VIP, or the name of a Private
+	// Service Connect endpoint, API clients connect to
+	APIEndpoint string `yaml:"api_endpoint,omitempty"`
+}
+
+type projectCfg struct {
+	ID          string         `yaml:"id"`
+	Region      string         `yaml:"region"`
+	Regions     []string       `yaml:"regions,omitempty"`
+	Services    []serviceCfg   `yaml:"services"`
+	Credentials credentialsCfg `yaml:"credentials,omitempty"`
+}
+
+// credentialsCfg is either the path of a credentials file or a map:
+//
+//	credentials:
+//	  file: sa.json                  # or json: '{"type": "service_account", ...}'
+//	  impersonate_service_account: deployer@project.iam.gserviceaccount.com
+type credentialsCfg struct {
+	File string `yaml:"file,omitempty"`
+	JSON string `yaml:"json,omitempty"`
+	// Keyring is the alias the credentials are stored under in the
+	// keychain of the operating system, which are read into JSON
+	Keyring                   string `yaml:"keyring,omitempty"`
+	ImpersonateServiceAccount string `yaml:"impersonate_service_account,omitempty"`
+}
+
+// sources counts how many of file, json and keyring are set
+func (c credentialsCfg) sources() int {
+	n := 0
+	for _, value := range []string{c.File, c.JSON, c.Keyring} {
+		if value != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *credentialsCfg) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.File = value.Value
+		return nil
+	}
+	type plain credentialsCfg
+	return value.Decode((*plain)(c))
+}
+
+func (c credentialsCfg) IsZero() bool {
+	return c == credentialsCfg{}
+}
+
+func (c credentialsCfg) credentials() providers.Credentials {
+	return providers.Credentials{
+		File:                      c.File,
+		JSON:                      c.JSON,
+		ImpersonateServiceAccount: c.ImpersonateServiceAccount,
+	}
+}
+
+// projectCredentials returns the credentials of a project, which replace
+// those of its provider if set. User credentials bill the project for quota.
+func projectCredentials(provider providerCfg, project projectCfg) providers.Credentials {
+	creds := provider.Credentials.credentials()
+	if !project.Credentials.IsZero() {
+		creds = project.Credentials.credentials()
+	}
+	creds.QuotaProject = project.ID
+	return creds
+}
+
+// serviceCfg is either a plain service name or a single-key map from the
+// service name to its options:
+//
+//	services:
+//	  - pubsub
+//	  - storage:
+//	      include_iam: false
+type serviceCfg struct {
+	Name    string
+	Options *yaml.Node
+}
+
+func (s *serviceCfg) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		s.Name = value.Value
+		return nil
+	case yaml.MappingNode:
+		if len(value.Content) != 2 {
+			return fmt.Errorf("line %d: service options must be a map with a single service name", value.Line)
+		}
+		s.Name = value.Content[0].Value
+		s.Options = value.Content[1]
+		return nil
+	default:
+		return fmt.Errorf("line %d: invalid service", value.Line)
+	}
+}
+
+// decodeOptions decodes the options of the service into the typed defaults
+// of the importer, rejecting unknown fields.
+func (s serviceCfg) decodeOptions() (any, error) {
+	opts := google.DefaultServiceOptions(resource.Service(s.Name))
+	if s.Options == nil {
+		return opts, nil
+	}
+	if opts == nil {
+		return nil, fmt.Errorf("service %s takes no options", s.Name)
+	}
+
+	data, err := yaml.Marshal(s.Options)
+	if err != nil {
+		return nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(opts); err != nil {
+		return nil, fmt.Errorf("invalid options for service %s: %w", s.Name, err)
+	}
+	return opts, nil
+}
+
+// GitConfig controls how init records the generated repository in git.
+type GitConfig struct {
+	Enabled       bool
+	DefaultBranch string
+	AuthorName    string
+	AuthorEmail   string
+	// CommitImports commits imported resources once per service or per run,
+	// see CommitPerService and CommitPerRun. Empty leaves them uncommitted.
+	CommitImports string
+}
+
+const (
+	CommitPerService = "per-service"
+	CommitPerRun     = "per-run"
+)
+
 type Config struct {
 	Name      string
 	Path      string
 	Providers []providers.Provider
-	cfg       cfg
+	Git       GitConfig
+	// Parallelism overrides the parallelism of every service when set
+	Parallelism int
+	// ForceUnlock breaks locks held by other runs instead of failing
+	ForceUnlock bool
+	// Force overwrites generated files even if they were changed since they
+	// were generated
+	Force bool
+	cfg   cfg
+	// projects are the IDs of every configured project, which ForProject
+	// keeps
+	projects []string
+	// templates are the parsed generation templates
+	templates []tfimport.TemplateRule
+	// excludeFilter and mappings have their patterns compiled
+	excludeFilter google.Filter
+	mappings      google.Mappings
 }
 
 func Load() (Config, error) {
@@ -57,27 +300,49 @@ func Load() (Config, error) {
 	if err != nil {
 		return Config{}, fmt.Errorf("error reading config file: %w", err)
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), validationTimeout)
+	defer cancel()
+	data, err = decryptSOPS(ctx, path, data, "yaml")
+	if err != nil {
+		return Config{}, err
+	}
 
 	var config cfg
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return Config{}, fmt.Errorf("error parsing config file: %w", err)
 	}
 
+	return build(config)
+}
+
+// build validates a parsed config file and resolves its providers
+func build(config cfg) (Config, error) {
 	if err := validateConfig(&config); err != nil {
 		return Config{}, err
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), validationTimeout)
+	defer cancel()
+	if err := decryptAllCredentials(ctx, &config); err != nil {
+		return Config{}, err
+	}
+
 	var ps []providers.Provider
+	var projects []string
 	for name, provider := range config.Providers {
 		if providers.ProviderTypeGoogle.String() != name {
 			return Config{}, fmt.Errorf("unsupported provider: %s", name)
 		}
 		for _, project := range provider.Projects {
 			ps = append(ps, providers.Provider{
-				Type:      providers.ProviderTypeGoogle,
-				ProjectID: project.ID,
-				Region:    project.Region,
+				Type:        providers.ProviderTypeGoogle,
+				ProjectID:   project.ID,
+				Region:      project.Region,
+				Regions:     project.Regions,
+				Credentials: projectCredentials(provider, project),
+				APIEndpoint: provider.APIEndpoint,
 			})
+			projects = append(projects, project.ID)
 		}
 	}
 
@@ -85,7 +350,32 @@ func Load() (Config, error) {
 		Name:      config.Name,
 		Path:      config.Path,
 		Providers: ps,
-		cfg:       config,
+		Git: GitConfig{
+			Enabled:       config.Git.Enabled == nil || *config.Git.Enabled,
+			DefaultBranch: config.Git.DefaultBranch,
+			AuthorName:    config.Git.AuthorName,
+			AuthorEmail:   config.Git.AuthorEmail,
+			CommitImports: config.Git.CommitImports,
+		},
+		cfg:      config,
+		projects: projects,
+	}
+
+	for _, rule := range config.Templates {
+		t, err := tfimport.ParseTemplate(rule.Path)
+		if err != nil {
+			return Config{}, fmt.Errorf("template %s: %w", rule.Path, err)
+		}
+		c.templates = append(c.templates, tfimport.TemplateRule{ResourceType: rule.Type, Name: rule.Name, Template: t})
+	}
+
+	filter, err := excludeFilter(config)
+	if err != nil {
+		return Config{}, fmt.Errorf("exclude: %w", err)
+	}
+	c.excludeFilter = filter
+	if c.mappings, err = mappings(config); err != nil {
+		return Config{}, err
 	}
 
 	if err := c.validateGoogleCredentials(); err != nil {
@@ -109,18 +399,141 @@ func validateConfig(config *cfg) error {
 		return fmt.Errorf("no providers configured")
 	}
 
+	for _, rule := range config.Normalize {
+		if rule.Type == "" {
+			return fmt.Errorf("normalize rule has no type")
+		}
+	}
+
+	for _, name := range append(append([]string{}, config.Drift.FailOn...), config.Drift.PROn...) {
+		if _, err := drift.ParseClass(name); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range config.Drift.Remediate {
+		if !slices.Contains(google.RemediableTypes(), resource.Type(t)) {
+			return fmt.Errorf("drift of %s can't be remediated (supported: %v)", t, google.RemediableTypes())
+		}
+	}
+
+	for key, limit := range config.Drift.UnmanagedBudget {
+		if err := drift.ValidateBudgetKey(key); err != nil {
+			return err
+		}
+		if limit < 0 {
+			return fmt.Errorf("unmanaged budget of %s is negative", key)
+		}
+	}
+
+	switch config.Drift.History {
+	case "", "local":
+	case "bucket":
+		if config.Backend.Type != "" && providers.BackendType(config.Backend.Type) != providers.BackendTypeGCS {
+			return fmt.Errorf("drift history can only be stored in the bucket of a gcs backend")
+		}
+	default:
+		return fmt.Errorf("unsupported drift history store: %s", config.Drift.History)
+	}
+
+	for _, rule := range config.Exclude.Resources {
+		if rule.Type == "" || rule.ID == "" {
+			return fmt.Errorf("exclude rule needs a type and an id")
+		}
+	}
+
+	if depth := config.Dependents.MaxDepth; depth != nil && *depth < 0 {
+		return fmt.Errorf("dependents max_depth must not be negative")
+	}
+
+	for _, mapping := range config.Mappings {
+		if mapping.ID == "" {
+			return fmt.Errorf("mapping needs an id")
+		}
+		if _, err := regexp.Compile(mapping.ID); err != nil {
+			return fmt.Errorf("mapping id %s: %w", mapping.ID, err)
+		}
+		if mapping.Override.Type == "" && mapping.Override.Name == "" && mapping.Override.ID == "" {
+			return fmt.Errorf("mapping %s overrides nothing", mapping.ID)
+		}
+	}
+
+	if config.Policy.Path != "" {
+		if _, err := os.Stat(config.Policy.Path); err != nil {
+			return fmt.Errorf("policy path %s: %w", config.Policy.Path, err)
+		}
+	}
+
+	for _, rule := range config.Lifecycle {
+		if rule.Type == "" {
+			return fmt.Errorf("lifecycle rule has no type")
+		}
+	}
+
+	for _, rule := range config.Templates {
+		if rule.Type == "" || rule.Path == "" {
+			return fmt.Errorf("template needs a type and a path")
+		}
+	}
+
+	switch config.Git.CommitImports {
+	case "", CommitPerService, CommitPerRun:
+	default:
+		return fmt.Errorf("unsupported git.commit_imports: %s (supported: per-service, per-run)", config.Git.CommitImports)
+	}
+
+	runner, err := tfimport.ParseRunner(config.Runner)
+	if err != nil {
+		return err
+	}
+	if config.RunnerImage != "" && runner != tfimport.RunnerDocker {
+		return fmt.Errorf("runner_image needs runner: docker")
+	}
+
+	if config.Output.Layout != "" {
+		if err := tfimport.ValidateLayout(config.Output.Layout); err != nil {
+			return err
+		}
+	}
+	if config.Output.FileLayout != "" {
+		if config.Output.Layout != "" {
+			return fmt.Errorf("output.layout and output.file_layout cannot both be set")
+		}
+		if _, err := tfimport.FileLayoutPath(tfimport.FileLayout(config.Output.FileLayout)); err != nil {
+			return err
+		}
+	}
+
+	if config.Output.ExtractVariables < 0 || config.Output.ExtractVariables == 1 {
+		return fmt.Errorf("output.extract_variables must be at least 2, or 0 to keep literals")
+	}
+
 	for name, provider := range config.Providers {
 		if len(provider.Projects) == 0 {
 			return fmt.Errorf("provider %s has no projects configured", name)
 		}
+		if err := google.ValidateAPIEndpoint(provider.APIEndpoint); err != nil {
+			return err
+		}
+		if provider.Credentials.sources() > 1 {
+			return fmt.Errorf("credentials of provider %s have more than one of file, json and keyring", name)
+		}
 
 		for _, project := range provider.Projects {
 			if project.ID == "" {
 				return fmt.Errorf("project in provider %s has no ID", name)
 			}
+			if project.Credentials.sources() > 1 {
+				return fmt.Errorf("credentials of project %s have more than one of file, json and keyring", project.ID)
+			}
 			if len(project.Services) == 0 {
 				return fmt.Errorf("project %s in provider %s has no services configured", project.ID, name)
 			}
+			for _, service := range project.Services {
+				if _, err := service.decodeOptions(); err != nil {
+					return fmt.Errorf("project %s: %w", project.ID, err)
+				}
+			}
 		}
 	}
 	return nil
@@ -150,20 +563,115 @@ func defaultConfigPath() (string, error) {
 	return path, nil
 }
 
-func (c *Config) GoogleServices(p providers.Provider) []google.Service {
-	var services []google.Service
+func (c *Config) GoogleServices(p providers.Provider) []resource.Service {
+	var services []resource.Service
 	for _, project := range c.cfg.Providers[p.Type.String()].Projects {
+		if p.ProjectID != "" && project.ID != p.ProjectID {
+			continue
+		}
 		for _, service := range project.Services {
-			services = append(services, google.Service(service))
+			services = append(services, resource.Service(service.Name))
 		}
 	}
 	return services
 }
 
+// ServiceOptions returns the typed options of a service for the project of
+// provider, see google.DefaultServiceOptions. Options are validated on load.
+func (c *Config) ServiceOptions(p providers.Provider, s resource.Service) any {
+	opts := google.DefaultServiceOptions(s)
+	for _, project := range c.cfg.Providers[p.Type.String()].Projects {
+		if p.ProjectID != "" && project.ID != p.ProjectID {
+			continue
+		}
+		for _, service := range project.Services {
+			if service.Name == s.String() {
+				opts, _ = service.decodeOptions()
+			}
+		}
+	}
+
+	if c.Parallelism > 0 {
+		switch opts := opts.(type) {
+		case *google.PubSubOptions:
+			opts.Parallelism = c.Parallelism
+		case *google.StorageOptions:
+			opts.Parallelism = c.Parallelism
+		}
+	}
+	return opts
+}
+
+// ForProject returns a copy of the config restricted to a single google project
+// and the given services. The regions are taken from the configured project, if any.
+func (c Config) ForProject(projectID string, services []string) (Config, error) {
+	if projectID == "" {
+		return Config{}, fmt.Errorf("project ID is required")
+	}
+	if len(services) == 0 {
+		return Config{}, fmt.Errorf("no services given for project %s", projectID)
+	}
+
+	name := providers.ProviderTypeGoogle.String()
+	googleCfg := c.cfg.Providers[name]
+
+	project := projectCfg{ID: projectID}
+	for _, service := range services {
+		project.Services = append(project.Services, serviceCfg{Name: service})
+	}
+	for _, p := range googleCfg.Projects {
+		if p.ID != projectID {
+			continue
+		}
+		project.Region = p.Region
+		project.Regions = p.Regions
+		project.Credentials = p.Credentials
+		// Keep the options of services which are configured for the project
+		for i, service := range project.Services {
+			for _, configured := range p.Services {
+				if configured.Name == service.Name {
+					project.Services[i] = configured
+				}
+			}
+		}
+	}
+
+	out := c
+	out.cfg.Providers = map[string]providerCfg{
+		name: {Projects: []projectCfg{project}, Credentials: googleCfg.Credentials, APIEndpoint: googleCfg.APIEndpoint},
+	}
+	out.Providers = []providers.Provider{{
+		Type:        providers.ProviderTypeGoogle,
+		ProjectID:   project.ID,
+		Region:      project.Region,
+		Regions:     project.Regions,
+		Credentials: projectCredentials(googleCfg, project),
+		APIEndpoint: googleCfg.APIEndpoint,
+	}}
+	return out, nil
+}
+
 func (c *Config) ProjectPath() string {
 	return filepath.Join(c.Path, c.Name)
 }
 
+// Hash returns the SHA-256 of the effective config, merged from every config
+// file, which tells runs made with different configs apart
+func (c *Config) Hash() string {
+	data, err := yaml.Marshal(c.cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ProjectIDs returns the IDs of every configured project, including those a
+// config restricted with ForProject leaves out
+func (c *Config) ProjectIDs() []string {
+	return c.projects
+}
+
 func (c *Config) DefaultProvider() providers.Provider {
 	if len(c.Providers) == 0 {
 		return providers.Provider{}
@@ -176,35 +684,336 @@ func (c *Config) DefaultBackend() providers.Backend {
 		return providers.Backend{}
 	}
 
+	if providers.BackendType(c.cfg.Backend.Type) == providers.BackendTypeHTTP {
+		return providers.Backend{
+			Type:          providers.BackendTypeHTTP,
+			Address:       c.cfg.Backend.Address,
+			LockAddress:   c.cfg.Backend.LockAddress,
+			UnlockAddress: c.cfg.Backend.UnlockAddress,
+			LockMethod:    c.cfg.Backend.LockMethod,
+			UnlockMethod:  c.cfg.Backend.UnlockMethod,
+		}
+	}
+
+	if providers.BackendType(c.cfg.Backend.Type) == providers.BackendTypeRemote {
+		return providers.Backend{
+			Type:         providers.BackendTypeRemote,
+			Hostname:     c.cfg.Backend.Hostname,
+			Organization: c.cfg.Backend.Organization,
+			Workspace:    c.cfg.Backend.Workspace,
+		}
+	}
+
 	return providers.Backend{
-		Type:   providers.BackendTypeGCS,
-		Bucket: c.cfg.Backend.BucketName,
+		Type:        providers.BackendTypeGCS,
+		Bucket:      c.cfg.Backend.BucketName,
+		Prefix:      c.cfg.Backend.Prefix,
+		Credentials: c.DefaultProvider().Credentials,
+		APIEndpoint: c.DefaultProvider().APIEndpoint,
 	}
 }
 
-func (c *Config) validateGoogleCredentials() error {
+// NormalizeRules returns the built-in attribute normalization rules followed by
+// the ones configured by the user, so user rules take precedence.
+func (c *Config) NormalizeRules() []tfimport.NormalizeRule {
+	rules := append([]tfimport.NormalizeRule{}, tfimport.DefaultNormalizeRules...)
+	for _, rule := range c.cfg.Normalize {
+		rules = append(rules, tfimport.NormalizeRule{
+			ResourceType: rule.Type,
+			Remove:       rule.Remove,
+			Rewrite:      rule.Rewrite,
+		})
+	}
+	return rules
+}
 
-	path := c.cfg.Providers[providers.ProviderTypeGoogle.String()].Credentials
-	if path != "" {
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			return fmt.Errorf("failed to get absolute path: %w", err)
+func (c *Config) LifecycleRules() []tfimport.LifecycleRule {
+	rules := append([]tfimport.LifecycleRule{}, tfimport.DefaultLifecycleRules...)
+	for _, rule := range c.cfg.Lifecycle {
+		rules = append(rules, tfimport.LifecycleRule{
+			ResourceType:   rule.Type,
+			Name:           rule.Name,
+			PreventDestroy: rule.PreventDestroy,
+			IgnoreChanges:  rule.IgnoreChanges,
+		})
+	}
+	return rules
+}
+
+func (c *Config) DriftIgnoreRules() []drift.IgnoreRule {
+	var rules []drift.IgnoreRule
+	for _, rule := range c.cfg.Drift.Ignore {
+		rules = append(rules, drift.IgnoreRule{
+			Resource:   rule.Resource,
+			Attributes: rule.Attributes,
+		})
+	}
+	return rules
+}
+
+// DriftFailClasses returns the drift classes which make sync exit nonzero.
+// None are configured by default.
+func (c *Config) DriftFailClasses() []drift.Class {
+	return parseClasses(c.cfg.Drift.FailOn, nil)
+}
+
+// DriftPRClasses returns the drift classes for which sync opens a pull
+// request, all of them by default.
+func (c *Config) DriftPRClasses() []drift.Class {
+	return parseClasses(c.cfg.Drift.PROn, drift.AllClasses)
+}
+
+// DriftHistory returns where sync records drift. Records are stored next to
+// the state in the GCS backend bucket unless drift.history is "local", or
+// there is no bucket, which keeps them in the project directory.
+//
+// The project directory doesn't outlive a CI job, so in CI an explicitly local
+// history is an error and a defaulted one is disabled, returning nil.
+func (c *Config) DriftHistory() (history.Store, error) {
+	backend := c.DefaultBackend()
+	mode := c.cfg.Drift.History
+	if mode == "" && backend.Bucket != "" {
+		mode = "bucket"
+	}
+
+	if mode == "bucket" {
+		return history.NewGCS(backend.Bucket, "infrasync/drift", backend.Credentials, backend.APIEndpoint), nil
+	}
+
+	if os.Getenv("CI") != "" {
+		if mode == "local" {
+			return nil, fmt.Errorf("drift.history: local is lost after every CI run, use bucket with a gcs backend")
 		}
+		return nil, nil
+	}
+	return history.NewLocal(filepath.Join(c.ProjectPath(), ".infrasync", "drift")), nil
+}
+
+// AuditLogPath returns the append-only log of every file, command and state
+// change made by infrasync in the project. It defaults to a file in the
+// gitignored .infrasync directory; CI runs should set audit.path to a
+// location which outlives the job.
+func (c *Config) AuditLogPath() string {
+	if c.cfg.Audit.Path != "" {
+		return c.cfg.Audit.Path
+	}
+	return filepath.Join(c.ProjectPath(), ".infrasync", "audit.jsonl")
+}
+
+// LockPath returns the lock file which keeps runs from writing to the project
+// at the same time
+func (c *Config) LockPath() string {
+	return filepath.Join(c.ProjectPath(), ".infrasync", "lock")
+}
 
-		if _, err := os.Stat(absPath); os.IsNotExist(err) {
-			return fmt.Errorf("credentials file does not exist: %s", absPath)
+// ManifestPath returns the manifest of the files generated by infrasync and
+// the resources they hold. Unlike the rest of the .infrasync directory it is
+// committed, so that fresh clones in CI know the generated files too.
+func (c *Config) ManifestPath() string {
+	return filepath.Join(c.ProjectPath(), ".infrasync", "manifest.json")
+}
+
+// EstimateCost reports whether sync estimates the monthly cost of unmanaged
+// resources with the Cloud Billing Catalog API.
+func (c *Config) EstimateCost() bool {
+	return c.cfg.Drift.EstimateCost
+}
+
+// UnmanagedBudget returns the number of unmanaged resources allowed per
+// service or resource type before sync fails. Nothing is capped by default.
+func (c *Config) UnmanagedBudget() drift.Budget {
+	return drift.Budget(c.cfg.Drift.UnmanagedBudget)
+}
+
+// RemediateTypes returns the resource types whose drift sync --remediate
+// reverts in the cloud. None are selected by default.
+func (c *Config) RemediateTypes() []resource.Type {
+	var types []resource.Type
+	for _, t := range c.cfg.Drift.Remediate {
+		types = append(types, resource.Type(t))
+	}
+	return types
+}
+
+// ExcludeFilter returns the filter dropping Google-managed and user excluded
+// resources from discovery. The built-in rules apply unless exclude.defaults
+// is false.
+func (c *Config) ExcludeFilter() google.Filter {
+	return c.excludeFilter
+}
+
+func excludeFilter(config cfg) (google.Filter, error) {
+	var rules []google.ExcludeRule
+	var members []string
+	if config.Exclude.Defaults == nil || *config.Exclude.Defaults {
+		rules = append(rules, google.DefaultExcludeRules...)
+		members = append(members, google.DefaultExcludeMembers...)
+	}
+	for _, rule := range config.Exclude.Resources {
+		rules = append(rules, google.ExcludeRule{ResourceType: rule.Type, ID: rule.ID})
+	}
+	members = append(members, config.Exclude.Members...)
+	return google.NewFilter(rules, members)
+}
+
+// DependentLimits returns the limits on the dependents of discovered
+// resources, without any unless dependents is configured
+func (c *Config) DependentLimits() google.DependentLimits {
+	limits := google.NoDependentLimits
+	if c.cfg.Dependents.MaxDepth != nil {
+		limits.MaxDepth = *c.cfg.Dependents.MaxDepth
+	}
+	limits.SkipIAM = c.cfg.Dependents.SkipIAM
+	return limits
+}
+
+// Templates returns the templates generated blocks are rendered with, in the
+// configured order
+func (c *Config) Templates() []tfimport.TemplateRule {
+	return c.templates
+}
+
+// Mappings returns the overrides of the type, name or import ID of
+// discovered resources, in the configured order
+func (c *Config) Mappings() google.Mappings {
+	return c.mappings
+}
+
+func mappings(config cfg) (google.Mappings, error) {
+	var mappings google.Mappings
+	for _, mapping := range config.Mappings {
+		var resourceType *regexp.Regexp
+		if mapping.Type != "" {
+			var err error
+			if resourceType, err = google.CompileGlob(mapping.Type); err != nil {
+				return nil, fmt.Errorf("mapping %s: %w", mapping.ID, err)
+			}
 		}
+		mappings = append(mappings, google.MappingRule{
+			ResourceType: resourceType,
+			// Validated when loading the config
+			ID:       regexp.MustCompile(mapping.ID),
+			Type:     resource.Type(mapping.Override.Type),
+			Name:     mapping.Override.Name,
+			ImportID: mapping.Override.ID,
+		})
+	}
+	return mappings, nil
+}
 
-		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", absPath)
+// Layout returns the path template generated config is written to, relative
+// to the project path, see tfimport.DefaultLayout. output.file_layout picks
+// one of the built-in layouts.
+func (c *Config) Layout() string {
+	if c.cfg.Output.Layout != "" {
+		return c.cfg.Output.Layout
 	}
+	if c.cfg.Output.FileLayout != "" {
+		// Validated on load
+		layout, _ := tfimport.FileLayoutPath(tfimport.FileLayout(c.cfg.Output.FileLayout))
+		return layout
+	}
+	return tfimport.DefaultLayout
+}
 
-	err := google.ValidateCredentials()
-	if err != nil {
-		return fmt.Errorf("failed to validate credentials: %w", err)
+// ExtractVariables returns how many generated resources a literal must repeat
+// in to be factored into a variable, see tfimport.ExtractVariables. Literals
+// are kept if it is 0.
+func (c *Config) ExtractVariables() int {
+	return c.cfg.Output.ExtractVariables
+}
+
+// Runner returns where terraform runs
+func (c *Config) Runner() tfimport.Runner {
+	// Validated on load
+	runner, _ := tfimport.ParseRunner(c.cfg.Runner)
+	return runner
+}
+
+// RunnerImage returns the image terraform runs in with the docker runner,
+// tfimport.DefaultDockerImage unless runner_image is set
+func (c *Config) RunnerImage() string {
+	if c.cfg.RunnerImage != "" {
+		return c.cfg.RunnerImage
+	}
+	return tfimport.DefaultDockerImage
+}
+
+// Labels returns the labels injected into generated resources which support them.
+func (c *Config) Labels() map[string]string {
+	return c.cfg.Labels
+}
+
+// PolicyPath returns the file or directory of Rego policies evaluated during
+// import, or an empty string if policies aren't configured.
+func (c *Config) PolicyPath() string {
+	return c.cfg.Policy.Path
+}
+
+// EnforcePolicy reports whether policy violations fail the import instead of
+// only being reported.
+func (c *Config) EnforcePolicy() bool {
+	return c.cfg.Policy.Enforce
+}
+
+func parseClasses(names []string, defaults []drift.Class) []drift.Class {
+	if len(names) == 0 {
+		return defaults
+	}
+	var classes []drift.Class
+	for _, name := range names {
+		// Names are checked in validateConfig
+		class, _ := drift.ParseClass(name)
+		classes = append(classes, class)
+	}
+	return classes
+}
+
+// validationTimeout bounds the API calls made to validate the config
+const validationTimeout = 30 * time.Second
+
+// validateGoogleCredentials checks the credentials of every project, making
+// credentials file paths absolute, and the backend bucket
+func (c *Config) validateGoogleCredentials() error {
+	// Config is loaded before any run starts, so validation is bounded on its
+	// own rather than by the caller
+	ctx, cancel := context.WithTimeout(context.Background(), validationTimeout)
+	defer cancel()
+
+	validated := make(map[providers.Credentials]bool)
+	for i, provider := range c.Providers {
+		creds := provider.Credentials
+		if creds.File != "" {
+			absPath, err := filepath.Abs(creds.File)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+
+			if _, err := os.Stat(absPath); os.IsNotExist(err) {
+				return fmt.Errorf("credentials file of project %s does not exist: %s", provider.ProjectID, absPath)
+			}
+			creds.File = absPath
+			c.Providers[i].Credentials = creds
+		}
+
+		// Projects sharing credentials only differ in their quota project
+		key := creds
+		key.QuotaProject = ""
+		if validated[key] {
+			continue
+		}
+		if err := google.ValidateCredentials(ctx, creds); err != nil {
+			return fmt.Errorf("failed to validate credentials of project %s: %w", provider.ProjectID, google.QuotaProjectHint(err))
+		}
+		validated[key] = true
+	}
+
+	backend := c.DefaultBackend()
+	if backend.Type != providers.BackendTypeGCS {
+		return nil
 	}
 
-	bucketName := c.DefaultBackend().Bucket
-	if err := google.ValidateBackend(bucketName); err != nil {
+	if err := google.ValidateBackend(ctx, backend.Credentials, backend.Bucket); err != nil {
 		return fmt.Errorf("failed to validate backend: %w", err)
 	}
 
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/binary"
+	"github.com/priyanshujain/infrasync/internal/keyring"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ageArmorHeader starts values and files encrypted with age --armor
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+	// ageHeader starts files encrypted with age without armor
+	ageHeader = "age-encryption.org/v1"
+)
+
+// sopsEncrypted reports whether data is a YAML or JSON document encrypted
+// with sops, which records how in a top-level sops key
+func sopsEncrypted(data []byte) bool {
+	var doc map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	_, ok := doc["sops"]
+	return ok
+}
+
+// decryptSOPS returns data, a document in format yaml or json read from
+// location, decrypted with sops if it is encrypted with it, or else as it is
+func decryptSOPS(ctx context.Context, location string, data []byte, format string) ([]byte, error) {
+	if !sopsEncrypted(data) {
+		return data, nil
+	}
+
+	// sops reads the document from a file, which only holds what is
+	// encrypted already
+	file, err := os.CreateTemp("", "infrasync-sops-*."+format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", location, err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", location, err)
+	}
+
+	plain, err := runTool(ctx, binary.SOPS, nil, "--decrypt", "--input-type", format, "--output-type", format, file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %w", location, err)
+	}
+	return plain, nil
+}
+
+// ageEncrypted reports whether data is encrypted with age, armored or not
+func ageEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ageHeader)) || bytes.HasPrefix(bytes.TrimSpace(data), []byte(ageArmorHeader))
+}
+
+// decryptAge decrypts data with the age identity sops uses too: the file
+// SOPS_AGE_KEY_FILE names, or keys.txt in the sops/age config directory
+func decryptAge(ctx context.Context, data []byte) ([]byte, error) {
+	identity := os.Getenv("SOPS_AGE_KEY_FILE")
+	if identity == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find age identity: %w", err)
+		}
+		identity = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+	return runTool(ctx, binary.Age, bytes.TrimSpace(data), "--decrypt", "--identity", identity)
+}
+
+// decryptCredentials decrypts credentials kept in an encrypted form: file
+// and json values encrypted with age --armor, and credential files encrypted
+// with age or sops, which are read into json. Credentials stored in the
+// keyring are read into json too.
+func decryptCredentials(ctx context.Context, creds *credentialsCfg) error {
+	if creds.Keyring != "" {
+		secret, err := keyring.Get(creds.Keyring)
+		if err != nil {
+			return err
+		}
+		creds.Keyring, creds.JSON = "", secret
+		return nil
+	}
+
+	for _, value := range []*string{&creds.File, &creds.JSON} {
+		if !ageEncrypted([]byte(*value)) {
+			continue
+		}
+		plain, err := decryptAge(ctx, []byte(*value))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt credentials: %w", err)
+		}
+		*value = strings.TrimSpace(string(plain))
+	}
+
+	if creds.File == "" || creds.JSON != "" {
+		return nil
+	}
+	// Missing files are reported by validation
+	data, err := os.ReadFile(creds.File)
+	if err != nil {
+		return nil
+	}
+	var plain []byte
+	switch {
+	case ageEncrypted(data):
+		plain, err = decryptAge(ctx, data)
+		if err != nil {
+			err = fmt.Errorf("failed to decrypt %s: %w", creds.File, err)
+		}
+	case sopsEncrypted(data):
+		plain, err = decryptSOPS(ctx, creds.File, data, "json")
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	creds.File, creds.JSON = "", string(plain)
+	return nil
+}
+
+// decryptAllCredentials decrypts the credentials of every provider and
+// project of config
+func decryptAllCredentials(ctx context.Context, config *cfg) error {
+	for name, provider := range config.Providers {
+		if err := decryptCredentials(ctx, &provider.Credentials); err != nil {
+			return fmt.Errorf("credentials of provider %s: %w", name, err)
+		}
+		for i := range provider.Projects {
+			if err := decryptCredentials(ctx, &provider.Projects[i].Credentials); err != nil {
+				return fmt.Errorf("credentials of project %s: %w", provider.Projects[i].ID, err)
+			}
+		}
+		config.Providers[name] = provider
+	}
+	return nil
+}
+
+// runTool runs program with args and stdin, returning what it writes to stdout
+func runTool(ctx context.Context, program string, stdin []byte, args ...string) ([]byte, error) {
+	if _, err := binary.LookPath(program); err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", program, err)
+	}
+	cmd := binary.CommandContext(ctx, program, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// fetchTimeout bounds reading a single config file from GCS or HTTPS
+const fetchTimeout = 30 * time.Second
+
+// LoadFrom loads a config from one or more locations instead of the default
+// config file. A location is a local path, a gs://bucket/object URL or an
+// https:// URL. Each file overrides the ones before it: mappings are merged
+// key by key and any other value, lists included, replaces the earlier one.
+// This lets a shared config be kept in one place and adjusted locally, e.g.
+//
+//	infrasync --config gs://bucket/infrasync.yaml --config infrasync.local.yaml
+func LoadFrom(ctx context.Context, locations ...string) (Config, error) {
+	if len(locations) == 0 {
+		return Load()
+	}
+
+	var merged *yaml.Node
+	for _, location := range locations {
+		data, err := fetch(ctx, location)
+		if err != nil {
+			return Config{}, err
+		}
+		data, err = decryptSOPS(ctx, location, data, "yaml")
+		if err != nil {
+			return Config{}, err
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return Config{}, fmt.Errorf("error parsing config file %s: %w", location, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		if doc.Content[0].Kind != yaml.MappingNode {
+			return Config{}, fmt.Errorf("error parsing config file %s: not a mapping", location)
+		}
+
+		if merged == nil {
+			merged = doc.Content[0]
+		} else {
+			mergeNode(merged, doc.Content[0])
+		}
+	}
+
+	var config cfg
+	if merged != nil {
+		if err := merged.Decode(&config); err != nil {
+			return Config{}, fmt.Errorf("error parsing config: %w", err)
+		}
+	}
+
+	return build(config)
+}
+
+// fetch reads a config file from a local path, GCS or HTTPS
+func fetch(ctx context.Context, location string) ([]byte, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// no scheme, or a windows drive letter
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	switch u.Scheme {
+	case "gs":
+		return fetchGCS(ctx, u.Host, strings.TrimPrefix(u.Path, "/"))
+	case "https":
+		return fetchHTTPS(ctx, location)
+	default:
+		return nil, fmt.Errorf("unsupported config location %s, use a path, gs:// or https:// URL", location)
+	}
+}
+
+func fetchGCS(ctx context.Context, bucket, object string) ([]byte, error) {
+	if bucket == "" || object == "" {
+		return nil, fmt.Errorf("invalid config location gs://%s/%s, expected gs://bucket/object", bucket, object)
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	reader, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config gs://%s/%s: %w", bucket, object, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config gs://%s/%s: %w", bucket, object, err)
+	}
+	return data, nil
+}
+
+func fetchHTTPS(ctx context.Context, location string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch config %s: %s", location, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config %s: %w", location, err)
+	}
+	return data, nil
+}
+
+// mergeNode merges the mapping override into base. Keys of both which map to
+// mappings are merged recursively, any other value of override replaces the
+// one in base.
+func mergeNode(base, override *yaml.Node) {
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+
+		found := false
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			if base.Content[j].Value != key.Value {
+				continue
+			}
+			found = true
+			if base.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				mergeNode(base.Content[j+1], value)
+			} else {
+				base.Content[j+1] = value
+			}
+			break
+		}
+		if !found {
+			base.Content = append(base.Content, key, value)
+		}
+	}
+}
@@ -10,12 +10,184 @@ providers:
     projects:
       - id: {{ gcp_project_id }}
         region: {{ gcp_region }}
+        # Optional: credentials of this project, replacing those of the provider
+        # credentials:
+        #   file: project-sa.json
+        #   impersonate_service_account: infrasync@{{ gcp_project_id }}.iam.gserviceaccount.com
+        # Optional: only discover Cloud SQL instances in these regions
+        regions:
+          - {{ gcp_region }}
         services:
           {{- range gcp_services }}
           - {{ . }}
           {{- end }}
+          # Services take optional settings:
+          # - pubsub:
+          #     include_iam: true
+          #     iam_mode: binding     # binding, member or policy
+          #     include_subscriptions: true
+          #     parallelism: 8
+          # - storage:
+          #     include_iam: false
+          #     include_acl: true
+          # - cloudsql:
+          #     include_databases: true
+          #     include_users: false
+          # - iam:
+          #     organization: "{{ gcp_organization_id }}"
+          #     include_audit_configs: true
+          # - compute:
+          #     include_iam: true
+          #     include_addresses: true
+          #     include_ssl_certificates: true
+          # - project:
+          #     include_essential_contacts: true
+          #     include_budgets: true
+          # - filestore:
+          #     include_backups: true
+          #     include_netapp: false
+          # - apigateway:
+          #     include_iam: true
+          # - eventarc:
+          #     include_managed: false
+          # - workflows:
+          #     source_files: true
+          # - iap:
+          #     include_iam: true
+          #     include_tenants: true
+          # - certificatemanager:
+          #     include_maps: true
+          # - privateca:
+          #     include_iam: true
+          # - binaryauthorization:
+          #     include_iam: true
+          #     include_notes: true
+          # - gkehub:
+          #     include_features: true
+          # - dataplex:
+          #     include_taxonomies: true
+          #     include_lakes: true
 
 backend:
   type: {{ backend_type }}
   bucket: {{ backend_bucket }}
+  # For type: remote (Terraform Cloud / HCP Terraform)
+  # hostname: app.terraform.io
+  # organization: {{ tfc_organization }}
+  # workspace: {{ tfc_workspace }}
+  # For type: http (e.g. GitLab managed state), credentials from TF_HTTP_USERNAME/TF_HTTP_PASSWORD
+  # address: {{ state_address }}
+  # lock_address: {{ state_lock_address }}
+  # unlock_address: {{ state_unlock_address }}
+  # lock_method: POST      # GitLab uses POST and DELETE, the default is LOCK and UNLOCK
+  # unlock_method: DELETE
+
+# Optional: git settings used by init
+git:
+  enabled: true
+  default_branch: {{ git_default_branch }}
+  author_name: {{ git_author_name }}
+  author_email: {{ git_author_email }}
+  # Commit imported resources and list them in imports.md: per-service or per-run
+  # commit_imports: per-service
+
+# Optional: drift which should not be reported
+drift:
+  ignore:
+    - resource: {{ resource_address_pattern }}
+      attributes:
+        - {{ attribute_path }}
+  # Drift classes (additive, destructive, modified) failing sync and opening PRs
+  fail_on:
+    - destructive
+  pr_on:
+    - additive
+    - destructive
+    - modified
+  # Where drift history is kept: bucket (default with a gcs backend) or local
+  # (project directory, not available in CI)
+  # history: bucket
+  # Estimate the monthly cost of unmanaged resources (needs the Cloud Billing API)
+  estimate_cost: false
+  # Unmanaged resources allowed per service or resource type before sync fails
+  # unmanaged_budget:
+  #   google_pubsub_topic: 5
+  # Resource types whose drift sync --remediate reverts in the cloud
+  # remediate:
+  #   - google_storage_bucket_iam_member
+
+# Optional: strip or rewrite attributes in generated resources
+normalize:
+  - type: {{ resource_type }}
+    remove:
+      - {{ attribute }}
+    rewrite:
+      {{ attribute }}: {{ hcl_expression }}
+
+# Optional: inject lifecycle blocks into generated resources
+lifecycle:
+  - type: {{ resource_type }}
+    name: {{ resource_name_pattern }}
+    prevent_destroy: true
+    ignore_changes:
+      - {{ attribute }}
+
+# Optional: where generated config is written, relative to path. Without
+# {{name}} all resources rendering to the same file share it.
+output:
+  layout: "resources/{{provider}}/{{project}}/{{service}}/{{name}}.tf"
+  # Or one of the built-in layouts: per-resource, per-service or per-type
+  # file_layout: per-service
+
+# Optional: run terraform in a container instead of the local binary (local
+# or docker). runner_image defaults to a pinned hashicorp/terraform image.
+runner: local
+# runner_image: ghcr.io/opentofu/opentofu:1.8.5
+
+# Optional: resources left out of discovery, on top of the built-in list of
+# Google-managed resources (set defaults: false to disable it)
+exclude:
+  defaults: true
+  resources:
+    - type: {{ resource_type }}
+      id: {{ import_id_pattern }}
+  members:
+    - {{ iam_member_pattern }}
+
+# Optional: leave out dependents of discovered resources below max_depth
+# levels, or IAM bindings, members and policies, to import incrementally
+dependents:
+  max_depth: {{ max_depth }}
+  skip_iam: false
+
+# Optional: Go text/templates rendering the generated blocks of matching
+# resources, e.g. to add comments or wrap them in a module call
+templates:
+  - type: {{ resource_type }}
+    name: {{ resource_name_pattern }}
+    path: {{ template_path }}
+
+# Optional: override the type, name or import ID generated for resources whose
+# import ID matches a regular expression; name and id may use its submatches
+mappings:
+  - type: {{ resource_type }}
+    id: {{ import_id_regex }}
+    override:
+      name: {{ resource_name }}
+      id: {{ import_id }}
+
+# Optional: labels injected into generated resources which support them
+labels:
+  managed-by: infrasync
+  team: {{ team }}
+
+# Optional: Rego policies (package infrasync, "deny" rules) checked on import
+policy:
+  path: {{ policy_path }}
+  enforce: false
+
+# Optional: where the audit log is appended to (defaults to
+# <project>/.infrasync/audit.jsonl, which is not committed)
+audit:
+  path: {{ audit_log_path }}
 `
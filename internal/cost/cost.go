@@ -0,0 +1,281 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+	"google.golang.org/api/cloudbilling/v1"
+)
+
+// Cloud Billing Catalog service IDs
+const (
+	serviceCloudSQL = "services/9662-B51E-5089"
+	serviceStorage  = "services/95FF-2EF5-5EA1"
+)
+
+const hoursPerMonth = 730
+
+// Estimate is the estimated monthly list price of a resource in USD. Resources
+// billed by usage, like bucket storage, carry a unit price instead.
+type Estimate struct {
+	Address   string
+	Monthly   float64
+	UnitPrice float64
+	Unit      string
+	Note      string
+}
+
+func (e Estimate) String() string {
+	switch {
+	case e.Note != "":
+		return e.Note
+	case e.Unit != "":
+		return fmt.Sprintf("$%.4f per %s", e.UnitPrice, e.Unit)
+	default:
+		return fmt.Sprintf("$%.2f/month", e.Monthly)
+	}
+}
+
+// Estimator prices resources using the Cloud Billing Catalog API. SKUs are
+// fetched once per billing service and cached.
+type Estimator struct {
+	service *cloudbilling.APIService
+	skus    map[string][]*cloudbilling.Sku
+}
+
+func NewEstimator(ctx context.Context, creds providers.Credentials) (*Estimator, error) {
+	opts, err := google.ClientOptions(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	service, err := cloudbilling.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud billing client: %w", err)
+	}
+	return &Estimator{service: service, skus: make(map[string][]*cloudbilling.Sku)}, nil
+}
+
+// Estimate prices a single resource. Resources which are free or whose price
+// depends only on usage get an estimate with a note.
+func (e *Estimator) Estimate(ctx context.Context, r resource.Resource) (Estimate, error) {
+	estimate := Estimate{Address: r.Address()}
+
+	switch r.Type {
+	case google.ResourceTypeSQLInstance:
+		return e.estimateSQLInstance(ctx, r, estimate)
+	case google.ResourceTypeStorageBucket:
+		return e.estimateBucket(ctx, r, estimate)
+	case google.ResourceTypePubSubTopic, google.ResourceTypePubSubSubscription:
+		estimate.Note = "usage-based"
+	default:
+		estimate.Note = "no charge"
+	}
+	return estimate, nil
+}
+
+var (
+	customTierRe = regexp.MustCompile(`^db-custom-(\d+)-(\d+)$`)
+	legacyTierRe = regexp.MustCompile(`^db-n1-(standard|highmem)-(\d+)$`)
+)
+
+// sqlTierSize returns the vCPUs and memory in GiB of a dedicated-core tier
+func sqlTierSize(tier string) (cpus, memoryGiB float64, ok bool) {
+	if m := customTierRe.FindStringSubmatch(tier); m != nil {
+		cpus, _ = strconv.ParseFloat(m[1], 64)
+		memoryMiB, _ := strconv.ParseFloat(m[2], 64)
+		return cpus, memoryMiB / 1024, true
+	}
+	if m := legacyTierRe.FindStringSubmatch(tier); m != nil {
+		cpus, _ = strconv.ParseFloat(m[2], 64)
+		perCPU := 3.75
+		if m[1] == "highmem" {
+			perCPU = 6.5
+		}
+		return cpus, cpus * perCPU, true
+	}
+	return 0, 0, false
+}
+
+func (e *Estimator) estimateSQLInstance(ctx context.Context, r resource.Resource, estimate Estimate) (Estimate, error) {
+	region, _ := r.Attributes["region"].(string)
+	version, _ := r.Attributes["database_version"].(string)
+
+	var tier, availability string
+	if settings, ok := r.Attributes["settings"].([]any); ok && len(settings) > 0 {
+		if s, ok := settings[0].(map[string]any); ok {
+			tier, _ = s["tier"].(string)
+			availability, _ = s["availability_type"].(string)
+		}
+	}
+
+	var engine string
+	switch {
+	case strings.HasPrefix(version, "MYSQL"):
+		engine = "MySQL"
+	case strings.HasPrefix(version, "POSTGRES"):
+		engine = "PostgreSQL"
+	case strings.HasPrefix(version, "SQLSERVER"):
+		engine = "SQL Server"
+	default:
+		estimate.Note = fmt.Sprintf("unknown database version %s", version)
+		return estimate, nil
+	}
+
+	zonal := "Zonal"
+	if availability == "REGIONAL" {
+		zonal = "Regional"
+	}
+
+	skus, err := e.skusOf(ctx, serviceCloudSQL)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	match := func(parts ...string) (float64, bool) {
+		prefix := fmt.Sprintf("Cloud SQL for %s: %s - ", engine, zonal)
+		return hourlyPrice(skus, region, append([]string{prefix}, parts...)...)
+	}
+
+	switch tier {
+	case "db-f1-micro", "db-g1-small":
+		kind := "Micro instance"
+		if tier == "db-g1-small" {
+			kind = "Small instance"
+		}
+		price, ok := match(kind)
+		if !ok {
+			estimate.Note = fmt.Sprintf("no price found for %s in %s", tier, region)
+			return estimate, nil
+		}
+		estimate.Monthly = price * hoursPerMonth
+	default:
+		cpus, memory, ok := sqlTierSize(tier)
+		if !ok {
+			estimate.Note = fmt.Sprintf("unknown tier %s", tier)
+			return estimate, nil
+		}
+		cpuPrice, cpuOK := match("vCPU")
+		memoryPrice, memoryOK := match("RAM")
+		if !cpuOK || !memoryOK {
+			estimate.Note = fmt.Sprintf("no price found for %s in %s", tier, region)
+			return estimate, nil
+		}
+		estimate.Monthly = (cpus*cpuPrice + memory*memoryPrice) * hoursPerMonth
+	}
+
+	return estimate, nil
+}
+
+func (e *Estimator) estimateBucket(ctx context.Context, r resource.Resource, estimate Estimate) (Estimate, error) {
+	location, _ := r.Attributes["location"].(string)
+	class, _ := r.Attributes["storage_class"].(string)
+
+	skus, err := e.skusOf(ctx, serviceStorage)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	// Buckets with the legacy classes are billed as standard storage
+	name := "Standard Storage"
+	switch class {
+	case "NEARLINE":
+		name = "Nearline Storage"
+	case "COLDLINE":
+		name = "Coldline Storage"
+	case "ARCHIVE":
+		name = "Archive Storage"
+	}
+
+	for _, sku := range skus {
+		if !strings.HasPrefix(sku.Description, name) || !inRegion(sku, location) ||
+			sku.Category == nil || sku.Category.UsageType != "OnDemand" {
+			continue
+		}
+		if price, unit, ok := unitPrice(sku); ok && unit == "GiBy.mo" {
+			estimate.UnitPrice = price
+			estimate.Unit = "GiB-month"
+			return estimate, nil
+		}
+	}
+
+	estimate.Note = fmt.Sprintf("no price found for %s in %s", class, location)
+	return estimate, nil
+}
+
+func (e *Estimator) skusOf(ctx context.Context, service string) ([]*cloudbilling.Sku, error) {
+	if skus, ok := e.skus[service]; ok {
+		return skus, nil
+	}
+
+	var skus []*cloudbilling.Sku
+	err := e.service.Services.Skus.List(service).CurrencyCode("USD").Pages(ctx, func(resp *cloudbilling.ListSkusResponse) error {
+		skus = append(skus, resp.Skus...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SKUs of %s: %w", service, err)
+	}
+
+	e.skus[service] = skus
+	return skus, nil
+}
+
+// hourlyPrice returns the on-demand hourly price of the first SKU in region
+// whose description contains all parts
+func hourlyPrice(skus []*cloudbilling.Sku, region string, parts ...string) (float64, bool) {
+	for _, sku := range skus {
+		if !inRegion(sku, region) || sku.Category == nil || sku.Category.UsageType != "OnDemand" {
+			continue
+		}
+		matched := true
+		for _, part := range parts {
+			if !strings.Contains(sku.Description, part) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if price, unit, ok := unitPrice(sku); ok && (unit == "h" || unit == "GiBy.h") {
+			return price, true
+		}
+	}
+	return 0, false
+}
+
+func inRegion(sku *cloudbilling.Sku, region string) bool {
+	for _, r := range sku.ServiceRegions {
+		if strings.EqualFold(r, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// unitPrice returns the highest tier price of a SKU, ignoring free tiers
+func unitPrice(sku *cloudbilling.Sku) (float64, string, bool) {
+	if len(sku.PricingInfo) == 0 || sku.PricingInfo[0].PricingExpression == nil {
+		return 0, "", false
+	}
+	expression := sku.PricingInfo[0].PricingExpression
+
+	var price float64
+	var found bool
+	for _, rate := range expression.TieredRates {
+		if rate.UnitPrice == nil {
+			continue
+		}
+		p := float64(rate.UnitPrice.Units) + float64(rate.UnitPrice.Nanos)/1e9
+		if p > price {
+			price, found = p, true
+		}
+	}
+	return price, expression.UsageUnit, found
+}
@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RunFunc is the work executed on every tick.
+type RunFunc func(context.Context) error
+
+type Status struct {
+	Running    bool      `json:"running"`
+	Runs       int       `json:"runs"`
+	LastStart  time.Time `json:"last_start,omitempty"`
+	LastFinish time.Time `json:"last_finish,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	NextRun    time.Time `json:"next_run"`
+}
+
+type daemon struct {
+	interval time.Duration
+	run      RunFunc
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Run executes fn immediately and then every interval until ctx is cancelled.
+// Health and last-run status are served over HTTP on addr when it is not empty.
+func Run(ctx context.Context, interval time.Duration, addr string, fn RunFunc) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	d := &daemon{interval: interval, run: fn}
+
+	if addr != "" {
+		// Listen before the first run so that a port in use fails startup
+		// instead of leaving the daemon running without its status server
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+
+		server := &http.Server{Handler: d.handler()}
+		go func() {
+			slog.Info("Serving daemon status", "addr", listener.Addr())
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Status server failed", "error", err)
+			}
+		}()
+		defer server.Shutdown(context.Background())
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *daemon) tick(ctx context.Context) {
+	d.mu.Lock()
+	d.status.Running = true
+	d.status.LastStart = time.Now()
+	d.mu.Unlock()
+
+	slog.Info("Starting scheduled run")
+	err := d.run(ctx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.Running = false
+	d.status.Runs++
+	d.status.LastFinish = time.Now()
+	d.status.NextRun = d.status.LastStart.Add(d.interval)
+	d.status.LastError = ""
+	if err != nil {
+		d.status.LastError = err.Error()
+		slog.Error("Scheduled run failed", "error", err)
+		return
+	}
+	slog.Info("Scheduled run finished", "next", d.status.NextRun)
+}
+
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		status := d.status
+		d.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if status.LastError != "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+	return mux
+}
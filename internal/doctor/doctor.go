@@ -0,0 +1,336 @@
+// Package doctor diagnoses the environment infrasync runs in and suggests
+// fixes for what is missing.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/binary"
+	"github.com/priyanshujain/infrasync/internal/config"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/tfimport"
+	"google.golang.org/api/transport"
+)
+
+type Status string
+
+var (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Check is the outcome of one diagnostic. Fix suggests how to resolve a
+// warning or failure.
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+	Fix    string
+}
+
+// Run performs every diagnostic. It loads the config itself, so that a missing
+// or invalid config is reported like any other problem. configLocations are
+// passed to config.LoadFrom, the default config file is used if empty.
+func Run(ctx context.Context, configLocations []string) []Check {
+	configCheck, cfg := checkConfig(ctx, configLocations)
+
+	outputDirCheck := Check{
+		Name:   "Output directory",
+		Status: StatusWarn,
+		Detail: "not checked, the config could not be loaded",
+	}
+	terraformCheck := checkTerraform(ctx)
+	var creds providers.Credentials
+	if cfg != nil {
+		creds = cfg.DefaultProvider().Credentials
+		outputDirCheck = checkOutputDir(cfg.ProjectPath())
+		if cfg.Runner() == tfimport.RunnerDocker {
+			terraformCheck = checkDocker(ctx, cfg.RunnerImage())
+		}
+	}
+
+	return []Check{
+		configCheck,
+		checkCredentials(ctx, creds),
+		checkGcloud(ctx),
+		terraformCheck,
+		checkPluginCache(),
+		outputDirCheck,
+		checkNetwork(ctx),
+	}
+}
+
+// Failed reports whether any check failed
+func Failed(checks []Check) bool {
+	for _, check := range checks {
+		if check.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+func checkConfig(ctx context.Context, locations []string) (Check, *config.Config) {
+	check := Check{Name: "Config"}
+
+	cfg, err := config.LoadFrom(ctx, locations...)
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = err.Error()
+		check.Fix = "Fill in the config file (~/.config/infrasync/config.yaml unless --config is given) as per the template in the README"
+		return check, nil
+	}
+
+	check.Status = StatusOK
+	check.Detail = fmt.Sprintf("project %s", cfg.DefaultProvider().ProjectID)
+	return check, &cfg
+}
+
+// checkCredentials checks the configured credentials of the default project,
+// or application default credentials if there are none
+func checkCredentials(ctx context.Context, configured providers.Credentials) Check {
+	check := Check{Name: "Google credentials"}
+
+	opts, err := google.ClientOptions(ctx, configured, "https://www.googleapis.com/auth/cloud-platform.read-only")
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = err.Error()
+		check.Fix = "Fix the credentials of the provider or project in the config"
+		return check
+	}
+	creds, err := transport.Creds(ctx, opts...)
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = err.Error()
+		check.Fix = "Run `gcloud auth application-default login` or set GOOGLE_APPLICATION_CREDENTIALS to a service account key"
+		return check
+	}
+
+	if _, err := creds.TokenSource.Token(); err != nil {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("credentials found but no token could be obtained: %v", err)
+		check.Fix = "Refresh the credentials with `gcloud auth application-default login`"
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Detail = "application default credentials found"
+	if !configured.IsZero() {
+		check.Detail = "configured credentials found"
+	}
+	if creds.ProjectID != "" {
+		check.Detail += fmt.Sprintf(" (project %s)", creds.ProjectID)
+	}
+	return check
+}
+
+func checkGcloud(ctx context.Context) Check {
+	check := Check{Name: "gcloud CLI"}
+
+	out, err := binary.CommandContext(ctx, binary.GCloud, "version").Output()
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = "gcloud is not installed or not in PATH"
+		check.Fix = "Install the Google Cloud CLI, it is used to discover Cloud SQL instances: https://cloud.google.com/sdk/docs/install"
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Detail = firstLine(string(out))
+	return check
+}
+
+var versionRe = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+func parseVersion(version string) (major, minor int, ok bool) {
+	m := versionRe.FindStringSubmatch(version)
+	if m == nil {
+		return 0, 0, false
+	}
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	return major, minor, true
+}
+
+func checkTerraform(ctx context.Context) Check {
+	check := Check{Name: "Terraform"}
+
+	// Config is generated with the terraform binary, OpenTofu is not used
+	out, err := binary.CommandContext(ctx, binary.Terraform, "version", "-json").Output()
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = "terraform is not installed or not in PATH"
+		check.Fix = "Install terraform 1.5 or later: https://developer.hashicorp.com/terraform/install"
+		return check
+	}
+
+	var v struct {
+		Version string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		check.Status = StatusWarn
+		check.Detail = fmt.Sprintf("failed to read the terraform version: %v", err)
+		return check
+	}
+
+	// Config generation needs import blocks with -generate-config-out,
+	// available from terraform 1.5
+	if major, minor, ok := parseVersion(v.Version); ok && (major < 1 || (major == 1 && minor < 5)) {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("terraform %s is too old", v.Version)
+		check.Fix = "Upgrade terraform to 1.5 or later: https://developer.hashicorp.com/terraform/install"
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Detail = fmt.Sprintf("terraform %s", v.Version)
+	return check
+}
+
+// checkDocker replaces checkTerraform with the docker runner, as terraform
+// runs in the image then
+func checkDocker(ctx context.Context, image string) Check {
+	check := Check{Name: "Docker"}
+
+	out, err := binary.CommandContext(ctx, binary.Docker, "version", "--format", "{{.Server.Version}}").Output()
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = "docker is not installed or the daemon is not running"
+		check.Fix = "Install Docker and start it, or remove `runner: docker` to use a local terraform: https://docs.docker.com/get-docker/"
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Detail = fmt.Sprintf("docker %s, terraform runs in %s", strings.TrimSpace(string(out)), image)
+	return check
+}
+
+var pluginCacheRe = regexp.MustCompile(`(?m)^\s*plugin_cache_dir\s*=\s*"([^"]+)"`)
+
+func checkPluginCache() Check {
+	check := Check{Name: "Provider plugin cache"}
+
+	dir := os.Getenv("TF_PLUGIN_CACHE_DIR")
+	if dir == "" {
+		if path := cliConfigPath(); path != "" {
+			if data, err := os.ReadFile(path); err == nil {
+				if m := pluginCacheRe.FindSubmatch(data); m != nil {
+					dir = os.ExpandEnv(string(m[1]))
+				}
+			}
+		}
+	}
+
+	if dir == "" {
+		check.Status = StatusWarn
+		check.Detail = "no plugin cache configured, providers are downloaded on every init"
+		check.Fix = "Set TF_PLUGIN_CACHE_DIR to a directory such as $HOME/.terraform.d/plugin-cache"
+		return check
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		check.Status = StatusWarn
+		check.Detail = fmt.Sprintf("plugin cache %s does not exist", dir)
+		check.Fix = fmt.Sprintf("Create it with `mkdir -p %s`", dir)
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Detail = dir
+	return check
+}
+
+// cliConfigPath returns the terraform CLI config file, which is terraform.rc
+// in %APPDATA% on Windows and ~/.terraformrc elsewhere
+func cliConfigPath() string {
+	if path := os.Getenv("TF_CLI_CONFIG_FILE"); path != "" {
+		return path
+	}
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "terraform.rc")
+		}
+		return ""
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".terraformrc")
+	}
+	return ""
+}
+
+func checkOutputDir(dir string) Check {
+	check := Check{Name: "Output directory"}
+
+	// The directory may not exist before init, in which case its closest
+	// existing parent has to be writable
+	existing := dir
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+
+	file, err := os.CreateTemp(existing, ".infrasync-doctor-*")
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("%s is not writable: %v", existing, err)
+		check.Fix = "Change the path in the config file or fix the permissions of the directory"
+		return check
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	check.Status = StatusOK
+	check.Detail = dir
+	return check
+}
+
+func checkNetwork(ctx context.Context) Check {
+	check := Check{Name: "Network"}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://www.googleapis.com/", nil)
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = err.Error()
+		return check
+	}
+
+	// Any response, whatever its status, means the API is reachable
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("googleapis.com is not reachable: %v", err)
+		check.Fix = "Check the firewall rules and set HTTPS_PROXY if a proxy is required"
+		return check
+	}
+	resp.Body.Close()
+
+	check.Status = StatusOK
+	check.Detail = "googleapis.com is reachable"
+	return check
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
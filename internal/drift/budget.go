@@ -0,0 +1,78 @@
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+)
+
+// Budget caps the number of unmanaged resources, keyed by service name, e.g.
+// "pubsub", or resource type, e.g. "google_pubsub_topic", so that teams can
+// adopt IaC incrementally without unmanaged resources piling up.
+type Budget map[string]int
+
+// ValidateBudgetKey returns an error unless key names a service or resource
+// type
+func ValidateBudgetKey(key string) error {
+	for _, s := range google.Services {
+		if string(s) == key {
+			return nil
+		}
+		for _, t := range google.ResourceTypes(s) {
+			if string(t) == key {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unmanaged budget of %q: not a service or resource type", key)
+}
+
+// BudgetOverrun is a budget exceeded by the unmanaged resources of a report
+type BudgetOverrun struct {
+	Key       string
+	Unmanaged int
+	Limit     int
+}
+
+func (o BudgetOverrun) String() string {
+	return fmt.Sprintf("%s: %d unmanaged, budget %d", o.Key, o.Unmanaged, o.Limit)
+}
+
+// UnmanagedCounts counts the unmanaged resources of report per service and
+// per resource type
+func UnmanagedCounts(report Report) map[string]int {
+	services := make(map[string]string)
+	for _, s := range google.Services {
+		for _, t := range google.ResourceTypes(s) {
+			if _, ok := services[string(t)]; !ok {
+				services[string(t)] = string(s)
+			}
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, r := range report.Unmanaged {
+		resourceType, _, _ := strings.Cut(r.Address, ".")
+		counts[resourceType]++
+		if service, ok := services[resourceType]; ok {
+			counts[service]++
+		}
+	}
+	return counts
+}
+
+// Check returns the budgets the unmanaged resources of report exceed, by key
+func (b Budget) Check(report Report) []BudgetOverrun {
+	counts := UnmanagedCounts(report)
+
+	var overruns []BudgetOverrun
+	for key, limit := range b {
+		if counts[key] > limit {
+			overruns = append(overruns, BudgetOverrun{Key: key, Unmanaged: counts[key], Limit: limit})
+		}
+	}
+	sort.Slice(overruns, func(i, j int) bool { return overruns[i].Key < overruns[j].Key })
+	return overruns
+}
@@ -0,0 +1,80 @@
+package drift
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+var budgetReport = Report{Unmanaged: []Unmanaged{
+	{Address: "google_pubsub_topic.orders"},
+	{Address: "google_pubsub_topic.events"},
+	{Address: "google_pubsub_subscription.orders_worker"},
+	{Address: "google_storage_bucket.assets"},
+	{Address: "google_unknown_thing.x"},
+}}
+
+func TestUnmanagedCounts(t *testing.T) {
+	want := map[string]int{
+		"google_pubsub_topic":        2,
+		"google_pubsub_subscription": 1,
+		"pubsub":                     3,
+		"google_storage_bucket":      1,
+		"storage":                    1,
+		// Types of no service are counted on their own
+		"google_unknown_thing": 1,
+	}
+	if got := UnmanagedCounts(budgetReport); !maps.Equal(got, want) {
+		t.Errorf("UnmanagedCounts() = %v, want %v", got, want)
+	}
+}
+
+func TestBudgetCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget Budget
+		want   []string
+	}{
+		{name: "within budget", budget: Budget{"pubsub": 3, "storage": 1}},
+		{name: "service over budget", budget: Budget{"pubsub": 2}, want: []string{"pubsub: 3 unmanaged, budget 2"}},
+		{
+			name:   "type over budget within service budget",
+			budget: Budget{"pubsub": 5, "google_pubsub_topic": 1},
+			want:   []string{"google_pubsub_topic: 2 unmanaged, budget 1"},
+		},
+		{
+			name:   "overruns sorted by key",
+			budget: Budget{"storage": 0, "pubsub": 0, "google_pubsub_subscription": 0},
+			want: []string{
+				"google_pubsub_subscription: 1 unmanaged, budget 0",
+				"pubsub: 3 unmanaged, budget 0",
+				"storage: 1 unmanaged, budget 0",
+			},
+		},
+		{name: "nothing unmanaged", budget: Budget{"compute": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, o := range tt.budget.Check(budgetReport) {
+				got = append(got, o.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBudgetKey(t *testing.T) {
+	for _, key := range []string{"pubsub", "google_storage_bucket"} {
+		if err := ValidateBudgetKey(key); err != nil {
+			t.Errorf("ValidateBudgetKey(%s) = %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "google_unknown_thing", "PubSub"} {
+		if err := ValidateBudgetKey(key); err == nil {
+			t.Errorf("ValidateBudgetKey(%q) succeeded, want an error", key)
+		}
+	}
+}
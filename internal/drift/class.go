@@ -0,0 +1,53 @@
+package drift
+
+import "fmt"
+
+// Class is the severity class of drift.
+type Class string
+
+var (
+	// ClassAdditive is a new resource which is not managed yet
+	ClassAdditive Class = "additive"
+	// ClassDestructive is a managed resource which was deleted
+	ClassDestructive Class = "destructive"
+	// ClassModified is a managed resource whose attributes changed
+	ClassModified Class = "modified"
+)
+
+var AllClasses = []Class{ClassAdditive, ClassDestructive, ClassModified}
+
+func ParseClass(s string) (Class, error) {
+	for _, c := range AllClasses {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown drift class %q, expected one of %v", s, AllClasses)
+}
+
+// Classes returns the classes of drift present in the report.
+func (r Report) Classes() []Class {
+	var classes []Class
+	if len(r.Unmanaged) > 0 {
+		classes = append(classes, ClassAdditive)
+	}
+	if len(r.Deleted) > 0 {
+		classes = append(classes, ClassDestructive)
+	}
+	if len(r.Modified) > 0 {
+		classes = append(classes, ClassModified)
+	}
+	return classes
+}
+
+// HasAny reports whether the report contains drift of any of the given classes.
+func (r Report) HasAny(classes []Class) bool {
+	for _, present := range r.Classes() {
+		for _, c := range classes {
+			if present == c {
+				return true
+			}
+		}
+	}
+	return false
+}
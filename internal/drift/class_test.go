@@ -0,0 +1,75 @@
+package drift
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/state"
+)
+
+func TestReportClasses(t *testing.T) {
+	unmanaged := []Unmanaged{{Address: "google_pubsub_topic.orders"}}
+	deleted := []state.Resource{{Address: "google_pubsub_topic.legacy"}}
+	modified := []Change{{Address: "google_pubsub_topic.events", Attribute: "labels.env"}}
+
+	tests := []struct {
+		name   string
+		report Report
+		want   []Class
+	}{
+		{name: "no drift", report: Report{}},
+		{name: "unmanaged", report: Report{Unmanaged: unmanaged}, want: []Class{ClassAdditive}},
+		{name: "deleted", report: Report{Deleted: deleted}, want: []Class{ClassDestructive}},
+		{name: "modified", report: Report{Modified: modified}, want: []Class{ClassModified}},
+		{
+			name:   "all",
+			report: Report{Unmanaged: unmanaged, Deleted: deleted, Modified: modified},
+			want:   []Class{ClassAdditive, ClassDestructive, ClassModified},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.Classes(); !slices.Equal(got, tt.want) {
+				t.Errorf("Classes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportHasAny(t *testing.T) {
+	report := Report{Deleted: []state.Resource{{Address: "google_pubsub_topic.legacy"}}}
+
+	tests := []struct {
+		classes []Class
+		want    bool
+	}{
+		{nil, false},
+		{[]Class{ClassAdditive}, false},
+		{[]Class{ClassDestructive}, true},
+		{[]Class{ClassAdditive, ClassDestructive}, true},
+		{AllClasses, true},
+	}
+	for _, tt := range tests {
+		if got := report.HasAny(tt.classes); got != tt.want {
+			t.Errorf("HasAny(%v) = %t, want %t", tt.classes, got, tt.want)
+		}
+	}
+
+	if (Report{}).HasAny(AllClasses) {
+		t.Error("HasAny() of a report without drift = true, want false")
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	for _, c := range AllClasses {
+		got, err := ParseClass(string(c))
+		if err != nil || got != c {
+			t.Errorf("ParseClass(%s) = %s, %v, want %s", c, got, err, c)
+		}
+	}
+	for _, s := range []string{"", "Additive", "deleted"} {
+		if _, err := ParseClass(s); err == nil {
+			t.Errorf("ParseClass(%q) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package drift
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change is a single attribute whose cloud value differs from state.
+type Change struct {
+	Address   string
+	Attribute string
+	Cloud     string
+	State     string
+}
+
+// compareAttributes compares the attributes known from discovery with the
+// same attributes in state. Attributes missing from either side are skipped.
+func compareAttributes(address string, cloud, state map[string]any) []Change {
+	cloudFlat := flattenAttributes("", cloud)
+	stateFlat := flattenAttributes("", state)
+
+	var changes []Change
+	for path, cloudValue := range cloudFlat {
+		stateValue, ok := stateFlat[path]
+		if !ok || sameValue(path, cloudValue, stateValue) {
+			continue
+		}
+		changes = append(changes, Change{
+			Address:   address,
+			Attribute: path,
+			Cloud:     cloudValue,
+			State:     stateValue,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Attribute < changes[j].Attribute })
+	return changes
+}
+
+// referenceAttributes hold the name of a resource, which discovery often
+// records in short form like "topic" where state holds
+// "projects/p/topics/topic"
+var referenceAttributes = map[string]bool{
+	"name":         true,
+	"topic":        true,
+	"subscription": true,
+	"bucket":       true,
+	"instance":     true,
+}
+
+// sameValue reports whether the values of attribute at path are equal. A
+// short name equals its fully qualified form for reference attributes only;
+// two qualified names must match exactly, so a different project is drift.
+func sameValue(path, a, b string) bool {
+	if a == b {
+		return true
+	}
+	if !referenceAttributes[path] {
+		return false
+	}
+	if !strings.Contains(a, "/") {
+		return strings.HasSuffix(b, "/"+a)
+	}
+	if !strings.Contains(b, "/") {
+		return strings.HasSuffix(a, "/"+b)
+	}
+	return false
+}
+
+// flattenAttributes flattens nested values into dotted paths like terraform's
+// flatmap format. Lists of scalars are sorted so ordering doesn't count as drift.
+func flattenAttributes(prefix string, value any) map[string]string {
+	out := make(map[string]string)
+
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case nil:
+	case map[string]any:
+		for key, nested := range v {
+			for p, s := range flattenAttributes(join(key), nested) {
+				out[p] = s
+			}
+		}
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			out[prefix] = fmt.Sprint(value)
+			break
+		}
+
+		var scalars []string
+		for i := 0; i < rv.Len(); i++ {
+			item := rv.Index(i).Interface()
+			switch item.(type) {
+			case map[string]any, []any:
+				for p, s := range flattenAttributes(join(fmt.Sprint(i)), item) {
+					out[p] = s
+				}
+			default:
+				scalars = append(scalars, fmt.Sprint(item))
+			}
+		}
+		sort.Strings(scalars)
+		for i, s := range scalars {
+			out[join(fmt.Sprint(i))] = s
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,83 @@
+package drift
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestSameValue(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		a, b string
+		want bool
+	}{
+		{name: "equal", path: "labels.env", a: "prod", b: "prod", want: true},
+		{name: "different", path: "labels.env", a: "prod", b: "dev"},
+		{name: "short and qualified", path: "topic", a: "orders", b: "projects/acme/topics/orders", want: true},
+		{name: "qualified and short", path: "name", a: "projects/acme/topics/orders", b: "orders", want: true},
+		{name: "short name of another resource", path: "topic", a: "orders", b: "projects/acme/topics/old-orders"},
+		{name: "qualified names of different projects", path: "topic", a: "projects/acme/topics/orders", b: "projects/other/topics/orders"},
+		{name: "not a reference attribute", path: "labels.topic", a: "orders", b: "projects/acme/topics/orders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameValue(tt.path, tt.a, tt.b); got != tt.want {
+				t.Errorf("sameValue(%s, %q, %q) = %t, want %t", tt.path, tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlattenAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value map[string]any
+		want  map[string]string
+	}{
+		{
+			name:  "nested maps",
+			value: map[string]any{"labels": map[string]any{"env": "prod"}, "size": 10},
+			want:  map[string]string{"labels.env": "prod", "size": "10"},
+		},
+		{
+			name:  "scalar lists are sorted",
+			value: map[string]any{"members": []string{"user:b@example.com", "user:a@example.com"}},
+			want:  map[string]string{"members.0": "user:a@example.com", "members.1": "user:b@example.com"},
+		},
+		{
+			name:  "scalar any lists are sorted",
+			value: map[string]any{"zones": []any{"b", "a"}},
+			want:  map[string]string{"zones.0": "a", "zones.1": "b"},
+		},
+		{
+			name:  "lists of blocks keep their order",
+			value: map[string]any{"rule": []any{map[string]any{"age": 30}, map[string]any{"age": 7}}},
+			want:  map[string]string{"rule.0.age": "30", "rule.1.age": "7"},
+		},
+		{
+			name:  "nil values are skipped",
+			value: map[string]any{"labels": nil},
+			want:  map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flattenAttributes("", tt.value); !maps.Equal(got, tt.want) {
+				t.Errorf("flattenAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareAttributes(t *testing.T) {
+	cloud := map[string]any{"labels": map[string]any{"env": "prod"}, "topic": "orders", "members": []any{"b", "a"}}
+	state := map[string]any{"labels": map[string]any{"env": "dev"}, "topic": "projects/acme/topics/orders",
+		"members": []any{"a", "b"}, "id": "projects/acme/topics/orders"}
+
+	changes := compareAttributes("google_pubsub_topic.orders", cloud, state)
+	want := Change{Address: "google_pubsub_topic.orders", Attribute: "labels.env", Cloud: "prod", State: "dev"}
+	if len(changes) != 1 || changes[0] != want {
+		t.Errorf("compareAttributes() = %+v, want %+v", changes, want)
+	}
+}
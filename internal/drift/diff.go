@@ -0,0 +1,72 @@
+package drift
+
+import (
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/state"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// ResourceDiff is the drift of a single resource address. Ignore rules are
+// not applied, so noisy attributes show up as well.
+type ResourceDiff struct {
+	Address string
+	InCloud bool
+	InState bool
+	Changes []Change
+}
+
+// DiffResource compares the discovered resource at address with its state.
+// Addresses may be inside modules, like module.pubsub.google_pubsub_topic.t.
+func DiffResource(address string, discovered []resource.Resource, managed []state.Resource) ResourceDiff {
+	diff := ResourceDiff{Address: address}
+	local := LocalAddress(address)
+
+	var cloudAttributes, stateAttributes map[string]any
+	for _, resource := range discovered {
+		for _, r := range resource.Flatten() {
+			if r.Address() == local {
+				diff.InCloud = true
+				cloudAttributes = r.Attributes
+				break
+			}
+		}
+	}
+	for _, r := range managed {
+		if r.Address == address {
+			diff.InState = true
+			stateAttributes = r.Attributes
+			break
+		}
+	}
+
+	if diff.InCloud && diff.InState {
+		diff.Changes = compareAttributes(address, cloudAttributes, stateAttributes)
+	}
+	return diff
+}
+
+// LocalAddress strips the module path from a resource address, turning
+// module.a["x"].module.b.google_pubsub_topic.t into google_pubsub_topic.t.
+func LocalAddress(address string) string {
+	for strings.HasPrefix(address, "module.") {
+		rest := address[len("module."):]
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			return address
+		}
+		// Module instance keys are quoted and may contain dots
+		if rest[end] == '[' {
+			closing := strings.Index(rest[end:], "]")
+			if closing < 0 {
+				return address
+			}
+			end += closing + 1
+		}
+		if end >= len(rest) || rest[end] != '.' {
+			return address
+		}
+		address = rest[end+1:]
+	}
+	return address
+}
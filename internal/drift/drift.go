@@ -0,0 +1,153 @@
+package drift
+
+import (
+	"github.com/priyanshujain/infrasync/internal/state"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// Report describes how discovered cloud resources differ from terraform state.
+type Report struct {
+	// Unmanaged resources exist in the cloud but not in state
+	Unmanaged []Unmanaged
+	// Deleted resources are in state but no longer exist in the cloud
+	Deleted []state.Resource
+	// Modified lists attributes whose cloud value differs from state
+	Modified []Change
+}
+
+// Unmanaged identifies a resource which exists in the cloud but not in state.
+// Only the address and ID are kept so that reports stay small for large
+// projects; use Detector.OnUnmanaged to see the full resource.
+type Unmanaged struct {
+	Address string
+	ID      string
+}
+
+func (r Report) HasDrift() bool {
+	return len(r.Unmanaged) > 0 || len(r.Deleted) > 0 || len(r.Modified) > 0
+}
+
+// Detector compares discovered resources with the managed resources in state
+// as they are observed, so discovery results don't need to be held in memory.
+// Discovered resources are matched to state by type and import ID, so that
+// resources with count or for_each keys or inside modules are found, and by
+// address for resource types whose id differs from the import ID.
+// Only state resources of the given types are considered for deletion, since
+// discovery doesn't cover other types. Drift matching one of the ignore rules
+// is left out of the report.
+type Detector struct {
+	byID      map[string]state.Resource
+	byAddress map[string]state.Resource
+	managed   []state.Resource
+	types     []resource.Type
+	ignore    []IgnoreRule
+
+	// inCloud holds the state addresses of the matched resources
+	inCloud     map[string]bool
+	report      Report
+	onUnmanaged func(resource.Resource)
+}
+
+func NewDetector(managed []state.Resource, types []resource.Type, ignore []IgnoreRule) *Detector {
+	byID := make(map[string]state.Resource, len(managed))
+	byAddress := make(map[string]state.Resource, len(managed))
+	for _, r := range managed {
+		if id := r.ID(); id != "" {
+			byID[idKey(resource.Type(r.Type), id)] = r
+		}
+		byAddress[r.Address] = r
+	}
+	return &Detector{
+		byID:      byID,
+		byAddress: byAddress,
+		managed:   managed,
+		types:     types,
+		ignore:    ignore,
+		inCloud:   make(map[string]bool),
+	}
+}
+
+func idKey(resourceType resource.Type, id string) string {
+	return string(resourceType) + " " + id
+}
+
+// match returns the state of a discovered resource
+func (d *Detector) match(resourceType resource.Type, id, address string) (state.Resource, bool) {
+	if r, ok := d.byID[idKey(resourceType, id)]; ok && id != "" {
+		return r, true
+	}
+	r, ok := d.byAddress[address]
+	return r, ok
+}
+
+// OnUnmanaged calls fn with every unmanaged resource as it is observed.
+func (d *Detector) OnUnmanaged(fn func(resource.Resource)) {
+	d.onUnmanaged = fn
+}
+
+// Observe records a discovered resource and its dependents.
+func (d *Detector) Observe(r resource.Resource) {
+	for _, r := range r.Flatten() {
+		address := r.Address()
+		managedResource, ok := d.match(r.Type, r.ID, address)
+		if ok {
+			d.inCloud[managedResource.Address] = true
+		}
+		if ignoresResource(d.ignore, address) {
+			continue
+		}
+
+		if !ok {
+			d.report.Unmanaged = append(d.report.Unmanaged, Unmanaged{Address: address, ID: r.ID})
+			if d.onUnmanaged != nil {
+				// Dependents are reported on their own
+				r.Dependents = nil
+				d.onUnmanaged(r)
+			}
+			continue
+		}
+
+		for _, change := range compareAttributes(managedResource.Address, r.Attributes, managedResource.Attributes) {
+			if !ignoresAttribute(d.ignore, address, change.Attribute) {
+				d.report.Modified = append(d.report.Modified, change)
+			}
+		}
+	}
+}
+
+// Disregard records a discovered resource which is left out of the
+// comparison, so that it isn't reported as deleted either.
+func (d *Detector) Disregard(resourceType resource.Type, id, address string) {
+	if managedResource, ok := d.match(resourceType, id, address); ok {
+		d.inCloud[managedResource.Address] = true
+	}
+}
+
+// Report returns the drift of the resources observed so far. Resources of the
+// covered types which weren't observed are reported as deleted.
+func (d *Detector) Report() Report {
+	report := d.report
+
+	covered := make(map[string]bool, len(d.types))
+	for _, t := range d.types {
+		covered[string(t)] = true
+	}
+	report.Deleted = nil
+	for _, r := range d.managed {
+		if covered[r.Type] && !d.inCloud[r.Address] && !ignoresResource(d.ignore, r.Address) {
+			report.Deleted = append(report.Deleted, r)
+		}
+	}
+
+	return report
+}
+
+// Detect compares discovered resources, including their dependents, with the
+// managed resources in state, see Detector.
+func Detect(discovered []resource.Resource, managed []state.Resource, types []resource.Type, ignore []IgnoreRule) Report {
+	detector := NewDetector(managed, types, ignore)
+	for _, r := range discovered {
+		detector.Observe(r)
+	}
+	return detector.Report()
+}
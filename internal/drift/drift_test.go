@@ -0,0 +1,61 @@
+package drift
+
+import (
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/state"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+func TestDetectorMatchesStateByID(t *testing.T) {
+	managed := []state.Resource{
+		{Address: "google_pubsub_topic.t[0]", Type: "google_pubsub_topic",
+			Attributes: map[string]any{"id": "projects/acme/topics/orders", "name": "orders"}},
+		{Address: `module.pubsub["prod"].google_pubsub_topic.t`, Type: "google_pubsub_topic",
+			Attributes: map[string]any{"id": "projects/acme/topics/events", "name": "events"}},
+		{Address: "google_pubsub_topic_iam_binding.orders_publisher", Type: "google_pubsub_topic_iam_binding",
+			Attributes: map[string]any{"id": "projects/acme/topics/orders/roles/pubsub.publisher"}},
+	}
+	discovered := []resource.Resource{
+		{Type: "google_pubsub_topic", Name: "orders", ID: "projects/acme/topics/orders",
+			Attributes: map[string]any{"name": "orders"}},
+		{Type: "google_pubsub_topic", Name: "events", ID: "projects/acme/topics/events",
+			Attributes: map[string]any{"name": "events"}},
+		// Matched by address, as the id differs from the import ID
+		{Type: "google_pubsub_topic_iam_binding", Name: "orders_publisher", ID: "projects/acme/topics/orders roles/pubsub.publisher"},
+	}
+
+	report := Detect(discovered, managed, []resource.Type{"google_pubsub_topic", "google_pubsub_topic_iam_binding"}, nil)
+	if report.HasDrift() {
+		t.Errorf("Detect() = %+v, want no drift", report)
+	}
+}
+
+func TestDetectorDisregard(t *testing.T) {
+	managed := []state.Resource{
+		{Address: "google_pubsub_topic.t[0]", Type: "google_pubsub_topic",
+			Attributes: map[string]any{"id": "projects/acme/topics/orders"}},
+	}
+
+	detector := NewDetector(managed, []resource.Type{"google_pubsub_topic"}, nil)
+	detector.Disregard("google_pubsub_topic", "projects/acme/topics/orders", "google_pubsub_topic.orders")
+	if report := detector.Report(); len(report.Deleted) > 0 {
+		t.Errorf("Deleted = %+v, want none", report.Deleted)
+	}
+}
+
+func TestDetectDeletedCoveredTypesOnly(t *testing.T) {
+	managed := []state.Resource{
+		{Address: "google_pubsub_topic.orders", Type: "google_pubsub_topic"},
+		{Address: "google_pubsub_topic.legacy", Type: "google_pubsub_topic"},
+		{Address: "google_storage_bucket.assets", Type: "google_storage_bucket"},
+		{Address: "google_pubsub_topic.ignored", Type: "google_pubsub_topic"},
+	}
+	discovered := []resource.Resource{{Type: "google_pubsub_topic", Name: "orders"}}
+	ignore := []IgnoreRule{{Resource: "google_pubsub_topic.ignored"}}
+
+	report := Detect(discovered, managed, []resource.Type{"google_pubsub_topic"}, ignore)
+	if len(report.Deleted) != 1 || report.Deleted[0].Address != "google_pubsub_topic.legacy" {
+		t.Errorf("Deleted = %+v, want google_pubsub_topic.legacy only", report.Deleted)
+	}
+}
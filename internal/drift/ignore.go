@@ -0,0 +1,46 @@
+package drift
+
+import (
+	"path"
+	"strings"
+)
+
+// IgnoreRule silences drift for resources whose address matches the Resource
+// glob, e.g. "google_storage_bucket.*". With no Attributes the whole resource
+// is ignored, otherwise only the listed attributes and anything nested below
+// them, e.g. "labels.updated-by" or "lifecycle_rule".
+type IgnoreRule struct {
+	Resource   string
+	Attributes []string
+}
+
+func (r IgnoreRule) matchesResource(address string) bool {
+	if r.Resource == "" {
+		return true
+	}
+	matched, err := path.Match(r.Resource, address)
+	return err == nil && matched
+}
+
+func ignoresResource(rules []IgnoreRule, address string) bool {
+	for _, rule := range rules {
+		if len(rule.Attributes) == 0 && rule.matchesResource(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func ignoresAttribute(rules []IgnoreRule, address, attribute string) bool {
+	for _, rule := range rules {
+		if !rule.matchesResource(address) {
+			continue
+		}
+		for _, a := range rule.Attributes {
+			if attribute == a || strings.HasPrefix(attribute, a+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
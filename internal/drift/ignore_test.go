@@ -0,0 +1,46 @@
+package drift
+
+import "testing"
+
+func TestIgnoreRules(t *testing.T) {
+	rules := []IgnoreRule{
+		{Resource: "google_storage_bucket.*"},
+		{Resource: "google_pubsub_topic.*", Attributes: []string{"labels", "message_retention_duration"}},
+		{Attributes: []string{"labels.updated-by"}},
+	}
+
+	resources := []struct {
+		address string
+		want    bool
+	}{
+		{"google_storage_bucket.assets", true},
+		{"google_pubsub_topic.orders", false},
+		{"module.storage.google_storage_bucket.assets", false},
+	}
+	for _, tt := range resources {
+		if got := ignoresResource(rules, tt.address); got != tt.want {
+			t.Errorf("ignoresResource(%s) = %t, want %t", tt.address, got, tt.want)
+		}
+	}
+
+	attributes := []struct {
+		address   string
+		attribute string
+		want      bool
+	}{
+		{"google_pubsub_topic.orders", "labels", true},
+		{"google_pubsub_topic.orders", "labels.env", true},
+		{"google_pubsub_topic.orders", "labels_extra", false},
+		{"google_pubsub_topic.orders", "message_retention_duration", true},
+		{"google_pubsub_topic.orders", "kms_key_name", false},
+		{"google_pubsub_subscription.orders", "labels.env", false},
+		// Rules without a resource apply to every resource
+		{"google_pubsub_subscription.orders", "labels.updated-by", true},
+		{"google_pubsub_subscription.orders", "labels.updated-by-ci", false},
+	}
+	for _, tt := range attributes {
+		if got := ignoresAttribute(rules, tt.address, tt.attribute); got != tt.want {
+			t.Errorf("ignoresAttribute(%s, %s) = %t, want %t", tt.address, tt.attribute, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"cloud.google.com/go/storage"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"google.golang.org/api/iterator"
+)
+
+type gcs struct {
+	bucket      string
+	prefix      string
+	credentials providers.Credentials
+	apiEndpoint string
+}
+
+// NewGCS stores records as JSON objects under prefix in a GCS bucket, usually
+// the state bucket, which is accessed with credentials through apiEndpoint.
+func NewGCS(bucket, prefix string, credentials providers.Credentials, apiEndpoint string) Store {
+	return &gcs{bucket: bucket, prefix: prefix, credentials: credentials, apiEndpoint: apiEndpoint}
+}
+
+func (g *gcs) client(ctx context.Context) (*storage.Client, error) {
+	opts, err := google.EndpointClientOptions(ctx, g.credentials, g.apiEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	return client, nil
+}
+
+func (g *gcs) Save(ctx context.Context, record Record) error {
+	client, err := g.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	writer := client.Bucket(g.bucket).Object(path.Join(g.prefix, recordName(record))).NewWriter(ctx)
+	writer.ContentType = "application/json"
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to write drift record: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write drift record: %w", err)
+	}
+	return nil
+}
+
+func (g *gcs) List(ctx context.Context) ([]Record, error) {
+	client, err := g.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	bucket := client.Bucket(g.bucket)
+	it := bucket.Objects(ctx, &storage.Query{Prefix: g.prefix + "/"})
+
+	var blobs [][]byte
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list drift records: %w", err)
+		}
+
+		reader, err := bucket.Object(attrs.Name).NewReader(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read drift record %s: %w", attrs.Name, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read drift record %s: %w", attrs.Name, err)
+		}
+		blobs = append(blobs, data)
+	}
+
+	return decodeRecords(blobs)
+}
@@ -0,0 +1,83 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/drift"
+)
+
+// Record is the drift found by a single sync run.
+type Record struct {
+	Time      time.Time      `json:"time"`
+	Project   string         `json:"project"`
+	Unmanaged []string       `json:"unmanaged"`
+	Deleted   []string       `json:"deleted"`
+	Modified  []drift.Change `json:"modified"`
+}
+
+func NewRecord(project string, report drift.Report) Record {
+	record := Record{
+		Time:     time.Now().UTC(),
+		Project:  project,
+		Modified: report.Modified,
+	}
+	for _, r := range report.Unmanaged {
+		record.Unmanaged = append(record.Unmanaged, r.Address)
+	}
+	for _, r := range report.Deleted {
+		record.Deleted = append(record.Deleted, r.Address)
+	}
+	return record
+}
+
+// Status returns how the resource at address drifted in this run, or an
+// empty string if it didn't.
+func (r Record) Status(address string) string {
+	for _, a := range r.Unmanaged {
+		if a == address {
+			return "unmanaged"
+		}
+	}
+	for _, a := range r.Deleted {
+		if a == address {
+			return "deleted"
+		}
+	}
+	var attributes []string
+	for _, c := range r.Modified {
+		if c.Address == address {
+			attributes = append(attributes, c.Attribute)
+		}
+	}
+	if len(attributes) > 0 {
+		return fmt.Sprintf("modified %v", attributes)
+	}
+	return ""
+}
+
+// Store persists drift records.
+type Store interface {
+	Save(ctx context.Context, record Record) error
+	List(ctx context.Context) ([]Record, error)
+}
+
+func recordName(record Record) string {
+	return record.Time.Format("20060102T150405.000000000Z") + ".json"
+}
+
+func decodeRecords(blobs [][]byte) ([]Record, error) {
+	var records []Record
+	for _, data := range blobs {
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to parse drift record: %w", err)
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+)
+
+type local struct {
+	dir string
+}
+
+// NewLocal stores records as JSON files in dir.
+func NewLocal(dir string) Store {
+	return &local{dir: dir}
+}
+
+func (l *local) Save(ctx context.Context, record Record) error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := audit.WriteFile(filepath.Join(l.dir, recordName(record)), data, 0644); err != nil {
+		return fmt.Errorf("failed to write drift record: %w", err)
+	}
+	return nil
+}
+
+func (l *local) List(ctx context.Context) ([]Record, error) {
+	entries, err := os.ReadDir(l.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var blobs [][]byte
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read drift record: %w", err)
+		}
+		blobs = append(blobs, data)
+	}
+
+	return decodeRecords(blobs)
+}
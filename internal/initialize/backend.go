@@ -0,0 +1,163 @@
+package initialize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/priyanshujain/infrasync/internal/config"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+)
+
+// noncurrentVersions is how many older versions of the state the backend
+// bucket keeps
+const noncurrentVersions = 10
+
+// BucketExists reports whether the state bucket exists
+func BucketExists(ctx context.Context, creds providers.Credentials, bucket string) (bool, error) {
+	opts, err := google.ClientOptions(ctx, creds)
+	if err != nil {
+		return false, err
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return false, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	_, err = client.Bucket(bucket).Attrs(ctx)
+	if errors.Is(err, storage.ErrBucketNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get bucket %s: %w", bucket, err)
+	}
+	return true, nil
+}
+
+// CreateBucket creates the state bucket in project unless it exists. State is
+// versioned so that it can be recovered, keeping the last noncurrentVersions
+// versions, and access is controlled by IAM alone.
+func CreateBucket(ctx context.Context, creds providers.Credentials, projectID, location, bucket string) error {
+	exists, err := BucketExists(ctx, creds, bucket)
+	if err != nil {
+		return err
+	}
+	if exists {
+		slog.Info("State bucket already exists", "bucket", bucket)
+		return nil
+	}
+
+	opts, err := google.ClientOptions(ctx, creds)
+	if err != nil {
+		return err
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	attrs := &storage.BucketAttrs{
+		Location:                 location,
+		VersioningEnabled:        true,
+		UniformBucketLevelAccess: storage.UniformBucketLevelAccess{Enabled: true},
+		PublicAccessPrevention:   storage.PublicAccessPreventionEnforced,
+		Lifecycle: storage.Lifecycle{Rules: []storage.LifecycleRule{{
+			Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+			Condition: storage.LifecycleCondition{NumNewerVersions: noncurrentVersions},
+		}}},
+	}
+	if err := client.Bucket(bucket).Create(ctx, projectID, attrs); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+	}
+	slog.Info("Created state bucket", "bucket", bucket, "location", location)
+	return nil
+}
+
+// BucketLocation returns the location the state bucket is created in: the
+// region of the project, or the US multi-region if it has none
+func BucketLocation(region string) string {
+	if region == "" {
+		return "US"
+	}
+	return strings.ToUpper(region)
+}
+
+// createStateBucketFile writes the config of the state bucket, as CreateBucket
+// creates it, to backend.tf with an import block. The file is recorded in the
+// manifest so that importing the storage service doesn't generate the bucket
+// a second time.
+func createStateBucketFile(cfg config.Config) error {
+	stateBucketTmpl := `# Generated by InfraSync
+# The bucket holding the state of this repository
+import {
+  to = google_storage_bucket.{{.Name}}
+  id = "{{.Bucket}}"
+}
+
+resource "google_storage_bucket" "{{.Name}}" {
+  name                        = "{{.Bucket}}"
+  project                     = "{{.ProjectID}}"
+  location                    = "{{.Location}}"
+  uniform_bucket_level_access = true
+  public_access_prevention    = "enforced"
+
+  versioning {
+    enabled = true
+  }
+
+  lifecycle_rule {
+    action {
+      type = "Delete"
+    }
+    condition {
+      num_newer_versions = {{.NoncurrentVersions}}
+    }
+  }
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+`
+
+	provider := cfg.DefaultProvider()
+	bucket := cfg.DefaultBackend().Bucket
+	data := struct {
+		Name               string
+		Bucket             string
+		ProjectID          string
+		Location           string
+		NoncurrentVersions int
+	}{
+		Name:               google.BucketResourceName(bucket),
+		Bucket:             bucket,
+		ProjectID:          provider.ProjectID,
+		Location:           BucketLocation(provider.Region),
+		NoncurrentVersions: noncurrentVersions,
+	}
+
+	path := filepath.Join(cfg.ProjectPath(), "backend.tf")
+	if err := createFileFromTemplate(path, stateBucketTmpl, data); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m, err := manifest.Load(cfg.ManifestPath(), cfg.ProjectPath())
+	if err != nil {
+		return err
+	}
+	m.Record(path, content, fmt.Sprintf("%s.%s", google.ResourceTypeStorageBucket, data.Name))
+	return m.Save()
+}
@@ -1,18 +1,41 @@
 package initialize
 
 import (
+	"bytes"
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 	"text/template"
+	"unicode"
 
+	"github.com/priyanshujain/infrasync/internal/audit"
 	"github.com/priyanshujain/infrasync/internal/config"
 	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/vcs"
 )
 
-func Init(cfg config.Config) error {
+// Options are the choices made when initializing a repository rather than in
+// the config
+type Options struct {
+	// EncryptionKey is the Cloud KMS key OpenTofu encrypts state and plans
+	// with. The encryption block is only added if it is set.
+	EncryptionKey string
+	// ManageStateBucket generates the config of the gcs backend's bucket,
+	// with an import block to bring it under management
+	ManageStateBucket bool
+	// GitHubRepository is the owner/name of the GitHub repository whose
+	// workflow authenticates with Workload Identity Federation instead of a
+	// service account key. The config of the pool is only generated if it is
+	// set.
+	GitHubRepository string
+	// ProjectNumber is the number of the default project, which the workflow
+	// names the workload identity provider by
+	ProjectNumber string
+}
+
+func Init(cfg config.Config, opts Options) error {
 	slog.Info("Initializing new IaC repository", "outputDir", cfg.Path)
 
 	path := cfg.ProjectPath()
@@ -21,18 +44,30 @@ func Init(cfg config.Config) error {
 		return fmt.Errorf("failed to create directory structure: %w", err)
 	}
 
-	if err := createTerraformDefaultFiles(cfg); err != nil {
+	if err := createTerraformDefaultFiles(cfg, opts); err != nil {
 		return fmt.Errorf("failed to create Terraform files: %w", err)
 	}
 
-	if err := initGitRepo(path); err != nil {
-		return fmt.Errorf("failed to initialize git repository: %w", err)
+	if opts.ManageStateBucket {
+		if err := createStateBucketFile(cfg); err != nil {
+			return fmt.Errorf("failed to create state bucket config: %w", err)
+		}
+	}
+
+	if opts.GitHubRepository != "" {
+		if err := createGitHubOIDCFile(cfg, opts.GitHubRepository); err != nil {
+			return fmt.Errorf("failed to create workload identity config: %w", err)
+		}
 	}
 
-	if err := setupGitHubActions(path); err != nil {
+	if err := setupGitHubActions(cfg, opts); err != nil {
 		return fmt.Errorf("failed to setup GitHub Actions: %w", err)
 	}
 
+	if err := initGitRepo(vcs.New(path, cfg.Git)); err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+
 	return nil
 }
 
@@ -53,7 +88,7 @@ func createDirectoryStructure(path string) error {
 	return nil
 }
 
-func createTerraformDefaultFiles(cfg config.Config) error {
+func createTerraformDefaultFiles(cfg config.Config, opts Options) error {
 	provider := cfg.DefaultProvider()
 
 	providerTmpl := `# Generated by InfraSync
@@ -61,7 +96,53 @@ terraform {
   {{if eq .StateBackend "gcs"}}
   backend "gcs" {
     bucket = "{{.StateBucket}}"
-    prefix = "terraform/state"
+    prefix = "{{.StatePrefix}}"
+  }
+  {{end}}
+  {{- if eq .StateBackend "http"}}
+  backend "http" {
+    address        = "{{.Address}}"
+    {{- if .LockAddress}}
+    lock_address   = "{{.LockAddress}}"
+    unlock_address = "{{.UnlockAddress}}"
+    {{- end}}
+    {{- if .LockMethod}}
+    lock_method    = "{{.LockMethod}}"
+    {{- end}}
+    {{- if .UnlockMethod}}
+    unlock_method  = "{{.UnlockMethod}}"
+    {{- end}}
+  }
+  {{end}}
+  {{- if eq .StateBackend "remote"}}
+  cloud {
+    hostname     = "{{.Hostname}}"
+    organization = "{{.Organization}}"
+
+    workspaces {
+      name = "{{.Workspace}}"
+    }
+  }
+  {{end}}
+  {{- if .EncryptionKey}}
+  # State encryption is only supported by OpenTofu
+  encryption {
+    key_provider "gcp_kms" "main" {
+      kms_encryption_key = "{{.EncryptionKey}}"
+      key_length         = 32
+    }
+
+    method "aes_gcm" "main" {
+      keys = key_provider.gcp_kms.main
+    }
+
+    state {
+      method = method.aes_gcm.main
+    }
+
+    plan {
+      method = method.aes_gcm.main
+    }
   }
   {{end}}
 
@@ -70,12 +151,20 @@ terraform {
       source  = "hashicorp/google"
       version = "~> 4.0"
     }
+    google-beta = {
+      source  = "hashicorp/google-beta"
+      version = "~> 4.0"
+    }
   }
 }
 
 provider "google" {
   project = "{{.ProjectID}}"
 }
+
+provider "google-beta" {
+  project = "{{.ProjectID}}"
+}
 `
 
 	variablesTmpl := `# Generated by InfraSync
@@ -94,6 +183,52 @@ variable "region" {
 
 	mainTmpl := `# Generated by InfraSync
 # Main Terraform configuration
+`
+
+	// Other repositories read this one's outputs with the same backend
+	// settings, so the example is parameterized like provider.tf
+	remoteStateTmpl := `# Generated by InfraSync
+# Copy this data source to other repositories to read the outputs of this one,
+# e.g. data.terraform_remote_state.{{.Name}}.outputs.google_storage_bucket_assets_url
+{{- if eq .StateBackend "gcs"}}
+data "terraform_remote_state" "{{.Name}}" {
+  backend = "gcs"
+
+  config = {
+    bucket = "{{.StateBucket}}"
+    prefix = "{{.StatePrefix}}"
+  }
+}
+{{- end}}
+{{- if eq .StateBackend "http"}}
+data "terraform_remote_state" "{{.Name}}" {
+  backend = "http"
+
+  config = {
+    address = "{{.Address}}"
+  }
+}
+{{- end}}
+{{- if eq .StateBackend "remote"}}
+data "terraform_remote_state" "{{.Name}}" {
+  backend = "remote"
+
+  config = {
+    hostname     = "{{.Hostname}}"
+    organization = "{{.Organization}}"
+
+    workspaces = {
+      name = "{{.Workspace}}"
+    }
+  }
+}
+{{- end}}
+{{- if .EncryptionKey}}
+
+# The state is encrypted, so the reading repository needs an encryption block
+# with the same key provider and method, and a remote_state_data_sources block
+# using it
+{{- end}}
 `
 
 	gitignoreTmpl := `# Generated by InfraSync
@@ -102,21 +237,52 @@ variable "region" {
 terraform.tfstate
 terraform.tfstate.backup
 *.tfvars
+.infrasync/*
+!.infrasync/manifest.json
 `
 
 	path := cfg.ProjectPath()
 	backend := cfg.DefaultBackend()
 
 	data := struct {
-		ProjectID    string
-		Region       string
-		StateBackend providers.BackendType
-		StateBucket  string
+		Name          string
+		ProjectID     string
+		Region        string
+		StateBackend  providers.BackendType
+		StateBucket   string
+		StatePrefix   string
+		Hostname      string
+		Organization  string
+		Workspace     string
+		Address       string
+		LockAddress   string
+		UnlockAddress string
+		LockMethod    string
+		UnlockMethod  string
+		EncryptionKey string
 	}{
-		ProjectID:    provider.ProjectID,
-		Region:       provider.Region,
-		StateBackend: backend.Type,
-		StateBucket:  backend.Bucket,
+		Name:          remoteStateName(cfg.Name),
+		ProjectID:     provider.ProjectID,
+		Region:        provider.Region,
+		StateBackend:  backend.Type,
+		StateBucket:   backend.Bucket,
+		StatePrefix:   backend.Prefix,
+		Hostname:      backend.Hostname,
+		Organization:  backend.Organization,
+		Workspace:     backend.Workspace,
+		Address:       backend.Address,
+		LockAddress:   backend.LockAddress,
+		UnlockAddress: backend.UnlockAddress,
+		LockMethod:    backend.LockMethod,
+		UnlockMethod:  backend.UnlockMethod,
+		EncryptionKey: opts.EncryptionKey,
+	}
+
+	if data.StatePrefix == "" {
+		data.StatePrefix = "terraform/state"
+	}
+	if data.Hostname == "" {
+		data.Hostname = "app.terraform.io"
 	}
 
 	if err := createFileFromTemplate(filepath.Join(path, "provider.tf"), providerTmpl, data); err != nil {
@@ -131,6 +297,10 @@ terraform.tfstate.backup
 		return err
 	}
 
+	if err := createFileFromTemplate(filepath.Join(path, "remote_state.tf.example"), remoteStateTmpl, data); err != nil {
+		return err
+	}
+
 	if err := createFileFromTemplate(filepath.Join(path, ".gitignore"), gitignoreTmpl, data); err != nil {
 		return err
 	}
@@ -143,6 +313,11 @@ Infrastructure as Code repository managed with [InfraSync](https://github.com/pr
 
 - resources/: Config files for resources
 - main.tf: Main Terraform configuration
+- outputs.tf: Outputs of the imported resources
+- remote_state.tf.example: Data source for other repositories to read the outputs with
+{{- if .GitHubOIDC}}
+- github_oidc.tf: Workload Identity Federation for the GitHub Actions workflow, to apply once before it runs
+{{- end}}
 
 ## Usage
 
@@ -152,17 +327,19 @@ To import existing resources:
 
 To detect drift and update configurations:
 
-    infrasync sync --project={{.ProjectID}} --state-bucket={{.StateBucket}}
+    infrasync sync
 `
 
 	readmeData := struct {
 		RepoName    string
 		ProjectID   string
 		StateBucket string
+		GitHubOIDC  bool
 	}{
 		RepoName:    cfg.Name,
 		ProjectID:   cfg.DefaultProvider().ProjectID,
 		StateBucket: cfg.DefaultBackend().Bucket,
+		GitHubOIDC:  opts.GitHubRepository != "",
 	}
 
 	if err := createFileFromTemplate(filepath.Join(path, "README.md"), readmeTmpl, readmeData); err != nil {
@@ -172,46 +349,53 @@ To detect drift and update configurations:
 	return nil
 }
 
-func createFileFromTemplate(filePath, tmplStr string, data any) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filePath, err)
+// remoteStateName returns the name of the remote state data source of the
+// repository called name, an identifier without dashes so that references to
+// it don't read as subtractions
+func remoteStateName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || !unicode.IsLetter(rune(name[0])) && name[0] != '_' {
+		name = "infra_" + name
 	}
-	defer file.Close()
+	return name
+}
 
+func createFileFromTemplate(filePath, tmplStr string, data any) error {
 	tmpl, err := template.New(filepath.Base(filePath)).Parse(tmplStr)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	if err := tmpl.Execute(file, data); err != nil {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	return nil
-}
-
-func initGitRepo(path string) error {
-	if err := os.Chdir(path); err != nil {
-		return fmt.Errorf("failed to change to output directory: %w", err)
+	if err := audit.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to create file %s: %w", filePath, err)
 	}
 
-	if err := runCommandHelper("git", "init"); err != nil {
-		return fmt.Errorf("failed to initialize git repository: %w", err)
-	}
+	return nil
+}
 
-	if err := runCommandHelper("git", "add", "."); err != nil {
-		return fmt.Errorf("failed to add files: %w", err)
+func initGitRepo(repo vcs.Repository) error {
+	if err := repo.Init(); err != nil {
+		return err
 	}
 
-	if err := runCommandHelper("git", "commit", "-m", "Initial commit by InfraSync"); err != nil {
-		return fmt.Errorf("failed to commit files: %w", err)
+	if err := repo.Commit("Initial commit by InfraSync"); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func setupGitHubActions(path string) error {
+func setupGitHubActions(cfg config.Config, opts Options) error {
 	workflowTmpl := `# Generated by InfraSync
 name: InfraSync - Infrastructure Drift Detection
 
@@ -227,6 +411,10 @@ jobs:
     permissions:
       contents: write
       pull-requests: write
+      security-events: write
+      {{- if .WorkloadIdentityProvider}}
+      id-token: write
+      {{- end}}
 
     steps:
       - name: Checkout code
@@ -245,7 +433,12 @@ jobs:
         id: auth
         uses: google-github-actions/auth@v1
         with:
+          {{- if .WorkloadIdentityProvider}}
+          workload_identity_provider: {{.WorkloadIdentityProvider}}
+          service_account: {{.ServiceAccount}}
+          {{- else}}
           credentials_json: ${{ "{{" }} secrets.GCP_SA_KEY {{ "}}" }}
+          {{- end}}
           export_environment_variables: true
 
       - name: Setup Terraform
@@ -253,14 +446,10 @@ jobs:
 
       - name: Run InfraSync Sync
         run: |
-          infrasync sync \
-            --project=${{ "{{" }} secrets.GCP_PROJECT {{ "}}" }} \
-            --services=pubsub,cloudsql \
-            --state-bucket=${{ "{{" }} secrets.GCS_STATE_BUCKET {{ "}}" }} \
-            --state-key=terraform/state \
-            --output=.
+          infrasync sync --sarif ${{ "{{" }} runner.temp {{ "}}" }}/infrasync.sarif
 
       - name: Create PR if drift detected
+        id: pr
         if: ${{ "{{" }} env.DRIFT_DETECTED == 'true' {{ "}}" }}
         uses: peter-evans/create-pull-request@v5
         with:
@@ -281,18 +470,29 @@ jobs:
           branch: "infrasync-drift-${{ "{{" }} github.run_id {{ "}}" }}"
           commit-message: "Update Terraform configurations to match cloud state"
           base: main
+
+      # Annotates the pull request with the drift and policy violations
+      - name: Upload SARIF
+        if: ${{ "{{" }} steps.pr.outputs.pull-request-head-sha {{ "}}" }}
+        uses: github/codeql-action/upload-sarif@v3
+        with:
+          sarif_file: ${{ "{{" }} runner.temp {{ "}}" }}/infrasync.sarif
+          ref: refs/pull/${{ "{{" }} steps.pr.outputs.pull-request-number {{ "}}" }}/head
+          sha: ${{ "{{" }} steps.pr.outputs.pull-request-head-sha {{ "}}" }}
 `
 
+	var data struct {
+		WorkloadIdentityProvider string
+		ServiceAccount           string
+	}
+	if opts.GitHubRepository != "" {
+		data.WorkloadIdentityProvider = workloadIdentityProvider(opts.ProjectNumber)
+		data.ServiceAccount = oidcServiceAccount(cfg.DefaultProvider().ProjectID)
+	}
+
 	return createFileFromTemplate(
-		filepath.Join(path, ".github", "workflows", "infrasync.yml"),
+		filepath.Join(cfg.ProjectPath(), ".github", "workflows", "infrasync.yml"),
 		workflowTmpl,
-		nil,
+		data,
 	)
 }
-
-func runCommandHelper(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
@@ -0,0 +1,127 @@
+package initialize
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/priyanshujain/infrasync/internal/config"
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+// The IDs of what createGitHubOIDCFile generates, which the workflow refers to
+const (
+	oidcServiceAccountID = "infrasync-ci"
+	oidcPoolID           = "infrasync-github"
+	oidcProviderID       = "github"
+)
+
+// workloadIdentityProvider returns the full name of the workload identity
+// provider of the project with number projectNumber, as the auth action takes
+// it
+func workloadIdentityProvider(projectNumber string) string {
+	return fmt.Sprintf("projects/%s/locations/global/workloadIdentityPools/%s/providers/%s",
+		projectNumber, oidcPoolID, oidcProviderID)
+}
+
+// oidcServiceAccount returns the email of the service account the workflow
+// impersonates in project
+func oidcServiceAccount(projectID string) string {
+	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", oidcServiceAccountID, projectID)
+}
+
+// createGitHubOIDCFile writes the config which lets the workflow of a GitHub
+// repository impersonate a service account with its OIDC token to
+// github_oidc.tf: a workload identity pool with a provider trusting only
+// tokens of the repository, and a service account which can read the project
+// and write the state.
+func createGitHubOIDCFile(cfg config.Config, repository string) error {
+	oidcTmpl := `# Generated by InfraSync
+# Lets the InfraSync workflow of {{.Repository}} authenticate with GitHub's OIDC
+# tokens instead of a service account key. Apply it once, with your own
+# credentials, before the workflow first runs.
+resource "google_service_account" "infrasync_ci" {
+  project      = "{{.ProjectID}}"
+  account_id   = "{{.AccountID}}"
+  display_name = "InfraSync CI"
+}
+
+resource "google_project_iam_member" "infrasync_ci" {
+  for_each = toset([
+    "roles/viewer",
+    "roles/iam.securityReviewer",
+  ])
+
+  project = "{{.ProjectID}}"
+  role    = each.value
+  member  = "serviceAccount:${google_service_account.infrasync_ci.email}"
+}
+{{- if .StateBucket}}
+
+resource "google_storage_bucket_iam_member" "infrasync_ci_state" {
+  bucket = "{{.StateBucket}}"
+  role   = "roles/storage.objectAdmin"
+  member = "serviceAccount:${google_service_account.infrasync_ci.email}"
+}
+{{- end}}
+
+resource "google_iam_workload_identity_pool" "github" {
+  project                   = "{{.ProjectID}}"
+  workload_identity_pool_id = "{{.PoolID}}"
+  display_name              = "GitHub Actions"
+}
+
+resource "google_iam_workload_identity_pool_provider" "github" {
+  project                            = "{{.ProjectID}}"
+  workload_identity_pool_id          = google_iam_workload_identity_pool.github.workload_identity_pool_id
+  workload_identity_pool_provider_id = "{{.ProviderID}}"
+  display_name                       = "GitHub OIDC"
+
+  attribute_mapping = {
+    "google.subject"       = "assertion.sub"
+    "attribute.repository" = "assertion.repository"
+  }
+  # Tokens of workflows in other repositories are rejected
+  attribute_condition = "assertion.repository == \"{{.Repository}}\""
+
+  oidc {
+    issuer_uri = "https://token.actions.githubusercontent.com"
+  }
+}
+
+resource "google_service_account_iam_member" "infrasync_ci_github" {
+  service_account_id = google_service_account.infrasync_ci.name
+  role               = "roles/iam.workloadIdentityUser"
+  member             = "principalSet://iam.googleapis.com/${google_iam_workload_identity_pool.github.name}/attribute.repository/{{.Repository}}"
+}
+
+output "infrasync_workload_identity_provider" {
+  value = google_iam_workload_identity_pool_provider.github.name
+}
+
+output "infrasync_service_account" {
+  value = google_service_account.infrasync_ci.email
+}
+`
+
+	provider := cfg.DefaultProvider()
+	backend := cfg.DefaultBackend()
+	data := struct {
+		Repository  string
+		ProjectID   string
+		AccountID   string
+		PoolID      string
+		ProviderID  string
+		StateBucket string
+	}{
+		Repository: repository,
+		ProjectID:  provider.ProjectID,
+		AccountID:  oidcServiceAccountID,
+		PoolID:     oidcPoolID,
+		ProviderID: oidcProviderID,
+	}
+	if backend.Type == providers.BackendTypeGCS {
+		data.StateBucket = backend.Bucket
+	}
+
+	return createFileFromTemplate(filepath.Join(cfg.ProjectPath(), "github_oidc.tf"), oidcTmpl, data)
+}
@@ -0,0 +1,203 @@
+// Package inventory reads and writes lists of discovered cloud resources, so
+// they can be reviewed, fed to other tools and imported later.
+package inventory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the encoding of an inventory
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatYAML Format = "yaml"
+)
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatJSON, FormatCSV, FormatYAML:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported inventory format: %s (supported: json, csv, yaml)", name)
+}
+
+// Item is a single discovered resource. Dependents of a resource, such as its
+// IAM bindings, are listed as items of their own.
+type Item struct {
+	Project    string           `json:"project" yaml:"project"`
+	Service    resource.Service `json:"service" yaml:"service"`
+	Type       resource.Type    `json:"type" yaml:"type"`
+	Name       string           `json:"name" yaml:"name"`
+	ID         string           `json:"id" yaml:"id"`
+	Attributes map[string]any   `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// NewItem returns the item of a resource of service in project. Dependents do
+// not always carry their service and provider, so both are passed explicitly.
+func NewItem(project string, service resource.Service, r resource.Resource) Item {
+	return Item{
+		Project:    project,
+		Service:    service,
+		Type:       r.Type,
+		Name:       r.Name,
+		ID:         r.ID,
+		Attributes: r.Attributes,
+	}
+}
+
+// Resource returns the resource of the item, discovered with provider
+func (i Item) Resource(provider providers.Provider) resource.Resource {
+	return resource.Resource{
+		Provider:   provider,
+		Type:       i.Type,
+		Service:    i.Service,
+		Name:       i.Name,
+		ID:         i.ID,
+		Attributes: i.Attributes,
+	}
+}
+
+// Validate checks that the item names a resource infrasync can import
+func (i Item) Validate() error {
+	if !slices.Contains(google.Services, i.Service) {
+		return fmt.Errorf("unsupported service %q", i.Service)
+	}
+	if !slices.Contains(google.ResourceTypes(i.Service), i.Type) {
+		return fmt.Errorf("resource type %q is not imported by service %s", i.Type, i.Service)
+	}
+	if i.Name == "" || i.ID == "" {
+		return fmt.Errorf("%s resource needs a name and an id", i.Type)
+	}
+	return nil
+}
+
+var csvHeader = []string{"project", "service", "type", "name", "id", "attributes"}
+
+// Write encodes items to w
+func Write(w io.Writer, format Format, items []Item) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(items); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, item := range items {
+			// Attributes are nested values, so they are kept as a JSON column
+			var attributes string
+			if len(item.Attributes) > 0 {
+				data, err := json.Marshal(item.Attributes)
+				if err != nil {
+					return fmt.Errorf("failed to encode attributes of %s: %w", item.ID, err)
+				}
+				attributes = string(data)
+			}
+			record := []string{item.Project, string(item.Service), string(item.Type), item.Name, item.ID, attributes}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unsupported inventory format: %s", format)
+}
+
+// ReadFile decodes the inventory at path, in the format given by its extension
+func ReadFile(path string) ([]Item, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "yml" {
+		ext = string(FormatYAML)
+	}
+	format, err := ParseFormat(ext)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory: %w", err)
+	}
+	defer f.Close()
+
+	items, err := Read(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory %s: %w", path, err)
+	}
+	for n, item := range items {
+		if err := item.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid inventory item %d: %w", n+1, err)
+		}
+	}
+	return items, nil
+}
+
+// Read decodes items written by Write
+func Read(r io.Reader, format Format) ([]Item, error) {
+	var items []Item
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	case FormatYAML:
+		if err := yaml.NewDecoder(r).Decode(&items); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return items, nil
+	case FormatCSV:
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		if !slices.Equal(records[0], csvHeader) {
+			return nil, fmt.Errorf("unexpected header, want %s", strings.Join(csvHeader, ","))
+		}
+		for _, record := range records[1:] {
+			item := Item{
+				Project: record[0],
+				Service: resource.Service(record[1]),
+				Type:    resource.Type(record[2]),
+				Name:    record[3],
+				ID:      record[4],
+			}
+			if record[5] != "" {
+				if err := json.Unmarshal([]byte(record[5]), &item.Attributes); err != nil {
+					return nil, fmt.Errorf("failed to decode attributes of %s: %w", item.ID, err)
+				}
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported inventory format: %s", format)
+}
@@ -0,0 +1,106 @@
+// Package keyring keeps credentials in the keychain of the operating system:
+// the macOS Keychain, the Secret Service or KWallet on Linux and the Windows
+// Credential Manager.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/99designs/keyring"
+)
+
+// Service is the service credentials are stored under, by alias
+const Service = "infrasync"
+
+// ErrNotFound is returned when no credentials are stored under an alias
+var ErrNotFound = errors.New("no credentials in keyring")
+
+// backends are the keychains of the operating systems. The others the
+// library supports, such as encrypted files, are left out, as they would
+// prompt for a password of their own.
+var backends = []keyring.BackendType{
+	keyring.KeychainBackend,
+	keyring.SecretServiceBackend,
+	keyring.KWalletBackend,
+	keyring.WinCredBackend,
+}
+
+// aliasRe matches valid aliases
+var aliasRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidateAlias checks that alias can name credentials
+func ValidateAlias(alias string) error {
+	if !aliasRe.MatchString(alias) {
+		return fmt.Errorf("invalid keyring alias %q, use letters, digits, '.', '_' and '-'", alias)
+	}
+	return nil
+}
+
+// Get returns the credentials stored under alias
+func Get(alias string) (string, error) {
+	ring, err := open(alias)
+	if err != nil {
+		return "", err
+	}
+	item, err := ring.Get(alias)
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return "", fmt.Errorf("%w under %s", ErrNotFound, alias)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from keyring: %w", alias, err)
+	}
+	return string(item.Data), nil
+}
+
+// Set stores secret under alias, replacing what was stored under it
+func Set(alias, secret string) error {
+	ring, err := open(alias)
+	if err != nil {
+		return err
+	}
+	err = ring.Set(keyring.Item{
+		Key:         alias,
+		Data:        []byte(secret),
+		Label:       Service + "-" + alias,
+		Description: "InfraSync credentials",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store %s in keyring: %w", alias, err)
+	}
+	return nil
+}
+
+// Delete removes the credentials stored under alias
+func Delete(alias string) error {
+	ring, err := open(alias)
+	if err != nil {
+		return err
+	}
+	err = ring.Remove(alias)
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return fmt.Errorf("%w under %s", ErrNotFound, alias)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s from keyring: %w", alias, err)
+	}
+	return nil
+}
+
+// open validates alias and opens the keychain of the operating system
+func open(alias string) (keyring.Keyring, error) {
+	if err := ValidateAlias(alias); err != nil {
+		return nil, err
+	}
+	ring, err := keyring.Open(keyring.Config{
+		AllowedBackends:          backends,
+		ServiceName:              Service,
+		KeychainTrustApplication: true,
+		WinCredPrefix:            Service,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open keyring: %w", err)
+	}
+	return ring, nil
+}
@@ -0,0 +1,148 @@
+// Package lock keeps concurrent infrasync runs from writing to the same
+// repository at once.
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Info describes the holder of a lock
+type Info struct {
+	Operation string    `json:"operation"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Created   time.Time `json:"created"`
+}
+
+var ErrLocked = fmt.Errorf("repository_locked")
+
+// Lock is a lock file held by the current process
+type Lock struct {
+	path string
+	info Info
+}
+
+// Acquire creates the lock file at path. A lock left behind by a process on
+// this host which is no longer running is stale and taken over; any other lock
+// is only broken when force is set.
+func Acquire(path, operation string, force bool) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	info := Info{
+		Operation: operation,
+		Host:      host,
+		PID:       os.Getpid(),
+		Created:   time.Now().UTC(),
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every attempt creates the file exclusively, so when the holder
+	// releases or the lock is broken, only one run takes it
+	for {
+		err := create(path, data)
+		if err == nil {
+			return &Lock{path: path, info: info}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		holder, err := read(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Released in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case force:
+			slog.Warn("Breaking lock", "operation", holder.Operation, "host", holder.Host, "pid", holder.PID)
+		case holder.stale(host):
+			slog.Warn("Taking over stale lock", "operation", holder.Operation, "pid", holder.PID)
+		default:
+			return nil, fmt.Errorf("%w: %s by %s (pid %d) since %s, pass --force-unlock if it is no longer running",
+				ErrLocked, holder.Operation, holder.Host, holder.PID, holder.Created.Format(time.RFC3339))
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove lock file: %w", err)
+		}
+	}
+}
+
+// Release removes the lock file, unless it was broken and is now held by
+// another run
+func (l *Lock) Release() error {
+	holder, err := read(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if holder.Host != l.info.Host || holder.PID != l.info.PID || !holder.Created.Equal(l.info.Created) {
+		return fmt.Errorf("lock was broken and is now held by %s (pid %d)", holder.Host, holder.PID)
+	}
+
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// create creates the lock file at path, failing with os.ErrExist if it is
+// held. The content is written to a temporary file which is then linked into
+// place, so the lock file is never seen half-written.
+func create(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Link(tmp.Name(), path)
+}
+
+func read(path string) (Info, error) {
+	var info Info
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	// Lock files are never written in place, so one which can't be decoded
+	// wasn't written by infrasync and is left to --force-unlock
+	if err := json.Unmarshal(data, &info); err != nil {
+		return Info{Operation: "unknown"}, nil
+	}
+	return info, nil
+}
+
+// stale reports whether the holder is known to have exited. Holders on other
+// hosts can't be checked and are never stale.
+func (i Info) stale(host string) bool {
+	return i.Host == host && !processRunning(i.PID)
+}
@@ -0,0 +1,82 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireRelease(t *testing.T) {
+	// The lock directory is created, with the platform's separators
+	path := filepath.Join(t.TempDir(), ".infrasync", "lock")
+
+	l, err := Acquire(path, "import", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Acquire(path, "sync", false); !errors.Is(err, ErrLocked) {
+		t.Errorf("second Acquire() = %v, want ErrLocked", err)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file exists after Release(): %v", err)
+	}
+
+	l, err = Acquire(path, "sync", false)
+	if err != nil {
+		t.Fatalf("Acquire() after Release() = %v", err)
+	}
+	l.Release()
+}
+
+func TestAcquireTakesOverStaleLock(t *testing.T) {
+	// The process of an exited command is known not to run anymore
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "lock")
+	host, _ := os.Hostname()
+	data, err := json.Marshal(Info{Operation: "import", Host: host, PID: cmd.Process.Pid, Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Acquire(path, "sync", false)
+	if err != nil {
+		t.Fatalf("Acquire() = %v, want the stale lock taken over", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireForce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+	held, err := Acquire(path, "import", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Acquire(path, "sync", true)
+	if err != nil {
+		t.Fatalf("Acquire() with force = %v", err)
+	}
+	if err := held.Release(); err == nil {
+		t.Error("Release() of the broken lock succeeded")
+	}
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package lock
+
+import "os"
+
+func processRunning(pid int) bool {
+	// FindProcess opens a handle to the process on Windows, which fails once
+	// it has exited
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,194 @@
+// Package manifest tracks the files infrasync generated and the resources they
+// hold, so that files edited by hand since are never overwritten unnoticed and
+// files of resources gone from the cloud can be found.
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Manifest maps generated files, relative to the project path, to what
+// infrasync last wrote to them and the resources they hold
+type Manifest struct {
+	path        string
+	projectPath string
+	Files       map[string]File `json:"files"`
+	// IDs are the import IDs of the generated resources, and their
+	// dependents, keyed by address, which tell renamed resources apart from
+	// new ones
+	IDs map[string]string `json:"ids,omitempty"`
+}
+
+// File is a generated file
+type File struct {
+	// Hash is the SHA-256 of the content infrasync last wrote
+	Hash string `json:"hash"`
+	// Addresses are the resources infrasync generated in the file
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// Load reads the manifest at path, or returns an empty manifest if there is
+// none yet or path is empty. Files are tracked relative to projectPath.
+func Load(path, projectPath string) (*Manifest, error) {
+	m := &Manifest{path: path, projectPath: projectPath, Files: make(map[string]File), IDs: make(map[string]string)}
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]File)
+	}
+	if m.IDs == nil {
+		m.IDs = make(map[string]string)
+	}
+	return m, nil
+}
+
+// Save writes the manifest, replacing the previous one atomically
+func (m *Manifest) Save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Record notes that content was written to the file at path, generating the
+// resource at address
+func (m *Manifest) Record(path string, content []byte, address string) {
+	key := m.key(path)
+	file := m.Files[key]
+	file.Hash = hash(content)
+	if !slices.Contains(file.Addresses, address) {
+		file.Addresses = append(file.Addresses, address)
+	}
+	m.Files[key] = file
+}
+
+// Rewrite notes that content was written to the file at path, without
+// generating a resource in it
+func (m *Manifest) Rewrite(path string, content []byte) {
+	key := m.key(path)
+	if file, ok := m.Files[key]; ok {
+		file.Hash = hash(content)
+		m.Files[key] = file
+	}
+}
+
+// Identify notes the import ID of the resource generated at address
+func (m *Manifest) Identify(address, id string) {
+	m.IDs[address] = id
+}
+
+// AddressOf returns the address the resource of the type with the import ID
+// was generated at
+func (m *Manifest) AddressOf(resourceType, id string) (string, bool) {
+	for address, recorded := range m.IDs {
+		if recorded == id && strings.HasPrefix(address, resourceType+".") {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// Rename notes that the resource at from was moved to the address to
+func (m *Manifest) Rename(from, to string) {
+	for key, file := range m.Files {
+		if i := slices.Index(file.Addresses, from); i >= 0 {
+			file.Addresses[i] = to
+			m.Files[key] = file
+		}
+	}
+	if id, ok := m.IDs[from]; ok {
+		delete(m.IDs, from)
+		m.IDs[to] = id
+	}
+}
+
+// Forget stops tracking the file at path, once it was deleted
+func (m *Manifest) Forget(path string) {
+	key := m.key(path)
+	for _, address := range m.Files[key].Addresses {
+		delete(m.IDs, address)
+	}
+	delete(m.Files, key)
+}
+
+// Owner returns the path of the file the resource at address was generated in
+func (m *Manifest) Owner(address string) (string, bool) {
+	for key, file := range m.Files {
+		if slices.Contains(file.Addresses, address) {
+			return m.filePath(key), true
+		}
+	}
+	return "", false
+}
+
+// Addresses returns the path of the file each generated resource is in, keyed
+// by its address
+func (m *Manifest) Addresses() map[string]string {
+	owners := make(map[string]string)
+	for key, file := range m.Files {
+		for _, address := range file.Addresses {
+			owners[address] = m.filePath(key)
+		}
+	}
+	return owners
+}
+
+// Modified reports whether the file at path, holding content, differs from
+// what infrasync last wrote to it. Files infrasync did not generate count as
+// modified.
+func (m *Manifest) Modified(path string, content []byte) bool {
+	file, ok := m.Files[m.key(path)]
+	return !ok || file.Hash != hash(content)
+}
+
+// key returns the slash separated path of a file relative to the project
+func (m *Manifest) key(path string) string {
+	if rel, err := filepath.Rel(m.projectPath, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// filePath returns the path of the file tracked as key
+func (m *Manifest) filePath(key string) string {
+	return filepath.Join(m.projectPath, filepath.FromSlash(key))
+}
+
+// hash returns the hash of content. Line endings are normalized first, so
+// that files checked out with CRLF endings on Windows count as unchanged.
+func hash(content []byte) string {
+	sum := sha256.Sum256(bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+func TestModified(t *testing.T) {
+	projectPath := t.TempDir()
+	m, err := Load("", projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectPath, "resources", "topics.tf")
+	m.Record(path, []byte("resource \"google_pubsub_topic\" \"orders\" {}\n"), "google_pubsub_topic.orders")
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    bool
+	}{
+		{name: "as written", path: path, content: "resource \"google_pubsub_topic\" \"orders\" {}\n"},
+		{name: "CRLF line endings", path: path, content: "resource \"google_pubsub_topic\" \"orders\" {}\r\n"},
+		{name: "edited by hand", path: path, content: "resource \"google_pubsub_topic\" \"orders\" {\n  labels = {}\n}\n", want: true},
+		{name: "not generated", path: filepath.Join(projectPath, "main.tf"), content: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Modified(tt.path, []byte(tt.content)); got != tt.want {
+				t.Errorf("Modified() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	// Rewriting the file tracks its new content
+	edited := []byte("resource \"google_pubsub_topic\" \"orders\" {\n  labels = {}\n}\n")
+	m.Rewrite(path, edited)
+	if m.Modified(path, edited) {
+		t.Error("Modified() = true after Rewrite, want false")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	projectPath := t.TempDir()
+	manifestPath := filepath.Join(projectPath, ".infrasync", "manifest.json")
+	path := filepath.Join(projectPath, "resources", "topics.tf")
+	content := []byte("resource \"google_pubsub_topic\" \"orders\" {}\n")
+
+	m, err := Load(manifestPath, projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Record(path, content, "google_pubsub_topic.orders")
+	m.Identify("google_pubsub_topic.orders", "projects/acme/topics/orders")
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(manifestPath, projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Modified(path, content) {
+		t.Error("Modified() of the loaded manifest = true, want false")
+	}
+	if _, ok := loaded.Files["resources/topics.tf"]; !ok {
+		t.Errorf("Files = %v, want resources/topics.tf relative to the project", loaded.Files)
+	}
+	if address, ok := loaded.AddressOf("google_pubsub_topic", "projects/acme/topics/orders"); !ok || address != "google_pubsub_topic.orders" {
+		t.Errorf("AddressOf() = %s, %t, want google_pubsub_topic.orders", address, ok)
+	}
+	if _, ok := loaded.AddressOf("google_pubsub_subscription", "projects/acme/topics/orders"); ok {
+		t.Error("AddressOf() matched a resource of another type")
+	}
+}
+
+func TestAddressesAndForget(t *testing.T) {
+	projectPath := t.TempDir()
+	m, err := Load("", projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics := filepath.Join(projectPath, "topics.tf")
+	buckets := filepath.Join(projectPath, "buckets.tf")
+	m.Record(topics, []byte("a"), "google_pubsub_topic.orders")
+	m.Record(topics, []byte("ab"), "google_pubsub_topic.events")
+	m.Record(buckets, []byte("c"), "google_storage_bucket.assets")
+	m.Identify("google_pubsub_topic.orders", "projects/acme/topics/orders")
+	m.Identify("google_storage_bucket.assets", "assets")
+
+	want := map[string]string{
+		"google_pubsub_topic.orders":   topics,
+		"google_pubsub_topic.events":   topics,
+		"google_storage_bucket.assets": buckets,
+	}
+	if got := m.Addresses(); !maps.Equal(got, want) {
+		t.Errorf("Addresses() = %v, want %v", got, want)
+	}
+	if owner, ok := m.Owner("google_pubsub_topic.events"); !ok || owner != topics {
+		t.Errorf("Owner() = %s, %t, want %s", owner, ok, topics)
+	}
+
+	m.Forget(topics)
+	want = map[string]string{"google_storage_bucket.assets": buckets}
+	if got := m.Addresses(); !maps.Equal(got, want) {
+		t.Errorf("Addresses() after Forget = %v, want %v", got, want)
+	}
+	if _, ok := m.IDs["google_pubsub_topic.orders"]; ok {
+		t.Error("IDs of the forgotten file are kept")
+	}
+	if m.IDs["google_storage_bucket.assets"] != "assets" {
+		t.Error("IDs of other files are forgotten")
+	}
+}
+
+func TestRename(t *testing.T) {
+	projectPath := t.TempDir()
+	m, err := Load("", projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectPath, "topics.tf")
+	m.Record(path, []byte("a"), "google_pubsub_topic.orders")
+	m.Identify("google_pubsub_topic.orders", "projects/acme/topics/orders")
+
+	m.Rename("google_pubsub_topic.orders", "google_pubsub_topic.orders_v2")
+	if owner, ok := m.Owner("google_pubsub_topic.orders_v2"); !ok || owner != path {
+		t.Errorf("Owner() = %s, %t, want %s", owner, ok, path)
+	}
+	if _, ok := m.Owner("google_pubsub_topic.orders"); ok {
+		t.Error("Owner() of the old address found")
+	}
+	if m.IDs["google_pubsub_topic.orders_v2"] != "projects/acme/topics/orders" {
+		t.Errorf("IDs = %v, want the ID at the new address", m.IDs)
+	}
+}
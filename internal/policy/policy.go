@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/priyanshujain/infrasync/internal/binary"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// Policies are Rego files in package "infrasync" which define a "deny" set of
+// messages. Each resource is evaluated on its own as input:
+//
+//	package infrasync
+//
+//	deny contains msg if {
+//		input.type == "google_storage_bucket"
+//		not input.attributes.uniform_bucket_level_access
+//		msg := "buckets must have uniform bucket-level access"
+//	}
+const query = `[{"address": r.address, "violations": [m | data.infrasync.deny[m] with input as r]} | r := input.resources[_]]`
+
+type Violation struct {
+	Address string
+	Message string
+}
+
+type input struct {
+	Type       string         `json:"type"`
+	Name       string         `json:"name"`
+	Address    string         `json:"address"`
+	ID         string         `json:"id"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// Evaluate runs the policies in path against the resources and their
+// dependents using the opa CLI.
+func Evaluate(ctx context.Context, path string, resources []resource.Resource) ([]Violation, error) {
+	if _, err := binary.LookPath(binary.OPA); err != nil {
+		return nil, fmt.Errorf("opa is not installed or not in PATH: %w", err)
+	}
+
+	var inputs []input
+	for _, resource := range resources {
+		for _, r := range resource.Flatten() {
+			inputs = append(inputs, input{
+				Type:       string(r.Type),
+				Name:       r.Name,
+				Address:    r.Address(),
+				ID:         r.ID,
+				Attributes: r.Attributes,
+			})
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"resources": inputs})
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := binary.CommandContext(ctx, binary.OPA, "eval", "--format", "json", "--stdin-input", "--data", path, query)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to evaluate policies: %w: %s", err, stderr.String())
+	}
+
+	var output struct {
+		Result []struct {
+			Expressions []struct {
+				Value []struct {
+					Address    string   `json:"address"`
+					Violations []string `json:"violations"`
+				} `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse opa output: %w", err)
+	}
+
+	var violations []Violation
+	for _, result := range output.Result {
+		for _, expression := range result.Expressions {
+			for _, value := range expression.Value {
+				for _, message := range value.Violations {
+					violations = append(violations, Violation{Address: value.Address, Message: message})
+				}
+			}
+		}
+	}
+	return violations, nil
+}
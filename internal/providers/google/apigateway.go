@@ -0,0 +1,274 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	apigateway "google.golang.org/api/apigateway/v1"
+)
+
+// apiGateway imports API Gateway APIs with their configs, and the gateways
+// serving them. APIs and configs are global while gateways are regional. The
+// resources are only supported by the google-beta provider.
+type apiGateway struct {
+	service  *apigateway.Service
+	provider providers.Provider
+	opts     APIGatewayOptions
+}
+
+func NewAPIGateway(ctx context.Context, provider providers.Provider, opts APIGatewayOptions) (*apiGateway, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, apigateway.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := apigateway.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api gateway service: %w", err)
+	}
+	return &apiGateway{
+		service:  service,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (ag *apiGateway) Close() {
+	// No close method for the service
+}
+
+func (ag *apiGateway) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(ag.resources), nil
+}
+
+func (ag *apiGateway) resources(ctx context.Context, resources *discovered) error {
+	projectID := ag.provider.ProjectID
+
+	err := ag.service.Projects.Locations.Apis.List(fmt.Sprintf("projects/%s/locations/global", projectID)).Pages(ctx, func(page *apigateway.ApigatewayListApisResponse) error {
+		for _, api := range page.Apis {
+			stub := Resource{Provider: ag.provider, Type: ResourceTypeAPIGatewayAPI, Service: ServiceAPIGateway,
+				Name: sanitizeName(path.Base(api.Name)), ID: api.Name}
+			resources.add(ctx, stub, func(ctx context.Context) (Resource, error) {
+				return ag.apiResource(ctx, api)
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing API Gateway APIs of project %s: %w", projectID, err)
+	}
+
+	err = ag.service.Projects.Locations.Gateways.List(fmt.Sprintf("projects/%s/locations/-", projectID)).Pages(ctx, func(page *apigateway.ApigatewayListGatewaysResponse) error {
+		for _, gateway := range page.Gateways {
+			if !ag.provider.InRegions(locationOf(gateway.Name)) {
+				continue
+			}
+			stub := Resource{Provider: ag.provider, Type: ResourceTypeAPIGatewayGateway, Service: ServiceAPIGateway,
+				Name: sanitizeName(fmt.Sprintf("%s_%s", locationOf(gateway.Name), path.Base(gateway.Name))), ID: gateway.Name}
+			resources.add(ctx, stub, func(ctx context.Context) (Resource, error) {
+				return ag.gatewayResource(ctx, gateway)
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing API Gateway gateways of project %s: %w", projectID, err)
+	}
+
+	return nil
+}
+
+// apiResource builds the resource of an API, including its IAM bindings and
+// configs. The import ID is its full name,
+// projects/<project>/locations/global/apis/<api>.
+func (ag *apiGateway) apiResource(ctx context.Context, api *apigateway.ApigatewayApi) (Resource, error) {
+	apiID := path.Base(api.Name)
+	attributes := map[string]any{
+		"api_id":       apiID,
+		"project":      ag.provider.ProjectID,
+		"display_name": api.DisplayName,
+	}
+	if api.ManagedService != "" {
+		attributes["managed_service"] = api.ManagedService
+	}
+	if len(api.Labels) > 0 {
+		attributes["labels"] = api.Labels
+	}
+
+	apiResource := Resource{
+		Provider:   ag.provider,
+		Type:       ResourceTypeAPIGatewayAPI,
+		Service:    ServiceAPIGateway,
+		Name:       sanitizeName(apiID),
+		ID:         api.Name,
+		Attributes: attributes,
+	}
+
+	if ag.opts.IncludeIAM {
+		policy, err := ag.policy(ctx, api.Name)
+		if err != nil {
+			return Resource{}, err
+		}
+		apiResource.Dependents = append(apiResource.Dependents, iamResources(ag.opts.IAMMode, iamParent{
+			provider:  ag.provider,
+			service:   ServiceAPIGateway,
+			types:     apiGatewayAPIIAMTypes,
+			attribute: "api",
+			name:      apiID,
+			id:        api.Name,
+		}, policy)...)
+	}
+
+	var configs []*apigateway.ApigatewayApiConfig
+	err := ag.service.Projects.Locations.Apis.Configs.List(api.Name).Pages(ctx, func(page *apigateway.ApigatewayListApiConfigsResponse) error {
+		configs = append(configs, page.ApiConfigs...)
+		return nil
+	})
+	if err != nil {
+		return Resource{}, fmt.Errorf("error listing configs of API %s: %w", apiID, err)
+	}
+	for _, config := range configs {
+		r, err := ag.configResource(ctx, apiID, config)
+		if err != nil {
+			return Resource{}, err
+		}
+		apiResource.Dependents = append(apiResource.Dependents, r)
+	}
+
+	return apiResource, nil
+}
+
+// configResource builds the resource of an API config, named after its API
+// as configs of different APIs may share IDs
+func (ag *apiGateway) configResource(ctx context.Context, apiID string, config *apigateway.ApigatewayApiConfig) (Resource, error) {
+	configID := path.Base(config.Name)
+	attributes := map[string]any{
+		"api":           apiID,
+		"api_config_id": configID,
+		"project":       ag.provider.ProjectID,
+		"display_name":  config.DisplayName,
+	}
+	if config.GatewayServiceAccount != "" {
+		attributes["gateway_config"] = []any{map[string]any{
+			"backend_config": []any{map[string]any{
+				"google_service_account": config.GatewayServiceAccount,
+			}},
+		}}
+	}
+	if len(config.Labels) > 0 {
+		attributes["labels"] = config.Labels
+	}
+
+	name := fmt.Sprintf("%s_%s", apiID, configID)
+	configResource := Resource{
+		Provider:   ag.provider,
+		Type:       ResourceTypeAPIGatewayAPIConfig,
+		Service:    ServiceAPIGateway,
+		Name:       sanitizeName(name),
+		ID:         config.Name,
+		Attributes: attributes,
+	}
+
+	if ag.opts.IncludeIAM {
+		policy, err := ag.policy(ctx, config.Name)
+		if err != nil {
+			return Resource{}, err
+		}
+		configResource.Dependents = append(configResource.Dependents, iamResources(ag.opts.IAMMode, iamParent{
+			provider:     ag.provider,
+			service:      ServiceAPIGateway,
+			types:        apiGatewayAPIConfigIAMTypes,
+			attribute:    "api_config",
+			name:         configID,
+			resourceName: name,
+			scope:        map[string]any{"api": apiID},
+			id:           config.Name,
+		}, policy)...)
+	}
+
+	return configResource, nil
+}
+
+// gatewayResource builds the resource of a gateway, including its IAM
+// bindings. Gateways of different regions may share IDs, so they are named
+// after their region.
+func (ag *apiGateway) gatewayResource(ctx context.Context, gateway *apigateway.ApigatewayGateway) (Resource, error) {
+	gatewayID := path.Base(gateway.Name)
+	region := locationOf(gateway.Name)
+	attributes := map[string]any{
+		"gateway_id":   gatewayID,
+		"project":      ag.provider.ProjectID,
+		"region":       region,
+		"api_config":   gateway.ApiConfig,
+		"display_name": gateway.DisplayName,
+	}
+	if len(gateway.Labels) > 0 {
+		attributes["labels"] = gateway.Labels
+	}
+
+	name := fmt.Sprintf("%s_%s", region, gatewayID)
+	gatewayResource := Resource{
+		Provider:   ag.provider,
+		Type:       ResourceTypeAPIGatewayGateway,
+		Service:    ServiceAPIGateway,
+		Name:       sanitizeName(name),
+		ID:         gateway.Name,
+		Attributes: attributes,
+	}
+
+	if ag.opts.IncludeIAM {
+		policy, err := ag.policy(ctx, gateway.Name)
+		if err != nil {
+			return Resource{}, err
+		}
+		gatewayResource.Dependents = append(gatewayResource.Dependents, iamResources(ag.opts.IAMMode, iamParent{
+			provider:     ag.provider,
+			service:      ServiceAPIGateway,
+			types:        apiGatewayGatewayIAMTypes,
+			attribute:    "gateway",
+			name:         gatewayID,
+			resourceName: name,
+			scope:        map[string]any{"region": region},
+			id:           gateway.Name,
+		}, policy)...)
+	}
+
+	return gatewayResource, nil
+}
+
+// policy returns the IAM policy of an API, config or gateway by its full name
+func (ag *apiGateway) policy(ctx context.Context, name string) (*iam.Policy, error) {
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("api_gateway", name))
+	defer span.End()
+
+	var policy *apigateway.ApigatewayPolicy
+	var err error
+	switch {
+	case locationOf(name) != "global":
+		policy, err = ag.service.Projects.Locations.Gateways.GetIamPolicy(name).Context(ctx).Do()
+	case path.Base(path.Dir(name)) == "configs":
+		policy, err = ag.service.Projects.Locations.Apis.Configs.GetIamPolicy(name).Context(ctx).Do()
+	default:
+		policy, err = ag.service.Projects.Locations.Apis.GetIamPolicy(name).Context(ctx).Do()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting IAM policy for %s: %w", name, err)
+	}
+
+	proto := &iampb.Policy{}
+	for _, binding := range policy.Bindings {
+		// The generated IAM resources have no conditions
+		if binding.Condition != nil {
+			slog.Warn("Skipping conditional API Gateway IAM binding", "resource", name, "role", binding.Role)
+			continue
+		}
+		proto.Bindings = append(proto.Bindings, &iampb.Binding{Role: binding.Role, Members: binding.Members})
+	}
+	return &iam.Policy{InternalProto: proto}, nil
+}
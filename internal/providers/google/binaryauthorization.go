@@ -0,0 +1,278 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	binaryauthorization "google.golang.org/api/binaryauthorization/v1"
+	containeranalysis "google.golang.org/api/containeranalysis/v1"
+)
+
+// binaryAuthorization imports the Binary Authorization policy of a project,
+// which decides which images GKE clusters admit, and its attestors with
+// their IAM policies and the Container Analysis notes they attest with
+type binaryAuthorization struct {
+	service  *binaryauthorization.Service
+	notes    *containeranalysis.Service
+	provider providers.Provider
+	opts     BinaryAuthorizationOptions
+}
+
+func NewBinaryAuthorization(ctx context.Context, provider providers.Provider, opts BinaryAuthorizationOptions) (*binaryAuthorization, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, binaryauthorization.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := binaryauthorization.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create binary authorization service: %w", err)
+	}
+	notes, err := containeranalysis.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container analysis service: %w", err)
+	}
+	return &binaryAuthorization{
+		service:  service,
+		notes:    notes,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (ba *binaryAuthorization) Close() {
+	// No close method for the services
+}
+
+func (ba *binaryAuthorization) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(ba.resources), nil
+}
+
+func (ba *binaryAuthorization) resources(ctx context.Context, resources *discovered) error {
+	projectID := ba.provider.ProjectID
+
+	// Attestors are imported before the policy which requires them
+	err := ba.service.Projects.Attestors.List("projects/"+projectID).Pages(ctx, func(page *binaryauthorization.ListAttestorsResponse) error {
+		for _, attestor := range page.Attestors {
+			stub := Resource{Provider: ba.provider, Type: ResourceTypeBinaryAuthorizationAttestor, Service: ServiceBinaryAuthorization,
+				Name: sanitizeName(path.Base(attestor.Name)), ID: attestor.Name}
+			resources.add(ctx, stub, func(ctx context.Context) (Resource, error) {
+				return ba.attestorResource(ctx, attestor)
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing attestors of project %s: %w", projectID, err)
+	}
+
+	policy, err := ba.service.Projects.GetPolicy(fmt.Sprintf("projects/%s/policy", projectID)).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting binary authorization policy of project %s: %w", projectID, err)
+	}
+	resources.append(ba.policyResource(policy))
+
+	return nil
+}
+
+// policyResource builds the resource of the project's policy, imported by
+// the project. Every project has one, allowing all images by default.
+func (ba *binaryAuthorization) policyResource(policy *binaryauthorization.Policy) Resource {
+	attributes := map[string]any{
+		"project": ba.provider.ProjectID,
+	}
+	if policy.Description != "" {
+		attributes["description"] = policy.Description
+	}
+	if policy.GlobalPolicyEvaluationMode != "" {
+		attributes["global_policy_evaluation_mode"] = policy.GlobalPolicyEvaluationMode
+	}
+	if rule := policy.DefaultAdmissionRule; rule != nil {
+		attributes["default_admission_rule"] = []any{admissionRule(*rule)}
+	}
+
+	var clusters []string
+	for cluster := range policy.ClusterAdmissionRules {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	var clusterRules []any
+	for _, cluster := range clusters {
+		rule := admissionRule(policy.ClusterAdmissionRules[cluster])
+		rule["cluster"] = cluster
+		clusterRules = append(clusterRules, rule)
+	}
+	if len(clusterRules) > 0 {
+		attributes["cluster_admission_rules"] = clusterRules
+	}
+
+	var patterns []any
+	for _, pattern := range policy.AdmissionWhitelistPatterns {
+		patterns = append(patterns, map[string]any{"name_pattern": pattern.NamePattern})
+	}
+	if len(patterns) > 0 {
+		attributes["admission_whitelist_patterns"] = patterns
+	}
+
+	return Resource{
+		Provider:   ba.provider,
+		Type:       ResourceTypeBinaryAuthorizationPolicy,
+		Service:    ServiceBinaryAuthorization,
+		Name:       sanitizeName(ba.provider.ProjectID),
+		ID:         "projects/" + ba.provider.ProjectID,
+		Attributes: attributes,
+	}
+}
+
+func admissionRule(rule binaryauthorization.AdmissionRule) map[string]any {
+	attributes := map[string]any{
+		"evaluation_mode":  rule.EvaluationMode,
+		"enforcement_mode": rule.EnforcementMode,
+	}
+	if len(rule.RequireAttestationsBy) > 0 {
+		attributes["require_attestations_by"] = rule.RequireAttestationsBy
+	}
+	return attributes
+}
+
+// attestorResource builds the resource of an attestor, including its IAM
+// bindings and note. The import ID is its name,
+// projects/<project>/attestors/<name>.
+func (ba *binaryAuthorization) attestorResource(ctx context.Context, attestor *binaryauthorization.Attestor) (Resource, error) {
+	attestorID := path.Base(attestor.Name)
+	attributes := map[string]any{
+		"name":    attestorID,
+		"project": ba.provider.ProjectID,
+	}
+	if attestor.Description != "" {
+		attributes["description"] = attestor.Description
+	}
+	note := attestor.UserOwnedGrafeasNote
+	if note != nil {
+		var keys []any
+		for _, key := range note.PublicKeys {
+			k := map[string]any{"id": key.Id}
+			if key.Comment != "" {
+				k["comment"] = key.Comment
+			}
+			if key.AsciiArmoredPgpPublicKey != "" {
+				k["ascii_armored_pgp_public_key"] = key.AsciiArmoredPgpPublicKey
+			}
+			if pkix := key.PkixPublicKey; pkix != nil {
+				k["pkix_public_key"] = []any{map[string]any{
+					"public_key_pem":      pkix.PublicKeyPem,
+					"signature_algorithm": pkix.SignatureAlgorithm,
+				}}
+			}
+			keys = append(keys, k)
+		}
+		attributes["attestation_authority_note"] = []any{map[string]any{
+			"note_reference": note.NoteReference,
+			"public_keys":    keys,
+		}}
+	}
+
+	attestorResource := Resource{
+		Provider:   ba.provider,
+		Type:       ResourceTypeBinaryAuthorizationAttestor,
+		Service:    ServiceBinaryAuthorization,
+		Name:       sanitizeName(attestorID),
+		ID:         attestor.Name,
+		Attributes: attributes,
+	}
+
+	if ba.opts.IncludeNotes && note != nil {
+		r, ok, err := ba.noteResource(ctx, note.NoteReference)
+		if err != nil {
+			return Resource{}, err
+		}
+		if ok {
+			attestorResource.Dependents = append(attestorResource.Dependents, r)
+		}
+	}
+
+	if ba.opts.IncludeIAM {
+		policy, err := ba.attestorPolicy(ctx, attestor.Name)
+		if err != nil {
+			return Resource{}, err
+		}
+		attestorResource.Dependents = append(attestorResource.Dependents, iamResources(ba.opts.IAMMode, iamParent{
+			provider:  ba.provider,
+			service:   ServiceBinaryAuthorization,
+			types:     binaryAuthorizationAttestorIAMTypes,
+			attribute: "attestor",
+			name:      attestorID,
+			scope:     map[string]any{"project": ba.provider.ProjectID},
+			id:        attestor.Name,
+		}, policy)...)
+	}
+
+	return attestorResource, nil
+}
+
+// noteResource builds the resource of the Container Analysis note an
+// attestor attests with, projects/<project>/notes/<name>. Notes of other
+// projects are left to their project.
+func (ba *binaryAuthorization) noteResource(ctx context.Context, name string) (Resource, bool, error) {
+	if !strings.HasPrefix(name, fmt.Sprintf("projects/%s/notes/", ba.provider.ProjectID)) {
+		return Resource{}, false, nil
+	}
+	note, err := ba.notes.Projects.Notes.Get(name).Context(ctx).Do()
+	if err != nil {
+		return Resource{}, false, fmt.Errorf("error getting note %s: %w", name, err)
+	}
+
+	attributes := map[string]any{
+		"name":    path.Base(note.Name),
+		"project": ba.provider.ProjectID,
+	}
+	if note.Attestation != nil && note.Attestation.Hint != nil {
+		attributes["attestation_authority"] = []any{map[string]any{
+			"hint": []any{map[string]any{"human_readable_name": note.Attestation.Hint.HumanReadableName}},
+		}}
+	}
+	if note.ShortDescription != "" {
+		attributes["short_description"] = note.ShortDescription
+	}
+	if note.LongDescription != "" {
+		attributes["long_description"] = note.LongDescription
+	}
+
+	return Resource{
+		Provider:   ba.provider,
+		Type:       ResourceTypeContainerAnalysisNote,
+		Service:    ServiceBinaryAuthorization,
+		Name:       sanitizeName(path.Base(note.Name)),
+		ID:         note.Name,
+		Attributes: attributes,
+	}, true, nil
+}
+
+func (ba *binaryAuthorization) attestorPolicy(ctx context.Context, attestor string) (*iam.Policy, error) {
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("attestor", attestor))
+	defer span.End()
+
+	policy, err := ba.service.Projects.Attestors.GetIamPolicy(attestor).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting IAM policy for attestor %s: %w", attestor, err)
+	}
+
+	proto := &iampb.Policy{}
+	for _, binding := range policy.Bindings {
+		// The generated IAM resources have no conditions
+		if binding.Condition != nil {
+			slog.Warn("Skipping conditional attestor IAM binding", "attestor", attestor, "role", binding.Role)
+			continue
+		}
+		proto.Bindings = append(proto.Bindings, &iampb.Binding{Role: binding.Role, Members: binding.Members})
+	}
+	return &iam.Policy{InternalProto: proto}, nil
+}
@@ -0,0 +1,245 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	certificatemanager "google.golang.org/api/certificatemanager/v1"
+)
+
+// certificateManager imports the Certificate Manager certificates, DNS
+// authorizations and certificate maps of a project. Global resources are
+// always imported, regional ones in the configured regions.
+type certificateManager struct {
+	service  *certificatemanager.Service
+	provider providers.Provider
+	opts     CertificateManagerOptions
+}
+
+func NewCertificateManager(ctx context.Context, provider providers.Provider, opts CertificateManagerOptions) (*certificateManager, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, certificatemanager.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := certificatemanager.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate manager service: %w", err)
+	}
+	return &certificateManager{
+		service:  service,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (cm *certificateManager) Close() {
+	// No close method for the service
+}
+
+func (cm *certificateManager) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(cm.resources), nil
+}
+
+func (cm *certificateManager) resources(ctx context.Context, resources *discovered) error {
+	projectID := cm.provider.ProjectID
+
+	var locations []string
+	err := cm.service.Projects.Locations.List("projects/"+projectID).Pages(ctx, func(page *certificatemanager.ListLocationsResponse) error {
+		for _, location := range page.Locations {
+			if location.LocationId == "global" || cm.provider.InRegions(location.LocationId) {
+				locations = append(locations, location.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing certificate manager locations of project %s: %w", projectID, err)
+	}
+
+	for _, location := range locations {
+		if cm.opts.IncludeDNSAuthorizations {
+			err := cm.service.Projects.Locations.DnsAuthorizations.List(location).Pages(ctx, func(page *certificatemanager.ListDnsAuthorizationsResponse) error {
+				for _, authorization := range page.DnsAuthorizations {
+					resources.append(cm.dnsAuthorizationResource(authorization))
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("error listing DNS authorizations in %s: %w", location, err)
+			}
+		}
+
+		err := cm.service.Projects.Locations.Certificates.List(location).Pages(ctx, func(page *certificatemanager.ListCertificatesResponse) error {
+			for _, certificate := range page.Certificates {
+				resources.append(cm.certificateResource(certificate))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing certificates in %s: %w", location, err)
+		}
+
+		// Certificate maps serve global load balancers and are global only
+		if cm.opts.IncludeMaps && locationOf(location) == "global" {
+			if err := cm.certificateMaps(ctx, location, resources); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// certificateManagerName returns the name of a resource, prefixed by its
+// location as resources of different locations may share names
+func certificateManagerName(name string) string {
+	return sanitizeName(fmt.Sprintf("%s_%s", locationOf(name), path.Base(name)))
+}
+
+func (cm *certificateManager) dnsAuthorizationResource(authorization *certificatemanager.DnsAuthorization) Resource {
+	attributes := map[string]any{
+		"name":     path.Base(authorization.Name),
+		"project":  cm.provider.ProjectID,
+		"location": locationOf(authorization.Name),
+		"domain":   authorization.Domain,
+	}
+	if authorization.Type != "" {
+		attributes["type"] = authorization.Type
+	}
+	if len(authorization.Labels) > 0 {
+		attributes["labels"] = authorization.Labels
+	}
+	return Resource{
+		Provider: cm.provider,
+		Type:     ResourceTypeCertificateManagerDNSAuthorization,
+		Service:  ServiceCertificateManager,
+		Name:     certificateManagerName(authorization.Name),
+		// Import ID for Certificate Manager resources is their full name
+		ID:         authorization.Name,
+		Attributes: attributes,
+	}
+}
+
+// certificateResource builds the resource of a certificate. The key of a
+// self-managed certificate can't be read back, so terraform can neither
+// import nor compare it; generated config ignores changes to it, see
+// tfimport.DefaultLifecycleRules.
+func (cm *certificateManager) certificateResource(certificate *certificatemanager.Certificate) Resource {
+	attributes := map[string]any{
+		"name":     path.Base(certificate.Name),
+		"project":  cm.provider.ProjectID,
+		"location": locationOf(certificate.Name),
+	}
+	if certificate.Scope != "" {
+		attributes["scope"] = certificate.Scope
+	}
+	if certificate.Description != "" {
+		attributes["description"] = certificate.Description
+	}
+	if len(certificate.Labels) > 0 {
+		attributes["labels"] = certificate.Labels
+	}
+	if managed := certificate.Managed; managed != nil {
+		block := map[string]any{"domains": managed.Domains}
+		if len(managed.DnsAuthorizations) > 0 {
+			block["dns_authorizations"] = managed.DnsAuthorizations
+		}
+		if managed.IssuanceConfig != "" {
+			block["issuance_config"] = managed.IssuanceConfig
+		}
+		attributes["managed"] = []any{block}
+	}
+	// The API doesn't return the contents of self-managed certificates
+	if certificate.Managed == nil {
+		slog.Info("Self-managed certificate is imported without its private key, supply it to recreate the certificate",
+			"certificate", certificate.Name)
+	}
+
+	return Resource{
+		Provider:   cm.provider,
+		Type:       ResourceTypeCertificateManagerCertificate,
+		Service:    ServiceCertificateManager,
+		Name:       certificateManagerName(certificate.Name),
+		ID:         certificate.Name,
+		Attributes: attributes,
+	}
+}
+
+// certificateMaps adds the certificate maps of a location to resources, each
+// with its entries as dependents
+func (cm *certificateManager) certificateMaps(ctx context.Context, location string, resources *discovered) error {
+	err := cm.service.Projects.Locations.CertificateMaps.List(location).Pages(ctx, func(page *certificatemanager.ListCertificateMapsResponse) error {
+		for _, certificateMap := range page.CertificateMaps {
+			mapResource, err := cm.certificateMapResource(ctx, certificateMap)
+			if err != nil {
+				return err
+			}
+			resources.append(mapResource)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing certificate maps in %s: %w", location, err)
+	}
+	return nil
+}
+
+// certificateMapResource builds the resource of a certificate map, with its
+// entries
+func (cm *certificateManager) certificateMapResource(ctx context.Context, certificateMap *certificatemanager.CertificateMap) (Resource, error) {
+	attributes := map[string]any{
+		"name":    path.Base(certificateMap.Name),
+		"project": cm.provider.ProjectID,
+	}
+	if certificateMap.Description != "" {
+		attributes["description"] = certificateMap.Description
+	}
+	if len(certificateMap.Labels) > 0 {
+		attributes["labels"] = certificateMap.Labels
+	}
+	mapResource := Resource{
+		Provider:   cm.provider,
+		Type:       ResourceTypeCertificateManagerCertificateMap,
+		Service:    ServiceCertificateManager,
+		Name:       sanitizeName(path.Base(certificateMap.Name)),
+		ID:         certificateMap.Name,
+		Attributes: attributes,
+	}
+
+	err := cm.service.Projects.Locations.CertificateMaps.CertificateMapEntries.List(certificateMap.Name).Pages(ctx, func(page *certificatemanager.ListCertificateMapEntriesResponse) error {
+		for _, entry := range page.CertificateMapEntries {
+			entryAttributes := map[string]any{
+				"name":         path.Base(entry.Name),
+				"project":      cm.provider.ProjectID,
+				"map":          path.Base(certificateMap.Name),
+				"certificates": entry.Certificates,
+			}
+			if entry.Hostname != "" {
+				entryAttributes["hostname"] = entry.Hostname
+			}
+			if entry.Matcher != "" {
+				entryAttributes["matcher"] = entry.Matcher
+			}
+			if len(entry.Labels) > 0 {
+				entryAttributes["labels"] = entry.Labels
+			}
+			mapResource.Dependents = append(mapResource.Dependents, Resource{
+				Provider: cm.provider,
+				Type:     ResourceTypeCertificateManagerCertificateMapEntry,
+				Service:  ServiceCertificateManager,
+				// Entries of different maps may share names
+				Name:       sanitizeName(fmt.Sprintf("%s_%s", path.Base(certificateMap.Name), path.Base(entry.Name))),
+				ID:         entry.Name,
+				Attributes: entryAttributes,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return Resource{}, fmt.Errorf("error listing entries of certificate map %s: %w", certificateMap.Name, err)
+	}
+
+	return mapResource, nil
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/priyanshujain/infrasync/internal/providers"
 	"github.com/priyanshujain/infrasync/internal/providers/google/gcloudclient/cloudsql"
-	"google.golang.org/api/option"
 	sqladmin "google.golang.org/api/sqladmin/v1beta4"
 )
 
@@ -24,39 +25,60 @@ import (
 type cloudSQL struct {
 	service      *sqladmin.Service
 	provider     providers.Provider
+	opts         CloudSQLOptions
 	gcloudClient *cloudsql.Client
+	cleanup      func()
 }
 
-func NewCloudSQL(ctx context.Context, provider providers.Provider) (*cloudSQL, error) {
-	service, err := sqladmin.NewService(ctx, option.WithScopes(sqladmin.CloudPlatformScope))
+func NewCloudSQL(ctx context.Context, provider providers.Provider, opts CloudSQLOptions) (*cloudSQL, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, sqladmin.SqlserviceAdminScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := sqladmin.NewService(ctx, clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cloudsql service: %w", err)
 	}
 
+	env, cleanup, err := gcloudEnv(provider.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
 	return &cloudSQL{
 		service:      service,
 		provider:     provider,
-		gcloudClient: cloudsql.NewClient(),
+		opts:         opts,
+		gcloudClient: cloudsql.NewClient(env...),
+		cleanup:      cleanup,
 	}, nil
 }
 
 func (cs *cloudSQL) Close() {
 	// No close method for the service
+	cs.cleanup()
 }
 
 type cloudSQLIterator struct {
-	ctx           context.Context
 	cloudsql      *cloudSQL
-	instances     []*sqladmin.DatabaseInstance
-	instanceIndex int
+	instances     *cloudsql.InstanceIterator
 	resourceQueue []Resource
 	err           error
 	isClosed      bool
+	// failed is the instance whose error Next returned last
+	failed *sqladmin.DatabaseInstance
+
+	// Replicas are yielded after their primary. imported holds the
+	// connection names of yielded instances, waiting the replicas of
+	// primaries not yielded yet by primary, and ready the instances to
+	// yield before reading more from gcloud.
+	imported map[string]bool
+	waiting  map[string][]*sqladmin.DatabaseInstance
+	ready    []*sqladmin.DatabaseInstance
+	listed   bool
 }
 
 func (it *cloudSQLIterator) Next(ctx context.Context) (*Resource, error) {
-	it.ctx = ctx
-
 	if it.isClosed {
 		return nil, fmt.Errorf("iterator is closed")
 	}
@@ -72,54 +94,102 @@ func (it *cloudSQLIterator) Next(ctx context.Context) (*Resource, error) {
 		return &resource, nil
 	}
 
-	// Check if we have processed all instances
-	if it.instanceIndex >= len(it.instances) {
-		return nil, nil
+	// An instance whose databases or users couldn't be listed is looked up
+	// again until it is skipped
+	instance := it.failed
+	if instance == nil {
+		var err error
+		instance, err = it.nextInstance(ctx)
+		if err != nil || instance == nil {
+			return nil, err
+		}
+		it.imported[connectionName(instance)] = true
+		it.ready = append(it.ready, it.waiting[connectionName(instance)]...)
+		delete(it.waiting, connectionName(instance))
 	}
 
-	// Process next instance
-	instance := it.instances[it.instanceIndex]
-	it.instanceIndex++
-
-	if err := isImportable(instance); err != nil {
-		// Skip this instance and try the next one
-		//
-		slog.Info("Skipping instance due to terraform pre-check", "instance", instance.Name, "error", err)
-		return it.Next(ctx)
+	resource, err := it.cloudsql.instanceResource(ctx, instance)
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return nil, err
 	}
+	if err != nil {
+		it.failed = instance
+		return nil, &ResourceError{Resource: it.cloudsql.instanceStub(instance), Err: err}
+	}
+	it.failed = nil
+	return resource, nil
+}
 
-	instanceName := instance.Name
-	id := fmt.Sprintf("projects/%s/instances/%s", it.cloudsql.provider.ProjectID, instanceName)
-	instanceResource := Resource{
-		Provider: it.cloudsql.provider,
+func (it *cloudSQLIterator) Skip() {
+	it.failed = nil
+}
+
+func (it *cloudSQLIterator) Err() error {
+	return it.err
+}
+
+// instanceStub names an instance which failed to build
+func (cs *cloudSQL) instanceStub(instance *sqladmin.DatabaseInstance) Resource {
+	return Resource{
+		Provider: cs.provider,
 		Type:     ResourceTypeSQLInstance,
 		Service:  ServiceCloudSQL,
-		Name:     sanitizeName(instanceName),
-		ID:       id,
-		Attributes: map[string]any{
-			"project":          it.cloudsql.provider.ProjectID,
-			"name":             instanceName,
-			"database_version": instance.DatabaseVersion,
-			"region":           instance.Region,
-		},
+		Name:     sanitizeName(instance.Name),
+		ID:       fmt.Sprintf("projects/%s/instances/%s", cs.provider.ProjectID, instance.Name),
+	}
+}
+
+// instanceResource builds the resource of an instance, including the
+// databases and users of primaries
+func (cs *cloudSQL) instanceResource(ctx context.Context, instance *sqladmin.DatabaseInstance) (*Resource, error) {
+	instanceName := instance.Name
+	instanceResource := cs.instanceStub(instance)
+	instanceResource.Attributes = map[string]any{
+		"project":          cs.provider.ProjectID,
+		"name":             instanceName,
+		"database_version": instance.DatabaseVersion,
+		"region":           instance.Region,
+	}
+	if instance.Settings != nil {
+		instanceResource.Attributes["settings"] = []any{map[string]any{
+			"tier":              instance.Settings.Tier,
+			"availability_type": instance.Settings.AvailabilityType,
+		}}
+	}
+
+	if instance.MasterInstanceName != "" {
+		instanceResource.Attributes["master_instance_name"] = cs.masterInstanceName(instance)
+		instanceResource.Attributes["instance_type"] = instance.InstanceType
+		if instance.ReplicaConfiguration != nil {
+			instanceResource.Attributes["replica_configuration"] = []any{map[string]any{
+				"failover_target": instance.ReplicaConfiguration.FailoverTarget,
+			}}
+		}
 	}
 
-	if isRunning(instance) {
+	// Databases and users of replicas are those of the primary, which
+	// manages them
+	if instance.MasterInstanceName != "" {
+		return &instanceResource, nil
+	}
+
+	if isRunning(instance) && cs.opts.IncludeDatabases {
 		// Get databases for this instance
-		databases, err := it.cloudsql.getDatabases(it.ctx, instanceName)
+		databases, err := cs.getDatabases(ctx, instanceName)
 		if err != nil {
-			it.err = fmt.Errorf("error getting databases for instance %s: %w", instanceName, err)
-			return nil, it.err
+			return nil, fmt.Errorf("error getting databases for instance %s: %w", instanceName, err)
 		}
 		if len(databases) > 0 {
 			instanceResource.Dependents = append(instanceResource.Dependents, databases...)
 		}
+	}
 
+	if isRunning(instance) && cs.opts.IncludeUsers {
 		// Get users for this instance
-		users, err := it.cloudsql.getUsers(it.ctx, instance)
+		users, err := cs.getUsers(ctx, instance)
 		if err != nil {
-			it.err = fmt.Errorf("error getting users for instance %s: %w", instanceName, err)
-			return nil, it.err
+			return nil, fmt.Errorf("error getting users for instance %s: %w", instanceName, err)
 		}
 		if len(users) > 0 {
 			instanceResource.Dependents = append(instanceResource.Dependents, users...)
@@ -129,27 +199,102 @@ func (it *cloudSQLIterator) Next(ctx context.Context) (*Resource, error) {
 	return &instanceResource, nil
 }
 
+// nextInstance returns the next instance to import, or nil once all were
+// returned. Instances outside the configured regions or which terraform can't
+// import are skipped, and replicas are held back until their primary was
+// returned. Replicas whose primary isn't imported, e.g. because it belongs to
+// another project, are returned last.
+func (it *cloudSQLIterator) nextInstance(ctx context.Context) (*sqladmin.DatabaseInstance, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if len(it.ready) > 0 {
+			instance := it.ready[0]
+			it.ready = it.ready[1:]
+			return instance, nil
+		}
+
+		if it.listed {
+			if len(it.waiting) == 0 {
+				return nil, nil
+			}
+			for _, primary := range slices.Sorted(maps.Keys(it.waiting)) {
+				for _, replica := range it.waiting[primary] {
+					slog.Warn("Importing replica whose primary is not imported", "instance", replica.Name, "primary", primary)
+				}
+				it.ready = append(it.ready, it.waiting[primary]...)
+			}
+			clear(it.waiting)
+			continue
+		}
+
+		next, err := it.instances.Next()
+		if err != nil {
+			it.err = fmt.Errorf("error listing SQL instances: %w", err)
+			return nil, it.err
+		}
+		if next == nil {
+			it.listed = true
+			continue
+		}
+
+		if !it.cloudsql.provider.InRegions(next.Region) {
+			continue
+		}
+
+		if err := isImportable(next); err != nil {
+			slog.Info("Skipping instance due to terraform pre-check", "instance", next.Name, "error", err)
+			continue
+		}
+
+		if primary := next.MasterInstanceName; primary != "" && !it.imported[primary] {
+			it.waiting[primary] = append(it.waiting[primary], next)
+			continue
+		}
+		return next, nil
+	}
+}
+
+// connectionName returns the name replicas refer to their primary by,
+// project:instance
+func connectionName(instance *sqladmin.DatabaseInstance) string {
+	return instance.Project + ":" + instance.Name
+}
+
+// masterInstanceName returns the primary of a replica as master_instance_name
+// takes it: the instance name within the project, or project:instance for a
+// primary in another project
+func (cs *cloudSQL) masterInstanceName(replica *sqladmin.DatabaseInstance) string {
+	project, name, ok := strings.Cut(replica.MasterInstanceName, ":")
+	if ok && project == cs.provider.ProjectID {
+		return name
+	}
+	return replica.MasterInstanceName
+}
+
 func (it *cloudSQLIterator) Close() error {
 	if it.isClosed {
 		return nil
 	}
 	it.isClosed = true
-	return nil
+	return it.instances.Close()
 }
 
 func (cs *cloudSQL) Import(ctx context.Context) (ResourceIterator, error) {
-	// Fetch all instances upfront
-	instances, err := cs.gcloudClient.ListInstances(cs.provider.ProjectID)
+	// Instances are streamed from gcloud as they are consumed
+	instances, err := cs.gcloudClient.Instances(ctx, cs.provider.ProjectID, cs.provider.Regions...)
 	if err != nil {
 		return nil, fmt.Errorf("error listing SQL instances: %w", err)
 	}
 
 	return &cloudSQLIterator{
-		ctx:           ctx,
 		cloudsql:      cs,
 		instances:     instances,
-		instanceIndex: 0,
 		resourceQueue: make([]Resource, 0),
+		imported:      make(map[string]bool),
+		waiting:       make(map[string][]*sqladmin.DatabaseInstance),
 	}, nil
 }
 
@@ -237,23 +382,13 @@ func (cs *cloudSQL) getUsers(ctx context.Context, instance *sqladmin.DatabaseIns
 	return resources, nil
 }
 
+// isImportable reports why terraform can't import an instance. Settings
+// the provider rejects, such as an "any" maintenance window, are corrected in
+// the generated config instead, see tfimport.
 func isImportable(instance *sqladmin.DatabaseInstance) error {
 	if instance.Settings == nil {
 		return fmt.Errorf("instance settings are nil instance")
 	}
-
-	// NOTE: terraform expected settings.0.maintenance_window.0.day to be in the range (1 - 7), got 0
-	if instance.Settings.MaintenanceWindow != nil && instance.Settings.MaintenanceWindow.Day == 0 &&
-		instance.Settings.MaintenanceWindow.Hour == 0 {
-		return fmt.Errorf("instance maintenance window is invalid instance(Any Window is not supported)")
-	}
-
-	// terraform expected settings.0.insights_config.0.query_string_length to be in the range (256 - 4500), got 0
-	if instance.Settings.InsightsConfig != nil &&
-		instance.Settings.InsightsConfig.QueryStringLength == 0 {
-		return fmt.Errorf("instance insights query string length is zero")
-	}
-
 	return nil
 }
 
@@ -0,0 +1,335 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// computeEngine imports the Shared VPC setup, static addresses and managed SSL
+// certificates of a project. A Shared VPC host project gets its host project
+// resource with an attachment per service project and the IAM policies of its
+// subnetworks, which grant service projects their use. Attachments belong to
+// the host, so service projects import none.
+type computeEngine struct {
+	service  *compute.Service
+	provider providers.Provider
+	opts     ComputeOptions
+}
+
+func NewCompute(ctx context.Context, provider providers.Provider, opts ComputeOptions) (*computeEngine, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, compute.ComputeReadonlyScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := compute.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compute service: %w", err)
+	}
+	return &computeEngine{
+		service:  service,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (ce *computeEngine) Close() {
+	// No close method for the service
+}
+
+func (ce *computeEngine) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(ce.resources), nil
+}
+
+func (ce *computeEngine) resources(ctx context.Context, resources *discovered) error {
+	// The host project holds the IAM bindings of every subnetwork, of which
+	// a single failed lookup fails only the host
+	stub := Resource{Provider: ce.provider, Type: ResourceTypeComputeSharedVPCHostProject, Service: ServiceCompute,
+		Name: sanitizeName(ce.provider.ProjectID), ID: ce.provider.ProjectID}
+	resources.addAll(ctx, stub, ce.sharedVPC)
+
+	if ce.opts.IncludeAddresses {
+		if err := ce.addresses(ctx, resources); err != nil {
+			return err
+		}
+	}
+
+	if ce.opts.IncludeSSLCertificates {
+		if err := ce.managedSSLCertificates(ctx, resources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sharedVPC returns the Shared VPC host project resource of a host project
+func (ce *computeEngine) sharedVPC(ctx context.Context) ([]Resource, error) {
+	projectID := ce.provider.ProjectID
+
+	project, err := ce.service.Projects.Get(projectID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting compute project %s: %w", projectID, err)
+	}
+
+	if project.XpnProjectStatus != "HOST" {
+		host, err := ce.service.Projects.GetXpnHost(projectID).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("error getting Shared VPC host of project %s: %w", projectID, err)
+		}
+		if host.Name != "" {
+			slog.Info("Project is a Shared VPC service project, its attachment is imported with the host project",
+				"project", projectID, "host", host.Name)
+		}
+		return nil, nil
+	}
+
+	hostResource := Resource{
+		Provider: ce.provider,
+		Type:     ResourceTypeComputeSharedVPCHostProject,
+		Service:  ServiceCompute,
+		Name:     sanitizeName(projectID),
+		// Import ID for the host project is the project ID
+		ID: projectID,
+		Attributes: map[string]any{
+			"project": projectID,
+		},
+	}
+
+	serviceProjects, err := ce.serviceProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, serviceProject := range serviceProjects {
+		hostResource.Dependents = append(hostResource.Dependents, Resource{
+			Provider: ce.provider,
+			Type:     ResourceTypeComputeSharedVPCServiceProject,
+			Service:  ServiceCompute,
+			Name:     sanitizeName(serviceProject),
+			// Import ID for attachments is host/service
+			ID: fmt.Sprintf("%s/%s", projectID, serviceProject),
+			Attributes: map[string]any{
+				"host_project":    projectID,
+				"service_project": serviceProject,
+			},
+		})
+	}
+
+	if ce.opts.IncludeIAM {
+		bindings, err := ce.subnetworkIAMBindings(ctx)
+		if err != nil {
+			return nil, err
+		}
+		hostResource.Dependents = append(hostResource.Dependents, bindings...)
+	}
+
+	return []Resource{hostResource}, nil
+}
+
+// serviceProjects returns the IDs of the service projects attached to the
+// host project, sorted
+func (ce *computeEngine) serviceProjects(ctx context.Context) ([]string, error) {
+	var projects []string
+	err := ce.service.Projects.GetXpnResources(ce.provider.ProjectID).Pages(ctx, func(page *compute.ProjectsGetXpnResources) error {
+		for _, resource := range page.Resources {
+			if resource.Type == "PROJECT" {
+				projects = append(projects, resource.Id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing Shared VPC service projects of %s: %w", ce.provider.ProjectID, err)
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// subnetworkIAMBindings returns the IAM resources of every subnetwork of the
+// host project. They are attributed to the host, which owns the subnetworks,
+// even when their members are of service projects.
+func (ce *computeEngine) subnetworkIAMBindings(ctx context.Context) ([]Resource, error) {
+	projectID := ce.provider.ProjectID
+
+	var subnetworks []*compute.Subnetwork
+	err := ce.service.Subnetworks.AggregatedList(projectID).Pages(ctx, func(page *compute.SubnetworkAggregatedList) error {
+		for _, scoped := range page.Items {
+			subnetworks = append(subnetworks, scoped.Subnetworks...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing subnetworks of project %s: %w", projectID, err)
+	}
+	sort.Slice(subnetworks, func(i, j int) bool {
+		return subnetworks[i].SelfLink < subnetworks[j].SelfLink
+	})
+
+	var resources []Resource
+	for _, subnetwork := range subnetworks {
+		region := path.Base(subnetwork.Region)
+		if !ce.provider.InRegions(region) {
+			continue
+		}
+		policy, err := ce.subnetworkPolicy(ctx, region, subnetwork.Name)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, iamResources(ce.opts.IAMMode, iamParent{
+			provider:  ce.provider,
+			service:   ServiceCompute,
+			types:     computeSubnetworkIAMTypes,
+			attribute: "subnetwork",
+			name:      subnetwork.Name,
+			// Subnetworks of different regions share names, e.g. default
+			resourceName: fmt.Sprintf("%s_%s", region, subnetwork.Name),
+			// Import ID for subnetworks is their relative path
+			id: fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", projectID, region, subnetwork.Name),
+		}, policy)...)
+	}
+	return resources, nil
+}
+
+func (ce *computeEngine) subnetworkPolicy(ctx context.Context, region, subnetwork string) (*iam.Policy, error) {
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("subnetwork", subnetwork))
+	defer span.End()
+
+	policy, err := ce.service.Subnetworks.GetIamPolicy(ce.provider.ProjectID, region, subnetwork).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting IAM policy for subnetwork %s in %s: %w", subnetwork, region, err)
+	}
+
+	proto := &iampb.Policy{}
+	for _, binding := range policy.Bindings {
+		// The generated IAM resources have no conditions
+		if binding.Condition != nil {
+			slog.Warn("Skipping conditional subnetwork IAM binding", "subnetwork", subnetwork, "role", binding.Role)
+			continue
+		}
+		proto.Bindings = append(proto.Bindings, &iampb.Binding{Role: binding.Role, Members: binding.Members})
+	}
+	return &iam.Policy{InternalProto: proto}, nil
+}
+
+// addresses adds the regional addresses in the configured regions, then the
+// global addresses, to resources
+func (ce *computeEngine) addresses(ctx context.Context, resources *discovered) error {
+	projectID := ce.provider.ProjectID
+
+	err := ce.service.Addresses.AggregatedList(projectID).Pages(ctx, func(page *compute.AddressAggregatedList) error {
+		var regional []*compute.Address
+		for _, scoped := range page.Items {
+			regional = append(regional, scoped.Addresses...)
+		}
+		sort.Slice(regional, func(i, j int) bool {
+			return regional[i].SelfLink < regional[j].SelfLink
+		})
+
+		for _, address := range regional {
+			region := path.Base(address.Region)
+			if !ce.provider.InRegions(region) {
+				continue
+			}
+			resource := ce.addressResource(ResourceTypeComputeAddress, address,
+				fmt.Sprintf("projects/%s/regions/%s/addresses/%s", projectID, region, address.Name))
+			// Addresses of different regions share names
+			resource.Name = sanitizeName(fmt.Sprintf("%s_%s", region, address.Name))
+			resource.Attributes["region"] = region
+			resources.append(resource)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing addresses of project %s: %w", projectID, err)
+	}
+
+	err = ce.service.GlobalAddresses.List(projectID).Pages(ctx, func(page *compute.AddressList) error {
+		for _, address := range page.Items {
+			resources.append(ce.addressResource(ResourceTypeComputeGlobalAddress, address,
+				fmt.Sprintf("projects/%s/global/addresses/%s", projectID, address.Name)))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing global addresses of project %s: %w", projectID, err)
+	}
+
+	return nil
+}
+
+// addressResource builds the resource of an address. Reserved addresses no
+// resource uses are logged, as they are billed while forgotten.
+func (ce *computeEngine) addressResource(resourceType ResourceType, address *compute.Address, id string) Resource {
+	if address.Status == "RESERVED" && address.AddressType != "INTERNAL" {
+		slog.Info("Address is reserved but not in use", "address", id, "ip", address.Address)
+	}
+
+	attributes := map[string]any{
+		"name":         address.Name,
+		"project":      ce.provider.ProjectID,
+		"address":      address.Address,
+		"address_type": address.AddressType,
+	}
+	if address.Purpose != "" {
+		attributes["purpose"] = address.Purpose
+	}
+	if address.NetworkTier != "" {
+		attributes["network_tier"] = address.NetworkTier
+	}
+	if len(address.Labels) > 0 {
+		attributes["labels"] = address.Labels
+	}
+
+	return Resource{
+		Provider: ce.provider,
+		Type:     resourceType,
+		Service:  ServiceCompute,
+		Name:     sanitizeName(address.Name),
+		// Import ID for addresses is their relative path
+		ID:         id,
+		Attributes: attributes,
+	}
+}
+
+// managedSSLCertificates adds the Google-managed global SSL certificates to
+// resources. Self-managed certificates are skipped, as their private key
+// can't be read back.
+func (ce *computeEngine) managedSSLCertificates(ctx context.Context, resources *discovered) error {
+	projectID := ce.provider.ProjectID
+
+	err := ce.service.SslCertificates.List(projectID).Pages(ctx, func(page *compute.SslCertificateList) error {
+		for _, certificate := range page.Items {
+			if certificate.Type != "MANAGED" || certificate.Managed == nil {
+				slog.Info("Skipping self-managed SSL certificate", "certificate", certificate.Name)
+				continue
+			}
+			resources.append(Resource{
+				Provider: ce.provider,
+				Type:     ResourceTypeComputeManagedSSLCertificate,
+				Service:  ServiceCompute,
+				Name:     sanitizeName(certificate.Name),
+				// Import ID for certificates is their relative path
+				ID: fmt.Sprintf("projects/%s/global/sslCertificates/%s", projectID, certificate.Name),
+				Attributes: map[string]any{
+					"name":    certificate.Name,
+					"project": projectID,
+					"managed": []any{map[string]any{"domains": certificate.Managed.Domains}},
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing SSL certificates of project %s: %w", projectID, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package google
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/iterator"
+)
+
+func (ps *pubSub) Count(ctx context.Context, filter Filter) (Count, error) {
+	count := Count{Resources: make(map[ResourceType]int)}
+
+	topics := ps.client.Topics(ctx)
+	for {
+		topic, err := topics.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return Count{}, fmt.Errorf("error iterating topics: %w", err)
+		}
+		id := fmt.Sprintf("projects/%s/topics/%s", ps.provider.ProjectID, topic.ID())
+		if filter.Excludes(ResourceTypePubSubTopic, id) {
+			continue
+		}
+		count.Resources[ResourceTypePubSubTopic]++
+	}
+
+	if ps.opts.IncludeSubscriptions {
+		subscriptions := ps.client.Subscriptions(ctx)
+		for {
+			subscription, err := subscriptions.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return Count{}, fmt.Errorf("error iterating subscriptions: %w", err)
+			}
+			id := fmt.Sprintf("projects/%s/subscriptions/%s", ps.provider.ProjectID, subscription.ID())
+			if filter.Excludes(ResourceTypePubSubSubscription, id) {
+				continue
+			}
+			count.Resources[ResourceTypePubSubSubscription]++
+		}
+	}
+
+	topicCount := count.Resources[ResourceTypePubSubTopic]
+	subscriptionCount := count.Resources[ResourceTypePubSubSubscription]
+	if ps.opts.IncludeIAM {
+		count.APICalls += topicCount + subscriptionCount
+	}
+	if ps.opts.IncludeSubscriptions {
+		// Subscriptions are listed once for the project rather than per topic
+		count.APICalls++
+	}
+	return count, nil
+}
+
+func (gs *gcsStorage) Count(ctx context.Context, filter Filter) (Count, error) {
+	count := Count{Resources: make(map[ResourceType]int)}
+
+	var withACL int
+	buckets := gs.client.Buckets(ctx, gs.provider.ProjectID)
+	for {
+		bucket, err := buckets.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return Count{}, fmt.Errorf("error iterating buckets: %w", err)
+		}
+		if filter.Excludes(ResourceTypeStorageBucket, bucket.Name) {
+			continue
+		}
+		count.Resources[ResourceTypeStorageBucket]++
+		if !bucket.UniformBucketLevelAccess.Enabled {
+			withACL++
+		}
+	}
+
+	if gs.opts.IncludeIAM {
+		count.APICalls += count.Resources[ResourceTypeStorageBucket]
+	}
+	if gs.opts.IncludeACL {
+		// the bucket ACL and default object ACL
+		count.APICalls += 2 * withACL
+	}
+	return count, nil
+}
+
+func (cs *cloudSQL) Count(ctx context.Context, filter Filter) (Count, error) {
+	count := Count{Resources: make(map[ResourceType]int)}
+
+	instances, err := cs.gcloudClient.Instances(ctx, cs.provider.ProjectID, cs.provider.Regions...)
+	if err != nil {
+		return Count{}, fmt.Errorf("error listing SQL instances: %w", err)
+	}
+	defer instances.Close()
+
+	var primaries int
+	for {
+		instance, err := instances.Next()
+		if err != nil {
+			return Count{}, fmt.Errorf("error listing SQL instances: %w", err)
+		}
+		if instance == nil {
+			break
+		}
+		if !cs.provider.InRegions(instance.Region) {
+			continue
+		}
+		id := fmt.Sprintf("projects/%s/instances/%s", cs.provider.ProjectID, instance.Name)
+		if filter.Excludes(ResourceTypeSQLInstance, id) {
+			continue
+		}
+		count.Resources[ResourceTypeSQLInstance]++
+		// Databases and users are only listed for primaries
+		if instance.MasterInstanceName == "" {
+			primaries++
+		}
+	}
+
+	if cs.opts.IncludeDatabases {
+		count.APICalls += primaries
+	}
+	if cs.opts.IncludeUsers {
+		count.APICalls += primaries
+	}
+	return count, nil
+}
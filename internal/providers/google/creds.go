@@ -2,34 +2,164 @@ package google
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"golang.org/x/oauth2/google"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	googleoauth "golang.org/x/oauth2/google"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
 	"google.golang.org/api/storage/v1"
+	"google.golang.org/api/transport"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	readOnlyScope      = "https://www.googleapis.com/auth/cloud-platform.read-only"
 )
 
-func ValidateCredentials() error {
-	const ReadOnly = "https://www.googleapis.com/auth/cloud-platform.read-only"
+// ClientOptions returns the options which make a Google API client use creds
+// with the given scopes. Impersonated tokens are issued for the scopes, or for
+// cloud-platform if there are none. The client connects to the public
+// endpoints, see EndpointClientOptions.
+func ClientOptions(ctx context.Context, creds providers.Credentials, scopes ...string) ([]option.ClientOption, error) {
+	return clientOptions(ctx, creds, nil, scopes...)
+}
+
+// clientOptions is ClientOptions with the IAM credentials API, which issues
+// impersonated tokens, called through base if it is not nil
+func clientOptions(ctx context.Context, creds providers.Credentials, base http.RoundTripper, scopes ...string) ([]option.ClientOption, error) {
+	var opts []option.ClientOption
+	switch {
+	case creds.File != "" && creds.JSON != "":
+		return nil, fmt.Errorf("credentials have both a file and json")
+	case creds.File != "":
+		opts = append(opts, option.WithCredentialsFile(creds.File))
+	case creds.JSON != "":
+		opts = append(opts, option.WithCredentialsJSON([]byte(creds.JSON)))
+	}
+
+	if creds.ImpersonateServiceAccount == "" {
+		if len(scopes) > 0 {
+			opts = append(opts, option.WithScopes(scopes...))
+		}
+		if NeedsQuotaProject(ctx, creds) {
+			opts = append(opts, option.WithQuotaProject(creds.QuotaProject))
+		}
+		return opts, nil
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{cloudPlatformScope}
+	}
+	if base != nil {
+		trans, err := htransport.NewTransport(ctx, base, append(opts, option.WithScopes(cloudPlatformScope))...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create transport: %w", err)
+		}
+		opts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: trans})}
+	}
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: creds.ImpersonateServiceAccount,
+		Scopes:          scopes,
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate %s: %w", creds.ImpersonateServiceAccount, err)
+	}
+	return []option.ClientOption{option.WithTokenSource(ts)}, nil
+}
+
+// NeedsQuotaProject reports whether creds are user credentials, such as those
+// of gcloud auth application-default login, which name no quota project, so
+// that creds.QuotaProject is billed instead. Many APIs reject calls made with
+// user credentials without one. GOOGLE_CLOUD_QUOTA_PROJECT takes precedence.
+func NeedsQuotaProject(ctx context.Context, creds providers.Credentials) bool {
+	if creds.QuotaProject == "" || creds.ImpersonateServiceAccount != "" || os.Getenv("GOOGLE_CLOUD_QUOTA_PROJECT") != "" {
+		return false
+	}
+
+	var data []byte
+	switch {
+	case creds.File != "":
+		data, _ = os.ReadFile(creds.File)
+	case creds.JSON != "":
+		data = []byte(creds.JSON)
+	default:
+		found, err := googleoauth.FindDefaultCredentials(ctx)
+		if err != nil {
+			return false
+		}
+		data = found.JSON
+	}
+
+	var file struct {
+		Type           string `json:"type"`
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return false
+	}
+	return file.Type == "authorized_user" && file.QuotaProjectID == ""
+}
+
+// QuotaProjectHint adds how to fix it to err if an API call failed for lack
+// of a quota project the caller may bill, which happens with user credentials
+func QuotaProjectHint(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	if !strings.Contains(message, "quota project") && !strings.Contains(message, "serviceusage.services.use") &&
+		!strings.Contains(message, "USER_PROJECT_DENIED") {
+		return err
+	}
+	return fmt.Errorf("%w (user credentials need a quota project: grant roles/serviceusage.serviceUsageConsumer "+
+		"on the project to the user, or run `gcloud auth application-default set-quota-project <project>` "+
+		"or set GOOGLE_CLOUD_QUOTA_PROJECT to bill another one)", err)
+}
 
-	_, err := google.FindDefaultCredentials(context.Background(), ReadOnly)
+// ValidateCredentials checks that creds can be loaded and, when a service
+// account is impersonated, that a token can be issued for it
+func ValidateCredentials(ctx context.Context, creds providers.Credentials) error {
+	opts, err := ClientOptions(ctx, creds, readOnlyScope)
 	if err != nil {
 		return err
 	}
 
+	found, err := transport.Creds(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	if creds.ImpersonateServiceAccount != "" {
+		if _, err := found.TokenSource.Token(); err != nil {
+			return fmt.Errorf("failed to impersonate %s: %w", creds.ImpersonateServiceAccount, err)
+		}
+	}
+
 	return nil
 }
 
-func ValidateBackend(bucketName string) error {
+func ValidateBackend(ctx context.Context, creds providers.Credentials, bucketName string) error {
 	if bucketName == "" {
 		return fmt.Errorf("bucket name is empty")
 	}
 
-	ctx := context.Background()
-	service, err := storage.NewService(ctx)
+	opts, err := ClientOptions(ctx, creds)
+	if err != nil {
+		return err
+	}
+	service, err := storage.NewService(ctx, opts...)
 	if err != nil {
 		return err
 	}
 
-	bucket, err := service.Buckets.Get(bucketName).Do()
+	bucket, err := service.Buckets.Get(bucketName).Context(ctx).Do()
 	if err != nil {
 		return fmt.Errorf("failed to get bucket %s: %w", bucketName, err)
 	}
@@ -44,3 +174,39 @@ func ValidateBackend(bucketName string) error {
 
 	return nil
 }
+
+// gcloudEnv returns the environment which makes gcloud use creds. JSON
+// credentials are written to a temporary file, which cleanup removes.
+func gcloudEnv(creds providers.Credentials) ([]string, func(), error) {
+	var env []string
+	cleanup := func() {}
+
+	switch {
+	case creds.File != "":
+		path, err := filepath.Abs(creds.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		env = append(env, "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE="+path)
+	case creds.JSON != "":
+		file, err := os.CreateTemp("", "infrasync-credentials-*.json")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to write credentials for gcloud: %w", err)
+		}
+		cleanup = func() { os.Remove(file.Name()) }
+		_, err = file.WriteString(creds.JSON)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write credentials for gcloud: %w", err)
+		}
+		env = append(env, "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE="+file.Name())
+	}
+
+	if creds.ImpersonateServiceAccount != "" {
+		env = append(env, "CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT="+creds.ImpersonateServiceAccount)
+	}
+	return env, cleanup, nil
+}
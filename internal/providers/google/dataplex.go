@@ -0,0 +1,368 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	datacatalog "google.golang.org/api/datacatalog/v1"
+	dataplex "google.golang.org/api/dataplex/v1"
+)
+
+// multiRegions are the BigQuery multi-regions, in which taxonomies are
+// created for the datasets there. Like global resources, they are always
+// discovered.
+var multiRegions = []string{"us", "eu"}
+
+// dataplexGovernance imports the Data Catalog taxonomies of a project, with
+// their policy tags, and its Dataplex lakes, with their zones and assets, for
+// data governance teams. Data Catalog can't list its locations, so both are
+// looked up in the Dataplex locations in the configured regions.
+type dataplexGovernance struct {
+	dataplex *dataplex.Service
+	catalog  *datacatalog.Service
+	provider providers.Provider
+	opts     DataplexOptions
+}
+
+func NewDataplex(ctx context.Context, provider providers.Provider, opts DataplexOptions) (*dataplexGovernance, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, dataplex.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	dataplexService, err := dataplex.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dataplex service: %w", err)
+	}
+	catalogService, err := datacatalog.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data catalog service: %w", err)
+	}
+	return &dataplexGovernance{
+		dataplex: dataplexService,
+		catalog:  catalogService,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (dg *dataplexGovernance) Close() {
+	// No close method for the services
+}
+
+func (dg *dataplexGovernance) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(dg.resources), nil
+}
+
+func (dg *dataplexGovernance) resources(ctx context.Context, resources *discovered) error {
+	projectID := dg.provider.ProjectID
+
+	var locations []string
+	err := dg.dataplex.Projects.Locations.List("projects/"+projectID).Pages(ctx, func(page *dataplex.GoogleCloudLocationListLocationsResponse) error {
+		for _, location := range page.Locations {
+			if slices.Contains(multiRegions, location.LocationId) || dg.provider.InRegions(location.LocationId) {
+				locations = append(locations, location.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing dataplex locations of project %s: %w", projectID, err)
+	}
+
+	for _, location := range locations {
+		if dg.opts.IncludeTaxonomies {
+			if err := dg.taxonomies(ctx, location, resources); err != nil {
+				return err
+			}
+		}
+
+		if dg.opts.IncludeLakes {
+			err := dg.dataplex.Projects.Locations.Lakes.List(location).Pages(ctx, func(page *dataplex.GoogleCloudDataplexV1ListLakesResponse) error {
+				for _, lake := range page.Lakes {
+					stub := Resource{Provider: dg.provider, Type: ResourceTypeDataplexLake, Service: ServiceDataplex,
+						Name: sanitizeName(fmt.Sprintf("%s_%s", locationOf(lake.Name), path.Base(lake.Name))), ID: lake.Name}
+					resources.add(ctx, stub, func(ctx context.Context) (Resource, error) {
+						return dg.lakeResource(ctx, lake)
+					})
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("error listing lakes in %s: %w", location, err)
+			}
+		}
+	}
+	return nil
+}
+
+// taxonomies adds the taxonomies of a location to resources, each with its
+// policy tags as dependents. Taxonomies owned by a Google service are
+// skipped. The import ID of both is their full name.
+func (dg *dataplexGovernance) taxonomies(ctx context.Context, location string, resources *discovered) error {
+	err := dg.catalog.Projects.Locations.Taxonomies.List(location).Pages(ctx, func(page *datacatalog.GoogleCloudDatacatalogV1ListTaxonomiesResponse) error {
+		for _, taxonomy := range page.Taxonomies {
+			if taxonomy.Service != nil {
+				slog.Debug("Skipping taxonomy owned by a service", "taxonomy", taxonomy.Name, "service", taxonomy.Service.Name)
+				continue
+			}
+			taxonomyResource, err := dg.taxonomyResource(ctx, taxonomy)
+			if err != nil {
+				return err
+			}
+			resources.append(taxonomyResource)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing taxonomies in %s: %w", location, err)
+	}
+	return nil
+}
+
+// taxonomyResource builds the resource of a taxonomy, with its policy tags
+func (dg *dataplexGovernance) taxonomyResource(ctx context.Context, taxonomy *datacatalog.GoogleCloudDatacatalogV1Taxonomy) (Resource, error) {
+	attributes := map[string]any{
+		"display_name": taxonomy.DisplayName,
+		"project":      dg.provider.ProjectID,
+		"region":       locationOf(taxonomy.Name),
+	}
+	if taxonomy.Description != "" {
+		attributes["description"] = taxonomy.Description
+	}
+	if len(taxonomy.ActivatedPolicyTypes) > 0 {
+		attributes["activated_policy_types"] = taxonomy.ActivatedPolicyTypes
+	}
+
+	// Taxonomy IDs are numbers, their display names are unique in a
+	// location
+	name := fmt.Sprintf("%s_%s", locationOf(taxonomy.Name), taxonomy.DisplayName)
+	taxonomyResource := Resource{
+		Provider:   dg.provider,
+		Type:       ResourceTypeDataCatalogTaxonomy,
+		Service:    ServiceDataplex,
+		Name:       sanitizeName(name),
+		ID:         taxonomy.Name,
+		Attributes: attributes,
+	}
+
+	err := dg.catalog.Projects.Locations.Taxonomies.PolicyTags.List(taxonomy.Name).Pages(ctx, func(page *datacatalog.GoogleCloudDatacatalogV1ListPolicyTagsResponse) error {
+		for _, tag := range page.PolicyTags {
+			tagAttributes := map[string]any{
+				"taxonomy":     taxonomy.Name,
+				"display_name": tag.DisplayName,
+			}
+			if tag.Description != "" {
+				tagAttributes["description"] = tag.Description
+			}
+			if tag.ParentPolicyTag != "" {
+				tagAttributes["parent_policy_tag"] = tag.ParentPolicyTag
+			}
+			taxonomyResource.Dependents = append(taxonomyResource.Dependents, Resource{
+				Provider: dg.provider,
+				Type:     ResourceTypeDataCatalogPolicyTag,
+				Service:  ServiceDataplex,
+				// Display names of policy tags are unique in a taxonomy
+				Name:       sanitizeName(fmt.Sprintf("%s_%s", name, tag.DisplayName)),
+				ID:         tag.Name,
+				Attributes: tagAttributes,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return Resource{}, fmt.Errorf("error listing policy tags of taxonomy %s: %w", taxonomy.Name, err)
+	}
+
+	return taxonomyResource, nil
+}
+
+// lakeResource builds the resource of a lake, with its zones and their
+// assets as dependents. The import ID of each is its full name,
+// projects/<project>/locations/<region>/lakes/<lake>[/zones/<zone>[/assets/<asset>]].
+func (dg *dataplexGovernance) lakeResource(ctx context.Context, lake *dataplex.GoogleCloudDataplexV1Lake) (Resource, error) {
+	lakeID := path.Base(lake.Name)
+	location := locationOf(lake.Name)
+
+	attributes := map[string]any{
+		"name":     lakeID,
+		"project":  dg.provider.ProjectID,
+		"location": location,
+	}
+	dataplexDescription(attributes, lake.DisplayName, lake.Description, lake.Labels)
+	if lake.Metastore != nil && lake.Metastore.Service != "" {
+		attributes["metastore"] = []any{map[string]any{"service": lake.Metastore.Service}}
+	}
+
+	// Lakes of different regions may share names
+	name := fmt.Sprintf("%s_%s", location, lakeID)
+	lakeResource := Resource{
+		Provider:   dg.provider,
+		Type:       ResourceTypeDataplexLake,
+		Service:    ServiceDataplex,
+		Name:       sanitizeName(name),
+		ID:         lake.Name,
+		Attributes: attributes,
+	}
+
+	var zones []*dataplex.GoogleCloudDataplexV1Zone
+	err := dg.dataplex.Projects.Locations.Lakes.Zones.List(lake.Name).Pages(ctx, func(page *dataplex.GoogleCloudDataplexV1ListZonesResponse) error {
+		zones = append(zones, page.Zones...)
+		return nil
+	})
+	if err != nil {
+		return Resource{}, fmt.Errorf("error listing zones of lake %s: %w", lake.Name, err)
+	}
+	for _, zone := range zones {
+		r, err := dg.zoneResource(ctx, name, zone)
+		if err != nil {
+			return Resource{}, err
+		}
+		lakeResource.Dependents = append(lakeResource.Dependents, r)
+	}
+
+	return lakeResource, nil
+}
+
+func (dg *dataplexGovernance) zoneResource(ctx context.Context, lakeName string, zone *dataplex.GoogleCloudDataplexV1Zone) (Resource, error) {
+	zoneID := path.Base(zone.Name)
+	attributes := map[string]any{
+		"name":     zoneID,
+		"project":  dg.provider.ProjectID,
+		"location": locationOf(zone.Name),
+		"lake":     path.Base(path.Dir(path.Dir(zone.Name))),
+		"type":     zone.Type,
+	}
+	dataplexDescription(attributes, zone.DisplayName, zone.Description, zone.Labels)
+	if zone.ResourceSpec != nil {
+		attributes["resource_spec"] = []any{map[string]any{"location_type": zone.ResourceSpec.LocationType}}
+	}
+	if spec := zone.DiscoverySpec; spec != nil {
+		block := discoverySpec(spec.Enabled, spec.IncludePatterns, spec.ExcludePatterns, spec.Schedule)
+		if csv := spec.CsvOptions; csv != nil {
+			block["csv_options"] = csvOptions(csv.Delimiter, csv.DisableTypeInference, csv.Encoding, csv.HeaderRows)
+		}
+		if json := spec.JsonOptions; json != nil {
+			block["json_options"] = jsonOptions(json.DisableTypeInference, json.Encoding)
+		}
+		attributes["discovery_spec"] = []any{block}
+	}
+
+	name := fmt.Sprintf("%s_%s", lakeName, zoneID)
+	zoneResource := Resource{
+		Provider:   dg.provider,
+		Type:       ResourceTypeDataplexZone,
+		Service:    ServiceDataplex,
+		Name:       sanitizeName(name),
+		ID:         zone.Name,
+		Attributes: attributes,
+	}
+
+	err := dg.dataplex.Projects.Locations.Lakes.Zones.Assets.List(zone.Name).Pages(ctx, func(page *dataplex.GoogleCloudDataplexV1ListAssetsResponse) error {
+		for _, asset := range page.Assets {
+			zoneResource.Dependents = append(zoneResource.Dependents, dg.assetResource(name, asset))
+		}
+		return nil
+	})
+	if err != nil {
+		return Resource{}, fmt.Errorf("error listing assets of zone %s: %w", zone.Name, err)
+	}
+
+	return zoneResource, nil
+}
+
+func (dg *dataplexGovernance) assetResource(zoneName string, asset *dataplex.GoogleCloudDataplexV1Asset) Resource {
+	assetID := path.Base(asset.Name)
+	zone := path.Dir(path.Dir(asset.Name))
+	attributes := map[string]any{
+		"name":          assetID,
+		"project":       dg.provider.ProjectID,
+		"location":      locationOf(asset.Name),
+		"lake":          path.Base(path.Dir(path.Dir(zone))),
+		"dataplex_zone": path.Base(zone),
+	}
+	dataplexDescription(attributes, asset.DisplayName, asset.Description, asset.Labels)
+	if spec := asset.ResourceSpec; spec != nil {
+		block := map[string]any{"type": spec.Type}
+		if spec.Name != "" {
+			block["name"] = spec.Name
+		}
+		if spec.ReadAccessMode != "" {
+			block["read_access_mode"] = spec.ReadAccessMode
+		}
+		attributes["resource_spec"] = []any{block}
+	}
+	if spec := asset.DiscoverySpec; spec != nil {
+		block := discoverySpec(spec.Enabled, spec.IncludePatterns, spec.ExcludePatterns, spec.Schedule)
+		if csv := spec.CsvOptions; csv != nil {
+			block["csv_options"] = csvOptions(csv.Delimiter, csv.DisableTypeInference, csv.Encoding, csv.HeaderRows)
+		}
+		if json := spec.JsonOptions; json != nil {
+			block["json_options"] = jsonOptions(json.DisableTypeInference, json.Encoding)
+		}
+		attributes["discovery_spec"] = []any{block}
+	}
+
+	return Resource{
+		Provider:   dg.provider,
+		Type:       ResourceTypeDataplexAsset,
+		Service:    ServiceDataplex,
+		Name:       sanitizeName(fmt.Sprintf("%s_%s", zoneName, assetID)),
+		ID:         asset.Name,
+		Attributes: attributes,
+	}
+}
+
+// dataplexDescription sets the attributes describing a lake, zone or asset
+func dataplexDescription(attributes map[string]any, displayName, description string, labels map[string]string) {
+	if displayName != "" {
+		attributes["display_name"] = displayName
+	}
+	if description != "" {
+		attributes["description"] = description
+	}
+	if len(labels) > 0 {
+		attributes["labels"] = labels
+	}
+}
+
+// discoverySpec returns the discovery_spec block of a zone or asset, without
+// its format options
+func discoverySpec(enabled bool, include, exclude []string, schedule string) map[string]any {
+	block := map[string]any{"enabled": enabled}
+	if len(include) > 0 {
+		block["include_patterns"] = include
+	}
+	if len(exclude) > 0 {
+		block["exclude_patterns"] = exclude
+	}
+	if schedule != "" {
+		block["schedule"] = schedule
+	}
+	return block
+}
+
+func csvOptions(delimiter string, disableTypeInference bool, encoding string, headerRows int64) []any {
+	block := map[string]any{"disable_type_inference": disableTypeInference}
+	if delimiter != "" {
+		block["delimiter"] = delimiter
+	}
+	if encoding != "" {
+		block["encoding"] = encoding
+	}
+	if headerRows > 0 {
+		block["header_rows"] = headerRows
+	}
+	return []any{block}
+}
+
+func jsonOptions(disableTypeInference bool, encoding string) []any {
+	block := map[string]any{"disable_type_inference": disableTypeInference}
+	if encoding != "" {
+		block["encoding"] = encoding
+	}
+	return []any{block}
+}
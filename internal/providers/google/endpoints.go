@@ -0,0 +1,149 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/transport"
+	htransport "google.golang.org/api/transport/http"
+	"google.golang.org/grpc"
+)
+
+// The API endpoints which aren't Private Service Connect endpoints
+const (
+	// APIEndpointPrivate is the private.googleapis.com VIP of Private
+	// Google Access
+	APIEndpointPrivate = "private"
+	// APIEndpointRestricted is the restricted.googleapis.com VIP, which only
+	// serves APIs supported by VPC Service Controls
+	APIEndpointRestricted = "restricted"
+)
+
+// pscEndpointRe matches the names of Private Service Connect endpoints
+var pscEndpointRe = regexp.MustCompile(`^[a-z][a-z0-9]{0,19}$`)
+
+// ValidateAPIEndpoint checks that endpoint is private, restricted or the name
+// of a Private Service Connect endpoint
+func ValidateAPIEndpoint(endpoint string) error {
+	if endpoint == "" || endpoint == APIEndpointPrivate || endpoint == APIEndpointRestricted || pscEndpointRe.MatchString(endpoint) {
+		return nil
+	}
+	return fmt.Errorf("invalid api_endpoint %q, use private, restricted or the name of a Private Service Connect endpoint", endpoint)
+}
+
+// EndpointClientOptions returns the options which make a REST API client use
+// creds like ClientOptions and connect to endpoint instead of the public
+// googleapis.com hosts: the private or restricted VIP, or else the Private
+// Service Connect endpoint of that name through its
+// <api>-<endpoint>.p.googleapis.com hosts. Requests keep the host name of the
+// API, which the VIPs and endpoints serve, so only the address connected to
+// changes. Token requests connect to endpoint as well. An empty endpoint
+// connects to the public hosts.
+func EndpointClientOptions(ctx context.Context, creds providers.Credentials, endpoint string, scopes ...string) ([]option.ClientOption, error) {
+	if endpoint == "" {
+		return ClientOptions(ctx, creds, scopes...)
+	}
+	if len(scopes) == 0 {
+		scopes = []string{cloudPlatformScope}
+	}
+
+	base := endpointTransport(endpoint)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: base})
+	opts, err := clientOptions(ctx, creds, base, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	trans, err := htransport.NewTransport(ctx, base, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %w", err)
+	}
+	return []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: trans})}, nil
+}
+
+// providerClientOptions returns the options of a REST API client of provider,
+// see EndpointClientOptions
+func providerClientOptions(ctx context.Context, provider providers.Provider, scopes ...string) ([]option.ClientOption, error) {
+	return EndpointClientOptions(ctx, provider.Credentials, provider.APIEndpoint, scopes...)
+}
+
+// grpcClientOptions returns the options of a gRPC API client of provider,
+// which connects to its API endpoint like a REST client does
+func grpcClientOptions(ctx context.Context, provider providers.Provider, scopes ...string) ([]option.ClientOption, error) {
+	endpoint := provider.APIEndpoint
+	if endpoint == "" {
+		return ClientOptions(ctx, provider.Credentials, scopes...)
+	}
+
+	base := endpointTransport(endpoint)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: base})
+	opts, err := clientOptions(ctx, provider.Credentials, base, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Credentials.ImpersonateServiceAccount == "" {
+		// Loaded here, as otherwise the client would request tokens from the
+		// public hosts
+		found, err := transport.Creds(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		opts = []option.ClientOption{option.WithCredentials(found)}
+		if NeedsQuotaProject(ctx, provider.Credentials) {
+			opts = append(opts, option.WithQuotaProject(provider.Credentials.QuotaProject))
+		}
+	}
+	// gRPC doesn't use the proxy of HTTPS_PROXY with a custom dialer, so it
+	// is only set when needed
+	return append(opts, option.WithGRPCDialOption(grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return dialAPI(ctx, endpoint, "tcp", addr)
+	}))), nil
+}
+
+// endpointTransport returns a clone of the default transport, which keeps its
+// proxy settings, connecting to endpoint
+func endpointTransport(endpoint string) *http.Transport {
+	var base *http.Transport
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		base = transport.Clone()
+	} else {
+		base = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+	base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialAPI(ctx, endpoint, network, addr)
+	}
+	return base
+}
+
+// dialAPI connects to addr, or to endpoint if addr is a googleapis.com host
+func dialAPI(ctx context.Context, endpoint, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return dialer.DialContext(ctx, network, net.JoinHostPort(apiHost(endpoint, host), port))
+}
+
+// apiHost returns the host connections to host go to
+func apiHost(endpoint, host string) string {
+	name, ok := strings.CutSuffix(host, ".googleapis.com")
+	if endpoint == "" || !ok || strings.HasSuffix(name, ".p") {
+		return host
+	}
+	switch endpoint {
+	case APIEndpointPrivate, APIEndpointRestricted:
+		return endpoint + ".googleapis.com"
+	default:
+		// Endpoint hosts are only named after the first label, e.g.
+		// storage-endpoint.p.googleapis.com
+		api, _, _ := strings.Cut(name, ".")
+		return fmt.Sprintf("%s-%s.p.googleapis.com", api, endpoint)
+	}
+}
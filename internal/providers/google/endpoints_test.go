@@ -0,0 +1,24 @@
+package google
+
+import "testing"
+
+func TestAPIHost(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		host     string
+		want     string
+	}{
+		{"", "pubsub.googleapis.com", "pubsub.googleapis.com"},
+		{APIEndpointRestricted, "pubsub.googleapis.com", "restricted.googleapis.com"},
+		{APIEndpointPrivate, "storage.googleapis.com", "private.googleapis.com"},
+		{"apis", "storage.googleapis.com", "storage-apis.p.googleapis.com"},
+		{"apis", "us-central1-aiplatform.googleapis.com", "us-central1-aiplatform-apis.p.googleapis.com"},
+		{"apis", "storage-apis.p.googleapis.com", "storage-apis.p.googleapis.com"},
+		{APIEndpointRestricted, "example.com", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := apiHost(tt.endpoint, tt.host); got != tt.want {
+			t.Errorf("apiHost(%q, %s) = %s, want %s", tt.endpoint, tt.host, got, tt.want)
+		}
+	}
+}
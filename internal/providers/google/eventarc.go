@@ -0,0 +1,167 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	eventarc "google.golang.org/api/eventarc/v1"
+)
+
+// managedByLabel marks triggers another service created and manages, e.g. the
+// trigger of an event-driven Cloud Function
+const managedByLabel = "goog-managed-by"
+
+// eventarcTriggers imports the Eventarc triggers of every configured region
+type eventarcTriggers struct {
+	service  *eventarc.Service
+	provider providers.Provider
+	opts     EventarcOptions
+}
+
+func NewEventarc(ctx context.Context, provider providers.Provider, opts EventarcOptions) (*eventarcTriggers, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, eventarc.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := eventarc.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create eventarc service: %w", err)
+	}
+	return &eventarcTriggers{
+		service:  service,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (et *eventarcTriggers) Close() {
+	// No close method for the service
+}
+
+func (et *eventarcTriggers) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(et.resources), nil
+}
+
+func (et *eventarcTriggers) resources(ctx context.Context, resources *discovered) error {
+	projectID := et.provider.ProjectID
+
+	var locations []string
+	err := et.service.Projects.Locations.List("projects/"+projectID).Pages(ctx, func(page *eventarc.ListLocationsResponse) error {
+		for _, location := range page.Locations {
+			if et.provider.InRegions(location.LocationId) {
+				locations = append(locations, location.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing eventarc locations of project %s: %w", projectID, err)
+	}
+
+	for _, location := range locations {
+		err := et.service.Projects.Locations.Triggers.List(location).Pages(ctx, func(page *eventarc.ListTriggersResponse) error {
+			for _, trigger := range page.Triggers {
+				if manager := trigger.Labels[managedByLabel]; manager != "" && !et.opts.IncludeManaged {
+					slog.Debug("Skipping trigger managed by another service", "trigger", trigger.Name, "managed_by", manager)
+					continue
+				}
+				resources.append(et.triggerResource(trigger))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing eventarc triggers in %s: %w", location, err)
+		}
+	}
+	return nil
+}
+
+// triggerResource builds the resource of a trigger. The import ID is its full
+// name, projects/<project>/locations/<location>/triggers/<name>. Destinations
+// are kept as the API reports them; generated config refers to destinations
+// infrasync generated config for by their address.
+func (et *eventarcTriggers) triggerResource(trigger *eventarc.Trigger) Resource {
+	name := path.Base(trigger.Name)
+	location := locationOf(trigger.Name)
+
+	var criteria []any
+	for _, filter := range trigger.EventFilters {
+		criterion := map[string]any{"attribute": filter.Attribute, "value": filter.Value}
+		if filter.Operator != "" {
+			criterion["operator"] = filter.Operator
+		}
+		criteria = append(criteria, criterion)
+	}
+
+	attributes := map[string]any{
+		"name":              name,
+		"project":           et.provider.ProjectID,
+		"location":          location,
+		"matching_criteria": criteria,
+	}
+	if trigger.ServiceAccount != "" {
+		attributes["service_account"] = trigger.ServiceAccount
+	}
+	if trigger.Channel != "" {
+		attributes["channel"] = trigger.Channel
+	}
+	if len(trigger.Labels) > 0 {
+		attributes["labels"] = trigger.Labels
+	}
+	if destination := triggerDestination(trigger.Destination); destination != nil {
+		attributes["destination"] = []any{destination}
+	}
+	if trigger.Transport != nil && trigger.Transport.Pubsub != nil && trigger.Transport.Pubsub.Topic != "" {
+		attributes["transport"] = []any{map[string]any{
+			"pubsub": []any{map[string]any{"topic": trigger.Transport.Pubsub.Topic}},
+		}}
+	}
+
+	return Resource{
+		Provider: et.provider,
+		Type:     ResourceTypeEventarcTrigger,
+		Service:  ServiceEventarc,
+		// Triggers of different locations may share names
+		Name:       sanitizeName(fmt.Sprintf("%s_%s", location, name)),
+		ID:         trigger.Name,
+		Attributes: attributes,
+	}
+}
+
+// triggerDestination returns the destination block of a trigger as the
+// google_eventarc_trigger schema names it
+func triggerDestination(d *eventarc.Destination) map[string]any {
+	if d == nil {
+		return nil
+	}
+	destination := map[string]any{}
+	if d.CloudRun != nil {
+		service := map[string]any{"service": d.CloudRun.Service, "region": d.CloudRun.Region}
+		if d.CloudRun.Path != "" {
+			service["path"] = d.CloudRun.Path
+		}
+		destination["cloud_run_service"] = []any{service}
+	}
+	if d.CloudFunction != "" {
+		destination["cloud_function"] = d.CloudFunction
+	}
+	if d.Workflow != "" {
+		destination["workflow"] = d.Workflow
+	}
+	if d.Gke != nil {
+		destination["gke"] = []any{map[string]any{
+			"cluster":   d.Gke.Cluster,
+			"location":  d.Gke.Location,
+			"namespace": d.Gke.Namespace,
+			"service":   d.Gke.Service,
+			"path":      d.Gke.Path,
+		}}
+	}
+	if d.HttpEndpoint != nil {
+		destination["http_endpoint"] = []any{map[string]any{"uri": d.HttpEndpoint.Uri}}
+	}
+	return destination
+}
@@ -0,0 +1,196 @@
+package google
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ExcludeRule drops discovered resources of a type whose import ID matches a
+// glob pattern. Unlike path globs, "*" also matches "/".
+type ExcludeRule struct {
+	ResourceType string
+	ID           string
+}
+
+// DefaultExcludeRules cover resources which Google services create and manage
+// on their own.
+var DefaultExcludeRules = []ExcludeRule{
+	// Container Registry, App Engine, Cloud Build and Cloud Functions buckets.
+	// Only Google can create buckets in the appspot.com domain.
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "*.appspot.com"},
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "*_cloudbuild"},
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "gcf-sources-*"},
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "gcf-v2-sources-*"},
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "gcf-v2-uploads-*"},
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "run-sources-*"},
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "dataproc-staging-*"},
+	{ResourceType: string(ResourceTypeStorageBucket), ID: "dataproc-temp-*"},
+	// Legacy bucket roles mirror the bucket ACL
+	{ResourceType: string(ResourceTypeStorageBucketIAMBinding), ID: "* roles/storage.legacy*"},
+	{ResourceType: string(ResourceTypeStorageBucketIAMMember), ID: "* roles/storage.legacy*"},
+	// ACL entries of the project's owners, editors and viewers, which every
+	// bucket is created with
+	{ResourceType: string(ResourceTypeStorageBucketAccessControl), ID: "*/project-*"},
+	{ResourceType: string(ResourceTypeStorageDefaultObjectAccessControl), ID: "*/project-*"},
+	// Topics and subscriptions of Container Analysis, Cloud Build and Eventarc
+	{ResourceType: string(ResourceTypePubSubTopic), ID: "projects/*/topics/container-analysis-*"},
+	{ResourceType: string(ResourceTypePubSubTopic), ID: "projects/*/topics/cloud-builds"},
+	{ResourceType: string(ResourceTypePubSubTopic), ID: "projects/*/topics/gcr"},
+	{ResourceType: string(ResourceTypePubSubTopic), ID: "projects/*/topics/eventarc-*"},
+	{ResourceType: string(ResourceTypePubSubSubscription), ID: "projects/*/subscriptions/eventarc-*"},
+	{ResourceType: string(ResourceTypeComputeNetwork), ID: "projects/*/global/networks/default"},
+	// Certificates of GKE ManagedCertificate objects
+	{ResourceType: string(ResourceTypeComputeManagedSSLCertificate), ID: "projects/*/global/sslCertificates/mcrt-*"},
+}
+
+// DefaultExcludeMembers are the IAM members of Google-managed service agents.
+var DefaultExcludeMembers = []string{
+	"serviceAccount:service-*@gcp-sa-*.iam.gserviceaccount.com",
+	"serviceAccount:service-*@containerregistry.iam.gserviceaccount.com",
+	"serviceAccount:service-*@compute-system.iam.gserviceaccount.com",
+	"serviceAccount:service-*@gs-project-accounts.iam.gserviceaccount.com",
+	"serviceAccount:service-*@serverless-robot-prod.iam.gserviceaccount.com",
+	"serviceAccount:*@cloudservices.gserviceaccount.com",
+	"serviceAccount:*@cloudbuild.gserviceaccount.com",
+	"projectOwner:*",
+	"projectEditor:*",
+	"projectViewer:*",
+}
+
+// Filter drops excluded resources from discovery results.
+type Filter struct {
+	rules   []excludeMatcher
+	members []*regexp.Regexp
+}
+
+// excludeMatcher is an ExcludeRule with its patterns compiled
+type excludeMatcher struct {
+	resourceType *regexp.Regexp
+	id           *regexp.Regexp
+}
+
+// NewFilter returns a filter dropping the resources matching rules and the IAM
+// members matching members, failing on invalid patterns.
+func NewFilter(rules []ExcludeRule, members []string) (Filter, error) {
+	var f Filter
+	for _, rule := range rules {
+		resourceType, err := CompileGlob(rule.ResourceType)
+		if err != nil {
+			return Filter{}, err
+		}
+		id, err := CompileGlob(rule.ID)
+		if err != nil {
+			return Filter{}, err
+		}
+		f.rules = append(f.rules, excludeMatcher{resourceType: resourceType, id: id})
+	}
+	for _, member := range members {
+		re, err := CompileGlob(member)
+		if err != nil {
+			return Filter{}, err
+		}
+		f.members = append(f.members, re)
+	}
+	return f, nil
+}
+
+// Apply returns the resource with excluded dependents removed, and false if
+// the resource itself is excluded. IAM bindings and policies are
+// authoritative, so they are only excluded when all of their members are;
+// those mixing in other members are kept whole. IAM members are excluded by
+// their member.
+func (f Filter) Apply(r Resource) (Resource, bool) {
+	for _, rule := range f.rules {
+		if rule.resourceType.MatchString(string(r.Type)) && rule.id.MatchString(r.ID) {
+			return r, false
+		}
+	}
+
+	if members, ok := r.Attributes["members"].([]string); ok && len(members) > 0 && f.allExcluded(members) {
+		return r, false
+	}
+	if member, ok := r.Attributes["member"].(string); ok && f.allExcluded([]string{member}) {
+		return r, false
+	}
+
+	var dependents []Resource
+	for _, d := range r.Dependents {
+		if d, ok := f.Apply(d); ok {
+			dependents = append(dependents, d)
+		}
+	}
+	r.Dependents = dependents
+	return r, true
+}
+
+// DependentLimits bound which dependents of discovered resources are kept, to
+// import large projects incrementally.
+type DependentLimits struct {
+	// MaxDepth is the number of levels of dependents kept below a discovered
+	// resource, e.g. 1 keeps the subscriptions of a topic but not their IAM
+	// bindings. It is negative for no limit.
+	MaxDepth int
+	// SkipIAM drops the IAM bindings, members and policies among dependents
+	SkipIAM bool
+}
+
+// NoDependentLimits keeps every dependent
+var NoDependentLimits = DependentLimits{MaxDepth: -1}
+
+// Apply returns the resource without the dependents beyond the limits, and
+// those of the dropped dependents.
+func (l DependentLimits) Apply(r Resource) Resource {
+	return l.apply(r, 0)
+}
+
+func (l DependentLimits) apply(r Resource, depth int) Resource {
+	if len(r.Dependents) == 0 {
+		return r
+	}
+	var dependents []Resource
+	if l.MaxDepth < 0 || depth < l.MaxDepth {
+		for _, d := range r.Dependents {
+			if !l.SkipIAM || !d.Type.IAM() {
+				dependents = append(dependents, l.apply(d, depth+1))
+			}
+		}
+	}
+	r.Dependents = dependents
+	return r
+}
+
+// Excludes reports whether a resource of the type with the import ID is
+// dropped, without looking at its attributes.
+func (f Filter) Excludes(resourceType ResourceType, id string) bool {
+	_, ok := f.Apply(Resource{Type: resourceType, ID: id})
+	return !ok
+}
+
+func (f Filter) allExcluded(members []string) bool {
+	for _, member := range members {
+		var excluded bool
+		for _, pattern := range f.members {
+			if pattern.MatchString(member) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileGlob compiles a glob pattern of exclude rules and mappings, whose
+// "*" matches any string, "/" included, into a regular expression matching
+// whole strings
+func CompileGlob(pattern string) (*regexp.Regexp, error) {
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,47 @@
+package google
+
+import "testing"
+
+func TestFilterExcludes(t *testing.T) {
+	filter, err := NewFilter(DefaultExcludeRules, DefaultExcludeMembers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		resourceType ResourceType
+		id           string
+		want         bool
+	}{
+		{ResourceTypeStorageBucket, "acme.appspot.com", true},
+		{ResourceTypeStorageBucket, "assets", false},
+		{ResourceTypeComputeNetwork, "projects/acme/global/networks/default", true},
+		{ResourceTypeComputeNetwork, "projects/acme/global/networks/prod", false},
+		{ResourceTypePubSubTopic, "projects/acme/topics/gcr", true},
+		{ResourceTypePubSubTopic, "projects/acme/topics/gcr-events", false},
+	}
+	for _, tt := range tests {
+		if got := filter.Excludes(tt.resourceType, tt.id); got != tt.want {
+			t.Errorf("Excludes(%s, %s) = %t, want %t", tt.resourceType, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestFilterExcludesManagedMembers(t *testing.T) {
+	filter, err := NewFilter(nil, DefaultExcludeMembers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	managed := Resource{Type: ResourceTypePubSubTopicIAMBinding, ID: "projects/acme/topics/orders roles/pubsub.publisher",
+		Attributes: map[string]any{"members": []string{"serviceAccount:service-1@gcp-sa-pubsub.iam.gserviceaccount.com"}}}
+	if _, ok := filter.Apply(managed); ok {
+		t.Error("binding of only service agents was kept")
+	}
+
+	mixed := managed
+	mixed.Attributes = map[string]any{"members": []string{"serviceAccount:service-1@gcp-sa-pubsub.iam.gserviceaccount.com", "user:ada@example.com"}}
+	if _, ok := filter.Apply(mixed); !ok {
+		t.Error("binding with other members was excluded")
+	}
+}
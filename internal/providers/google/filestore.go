@@ -0,0 +1,263 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	file "google.golang.org/api/file/v1"
+	netapp "google.golang.org/api/netapp/v1"
+)
+
+// filestore imports Filestore instances and backups of every zone and region
+// and, if configured, NetApp storage pools with their volumes
+type filestore struct {
+	file     *file.Service
+	netapp   *netapp.Service
+	provider providers.Provider
+	opts     FilestoreOptions
+}
+
+func NewFilestore(ctx context.Context, provider providers.Provider, opts FilestoreOptions) (*filestore, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, file.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	fileService, err := file.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filestore service: %w", err)
+	}
+	fs := &filestore{
+		file:     fileService,
+		provider: provider,
+		opts:     opts,
+	}
+	if opts.IncludeNetApp {
+		fs.netapp, err = netapp.NewService(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create netapp service: %w", err)
+		}
+	}
+	return fs, nil
+}
+
+func (fs *filestore) Close() {
+	// No close method for the services
+}
+
+func (fs *filestore) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(fs.resources), nil
+}
+
+// allLocations lists resources of every location of a project
+func (fs *filestore) allLocations() string {
+	return fmt.Sprintf("projects/%s/locations/-", fs.provider.ProjectID)
+}
+
+func (fs *filestore) resources(ctx context.Context, resources *discovered) error {
+	err := fs.file.Projects.Locations.Instances.List(fs.allLocations()).Pages(ctx, func(page *file.ListInstancesResponse) error {
+		for _, instance := range page.Instances {
+			if !fs.provider.InRegions(locationRegion(locationOf(instance.Name))) {
+				continue
+			}
+			resources.append(fs.instanceResource(instance))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing filestore instances of project %s: %w", fs.provider.ProjectID, err)
+	}
+
+	if fs.opts.IncludeBackups {
+		err := fs.file.Projects.Locations.Backups.List(fs.allLocations()).Pages(ctx, func(page *file.ListBackupsResponse) error {
+			for _, backup := range page.Backups {
+				if !fs.provider.InRegions(locationRegion(locationOf(backup.Name))) {
+					continue
+				}
+				resources.append(fs.backupResource(backup))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing filestore backups of project %s: %w", fs.provider.ProjectID, err)
+		}
+	}
+
+	if fs.opts.IncludeNetApp {
+		pools, err := fs.netAppResources(ctx)
+		if err != nil {
+			return err
+		}
+		resources.append(pools...)
+	}
+
+	return nil
+}
+
+// instanceResource builds the resource of an instance. The import ID is its
+// full name, projects/<project>/locations/<zone or region>/instances/<name>.
+func (fs *filestore) instanceResource(instance *file.Instance) Resource {
+	var shares []any
+	for _, share := range instance.FileShares {
+		shares = append(shares, map[string]any{"name": share.Name, "capacity_gb": share.CapacityGb})
+	}
+	var networks []any
+	for _, network := range instance.Networks {
+		networks = append(networks, map[string]any{"network": network.Network, "modes": network.Modes})
+	}
+
+	attributes := map[string]any{
+		"name":        path.Base(instance.Name),
+		"project":     fs.provider.ProjectID,
+		"location":    locationOf(instance.Name),
+		"tier":        instance.Tier,
+		"file_shares": shares,
+		"networks":    networks,
+	}
+	if len(instance.Labels) > 0 {
+		attributes["labels"] = instance.Labels
+	}
+
+	return Resource{
+		Provider:   fs.provider,
+		Type:       ResourceTypeFilestoreInstance,
+		Service:    ServiceFilestore,
+		Name:       sanitizeName(path.Base(instance.Name)),
+		ID:         instance.Name,
+		Attributes: attributes,
+	}
+}
+
+// backupResource builds the resource of a backup, imported by its full name
+func (fs *filestore) backupResource(backup *file.Backup) Resource {
+	attributes := map[string]any{
+		"name":              path.Base(backup.Name),
+		"project":           fs.provider.ProjectID,
+		"location":          locationOf(backup.Name),
+		"source_instance":   backup.SourceInstance,
+		"source_file_share": backup.SourceFileShare,
+	}
+	if len(backup.Labels) > 0 {
+		attributes["labels"] = backup.Labels
+	}
+
+	return Resource{
+		Provider:   fs.provider,
+		Type:       ResourceTypeFilestoreBackup,
+		Service:    ServiceFilestore,
+		Name:       sanitizeName(path.Base(backup.Name)),
+		ID:         backup.Name,
+		Attributes: attributes,
+	}
+}
+
+// netAppResources returns the NetApp storage pools of the project with their
+// volumes as dependents. Volumes are listed across pools, so the pools are
+// held until every volume is listed.
+func (fs *filestore) netAppResources(ctx context.Context) ([]Resource, error) {
+	var pools []Resource
+	err := fs.netapp.Projects.Locations.StoragePools.List(fs.allLocations()).Pages(ctx, func(page *netapp.ListStoragePoolsResponse) error {
+		for _, pool := range page.StoragePools {
+			if !fs.provider.InRegions(locationRegion(locationOf(pool.Name))) {
+				continue
+			}
+			pools = append(pools, Resource{
+				Provider: fs.provider,
+				Type:     ResourceTypeNetAppStoragePool,
+				Service:  ServiceFilestore,
+				Name:     sanitizeName(path.Base(pool.Name)),
+				ID:       pool.Name,
+				Attributes: map[string]any{
+					"name":          path.Base(pool.Name),
+					"project":       fs.provider.ProjectID,
+					"location":      locationOf(pool.Name),
+					"service_level": pool.ServiceLevel,
+					"capacity_gib":  pool.CapacityGib,
+					"network":       pool.Network,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing netapp storage pools of project %s: %w", fs.provider.ProjectID, err)
+	}
+
+	err = fs.netapp.Projects.Locations.Volumes.List(fs.allLocations()).Pages(ctx, func(page *netapp.ListVolumesResponse) error {
+		for _, volume := range page.Volumes {
+			for i := range pools {
+				if pools[i].Attributes["name"] != volume.StoragePool || pools[i].Attributes["location"] != locationOf(volume.Name) {
+					continue
+				}
+				pools[i].Dependents = append(pools[i].Dependents, Resource{
+					Provider: fs.provider,
+					Type:     ResourceTypeNetAppVolume,
+					Service:  ServiceFilestore,
+					Name:     sanitizeName(path.Base(volume.Name)),
+					ID:       volume.Name,
+					Attributes: map[string]any{
+						"name":         path.Base(volume.Name),
+						"project":      fs.provider.ProjectID,
+						"location":     locationOf(volume.Name),
+						"storage_pool": volume.StoragePool,
+						"share_name":   volume.ShareName,
+						"capacity_gib": volume.CapacityGib,
+						"protocols":    volume.Protocols,
+					},
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing netapp volumes of project %s: %w", fs.provider.ProjectID, err)
+	}
+
+	return pools, nil
+}
+
+// locationOf returns the location of a resource name such as
+// projects/<project>/locations/<location>/instances/<name>
+func locationOf(name string) string {
+	parts := strings.Split(name, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "locations" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+var zoneRe = regexp.MustCompile(`^([a-z]+-[a-z]+[0-9]+)-[a-z]$`)
+
+// locationRegion returns the region of a zone, or the location itself if it
+// is not a zone
+func locationRegion(location string) string {
+	if m := zoneRe.FindStringSubmatch(location); m != nil {
+		return m[1]
+	}
+	return location
+}
+
+// OutsideRegions reports whether a resource with the import ID id may be
+// left out of discovery because it is outside the regions of the provider.
+// Cloud SQL import IDs don't name the region, so with regions configured
+// their resources may always be outside them.
+func OutsideRegions(provider providers.Provider, resourceType ResourceType, id string) bool {
+	if len(provider.Regions) == 0 {
+		return false
+	}
+	parts := strings.Split(id, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "locations", "regions", "zones":
+			location := parts[i+1]
+			return location != "global" && !provider.InRegions(locationRegion(location))
+		}
+	}
+	return slices.Contains(ResourceTypes(ServiceCloudSQL), resourceType)
+}
@@ -0,0 +1,33 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+func TestOutsideRegions(t *testing.T) {
+	provider := providers.Provider{Regions: []string{"europe-west1"}}
+
+	tests := []struct {
+		resourceType ResourceType
+		id           string
+		want         bool
+	}{
+		{ResourceTypeFilestoreInstance, "projects/acme/locations/europe-west1-b/instances/files", false},
+		{ResourceTypeFilestoreInstance, "projects/acme/locations/us-central1-a/instances/files", true},
+		{ResourceTypeComputeAddress, "projects/acme/regions/us-east1/addresses/nat", true},
+		{ResourceTypeCertificateManagerCertificate, "projects/acme/locations/global/certificates/web", false},
+		{ResourceTypeSQLInstance, "projects/acme/instances/main", true},
+		{ResourceTypeStorageBucket, "assets", false},
+	}
+	for _, tt := range tests {
+		if got := OutsideRegions(provider, tt.resourceType, tt.id); got != tt.want {
+			t.Errorf("OutsideRegions(%s, %s) = %t, want %t", tt.resourceType, tt.id, got, tt.want)
+		}
+	}
+
+	if OutsideRegions(providers.Provider{}, ResourceTypeSQLInstance, "projects/acme/instances/main") {
+		t.Error("resource outside regions without configured regions")
+	}
+}
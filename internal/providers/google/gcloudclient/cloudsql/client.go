@@ -2,35 +2,108 @@ package cloudsql
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"strings"
+	"time"
 
+	"github.com/priyanshujain/infrasync/internal/binary"
 	sqladmin "google.golang.org/api/sqladmin/v1beta4"
 )
 
-type Client struct{}
+type Client struct {
+	env []string
+}
 
-func NewClient() *Client {
-	return &Client{}
+// NewClient returns a client which runs gcloud with env added to the
+// environment, e.g. to select credentials
+func NewClient(env ...string) *Client {
+	return &Client{env: env}
 }
 
-func (c *Client) ListInstances(projectID string) ([]*sqladmin.DatabaseInstance, error) {
-	cmd := exec.Command("gcloud", "sql", "instances", "list", fmt.Sprintf("--project=%s", projectID), "--format=json")
+// InstanceIterator decodes instances from the output of gcloud one at a time,
+// so that the whole list is never held in memory
+type InstanceIterator struct {
+	cmd *exec.Cmd
+	// cancel kills gcloud when the iterator is closed early
+	cancel  context.CancelFunc
+	stdout  io.ReadCloser
+	stderr  bytes.Buffer
+	decoder *json.Decoder
+	done    bool
+}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
+// Instances lists the instances of a project, limited to the given regions if any
+func (c *Client) Instances(ctx context.Context, projectID string, regions ...string) (*InstanceIterator, error) {
+	args := []string{"sql", "instances", "list", fmt.Sprintf("--project=%s", projectID), "--format=json"}
+	if len(regions) > 0 {
+		args = append(args, fmt.Sprintf("--filter=region:(%s)", strings.Join(regions, " ")))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	it := &InstanceIterator{cmd: binary.CommandContext(ctx, binary.GCloud, args...), cancel: cancel}
+	if len(c.env) > 0 {
+		it.cmd.Env = append(os.Environ(), c.env...)
+	}
+	it.cmd.Stderr = &it.stderr
+	// Once gcloud is killed, its children may still hold stderr open
+	it.cmd.WaitDelay = time.Second
 
-	if err := cmd.Run(); err != nil {
+	stdout, err := it.cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	it.stdout = stdout
+
+	if err := it.cmd.Start(); err != nil {
+		cancel()
 		return nil, errors.New("failed to execute gcloud command: " + err.Error())
 	}
 
-	var instances []*sqladmin.DatabaseInstance
-	if err := json.Unmarshal(out.Bytes(), &instances); err != nil {
-		return nil, errors.New("failed to parse gcloud output: " + err.Error())
+	it.decoder = json.NewDecoder(stdout)
+	if _, err := it.decoder.Token(); err != nil {
+		it.Close()
+		return nil, fmt.Errorf("failed to parse gcloud output: %w: %s", err, it.stderr.String())
+	}
+
+	return it, nil
+}
+
+// Next returns the next instance, or nil when there are no more
+func (it *InstanceIterator) Next() (*sqladmin.DatabaseInstance, error) {
+	if it.done || !it.decoder.More() {
+		it.done = true
+		return nil, it.Close()
 	}
 
-	return instances, nil
+	var instance sqladmin.DatabaseInstance
+	if err := it.decoder.Decode(&instance); err != nil {
+		return nil, errors.New("failed to parse gcloud output: " + err.Error())
+	}
+	return &instance, nil
+}
 
+// Close waits for gcloud to exit, reporting its failure once every instance
+// was read. When closed early gcloud is killed instead, rather than reading
+// the rest of its output.
+func (it *InstanceIterator) Close() error {
+	if it.cmd.ProcessState != nil {
+		return nil
+	}
+	defer it.cancel()
+	if !it.done {
+		it.cancel()
+		it.cmd.Wait()
+		return nil
+	}
+	if err := it.cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to execute gcloud command: %w: %s", err, it.stderr.String())
+	}
+	return nil
 }
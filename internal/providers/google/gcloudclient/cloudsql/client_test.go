@@ -0,0 +1,76 @@
+package cloudsql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeGCloud puts a gcloud in PATH which lists one instance and then hangs,
+// like a slow listing of many instances
+func fakeGCloud(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gcloud is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nprintf '[{\"name\": \"orders\"},'\nexec sleep 60\n"
+	if err := os.WriteFile(filepath.Join(dir, "gcloud"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestInstancesCloseStopsGCloud(t *testing.T) {
+	fakeGCloud(t)
+
+	it, err := NewClient().Instances(context.Background(), "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance, err := it.Next()
+	if err != nil || instance == nil || instance.Name != "orders" {
+		t.Fatalf("Next() = %v, %v, want instance orders", instance, err)
+	}
+
+	start := time.Now()
+	if err := it.Close(); err != nil {
+		t.Errorf("Close() = %v, want nil when closed early", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Close() took %s, want gcloud killed rather than waited for", elapsed)
+	}
+}
+
+func TestInstancesCanceled(t *testing.T) {
+	fakeGCloud(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it, err := NewClient().Instances(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := it.Next()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Next() succeeded after the context was canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next() didn't return after the context was canceled")
+	}
+}
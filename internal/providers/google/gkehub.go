@@ -0,0 +1,304 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	gkehub "google.golang.org/api/gkehub/v1"
+)
+
+// hubFeatures are the fleet features imported, those configuring the
+// clusters of a fleet. The specs of other features aren't generated.
+var hubFeatures = []string{"configmanagement", "policycontroller"}
+
+// gkeHub imports the fleet memberships of a project, global and in the
+// configured regions, and its Config Management and Policy Controller
+// features with the specs of each membership
+type gkeHub struct {
+	service  *gkehub.Service
+	provider providers.Provider
+	opts     GKEHubOptions
+}
+
+func NewGKEHub(ctx context.Context, provider providers.Provider, opts GKEHubOptions) (*gkeHub, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, gkehub.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := gkehub.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gke hub service: %w", err)
+	}
+	return &gkeHub{
+		service:  service,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (gh *gkeHub) Close() {
+	// No close method for the service
+}
+
+func (gh *gkeHub) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(gh.resources), nil
+}
+
+func (gh *gkeHub) resources(ctx context.Context, resources *discovered) error {
+	projectID := gh.provider.ProjectID
+
+	var locations []string
+	err := gh.service.Projects.Locations.List("projects/"+projectID).Pages(ctx, func(page *gkehub.ListLocationsResponse) error {
+		for _, location := range page.Locations {
+			if location.LocationId == "global" || gh.provider.InRegions(location.LocationId) {
+				locations = append(locations, location.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing gke hub locations of project %s: %w", projectID, err)
+	}
+
+	for _, location := range locations {
+		err := gh.service.Projects.Locations.Memberships.List(location).Pages(ctx, func(page *gkehub.ListMembershipsResponse) error {
+			for _, membership := range page.Resources {
+				if r, ok := gh.membershipResource(membership); ok {
+					resources.append(r)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing memberships in %s: %w", location, err)
+		}
+	}
+
+	// Features are global
+	if gh.opts.IncludeFeatures {
+		err := gh.service.Projects.Locations.Features.List(fmt.Sprintf("projects/%s/locations/global", projectID)).Pages(ctx, func(page *gkehub.ListFeaturesResponse) error {
+			for _, feature := range page.Resources {
+				if !slices.Contains(hubFeatures, path.Base(feature.Name)) {
+					slog.Debug("Skipping fleet feature", "feature", feature.Name)
+					continue
+				}
+				resources.append(gh.featureResource(feature))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing fleet features of project %s: %w", projectID, err)
+		}
+	}
+
+	return nil
+}
+
+// membershipResource builds the resource of a membership. Memberships a
+// cluster platform manages, e.g. those of GKE clusters registered by their
+// fleet block, are skipped as they belong to their cluster's config.
+func (gh *gkeHub) membershipResource(membership *gkehub.Membership) (Resource, bool) {
+	if membership.Endpoint != nil && membership.Endpoint.GoogleManaged {
+		slog.Debug("Skipping managed membership", "membership", membership.Name)
+		return Resource{}, false
+	}
+
+	membershipID := path.Base(membership.Name)
+	location := locationOf(membership.Name)
+	attributes := map[string]any{
+		"membership_id": membershipID,
+		"project":       gh.provider.ProjectID,
+		"location":      location,
+	}
+	if len(membership.Labels) > 0 {
+		attributes["labels"] = membership.Labels
+	}
+	if endpoint := membership.Endpoint; endpoint != nil && endpoint.GkeCluster != nil {
+		attributes["endpoint"] = []any{map[string]any{
+			"gke_cluster": []any{map[string]any{"resource_link": endpoint.GkeCluster.ResourceLink}},
+		}}
+	}
+	if authority := membership.Authority; authority != nil && authority.Issuer != "" {
+		attributes["authority"] = []any{map[string]any{"issuer": authority.Issuer}}
+	}
+
+	return Resource{
+		Provider: gh.provider,
+		Type:     ResourceTypeGKEHubMembership,
+		Service:  ServiceGKEHub,
+		// Memberships of different locations may share names
+		Name:       sanitizeName(fmt.Sprintf("%s_%s", location, membershipID)),
+		ID:         membership.Name,
+		Attributes: attributes,
+	}, true
+}
+
+// featureResource builds the resource of a fleet feature, including the spec
+// of each membership as a dependent. The import ID is its name,
+// projects/<project>/locations/global/features/<name>.
+func (gh *gkeHub) featureResource(feature *gkehub.Feature) Resource {
+	featureID := path.Base(feature.Name)
+	attributes := map[string]any{
+		"name":     featureID,
+		"project":  gh.provider.ProjectID,
+		"location": "global",
+	}
+	if len(feature.Labels) > 0 {
+		attributes["labels"] = feature.Labels
+	}
+	if defaults := feature.FleetDefaultMemberConfig; defaults != nil {
+		config := map[string]any{}
+		if defaults.Configmanagement != nil {
+			config["configmanagement"] = []any{configManagementSpec(defaults.Configmanagement)}
+		}
+		if defaults.Policycontroller != nil {
+			config["policycontroller"] = []any{policyControllerSpec(defaults.Policycontroller)}
+		}
+		if len(config) > 0 {
+			attributes["fleet_default_member_config"] = []any{config}
+		}
+	}
+
+	featureResource := Resource{
+		Provider:   gh.provider,
+		Type:       ResourceTypeGKEHubFeature,
+		Service:    ServiceGKEHub,
+		Name:       sanitizeName(featureID),
+		ID:         feature.Name,
+		Attributes: attributes,
+	}
+
+	var memberships []string
+	for membership := range feature.MembershipSpecs {
+		memberships = append(memberships, membership)
+	}
+	sort.Strings(memberships)
+	for _, membership := range memberships {
+		spec := feature.MembershipSpecs[membership]
+		// Specs inherited from the fleet default are generated by it
+		if spec.Origin != nil && spec.Origin.Type == "FLEET" {
+			continue
+		}
+		membershipAttributes := map[string]any{
+			"project":             gh.provider.ProjectID,
+			"location":            "global",
+			"feature":             featureID,
+			"membership":          path.Base(membership),
+			"membership_location": locationOf(membership),
+		}
+		switch {
+		case spec.Configmanagement != nil:
+			membershipAttributes["configmanagement"] = []any{configManagementSpec(spec.Configmanagement)}
+		case spec.Policycontroller != nil:
+			membershipAttributes["policycontroller"] = []any{policyControllerSpec(spec.Policycontroller)}
+		default:
+			continue
+		}
+		featureResource.Dependents = append(featureResource.Dependents, Resource{
+			Provider: gh.provider,
+			Type:     ResourceTypeGKEHubFeatureMembership,
+			Service:  ServiceGKEHub,
+			Name:     sanitizeName(fmt.Sprintf("%s_%s_%s", featureID, locationOf(membership), path.Base(membership))),
+			// Specs are keyed by the membership's name, which names the
+			// project by number, but imported by the membership's ID
+			ID:         fmt.Sprintf("projects/%s/locations/global/features/%s/membershipId/%s", gh.provider.ProjectID, featureID, path.Base(membership)),
+			Attributes: membershipAttributes,
+		})
+	}
+
+	return featureResource
+}
+
+// configManagementSpec returns the configmanagement block of a Config
+// Management spec, whether the fleet default or a membership's
+func configManagementSpec(spec *gkehub.ConfigManagementMembershipSpec) map[string]any {
+	block := map[string]any{}
+	if spec.Version != "" {
+		block["version"] = spec.Version
+	}
+	if spec.Management != "" {
+		block["management"] = spec.Management
+	}
+	if sync := spec.ConfigSync; sync != nil {
+		syncBlock := map[string]any{
+			"enabled":       sync.Enabled,
+			"prevent_drift": sync.PreventDrift,
+		}
+		if sync.SourceFormat != "" {
+			syncBlock["source_format"] = sync.SourceFormat
+		}
+		if git := sync.Git; git != nil {
+			gitBlock := map[string]any{
+				"sync_repo":   git.SyncRepo,
+				"secret_type": git.SecretType,
+			}
+			for name, value := range map[string]string{
+				"sync_branch":               git.SyncBranch,
+				"sync_rev":                  git.SyncRev,
+				"policy_dir":                git.PolicyDir,
+				"gcp_service_account_email": git.GcpServiceAccountEmail,
+				"https_proxy":               git.HttpsProxy,
+			} {
+				if value != "" {
+					gitBlock[name] = value
+				}
+			}
+			if git.SyncWaitSecs > 0 {
+				gitBlock["sync_wait_secs"] = strconv.FormatInt(git.SyncWaitSecs, 10)
+			}
+			syncBlock["git"] = []any{gitBlock}
+		}
+		if oci := sync.Oci; oci != nil {
+			ociBlock := map[string]any{
+				"sync_repo":   oci.SyncRepo,
+				"secret_type": oci.SecretType,
+			}
+			if oci.PolicyDir != "" {
+				ociBlock["policy_dir"] = oci.PolicyDir
+			}
+			if oci.GcpServiceAccountEmail != "" {
+				ociBlock["gcp_service_account_email"] = oci.GcpServiceAccountEmail
+			}
+			if oci.SyncWaitSecs > 0 {
+				ociBlock["sync_wait_secs"] = strconv.FormatInt(oci.SyncWaitSecs, 10)
+			}
+			syncBlock["oci"] = []any{ociBlock}
+		}
+		block["config_sync"] = []any{syncBlock}
+	}
+	return block
+}
+
+// policyControllerSpec returns the policycontroller block of a Policy
+// Controller spec, whether the fleet default or a membership's
+func policyControllerSpec(spec *gkehub.PolicyControllerMembershipSpec) map[string]any {
+	block := map[string]any{}
+	if spec.Version != "" {
+		block["version"] = spec.Version
+	}
+	if hub := spec.PolicyControllerHubConfig; hub != nil {
+		hubBlock := map[string]any{
+			"install_spec":              hub.InstallSpec,
+			"log_denies_enabled":        hub.LogDeniesEnabled,
+			"mutation_enabled":          hub.MutationEnabled,
+			"referential_rules_enabled": hub.ReferentialRulesEnabled,
+		}
+		if hub.AuditIntervalSeconds > 0 {
+			hubBlock["audit_interval_seconds"] = hub.AuditIntervalSeconds
+		}
+		if hub.ConstraintViolationLimit > 0 {
+			hubBlock["constraint_violation_limit"] = hub.ConstraintViolationLimit
+		}
+		if len(hub.ExemptableNamespaces) > 0 {
+			hubBlock["exemptable_namespaces"] = hub.ExemptableNamespaces
+		}
+		block["policy_controller_hub_config"] = []any{hubBlock}
+	}
+	return block
+}
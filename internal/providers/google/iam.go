@@ -0,0 +1,218 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/iam"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// IAMMode selects which terraform resources the IAM policy of a resource is
+// generated as
+type IAMMode string
+
+const (
+	// IAMModeBinding generates one authoritative *_iam_binding per role
+	IAMModeBinding IAMMode = "binding"
+	// IAMModeMember generates one non-authoritative *_iam_member per role
+	// and member, leaving members granted by other tooling alone
+	IAMModeMember IAMMode = "member"
+	// IAMModePolicy generates a single *_iam_policy which replaces the whole
+	// policy of the resource
+	IAMModePolicy IAMMode = "policy"
+)
+
+func (m *IAMMode) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	switch mode := IAMMode(s); mode {
+	case IAMModeBinding, IAMModeMember, IAMModePolicy:
+		*m = mode
+		return nil
+	}
+	return fmt.Errorf("invalid iam_mode %q (supported: binding, member, policy)", s)
+}
+
+// iamTypes are the resource types of each IAM mode for a resource type
+type iamTypes struct {
+	binding ResourceType
+	member  ResourceType
+	policy  ResourceType
+}
+
+var (
+	pubSubTopicIAMTypes = iamTypes{ResourceTypePubSubTopicIAMBinding,
+		ResourceTypePubSubTopicIAMMember, ResourceTypePubSubTopicIAMPolicy}
+	pubSubSubscriptionIAMTypes = iamTypes{ResourceTypePubSubSubscriptionIAMBinding,
+		ResourceTypePubSubSubscriptionIAMMember, ResourceTypePubSubSubscriptionIAMPolicy}
+	storageBucketIAMTypes = iamTypes{ResourceTypeStorageBucketIAMBinding,
+		ResourceTypeStorageBucketIAMMember, ResourceTypeStorageBucketIAMPolicy}
+	computeSubnetworkIAMTypes = iamTypes{ResourceTypeComputeSubnetworkIAMBinding,
+		ResourceTypeComputeSubnetworkIAMMember, ResourceTypeComputeSubnetworkIAMPolicy}
+	apiGatewayAPIIAMTypes = iamTypes{ResourceTypeAPIGatewayAPIIAMBinding,
+		ResourceTypeAPIGatewayAPIIAMMember, ResourceTypeAPIGatewayAPIIAMPolicy}
+	apiGatewayAPIConfigIAMTypes = iamTypes{ResourceTypeAPIGatewayAPIConfigIAMBinding,
+		ResourceTypeAPIGatewayAPIConfigIAMMember, ResourceTypeAPIGatewayAPIConfigIAMPolicy}
+	apiGatewayGatewayIAMTypes = iamTypes{ResourceTypeAPIGatewayGatewayIAMBinding,
+		ResourceTypeAPIGatewayGatewayIAMMember, ResourceTypeAPIGatewayGatewayIAMPolicy}
+	iapWebIAMTypes = iamTypes{ResourceTypeIAPWebIAMBinding,
+		ResourceTypeIAPWebIAMMember, ResourceTypeIAPWebIAMPolicy}
+	iapTunnelIAMTypes = iamTypes{ResourceTypeIAPTunnelIAMBinding,
+		ResourceTypeIAPTunnelIAMMember, ResourceTypeIAPTunnelIAMPolicy}
+	privateCAPoolIAMTypes = iamTypes{ResourceTypePrivateCAPoolIAMBinding,
+		ResourceTypePrivateCAPoolIAMMember, ResourceTypePrivateCAPoolIAMPolicy}
+	binaryAuthorizationAttestorIAMTypes = iamTypes{ResourceTypeBinaryAuthorizationAttestorIAMBinding,
+		ResourceTypeBinaryAuthorizationAttestorIAMMember, ResourceTypeBinaryAuthorizationAttestorIAMPolicy}
+)
+
+// iamParent is the resource an IAM policy is attached to
+type iamParent struct {
+	provider providers.Provider
+	service  Service
+	types    iamTypes
+	// attribute names the parent in the IAM resources, e.g. "topic"
+	attribute string
+	name      string
+	// resourceName prefixes the names of the IAM resources, name if empty
+	resourceName string
+	// scope holds further attributes locating the parent, e.g. the region of
+	// a gateway
+	scope map[string]any
+	// id is the import ID of the parent, which prefixes the IAM import IDs
+	id string
+}
+
+func (p iamParent) sanitizedName() string {
+	if p.resourceName != "" {
+		return sanitizeName(p.resourceName)
+	}
+	return sanitizeName(p.name)
+}
+
+// stub names the IAM resources of the parent as a whole, by its policy
+// resource, when they couldn't be discovered
+func (p iamParent) stub() Resource {
+	return Resource{Provider: p.provider, Type: p.types.policy, Service: p.service, Name: p.sanitizedName(), ID: p.id}
+}
+
+// attributes returns the attributes of an IAM resource of the parent
+func (p iamParent) attributes(attributes map[string]any) map[string]any {
+	attributes[p.attribute] = p.name
+	for k, v := range p.scope {
+		attributes[k] = v
+	}
+	return attributes
+}
+
+// iamResources returns the resources of an IAM policy in the given mode
+func iamResources(mode IAMMode, parent iamParent, policy *iam.Policy) []Resource {
+	var resources []Resource
+	switch mode {
+	case IAMModePolicy:
+		policyData, ok := policyData(policy)
+		if !ok {
+			return nil
+		}
+		resources = append(resources, Resource{
+			Provider: parent.provider,
+			Type:     parent.types.policy,
+			Service:  parent.service,
+			Name:     parent.sanitizedName(),
+			ID:       parent.id,
+			Attributes: parent.attributes(map[string]any{
+				"policy_data": policyData,
+			}),
+		})
+	case IAMModeMember:
+		for _, role := range policy.Roles() {
+			for _, member := range policy.Members(role) {
+				resources = append(resources, Resource{
+					Provider: parent.provider,
+					Type:     parent.types.member,
+					Service:  parent.service,
+					Name: fmt.Sprintf("%s_%s_%s",
+						parent.sanitizedName(), sanitizeRole(role), sanitizeMember(member)),
+					ID: fmt.Sprintf("%s %s %s", parent.id, role, member),
+					Attributes: parent.attributes(map[string]any{
+						"role":   role,
+						"member": member,
+					}),
+				})
+			}
+		}
+	default:
+		for _, role := range policy.Roles() {
+			members := policy.Members(role)
+			if len(members) == 0 {
+				continue
+			}
+			resources = append(resources, Resource{
+				Provider: parent.provider,
+				Type:     parent.types.binding,
+				Service:  parent.service,
+				Name:     fmt.Sprintf("%s_%s", parent.sanitizedName(), sanitizeRole(role)),
+				ID:       fmt.Sprintf("%s %s", parent.id, role),
+				Attributes: parent.attributes(map[string]any{
+					"role":    role,
+					"members": members,
+				}),
+			})
+		}
+	}
+	return resources
+}
+
+// policyBinding is a binding of policy_data, whose fields are in the order
+// the google provider writes them to state
+type policyBinding struct {
+	Members []string `json:"members"`
+	Role    string   `json:"role"`
+}
+
+// policyData returns the policy_data of *_iam_policy resources for policy,
+// with one binding per role, like the google provider records it: bindings
+// sorted by role and their members sorted. It reports false if no role has
+// members.
+func policyData(policy *iam.Policy) (string, bool) {
+	var bindings []policyBinding
+	for _, role := range policy.Roles() {
+		members := slices.Sorted(slices.Values(policy.Members(role)))
+		if len(members) == 0 {
+			continue
+		}
+		bindings = append(bindings, policyBinding{Members: members, Role: string(role)})
+	}
+	if len(bindings) == 0 {
+		return "", false
+	}
+	slices.SortFunc(bindings, func(a, b policyBinding) int { return strings.Compare(a.Role, b.Role) })
+
+	data, err := json.Marshal(struct {
+		Bindings []policyBinding `json:"bindings"`
+	}{bindings})
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+func sanitizeRole(role iam.RoleName) string {
+	suffix := strings.Replace(string(role), "/", "_", -1)
+	suffix = strings.Replace(suffix, ".", "_", -1)
+	return sanitizeName(suffix)
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// sanitizeMember turns a member such as user:jane@example.com into a valid
+// terraform name
+func sanitizeMember(member string) string {
+	return invalidNameChars.ReplaceAllString(member, "_")
+}
@@ -0,0 +1,30 @@
+package google
+
+import (
+	"testing"
+
+	"cloud.google.com/go/iam"
+)
+
+func TestPolicyDataKeepsRolesOfMembers(t *testing.T) {
+	policy := &iam.Policy{}
+	policy.Add("user:b@example.com", "roles/pubsub.subscriber")
+	policy.Add("user:a@example.com", "roles/pubsub.subscriber")
+	policy.Add("group:admins@example.com", "roles/pubsub.admin")
+
+	got, ok := policyData(policy)
+	if !ok {
+		t.Fatal("policyData() reported no bindings")
+	}
+	want := `{"bindings":[{"members":["group:admins@example.com"],"role":"roles/pubsub.admin"},` +
+		`{"members":["user:a@example.com","user:b@example.com"],"role":"roles/pubsub.subscriber"}]}`
+	if got != want {
+		t.Errorf("policyData() = %s, want %s", got, want)
+	}
+}
+
+func TestPolicyDataEmpty(t *testing.T) {
+	if _, ok := policyData(&iam.Policy{}); ok {
+		t.Error("policyData() of an empty policy reported bindings")
+	}
+}
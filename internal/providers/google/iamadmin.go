@@ -0,0 +1,161 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+	iamadmin "google.golang.org/api/iam/v1"
+)
+
+// iamAdmin imports custom IAM roles and audit configs of a project and,
+// if configured, of its organization. These are usually set up by hand in the
+// console and grant or hide access, so they belong in reviewed code.
+type iamAdmin struct {
+	iam      *iamadmin.Service
+	crm      *cloudresourcemanager.Service
+	provider providers.Provider
+	opts     IAMOptions
+}
+
+func NewIAM(ctx context.Context, provider providers.Provider, opts IAMOptions) (*iamAdmin, error) {
+	iamOpts, err := providerClientOptions(ctx, provider, iamadmin.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	iamService, err := iamadmin.NewService(ctx, iamOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iam service: %w", err)
+	}
+	ia := &iamAdmin{
+		iam:      iamService,
+		provider: provider,
+		opts:     opts,
+	}
+	// IAM policies, which hold the audit configs, are only read if imported
+	if opts.IncludeAuditConfigs {
+		crmOpts, err := providerClientOptions(ctx, provider, cloudresourcemanager.CloudPlatformReadOnlyScope)
+		if err != nil {
+			return nil, err
+		}
+		ia.crm, err = cloudresourcemanager.NewService(ctx, crmOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create resource manager service: %w", err)
+		}
+	}
+	return ia, nil
+}
+
+func (ia *iamAdmin) Close() {
+	// No close method for the services
+}
+
+func (ia *iamAdmin) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(ia.resources), nil
+}
+
+func (ia *iamAdmin) resources(ctx context.Context, resources *discovered) error {
+	projectID := ia.provider.ProjectID
+
+	err := ia.iam.Projects.Roles.List("projects/"+projectID).Pages(ctx, func(page *iamadmin.ListRolesResponse) error {
+		for _, role := range page.Roles {
+			resources.append(ia.customRoleResource(ResourceTypeProjectIAMCustomRole, role,
+				map[string]any{"project": projectID}))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing custom roles of project %s: %w", projectID, err)
+	}
+
+	if ia.opts.IncludeAuditConfigs {
+		stub := Resource{Provider: ia.provider, Type: ResourceTypeProjectIAMAuditConfig, Service: ServiceIAM,
+			Name: sanitizeName(projectID), ID: projectID}
+		resources.addAll(ctx, stub, func(ctx context.Context) ([]Resource, error) {
+			policy, err := ia.crm.Projects.GetIamPolicy(projectID, &cloudresourcemanager.GetIamPolicyRequest{}).Context(ctx).Do()
+			if err != nil {
+				return nil, fmt.Errorf("error getting IAM policy of project %s: %w", projectID, err)
+			}
+			var configs []Resource
+			for _, config := range policy.AuditConfigs {
+				configs = append(configs, ia.auditConfigResource(ResourceTypeProjectIAMAuditConfig,
+					projectID, "project", config))
+			}
+			return configs, nil
+		})
+	}
+
+	org := ia.opts.Organization
+	if org == "" {
+		return nil
+	}
+
+	err = ia.iam.Organizations.Roles.List("organizations/"+org).Pages(ctx, func(page *iamadmin.ListRolesResponse) error {
+		for _, role := range page.Roles {
+			resources.append(ia.customRoleResource(ResourceTypeOrganizationIAMCustomRole, role,
+				map[string]any{"org_id": org}))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing custom roles of organization %s: %w", org, err)
+	}
+
+	if ia.opts.IncludeAuditConfigs {
+		stub := Resource{Provider: ia.provider, Type: ResourceTypeOrganizationIAMAuditConfig, Service: ServiceIAM,
+			Name: sanitizeName("org_" + org), ID: org}
+		resources.addAll(ctx, stub, func(ctx context.Context) ([]Resource, error) {
+			policy, err := ia.crm.Organizations.GetIamPolicy("organizations/"+org, &cloudresourcemanager.GetIamPolicyRequest{}).Context(ctx).Do()
+			if err != nil {
+				return nil, fmt.Errorf("error getting IAM policy of organization %s: %w", org, err)
+			}
+			var configs []Resource
+			for _, config := range policy.AuditConfigs {
+				configs = append(configs, ia.auditConfigResource(ResourceTypeOrganizationIAMAuditConfig,
+					org, "org_id", config))
+			}
+			return configs, nil
+		})
+	}
+
+	return nil
+}
+
+// customRoleResource builds the resource of a custom role. The import ID is
+// the full role name, e.g. projects/my-project/roles/myRole.
+func (ia *iamAdmin) customRoleResource(resourceType ResourceType, role *iamadmin.Role, attributes map[string]any) Resource {
+	roleID := path.Base(role.Name)
+	attributes["role_id"] = roleID
+	attributes["title"] = role.Title
+	return Resource{
+		Provider:   ia.provider,
+		Type:       resourceType,
+		Service:    ServiceIAM,
+		Name:       sanitizeName(roleID),
+		ID:         role.Name,
+		Attributes: attributes,
+	}
+}
+
+// auditConfigResource builds the resource of the audit config of a service,
+// imported as "<parent> <service>"
+func (ia *iamAdmin) auditConfigResource(resourceType ResourceType, parent, attribute string, config *cloudresourcemanager.AuditConfig) Resource {
+	name := strings.TrimSuffix(config.Service, ".googleapis.com")
+	if resourceType == ResourceTypeOrganizationIAMAuditConfig {
+		name = "org_" + name
+	}
+	return Resource{
+		Provider: ia.provider,
+		Type:     resourceType,
+		Service:  ServiceIAM,
+		Name:     sanitizeName(name),
+		ID:       fmt.Sprintf("%s %s", parent, config.Service),
+		Attributes: map[string]any{
+			attribute: parent,
+			"service": config.Service,
+		},
+	}
+}
@@ -0,0 +1,214 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strconv"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+	iap "google.golang.org/api/iap/v1"
+	identitytoolkit "google.golang.org/api/identitytoolkit/v2"
+)
+
+// identityAwareProxy imports the IAP OAuth brand and clients of a project, who
+// may access its web apps and tunnel to its VMs through IAP, and its Identity
+// Platform tenants. These are usually set up in the console only.
+type identityAwareProxy struct {
+	crm      *cloudresourcemanager.Service
+	iap      *iap.Service
+	identity *identitytoolkit.Service
+	provider providers.Provider
+	opts     IAPOptions
+}
+
+func NewIAP(ctx context.Context, provider providers.Provider, opts IAPOptions) (*identityAwareProxy, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, iap.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	crmService, err := cloudresourcemanager.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource manager service: %w", err)
+	}
+	iapService, err := iap.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iap service: %w", err)
+	}
+	identityService, err := identitytoolkit.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity platform service: %w", err)
+	}
+	return &identityAwareProxy{
+		crm:      crmService,
+		iap:      iapService,
+		identity: identityService,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (ip *identityAwareProxy) Close() {
+	// No close method for the services
+}
+
+func (ip *identityAwareProxy) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(ip.resources), nil
+}
+
+func (ip *identityAwareProxy) resources(ctx context.Context, resources *discovered) error {
+	projectID := ip.provider.ProjectID
+
+	// IAP names projects by number
+	project, err := ip.crm.Projects.Get(projectID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting project %s: %w", projectID, err)
+	}
+	projectNumber := strconv.FormatInt(project.ProjectNumber, 10)
+
+	brands, err := ip.iap.Projects.Brands.List("projects/" + projectNumber).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error listing IAP brands of project %s: %w", projectID, err)
+	}
+	for _, brand := range brands.Brands {
+		stub := Resource{Provider: ip.provider, Type: ResourceTypeIAPBrand, Service: ServiceIAP,
+			Name: "brand_" + path.Base(brand.Name), ID: brand.Name}
+		resources.add(ctx, stub, func(ctx context.Context) (Resource, error) {
+			return ip.brandResource(ctx, brand)
+		})
+	}
+
+	if ip.opts.IncludeIAM {
+		for _, target := range []struct {
+			resource string
+			types    iamTypes
+		}{
+			{"iap_web", iapWebIAMTypes},
+			{"iap_tunnel", iapTunnelIAMTypes},
+		} {
+			parent := iamParent{
+				provider:  ip.provider,
+				service:   ServiceIAP,
+				types:     target.types,
+				attribute: "project",
+				name:      projectID,
+				// Import ID for IAP IAM is the project's IAP resource
+				id: fmt.Sprintf("projects/%s/%s", projectID, target.resource),
+			}
+			resource := fmt.Sprintf("projects/%s/%s", projectNumber, target.resource)
+			resources.addAll(ctx, parent.stub(), func(ctx context.Context) ([]Resource, error) {
+				policy, err := ip.policy(ctx, resource)
+				if err != nil {
+					return nil, err
+				}
+				return iamResources(ip.opts.IAMMode, parent, policy), nil
+			})
+		}
+	}
+
+	if ip.opts.IncludeTenants {
+		if err := ip.tenants(ctx, resources); err != nil {
+			// Tenants need Identity Platform, which most projects don't
+			// enable
+			slog.Warn("Error listing Identity Platform tenants, they are not imported", "project", projectID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// brandResource builds the resource of the OAuth brand, including its
+// clients. The import ID is its name, projects/<number>/brands/<id>.
+func (ip *identityAwareProxy) brandResource(ctx context.Context, brand *iap.Brand) (Resource, error) {
+	brandResource := Resource{
+		Provider: ip.provider,
+		Type:     ResourceTypeIAPBrand,
+		Service:  ServiceIAP,
+		// Brand IDs are numbers, which names can't start with
+		Name: "brand_" + path.Base(brand.Name),
+		ID:   brand.Name,
+		Attributes: map[string]any{
+			"project":           ip.provider.ProjectID,
+			"support_email":     brand.SupportEmail,
+			"application_title": brand.ApplicationTitle,
+		},
+	}
+
+	err := ip.iap.Projects.Brands.IdentityAwareProxyClients.List(brand.Name).Pages(ctx, func(page *iap.ListIdentityAwareProxyClientsResponse) error {
+		for _, client := range page.IdentityAwareProxyClients {
+			// The secret is left out, it is only readable by terraform
+			brandResource.Dependents = append(brandResource.Dependents, Resource{
+				Provider: ip.provider,
+				Type:     ResourceTypeIAPClient,
+				Service:  ServiceIAP,
+				Name:     "client_" + sanitizeMember(path.Base(client.Name)),
+				ID:       client.Name,
+				Attributes: map[string]any{
+					"brand":        brand.Name,
+					"display_name": client.DisplayName,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return Resource{}, fmt.Errorf("error listing IAP clients of brand %s: %w", brand.Name, err)
+	}
+
+	return brandResource, nil
+}
+
+func (ip *identityAwareProxy) policy(ctx context.Context, resource string) (*iam.Policy, error) {
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("iap", resource))
+	defer span.End()
+
+	policy, err := ip.iap.V1.GetIamPolicy(resource, &iap.GetIamPolicyRequest{}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting IAM policy for %s: %w", resource, err)
+	}
+
+	proto := &iampb.Policy{}
+	for _, binding := range policy.Bindings {
+		// The generated IAM resources have no conditions
+		if binding.Condition != nil {
+			slog.Warn("Skipping conditional IAP IAM binding", "resource", resource, "role", binding.Role)
+			continue
+		}
+		proto.Bindings = append(proto.Bindings, &iampb.Binding{Role: binding.Role, Members: binding.Members})
+	}
+	return &iam.Policy{InternalProto: proto}, nil
+}
+
+// tenants adds the Identity Platform tenants of the project to resources. The
+// import ID of a tenant is its name, projects/<project>/tenants/<id>.
+func (ip *identityAwareProxy) tenants(ctx context.Context, resources *discovered) error {
+	err := ip.identity.Projects.Tenants.List("projects/"+ip.provider.ProjectID).Pages(ctx, func(page *identitytoolkit.GoogleCloudIdentitytoolkitAdminV2ListTenantsResponse) error {
+		for _, tenant := range page.Tenants {
+			resources.append(Resource{
+				Provider: ip.provider,
+				Type:     ResourceTypeIdentityPlatformTenant,
+				Service:  ServiceIAP,
+				Name:     sanitizeName(path.Base(tenant.Name)),
+				ID:       tenant.Name,
+				Attributes: map[string]any{
+					"project":                  ip.provider.ProjectID,
+					"display_name":             tenant.DisplayName,
+					"allow_password_signup":    tenant.AllowPasswordSignup,
+					"enable_email_link_signin": tenant.EnableEmailLinkSignin,
+					"disable_auth":             tenant.DisableAuth,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing tenants of project %s: %w", ip.provider.ProjectID, err)
+	}
+	return nil
+}
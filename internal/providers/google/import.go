@@ -1,14 +1,94 @@
 package google
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResourceIterator yields the resources of a service. Next returns nil once
+// every resource was yielded.
+//
+// An error of Next wrapping a *ResourceError is scoped to the resource it
+// names and leaves the iterator usable: calling Next again retries the
+// resource, while calling Skip gives up on it so that Next moves on to the
+// next one. Any other error is fatal, Next keeps returning it and Err reports
+// it.
 type ResourceIterator interface {
 	Next(context.Context) (*Resource, error)
+	// Skip gives up on the resource whose error Next returned last
+	Skip()
+	// Err returns the fatal error which ended the iteration, if any
+	Err() error
 
 	Close() error
 }
 
+// ResourceError is returned by Next for a resource which couldn't be
+// discovered, e.g. because its IAM policy couldn't be read
+type ResourceError struct {
+	// Resource is the resource as far as it is known without the failed
+	// lookups: its service, type, name and ID
+	Resource Resource
+	Err      error
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("error discovering %s: %v", e.Resource.ID, e.Err)
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the lookup failed for a transient reason, such as
+// rate limiting or the API being unavailable, so that retrying may succeed
+func (e *ResourceError) Retryable() bool {
+	return Retryable(e.Err)
+}
+
+// Retryable reports whether a Google API call failed with err for a transient
+// reason, such as rate limiting or the API being unavailable
+func Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Internal, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	}
+	return false
+}
+
 type ResourceImporter interface {
 	Import(context.Context) (ResourceIterator, error)
 	Close()
 }
+
+// Count is the result of a cheap listing pass over a service.
+type Count struct {
+	Resources map[ResourceType]int
+	// APICalls is the number of calls discovery is expected to make
+	APICalls int
+}
+
+// ResourceCounter is implemented by importers which can count their resources
+// without fetching their details. Resources dropped by the filter are not
+// counted.
+type ResourceCounter interface {
+	Count(context.Context, Filter) (Count, error)
+}
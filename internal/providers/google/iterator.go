@@ -0,0 +1,171 @@
+package google
+
+import (
+	"context"
+	"fmt"
+)
+
+// pending is an entry of the resources an importer discovers: resources which
+// were built, the error of a lookup which failed and the build to retry it
+// with, or without a build, the error which ended the listing
+type pending struct {
+	resources []Resource
+	stub      Resource
+	build     func(context.Context) ([]Resource, error)
+	err       error
+	// reported is set once the error was returned by Next, which retries
+	// the build when called again
+	reported bool
+}
+
+// discovered hands the resources an importer discovers to its queueIterator
+// while they are listed, page by page. A resource whose lookups fail is kept
+// in its place with its error rather than failing the whole service.
+type discovered struct {
+	ctx     context.Context
+	pending chan pending
+}
+
+// push waits for the consumer to take p, unless the iteration is closed
+func (d *discovered) push(p pending) {
+	select {
+	case d.pending <- p:
+	case <-d.ctx.Done():
+	}
+}
+
+// append adds resources which need no further lookups
+func (d *discovered) append(resources ...Resource) {
+	if len(resources) > 0 {
+		d.push(pending{resources: resources})
+	}
+}
+
+// add builds a resource with build. stub names the resource if the build
+// fails.
+func (d *discovered) add(ctx context.Context, stub Resource, build func(context.Context) (Resource, error)) {
+	d.addAll(ctx, stub, func(ctx context.Context) ([]Resource, error) {
+		r, err := build(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []Resource{r}, nil
+	})
+}
+
+// addAll builds the resources read by a single lookup, such as the IAM
+// resources of a policy
+func (d *discovered) addAll(ctx context.Context, stub Resource, build func(context.Context) ([]Resource, error)) {
+	resources, err := build(ctx)
+	d.push(pending{resources: resources, stub: stub, build: build, err: err})
+}
+
+// queueIterator yields the resources of an importer as its load function
+// lists them, see ResourceIterator for how errors are handled. Listing waits
+// for the consumer to take each entry, so at most the page being listed is
+// held in memory.
+type queueIterator struct {
+	load     func(context.Context, *discovered) error
+	pending  chan pending
+	cancel   context.CancelFunc
+	head     *pending
+	err      error
+	isClosed bool
+}
+
+func newQueueIterator(load func(context.Context, *discovered) error) *queueIterator {
+	return &queueIterator{load: load}
+}
+
+// start runs load until it has listed everything, the iteration is closed or
+// ctx is canceled. An error of load ends the iteration after the resources
+// listed before it.
+func (it *queueIterator) start(ctx context.Context) {
+	ctx, it.cancel = context.WithCancel(ctx)
+	d := &discovered{ctx: ctx, pending: make(chan pending)}
+	it.pending = d.pending
+
+	go func() {
+		defer close(d.pending)
+		if err := it.load(ctx, d); err != nil {
+			d.push(pending{err: err})
+		}
+	}()
+}
+
+func (it *queueIterator) Next(ctx context.Context) (*Resource, error) {
+	if it.isClosed {
+		return nil, fmt.Errorf("iterator is closed")
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+	if it.pending == nil {
+		it.start(ctx)
+	}
+
+	for {
+		if it.head == nil {
+			var p pending
+			var ok bool
+			select {
+			case p, ok = <-it.pending:
+			case <-ctx.Done():
+				it.err = ctx.Err()
+				return nil, it.err
+			}
+			if !ok {
+				it.err = ctx.Err()
+				return nil, it.err
+			}
+			it.head = &p
+		}
+
+		head := it.head
+		if head.err != nil && head.build == nil {
+			it.err = head.err
+			return nil, it.err
+		}
+		if head.err != nil && head.reported {
+			head.resources, head.err = head.build(ctx)
+		}
+		if head.err != nil && ctx.Err() != nil {
+			// Lookups failing as the run is canceled are no errors of
+			// their resources
+			it.err = ctx.Err()
+			return nil, it.err
+		}
+		if head.err != nil {
+			head.reported = true
+			return nil, &ResourceError{Resource: head.stub, Err: head.err}
+		}
+
+		if len(head.resources) == 0 {
+			it.head = nil
+			continue
+		}
+		resource := head.resources[0]
+		head.resources = head.resources[1:]
+		return &resource, nil
+	}
+}
+
+func (it *queueIterator) Skip() {
+	if it.head != nil && it.head.err != nil {
+		it.head = nil
+	}
+}
+
+func (it *queueIterator) Err() error {
+	return it.err
+}
+
+func (it *queueIterator) Close() error {
+	it.isClosed = true
+	if it.cancel != nil {
+		it.cancel()
+		for range it.pending {
+		}
+	}
+	return nil
+}
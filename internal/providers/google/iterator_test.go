@@ -0,0 +1,115 @@
+package google
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueueIteratorYieldsPerPage(t *testing.T) {
+	secondPage := make(chan struct{})
+	it := newQueueIterator(func(ctx context.Context, resources *discovered) error {
+		resources.append(Resource{ID: "first"})
+		// The next page is only listed once the first was yielded
+		select {
+		case <-secondPage:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		resources.append(Resource{ID: "second"})
+		return nil
+	})
+	defer it.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r, err := it.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.ID != "first" {
+		t.Fatalf("Next() = %v, want the first resource before the second page is listed", r)
+	}
+
+	close(secondPage)
+	r, err = it.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.ID != "second" {
+		t.Fatalf("Next() = %v, want the second resource", r)
+	}
+	if r, err := it.Next(ctx); r != nil || err != nil {
+		t.Fatalf("Next() = %v, %v at the end, want nil, nil", r, err)
+	}
+}
+
+func TestQueueIteratorListingError(t *testing.T) {
+	errList := errors.New("listing failed")
+	it := newQueueIterator(func(ctx context.Context, resources *discovered) error {
+		resources.append(Resource{ID: "first"})
+		return errList
+	})
+	defer it.Close()
+
+	ctx := context.Background()
+	if r, err := it.Next(ctx); err != nil || r == nil || r.ID != "first" {
+		t.Fatalf("Next() = %v, %v, want the resource listed before the error", r, err)
+	}
+	if _, err := it.Next(ctx); !errors.Is(err, errList) {
+		t.Fatalf("Next() error = %v, want %v", err, errList)
+	}
+	if err := it.Err(); !errors.Is(err, errList) {
+		t.Errorf("Err() = %v, want %v", err, errList)
+	}
+}
+
+func TestQueueIteratorRetriesResource(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+	var attempts int
+	it := newQueueIterator(func(ctx context.Context, resources *discovered) error {
+		resources.add(ctx, Resource{ID: "flaky"}, func(context.Context) (Resource, error) {
+			attempts++
+			if attempts == 1 {
+				return Resource{}, errLookup
+			}
+			return Resource{ID: "flaky"}, nil
+		})
+		return nil
+	})
+	defer it.Close()
+
+	ctx := context.Background()
+	_, err := it.Next(ctx)
+	var resourceErr *ResourceError
+	if !errors.As(err, &resourceErr) || resourceErr.Resource.ID != "flaky" {
+		t.Fatalf("Next() error = %v, want a ResourceError of the resource", err)
+	}
+	if r, err := it.Next(ctx); err != nil || r == nil || r.ID != "flaky" {
+		t.Fatalf("Next() = %v, %v, want the resource built again", r, err)
+	}
+}
+
+func TestQueueIteratorCloseStopsListing(t *testing.T) {
+	stopped := make(chan struct{})
+	it := newQueueIterator(func(ctx context.Context, resources *discovered) error {
+		defer close(stopped)
+		for ctx.Err() == nil {
+			resources.append(Resource{ID: "endless"})
+		}
+		return ctx.Err()
+	})
+
+	if _, err := it.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listing went on after Close")
+	}
+}
@@ -0,0 +1,88 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+)
+
+// ResolveEncryptionKey returns a Cloud KMS key which can encrypt state. A key
+// given by name is checked to exist, be a symmetric encryption key and have
+// an enabled primary version. Without a name, the project's keys in the global
+// location and in region are searched, and the key is only picked if there is
+// exactly one.
+func ResolveEncryptionKey(ctx context.Context, creds providers.Credentials, projectID, region, name string) (string, error) {
+	opts, err := ClientOptions(ctx, creds, cloudkms.CloudkmsScope)
+	if err != nil {
+		return "", err
+	}
+	service, err := cloudkms.NewService(ctx, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create kms service: %w", err)
+	}
+
+	if name != "" {
+		key, err := service.Projects.Locations.KeyRings.CryptoKeys.Get(name).Context(ctx).Do()
+		if err != nil {
+			return "", fmt.Errorf("failed to get kms key %s: %w", name, err)
+		}
+		if err := checkEncryptionKey(key); err != nil {
+			return "", err
+		}
+		return key.Name, nil
+	}
+
+	locations := []string{"global"}
+	if region != "" {
+		locations = append(locations, region)
+	}
+
+	var candidates []string
+	for _, location := range locations {
+		parent := fmt.Sprintf("projects/%s/locations/%s", projectID, location)
+		err := service.Projects.Locations.KeyRings.List(parent).Pages(ctx, func(rings *cloudkms.ListKeyRingsResponse) error {
+			for _, ring := range rings.KeyRings {
+				err := service.Projects.Locations.KeyRings.CryptoKeys.List(ring.Name).Pages(ctx, func(keys *cloudkms.ListCryptoKeysResponse) error {
+					for _, key := range keys.CryptoKeys {
+						if checkEncryptionKey(key) == nil {
+							candidates = append(candidates, key.Name)
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					return fmt.Errorf("failed to list keys of %s: %w", ring.Name, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to list key rings in %s: %w", parent, err)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no enabled encryption key found in project %s (locations %s), create one or pass its name",
+			projectID, strings.Join(locations, ", "))
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("%d encryption keys found in project %s, pass the name of one of them: %s",
+			len(candidates), projectID, strings.Join(candidates, ", "))
+	}
+}
+
+// checkEncryptionKey returns an error unless key can encrypt data
+func checkEncryptionKey(key *cloudkms.CryptoKey) error {
+	if key.Purpose != "ENCRYPT_DECRYPT" {
+		return fmt.Errorf("kms key %s has purpose %s, state encryption needs an ENCRYPT_DECRYPT key", key.Name, key.Purpose)
+	}
+	if key.Primary == nil || key.Primary.State != "ENABLED" {
+		return fmt.Errorf("kms key %s has no enabled primary version", key.Name)
+	}
+	return nil
+}
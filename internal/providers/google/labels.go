@@ -0,0 +1,179 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/storage"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// LabelAttributes maps the resource types supporting labels to the attribute
+// which holds them in generated config, with nested blocks separated by dots.
+var LabelAttributes = map[ResourceType]string{
+	ResourceTypePubSubTopic:        "labels",
+	ResourceTypePubSubSubscription: "labels",
+	ResourceTypeStorageBucket:      "labels",
+	ResourceTypeSQLInstance:        "settings.user_labels",
+}
+
+// OutputAttributes maps resource types to the attributes which other
+// configurations commonly refer to them by, and which the project exposes as
+// outputs.
+var OutputAttributes = map[ResourceType][]string{
+	ResourceTypeStorageBucket:        {"name", "url"},
+	ResourceTypeSQLInstance:          {"connection_name", "private_ip_address"},
+	ResourceTypePubSubTopic:          {"id", "name"},
+	ResourceTypePubSubSubscription:   {"id"},
+	ResourceTypeComputeAddress:       {"address"},
+	ResourceTypeComputeGlobalAddress: {"address"},
+}
+
+// Labeler writes labels to cloud resources.
+type Labeler struct {
+	provider providers.Provider
+	pubsub   *pubsub.Client
+	storage  *storage.Client
+	sqladmin *sqladmin.Service
+}
+
+func NewLabeler(ctx context.Context, provider providers.Provider) (*Labeler, error) {
+	pubsubOpts, err := grpcClientOptions(ctx, provider, pubsub.ScopePubSub)
+	if err != nil {
+		return nil, err
+	}
+	storageOpts, err := providerClientOptions(ctx, provider, storage.ScopeFullControl)
+	if err != nil {
+		return nil, err
+	}
+	sqlOpts, err := providerClientOptions(ctx, provider, sqladmin.SqlserviceAdminScope)
+	if err != nil {
+		return nil, err
+	}
+
+	pubsubClient, err := pubsub.NewClient(ctx, provider.ProjectID, pubsubOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub client: %w", err)
+	}
+
+	storageClient, err := storage.NewClient(ctx, storageOpts...)
+	if err != nil {
+		pubsubClient.Close()
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	service, err := sqladmin.NewService(ctx, sqlOpts...)
+	if err != nil {
+		pubsubClient.Close()
+		storageClient.Close()
+		return nil, fmt.Errorf("failed to create cloudsql service: %w", err)
+	}
+
+	return &Labeler{
+		provider: provider,
+		pubsub:   pubsubClient,
+		storage:  storageClient,
+		sqladmin: service,
+	}, nil
+}
+
+func (l *Labeler) Close() {
+	l.pubsub.Close()
+	l.storage.Close()
+}
+
+// Apply adds the labels missing from a cloud resource. Labels already set on
+// the resource keep their value. It returns whether the resource was updated.
+func (l *Labeler) Apply(ctx context.Context, r Resource, labels map[string]string) (bool, error) {
+	switch r.Type {
+	case ResourceTypePubSubTopic:
+		topic := l.pubsub.Topic(path.Base(r.ID))
+		config, err := topic.Config(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get topic %s: %w", r.ID, err)
+		}
+		merged, changed := mergeLabels(config.Labels, labels)
+		if !changed {
+			return false, nil
+		}
+		if _, err := topic.Update(ctx, pubsub.TopicConfigToUpdate{Labels: merged}); err != nil {
+			return false, fmt.Errorf("failed to label topic %s: %w", r.ID, err)
+		}
+		return true, nil
+
+	case ResourceTypePubSubSubscription:
+		subscription := l.pubsub.Subscription(path.Base(r.ID))
+		config, err := subscription.Config(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get subscription %s: %w", r.ID, err)
+		}
+		merged, changed := mergeLabels(config.Labels, labels)
+		if !changed {
+			return false, nil
+		}
+		if _, err := subscription.Update(ctx, pubsub.SubscriptionConfigToUpdate{Labels: merged}); err != nil {
+			return false, fmt.Errorf("failed to label subscription %s: %w", r.ID, err)
+		}
+		return true, nil
+
+	case ResourceTypeStorageBucket:
+		bucket := l.storage.Bucket(r.ID)
+		attrs, err := bucket.Attrs(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get bucket %s: %w", r.ID, err)
+		}
+		merged, changed := mergeLabels(attrs.Labels, labels)
+		if !changed {
+			return false, nil
+		}
+		var update storage.BucketAttrsToUpdate
+		for k, v := range merged {
+			update.SetLabel(k, v)
+		}
+		if _, err := bucket.Update(ctx, update); err != nil {
+			return false, fmt.Errorf("failed to label bucket %s: %w", r.ID, err)
+		}
+		return true, nil
+
+	case ResourceTypeSQLInstance:
+		name, _ := r.Attributes["name"].(string)
+		instance, err := l.sqladmin.Instances.Get(l.provider.ProjectID, name).Context(ctx).Do()
+		if err != nil {
+			return false, fmt.Errorf("failed to get instance %s: %w", name, err)
+		}
+		var current map[string]string
+		if instance.Settings != nil {
+			current = instance.Settings.UserLabels
+		}
+		merged, changed := mergeLabels(current, labels)
+		if !changed {
+			return false, nil
+		}
+		patch := &sqladmin.DatabaseInstance{Settings: &sqladmin.Settings{UserLabels: merged}}
+		if _, err := l.sqladmin.Instances.Patch(l.provider.ProjectID, name, patch).Context(ctx).Do(); err != nil {
+			return false, fmt.Errorf("failed to label instance %s: %w", name, err)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func mergeLabels(current, labels map[string]string) (map[string]string, bool) {
+	merged := make(map[string]string, len(current)+len(labels))
+	for k, v := range current {
+		merged[k] = v
+	}
+
+	var changed bool
+	for k, v := range labels {
+		if _, ok := merged[k]; !ok {
+			merged[k] = v
+			changed = true
+		}
+	}
+	return merged, changed
+}
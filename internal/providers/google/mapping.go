@@ -0,0 +1,62 @@
+package google
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+// MappingRule overrides the type, name or import ID generated for the
+// discovered resources of a type whose import ID matches a regular
+// expression, to work around provider quirks. Name and ImportID may refer to
+// submatches of the expression, e.g. "$1". Empty overrides are left as
+// discovered.
+type MappingRule struct {
+	// ResourceType matches the discovered type, see CompileGlob, any if nil
+	ResourceType *regexp.Regexp
+	ID           *regexp.Regexp
+
+	Type     ResourceType
+	Name     string
+	ImportID string
+}
+
+// Mappings apply the first rule matching a resource to it and its
+// dependents, in order.
+type Mappings []MappingRule
+
+// Apply returns the resource and its dependents with their overrides
+// applied
+func (m Mappings) Apply(r Resource) Resource {
+	for _, rule := range m {
+		if rule.ResourceType != nil && !rule.ResourceType.MatchString(string(r.Type)) {
+			continue
+		}
+		match := rule.ID.FindStringSubmatchIndex(r.ID)
+		if match == nil {
+			continue
+		}
+
+		before := r.Address()
+		id := r.ID
+		if rule.Type != "" {
+			r.Type = rule.Type
+		}
+		if rule.Name != "" {
+			r.Name = sanitizeName(string(rule.ID.ExpandString(nil, rule.Name, id, match)))
+		}
+		if rule.ImportID != "" {
+			r.ID = string(rule.ID.ExpandString(nil, rule.ImportID, id, match))
+		}
+		slog.Debug("Mapped resource", "resource", before, "address", r.Address(), "id", r.ID)
+		break
+	}
+
+	if len(r.Dependents) > 0 {
+		dependents := make([]Resource, len(r.Dependents))
+		for i, d := range r.Dependents {
+			dependents[i] = m.Apply(d)
+		}
+		r.Dependents = dependents
+	}
+	return r
+}
@@ -0,0 +1,253 @@
+package google
+
+// PubSubOptions tune the PubSub importer
+type PubSubOptions struct {
+	IncludeIAM           bool    `yaml:"include_iam"`
+	IAMMode              IAMMode `yaml:"iam_mode"`
+	IncludeSubscriptions bool    `yaml:"include_subscriptions"`
+	// Parallelism is the number of topics fetched concurrently
+	Parallelism int `yaml:"parallelism"`
+}
+
+// StorageOptions tune the Storage importer
+type StorageOptions struct {
+	IncludeIAM bool    `yaml:"include_iam"`
+	IAMMode    IAMMode `yaml:"iam_mode"`
+	// IncludeACL imports the ACL and default object ACL of buckets without
+	// uniform bucket-level access
+	IncludeACL bool `yaml:"include_acl"`
+	// Parallelism is the number of buckets fetched concurrently
+	Parallelism int `yaml:"parallelism"`
+}
+
+// CloudSQLOptions tune the CloudSQL importer
+type CloudSQLOptions struct {
+	IncludeDatabases bool `yaml:"include_databases"`
+	IncludeUsers     bool `yaml:"include_users"`
+}
+
+// IAMOptions tune the IAM importer
+type IAMOptions struct {
+	// Organization is the numeric ID of the organization whose custom roles
+	// and audit configs are imported along with the project's
+	Organization        string `yaml:"organization"`
+	IncludeAuditConfigs bool   `yaml:"include_audit_configs"`
+}
+
+// ComputeOptions tune the Compute importer
+type ComputeOptions struct {
+	// IncludeIAM imports the IAM policies of the subnetworks of a Shared VPC
+	// host project
+	IncludeIAM bool    `yaml:"include_iam"`
+	IAMMode    IAMMode `yaml:"iam_mode"`
+	// IncludeAddresses imports regional and global static addresses
+	IncludeAddresses bool `yaml:"include_addresses"`
+	// IncludeSSLCertificates imports Google-managed SSL certificates
+	IncludeSSLCertificates bool `yaml:"include_ssl_certificates"`
+}
+
+// ProjectOptions tune the Project importer
+type ProjectOptions struct {
+	IncludeEssentialContacts bool `yaml:"include_essential_contacts"`
+	// IncludeBudgets imports the budgets tracking the project's costs, which
+	// needs access to its billing account
+	IncludeBudgets bool `yaml:"include_budgets"`
+}
+
+// FilestoreOptions tune the Filestore importer
+type FilestoreOptions struct {
+	IncludeBackups bool `yaml:"include_backups"`
+	// IncludeNetApp imports NetApp storage pools and volumes, which needs the
+	// NetApp API to be enabled
+	IncludeNetApp bool `yaml:"include_netapp"`
+}
+
+// APIGatewayOptions tune the API Gateway importer
+type APIGatewayOptions struct {
+	IncludeIAM bool    `yaml:"include_iam"`
+	IAMMode    IAMMode `yaml:"iam_mode"`
+}
+
+// EventarcOptions tune the Eventarc importer
+type EventarcOptions struct {
+	// IncludeManaged imports triggers another service manages, e.g. those of
+	// event-driven Cloud Functions, which are recreated with the function
+	IncludeManaged bool `yaml:"include_managed"`
+}
+
+// WorkflowsOptions tune the Workflows importer
+type WorkflowsOptions struct {
+	// SourceFiles writes the definition of each workflow to a YAML file next
+	// to its config, which reads it with file(), instead of inline
+	SourceFiles bool `yaml:"source_files"`
+}
+
+// IAPOptions tune the IAP importer
+type IAPOptions struct {
+	// IncludeIAM imports who may access web apps and tunnel to VMs through
+	// IAP
+	IncludeIAM bool    `yaml:"include_iam"`
+	IAMMode    IAMMode `yaml:"iam_mode"`
+	// IncludeTenants imports Identity Platform tenants
+	IncludeTenants bool `yaml:"include_tenants"`
+}
+
+// CertificateManagerOptions tune the Certificate Manager importer
+type CertificateManagerOptions struct {
+	IncludeDNSAuthorizations bool `yaml:"include_dns_authorizations"`
+	// IncludeMaps imports certificate maps with their entries
+	IncludeMaps bool `yaml:"include_maps"`
+}
+
+// PrivateCAOptions tune the Private CA importer
+type PrivateCAOptions struct {
+	IncludeIAM bool    `yaml:"include_iam"`
+	IAMMode    IAMMode `yaml:"iam_mode"`
+}
+
+// BinaryAuthorizationOptions tune the Binary Authorization importer
+type BinaryAuthorizationOptions struct {
+	IncludeIAM bool    `yaml:"include_iam"`
+	IAMMode    IAMMode `yaml:"iam_mode"`
+	// IncludeNotes imports the Container Analysis notes attestors of the
+	// project attest with
+	IncludeNotes bool `yaml:"include_notes"`
+}
+
+// GKEHubOptions tune the GKE Hub importer
+type GKEHubOptions struct {
+	// IncludeFeatures imports the Config Management and Policy Controller
+	// features of the fleet, with the spec of each membership
+	IncludeFeatures bool `yaml:"include_features"`
+}
+
+// DataplexOptions tune the Dataplex importer
+type DataplexOptions struct {
+	// IncludeTaxonomies imports Data Catalog taxonomies with their policy
+	// tags
+	IncludeTaxonomies bool `yaml:"include_taxonomies"`
+	// IncludeLakes imports Dataplex lakes with their zones and assets
+	IncludeLakes bool `yaml:"include_lakes"`
+}
+
+func DefaultPubSubOptions() PubSubOptions {
+	return PubSubOptions{IncludeIAM: true, IAMMode: IAMModeBinding, IncludeSubscriptions: true, Parallelism: defaultParallelism}
+}
+
+func DefaultStorageOptions() StorageOptions {
+	return StorageOptions{IncludeIAM: true, IAMMode: IAMModeBinding, IncludeACL: true, Parallelism: defaultParallelism}
+}
+
+func DefaultCloudSQLOptions() CloudSQLOptions {
+	return CloudSQLOptions{IncludeDatabases: true, IncludeUsers: true}
+}
+
+func DefaultIAMOptions() IAMOptions {
+	return IAMOptions{IncludeAuditConfigs: true}
+}
+
+func DefaultComputeOptions() ComputeOptions {
+	return ComputeOptions{IncludeIAM: true, IAMMode: IAMModeBinding, IncludeAddresses: true, IncludeSSLCertificates: true}
+}
+
+func DefaultProjectOptions() ProjectOptions {
+	return ProjectOptions{IncludeEssentialContacts: true, IncludeBudgets: true}
+}
+
+func DefaultFilestoreOptions() FilestoreOptions {
+	return FilestoreOptions{IncludeBackups: true}
+}
+
+func DefaultAPIGatewayOptions() APIGatewayOptions {
+	return APIGatewayOptions{IncludeIAM: true, IAMMode: IAMModeBinding}
+}
+
+func DefaultEventarcOptions() EventarcOptions {
+	return EventarcOptions{}
+}
+
+func DefaultWorkflowsOptions() WorkflowsOptions {
+	return WorkflowsOptions{SourceFiles: true}
+}
+
+func DefaultIAPOptions() IAPOptions {
+	return IAPOptions{IncludeIAM: true, IAMMode: IAMModeBinding, IncludeTenants: true}
+}
+
+func DefaultCertificateManagerOptions() CertificateManagerOptions {
+	return CertificateManagerOptions{IncludeDNSAuthorizations: true, IncludeMaps: true}
+}
+
+func DefaultPrivateCAOptions() PrivateCAOptions {
+	return PrivateCAOptions{IncludeIAM: true, IAMMode: IAMModeBinding}
+}
+
+func DefaultBinaryAuthorizationOptions() BinaryAuthorizationOptions {
+	return BinaryAuthorizationOptions{IncludeIAM: true, IAMMode: IAMModeBinding, IncludeNotes: true}
+}
+
+func DefaultGKEHubOptions() GKEHubOptions {
+	return GKEHubOptions{IncludeFeatures: true}
+}
+
+func DefaultDataplexOptions() DataplexOptions {
+	return DataplexOptions{IncludeTaxonomies: true, IncludeLakes: true}
+}
+
+// DefaultServiceOptions returns a pointer to the default options of a
+// service, for decoding configured options into, or nil if the service takes
+// no options.
+func DefaultServiceOptions(s Service) any {
+	switch s {
+	case ServicePubSub:
+		opts := DefaultPubSubOptions()
+		return &opts
+	case ServiceStorage:
+		opts := DefaultStorageOptions()
+		return &opts
+	case ServiceCloudSQL:
+		opts := DefaultCloudSQLOptions()
+		return &opts
+	case ServiceIAM:
+		opts := DefaultIAMOptions()
+		return &opts
+	case ServiceCompute:
+		opts := DefaultComputeOptions()
+		return &opts
+	case ServiceProject:
+		opts := DefaultProjectOptions()
+		return &opts
+	case ServiceFilestore:
+		opts := DefaultFilestoreOptions()
+		return &opts
+	case ServiceAPIGateway:
+		opts := DefaultAPIGatewayOptions()
+		return &opts
+	case ServiceEventarc:
+		opts := DefaultEventarcOptions()
+		return &opts
+	case ServiceWorkflows:
+		opts := DefaultWorkflowsOptions()
+		return &opts
+	case ServiceIAP:
+		opts := DefaultIAPOptions()
+		return &opts
+	case ServiceCertificateManager:
+		opts := DefaultCertificateManagerOptions()
+		return &opts
+	case ServicePrivateCA:
+		opts := DefaultPrivateCAOptions()
+		return &opts
+	case ServiceBinaryAuthorization:
+		opts := DefaultBinaryAuthorizationOptions()
+		return &opts
+	case ServiceGKEHub:
+		opts := DefaultGKEHubOptions()
+		return &opts
+	case ServiceDataplex:
+		opts := DefaultDataplexOptions()
+		return &opts
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,191 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+)
+
+// securityReviewerRole grants reading the IAM policies of every resource
+const securityReviewerRole = "roles/iam.securityReviewer"
+
+// permission is an IAM permission an importer needs, with the least
+// privileged predefined role which grants it
+type permission struct {
+	name string
+	role string
+}
+
+// RequiredPermissions returns the permissions the importer of a service needs
+// on the project with opts, the options of the service, keyed by the least
+// privileged predefined role which grants them. Permissions on other
+// resources, such as billing accounts and organizations, are left out.
+func RequiredPermissions(opts any) map[string][]string {
+	var required []permission
+	need := func(role string, names ...string) {
+		for _, name := range names {
+			required = append(required, permission{name: name, role: role})
+		}
+	}
+
+	switch o := opts.(type) {
+	case *PubSubOptions:
+		need("roles/pubsub.viewer", "pubsub.topics.list", "pubsub.topics.get")
+		if o.IncludeSubscriptions {
+			need("roles/pubsub.viewer", "pubsub.subscriptions.list", "pubsub.subscriptions.get")
+		}
+		if o.IncludeIAM {
+			need(securityReviewerRole, "pubsub.topics.getIamPolicy")
+			if o.IncludeSubscriptions {
+				need(securityReviewerRole, "pubsub.subscriptions.getIamPolicy")
+			}
+		}
+	case *CloudSQLOptions:
+		need("roles/cloudsql.viewer", "cloudsql.instances.list", "cloudsql.instances.get")
+		if o.IncludeDatabases {
+			need("roles/cloudsql.viewer", "cloudsql.databases.list")
+		}
+		if o.IncludeUsers {
+			need("roles/cloudsql.viewer", "cloudsql.users.list")
+		}
+	case *StorageOptions:
+		need("roles/storage.bucketViewer", "storage.buckets.list", "storage.buckets.get")
+		if o.IncludeIAM || o.IncludeACL {
+			need(securityReviewerRole, "storage.buckets.getIamPolicy")
+		}
+	case *IAMOptions:
+		need("roles/iam.roleViewer", "iam.roles.list", "iam.roles.get")
+		if o.IncludeAuditConfigs {
+			need(securityReviewerRole, "resourcemanager.projects.getIamPolicy")
+		}
+	case *ComputeOptions:
+		need("roles/compute.networkViewer", "compute.projects.get", "compute.subnetworks.list")
+		if o.IncludeIAM {
+			need(securityReviewerRole, "compute.subnetworks.getIamPolicy")
+		}
+		if o.IncludeAddresses {
+			need("roles/compute.networkViewer", "compute.addresses.list", "compute.globalAddresses.list")
+		}
+		if o.IncludeSSLCertificates {
+			need("roles/compute.viewer", "compute.sslCertificates.list")
+		}
+	case *ProjectOptions:
+		need("roles/browser", "resourcemanager.projects.get")
+		if o.IncludeEssentialContacts {
+			need("roles/essentialcontacts.viewer", "essentialcontacts.contacts.list")
+		}
+	case *FilestoreOptions:
+		need("roles/file.viewer", "file.instances.list")
+		if o.IncludeBackups {
+			need("roles/file.viewer", "file.backups.list")
+		}
+		if o.IncludeNetApp {
+			need("roles/netapp.viewer", "netapp.storagePools.list", "netapp.volumes.list")
+		}
+	case *APIGatewayOptions:
+		need("roles/apigateway.viewer", "apigateway.apis.list", "apigateway.apiconfigs.list", "apigateway.gateways.list")
+		if o.IncludeIAM {
+			need(securityReviewerRole, "apigateway.apis.getIamPolicy", "apigateway.apiconfigs.getIamPolicy",
+				"apigateway.gateways.getIamPolicy")
+		}
+	case *EventarcOptions:
+		need("roles/eventarc.viewer", "eventarc.triggers.list")
+	case *WorkflowsOptions:
+		need("roles/workflows.viewer", "workflows.workflows.list")
+	case *IAPOptions:
+		need("roles/browser", "resourcemanager.projects.get")
+		need("roles/oauthconfig.viewer", "clientauthconfig.brands.list", "clientauthconfig.clients.listWithSecrets")
+		if o.IncludeIAM {
+			need(securityReviewerRole, "iap.web.getIamPolicy", "iap.tunnel.getIamPolicy")
+		}
+		if o.IncludeTenants {
+			need("roles/identityplatform.viewer", "identitytoolkit.tenants.list")
+		}
+	case *CertificateManagerOptions:
+		need("roles/certificatemanager.viewer", "certificatemanager.certs.list")
+		if o.IncludeDNSAuthorizations {
+			need("roles/certificatemanager.viewer", "certificatemanager.dnsauthorizations.list")
+		}
+		if o.IncludeMaps {
+			need("roles/certificatemanager.viewer", "certificatemanager.certmaps.list", "certificatemanager.certmapentries.list")
+		}
+	case *PrivateCAOptions:
+		need("roles/privateca.auditor", "privateca.caPools.list", "privateca.certificateAuthorities.list")
+		if o.IncludeIAM {
+			need(securityReviewerRole, "privateca.caPools.getIamPolicy")
+		}
+	case *BinaryAuthorizationOptions:
+		need("roles/binaryauthorization.policyViewer", "binaryauthorization.policy.get")
+		need("roles/binaryauthorization.attestorsViewer", "binaryauthorization.attestors.list")
+		if o.IncludeIAM {
+			need(securityReviewerRole, "binaryauthorization.attestors.getIamPolicy")
+		}
+		if o.IncludeNotes {
+			need("roles/containeranalysis.notes.viewer", "containeranalysis.notes.get")
+		}
+	case *GKEHubOptions:
+		need("roles/gkehub.viewer", "gkehub.memberships.list")
+		if o.IncludeFeatures {
+			need("roles/gkehub.viewer", "gkehub.features.list")
+		}
+	case *DataplexOptions:
+		if o.IncludeTaxonomies {
+			need("roles/datacatalog.categoryFineGrainedReader", "datacatalog.taxonomies.list", "datacatalog.taxonomies.get")
+		}
+		if o.IncludeLakes {
+			need("roles/dataplex.viewer", "dataplex.lakes.list", "dataplex.zones.list", "dataplex.assets.list")
+		}
+	}
+
+	roles := map[string][]string{}
+	for _, p := range required {
+		if !slices.Contains(roles[p.role], p.name) {
+			roles[p.role] = append(roles[p.role], p.name)
+		}
+	}
+	return roles
+}
+
+// MissingPermissions tests which of the permissions the importer of a service
+// needs with opts the credentials of provider lack on its project. It returns
+// the missing permissions keyed by the roles which grant them, none if the
+// importer may run.
+func MissingPermissions(ctx context.Context, provider providers.Provider, opts any) (map[string][]string, error) {
+	required := RequiredPermissions(opts)
+	if len(required) == 0 {
+		return nil, nil
+	}
+	var permissions []string
+	for _, role := range slices.Sorted(maps.Keys(required)) {
+		permissions = append(permissions, required[role]...)
+	}
+
+	clientOpts, err := providerClientOptions(ctx, provider, cloudresourcemanager.CloudPlatformReadOnlyScope)
+	if err != nil {
+		return nil, err
+	}
+	crm, err := cloudresourcemanager.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource manager service: %w", err)
+	}
+	response, err := crm.Projects.TestIamPermissions(provider.ProjectID, &cloudresourcemanager.TestIamPermissionsRequest{
+		Permissions: permissions,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to test permissions on project %s: %w", provider.ProjectID, QuotaProjectHint(err))
+	}
+
+	missing := map[string][]string{}
+	for role, names := range required {
+		for _, name := range names {
+			if !slices.Contains(response.Permissions, name) {
+				missing[role] = append(missing[role], name)
+			}
+		}
+	}
+	return missing, nil
+}
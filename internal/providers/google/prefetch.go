@@ -0,0 +1,153 @@
+package google
+
+import "context"
+
+// defaultParallelism is the number of resources whose IAM policies are
+// fetched concurrently
+const defaultParallelism = 8
+
+type prefetched struct {
+	resource *Resource
+	err      error
+	// retry builds the resource again if it failed, it is nil for errors
+	// of listing, which are fatal
+	retry func(context.Context) (*Resource, error)
+	stub  Resource
+}
+
+// prefetcher builds resources, including their IAM policy lookups, on a
+// bounded pool of workers ahead of the consumer and yields them in order.
+// At most parallelism resources are built or buffered at any time.
+type prefetcher struct {
+	pending chan chan prefetched
+	cancel  context.CancelFunc
+	// failed is the item whose error Next returned last
+	failed *prefetched
+	err    error
+}
+
+// newPrefetcher calls next until it reports no more items and builds each
+// item with build. A nil resource from build skips the item. stub names an
+// item whose build failed.
+func newPrefetcher[T any](ctx context.Context, parallelism int, next func() (T, bool, error),
+	build func(context.Context, T) (*Resource, error), stub func(T) Resource) *prefetcher {
+	if parallelism < 1 {
+		parallelism = defaultParallelism
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &prefetcher{
+		pending: make(chan chan prefetched, parallelism),
+		cancel:  cancel,
+	}
+
+	go func() {
+		defer close(p.pending)
+
+		sem := make(chan struct{}, parallelism)
+		for {
+			result := make(chan prefetched, 1)
+
+			item, ok, err := next()
+			if err != nil {
+				result <- prefetched{err: err}
+			} else if !ok {
+				return
+			} else {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				go func() {
+					defer func() { <-sem }()
+					resource, err := build(ctx, item)
+					result <- prefetched{resource: resource, err: err, stub: stub(item),
+						retry: func(ctx context.Context) (*Resource, error) { return build(ctx, item) }}
+				}()
+			}
+
+			select {
+			case p.pending <- result:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// Next returns the next resource, or nil when there are no more. After an
+// error of a single resource, Next builds it again unless Skip is called.
+func (p *prefetcher) Next(ctx context.Context) (*Resource, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if p.failed != nil {
+		resource, err := p.failed.retry(ctx)
+		if err := ctx.Err(); err != nil {
+			p.err = err
+			return nil, err
+		}
+		if err != nil {
+			return nil, &ResourceError{Resource: p.failed.stub, Err: err}
+		}
+		p.failed = nil
+		if resource != nil {
+			return resource, nil
+		}
+	}
+
+	for {
+		var result chan prefetched
+		var ok bool
+		select {
+		case result, ok = <-p.pending:
+		case <-ctx.Done():
+			p.err = ctx.Err()
+			return nil, p.err
+		}
+		if !ok {
+			p.err = ctx.Err()
+			return nil, p.err
+		}
+
+		r := <-result
+		switch {
+		case r.err != nil && r.retry == nil:
+			p.err = r.err
+			return nil, r.err
+		case r.err != nil && ctx.Err() != nil:
+			// Builds failing as the run is canceled are no errors of
+			// their resources
+			p.err = ctx.Err()
+			return nil, p.err
+		case r.err != nil:
+			p.failed = &r
+			return nil, &ResourceError{Resource: r.stub, Err: r.err}
+		case r.resource != nil:
+			return r.resource, nil
+		}
+	}
+}
+
+// Skip gives up on the resource whose error Next returned last
+func (p *prefetcher) Skip() {
+	p.failed = nil
+}
+
+// Err returns the error which ended the iteration, if any
+func (p *prefetcher) Err() error {
+	return p.err
+}
+
+func (p *prefetcher) Close() {
+	p.cancel()
+	for range p.pending {
+	}
+}
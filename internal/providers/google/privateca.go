@@ -0,0 +1,210 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	privateca "google.golang.org/api/privateca/v1"
+)
+
+// privateCA imports the CA pools of every configured region, each with its
+// certificate authorities and IAM policy. Issued certificates are not
+// imported.
+type privateCA struct {
+	service  *privateca.Service
+	provider providers.Provider
+	opts     PrivateCAOptions
+}
+
+func NewPrivateCA(ctx context.Context, provider providers.Provider, opts PrivateCAOptions) (*privateCA, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, privateca.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := privateca.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create private ca service: %w", err)
+	}
+	return &privateCA{
+		service:  service,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (pc *privateCA) Close() {
+	// No close method for the service
+}
+
+func (pc *privateCA) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(pc.resources), nil
+}
+
+func (pc *privateCA) resources(ctx context.Context, resources *discovered) error {
+	projectID := pc.provider.ProjectID
+
+	var locations []string
+	err := pc.service.Projects.Locations.List("projects/"+projectID).Pages(ctx, func(page *privateca.ListLocationsResponse) error {
+		for _, location := range page.Locations {
+			if pc.provider.InRegions(location.LocationId) {
+				locations = append(locations, location.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing private ca locations of project %s: %w", projectID, err)
+	}
+
+	for _, location := range locations {
+		err := pc.service.Projects.Locations.CaPools.List(location).Pages(ctx, func(page *privateca.ListCaPoolsResponse) error {
+			for _, pool := range page.CaPools {
+				stub := Resource{Provider: pc.provider, Type: ResourceTypePrivateCAPool, Service: ServicePrivateCA,
+					Name: sanitizeName(fmt.Sprintf("%s_%s", locationOf(pool.Name), path.Base(pool.Name))), ID: pool.Name}
+				resources.add(ctx, stub, func(ctx context.Context) (Resource, error) {
+					return pc.poolResource(ctx, pool)
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing CA pools in %s: %w", location, err)
+		}
+	}
+	return nil
+}
+
+// poolResource builds the resource of a CA pool, including its IAM bindings
+// and certificate authorities. The import ID is its full name,
+// projects/<project>/locations/<region>/caPools/<name>.
+func (pc *privateCA) poolResource(ctx context.Context, pool *privateca.CaPool) (Resource, error) {
+	poolID := path.Base(pool.Name)
+	location := locationOf(pool.Name)
+
+	attributes := map[string]any{
+		"name":     poolID,
+		"project":  pc.provider.ProjectID,
+		"location": location,
+		"tier":     pool.Tier,
+	}
+	if len(pool.Labels) > 0 {
+		attributes["labels"] = pool.Labels
+	}
+
+	// Pools of different regions may share names
+	name := fmt.Sprintf("%s_%s", location, poolID)
+	poolResource := Resource{
+		Provider:   pc.provider,
+		Type:       ResourceTypePrivateCAPool,
+		Service:    ServicePrivateCA,
+		Name:       sanitizeName(name),
+		ID:         pool.Name,
+		Attributes: attributes,
+	}
+
+	if pc.opts.IncludeIAM {
+		policy, err := pc.poolPolicy(ctx, pool.Name)
+		if err != nil {
+			return Resource{}, err
+		}
+		poolResource.Dependents = append(poolResource.Dependents, iamResources(pc.opts.IAMMode, iamParent{
+			provider:     pc.provider,
+			service:      ServicePrivateCA,
+			types:        privateCAPoolIAMTypes,
+			attribute:    "ca_pool",
+			name:         pool.Name,
+			resourceName: name,
+			id:           pool.Name,
+		}, policy)...)
+	}
+
+	err := pc.service.Projects.Locations.CaPools.CertificateAuthorities.List(pool.Name).Pages(ctx, func(page *privateca.ListCertificateAuthoritiesResponse) error {
+		for _, authority := range page.CertificateAuthorities {
+			if r, ok := pc.authorityResource(name, authority); ok {
+				poolResource.Dependents = append(poolResource.Dependents, r)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Resource{}, fmt.Errorf("error listing certificate authorities of CA pool %s: %w", poolID, err)
+	}
+
+	return poolResource, nil
+}
+
+// authorityResource builds the resource of a certificate authority, named
+// after its pool. Deleted authorities, which are kept for a grace period, are
+// skipped. Only the settings terraform reads back are set: its virtual
+// fields, e.g. deletion_protection, are left to their defaults and the issuer
+// certificate of a subordinate is only needed to activate it.
+func (pc *privateCA) authorityResource(poolName string, authority *privateca.CertificateAuthority) (Resource, bool) {
+	if authority.State == "DELETED" {
+		slog.Debug("Skipping deleted certificate authority", "authority", authority.Name)
+		return Resource{}, false
+	}
+	if authority.State == "AWAITING_USER_ACTIVATION" {
+		slog.Warn("Subordinate certificate authority isn't activated, supply pem_ca_certificate to activate it",
+			"authority", authority.Name)
+	}
+
+	authorityID := path.Base(authority.Name)
+	attributes := map[string]any{
+		"certificate_authority_id": authorityID,
+		"project":                  pc.provider.ProjectID,
+		"location":                 locationOf(authority.Name),
+		"pool":                     path.Base(path.Dir(path.Dir(authority.Name))),
+		"type":                     authority.Type,
+		"lifetime":                 authority.Lifetime,
+	}
+	if spec := authority.KeySpec; spec != nil {
+		if spec.CloudKmsKeyVersion != "" {
+			attributes["key_spec"] = []any{map[string]any{"cloud_kms_key_version": spec.CloudKmsKeyVersion}}
+		} else {
+			attributes["key_spec"] = []any{map[string]any{"algorithm": spec.Algorithm}}
+		}
+	}
+	if authority.GcsBucket != "" {
+		attributes["gcs_bucket"] = authority.GcsBucket
+	}
+	if len(authority.Labels) > 0 {
+		attributes["labels"] = authority.Labels
+	}
+
+	return Resource{
+		Provider:   pc.provider,
+		Type:       ResourceTypePrivateCACertificateAuthority,
+		Service:    ServicePrivateCA,
+		Name:       sanitizeName(fmt.Sprintf("%s_%s", poolName, authorityID)),
+		ID:         authority.Name,
+		Attributes: attributes,
+	}, true
+}
+
+func (pc *privateCA) poolPolicy(ctx context.Context, pool string) (*iam.Policy, error) {
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("ca_pool", pool))
+	defer span.End()
+
+	policy, err := pc.service.Projects.Locations.CaPools.GetIamPolicy(pool).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting IAM policy for CA pool %s: %w", pool, err)
+	}
+
+	proto := &iampb.Policy{}
+	for _, binding := range policy.Bindings {
+		// The generated IAM resources have no conditions
+		if binding.Condition != nil {
+			slog.Warn("Skipping conditional CA pool IAM binding", "ca_pool", pool, "role", binding.Role)
+			continue
+		}
+		proto.Bindings = append(proto.Bindings, &iampb.Binding{Role: binding.Role, Members: binding.Members})
+	}
+	return &iam.Policy{InternalProto: proto}, nil
+}
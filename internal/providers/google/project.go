@@ -0,0 +1,227 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strconv"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	billingbudgets "google.golang.org/api/billingbudgets/v1"
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+	essentialcontacts "google.golang.org/api/essentialcontacts/v1"
+)
+
+// projectSettings imports the governance settings of a project: the project
+// itself with its labels, billing account and parent, its essential contacts
+// and the budgets tracking its costs.
+type projectSettings struct {
+	crm      *cloudresourcemanager.Service
+	billing  *cloudbilling.APIService
+	budgets  *billingbudgets.Service
+	contacts *essentialcontacts.Service
+	provider providers.Provider
+	opts     ProjectOptions
+}
+
+func NewProject(ctx context.Context, provider providers.Provider, opts ProjectOptions) (*projectSettings, error) {
+	crmOpts, err := providerClientOptions(ctx, provider, cloudresourcemanager.CloudPlatformReadOnlyScope)
+	if err != nil {
+		return nil, err
+	}
+	crmService, err := cloudresourcemanager.NewService(ctx, crmOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource manager service: %w", err)
+	}
+	billingOpts, err := providerClientOptions(ctx, provider, cloudbilling.CloudBillingReadonlyScope)
+	if err != nil {
+		return nil, err
+	}
+	billingService, err := cloudbilling.NewService(ctx, billingOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create billing service: %w", err)
+	}
+	ps := &projectSettings{
+		crm:      crmService,
+		billing:  billingService,
+		provider: provider,
+		opts:     opts,
+	}
+	// The budgets and contacts APIs only accept broader scopes, so they are
+	// only requested when their resources are imported
+	if opts.IncludeBudgets {
+		budgetOpts, err := providerClientOptions(ctx, provider, billingbudgets.CloudBillingScope)
+		if err != nil {
+			return nil, err
+		}
+		ps.budgets, err = billingbudgets.NewService(ctx, budgetOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create billing budgets service: %w", err)
+		}
+	}
+	if opts.IncludeEssentialContacts {
+		contactOpts, err := providerClientOptions(ctx, provider, essentialcontacts.CloudPlatformScope)
+		if err != nil {
+			return nil, err
+		}
+		ps.contacts, err = essentialcontacts.NewService(ctx, contactOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create essential contacts service: %w", err)
+		}
+	}
+	return ps, nil
+}
+
+func (ps *projectSettings) Close() {
+	// No close method for the services
+}
+
+func (ps *projectSettings) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(ps.resources), nil
+}
+
+func (ps *projectSettings) resources(ctx context.Context, resources *discovered) error {
+	projectID := ps.provider.ProjectID
+
+	project, err := ps.crm.Projects.Get(projectID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting project %s: %w", projectID, err)
+	}
+
+	attributes := map[string]any{
+		"project_id": project.ProjectId,
+		"name":       project.Name,
+		"number":     strconv.FormatInt(project.ProjectNumber, 10),
+	}
+	if len(project.Labels) > 0 {
+		attributes["labels"] = project.Labels
+	}
+	if parent := project.Parent; parent != nil {
+		switch parent.Type {
+		case "organization":
+			attributes["org_id"] = parent.Id
+		case "folder":
+			attributes["folder_id"] = parent.Id
+		}
+	}
+
+	billingInfo, err := ps.billing.Projects.GetBillingInfo("projects/" + projectID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting billing info of project %s: %w", projectID, err)
+	}
+	billingAccount := path.Base(billingInfo.BillingAccountName)
+	if billingInfo.BillingAccountName != "" {
+		attributes["billing_account"] = billingAccount
+	}
+
+	resources.append(Resource{
+		Provider: ps.provider,
+		Type:     ResourceTypeProject,
+		Service:  ServiceProject,
+		Name:     sanitizeName(projectID),
+		// Import ID for a project is its ID
+		ID:         projectID,
+		Attributes: attributes,
+	})
+
+	if ps.opts.IncludeEssentialContacts {
+		if err := ps.essentialContacts(ctx, resources); err != nil {
+			return err
+		}
+	}
+
+	if ps.opts.IncludeBudgets && billingInfo.BillingAccountName != "" {
+		if err := ps.projectBudgets(ctx, billingAccount, resources); err != nil {
+			// Budgets are read from the billing account, which the project's
+			// credentials often have no access to
+			slog.Warn("Error listing budgets, they are not imported", "project", projectID,
+				"billing_account", billingAccount, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (ps *projectSettings) essentialContacts(ctx context.Context, resources *discovered) error {
+	projectID := ps.provider.ProjectID
+
+	err := ps.contacts.Projects.Contacts.List("projects/"+projectID).Pages(ctx, func(page *essentialcontacts.GoogleCloudEssentialcontactsV1ListContactsResponse) error {
+		for _, contact := range page.Contacts {
+			resources.append(Resource{
+				Provider: ps.provider,
+				Type:     ResourceTypeEssentialContactsContact,
+				Service:  ServiceProject,
+				// Names can't start with a digit, which emails can
+				Name: "contact_" + sanitizeMember(contact.Email),
+				// Import ID for a contact is its name, e.g.
+				// projects/123/contacts/456
+				ID: contact.Name,
+				Attributes: map[string]any{
+					"parent":                              path.Dir(path.Dir(contact.Name)),
+					"email":                               contact.Email,
+					"language_tag":                        contact.LanguageTag,
+					"notification_category_subscriptions": contact.NotificationCategorySubscriptions,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing essential contacts of project %s: %w", projectID, err)
+	}
+	return nil
+}
+
+// projectBudgets adds the budgets of the billing account which track the
+// project's costs to resources. A budget covering several projects is
+// imported with each of them, and generated once.
+func (ps *projectSettings) projectBudgets(ctx context.Context, billingAccount string, resources *discovered) error {
+	call := ps.budgets.BillingAccounts.Budgets.List("billingAccounts/" + billingAccount).
+		Scope("projects/" + ps.provider.ProjectID)
+	err := call.Pages(ctx, func(page *billingbudgets.GoogleCloudBillingBudgetsV1ListBudgetsResponse) error {
+		for _, budget := range page.Budgets {
+			name := budget.DisplayName
+			if name == "" {
+				name = path.Base(budget.Name)
+			}
+			resources.append(Resource{
+				Provider: ps.provider,
+				Type:     ResourceTypeBillingBudget,
+				Service:  ServiceProject,
+				Name:     "budget_" + sanitizeMember(name),
+				// Import ID for a budget is its name,
+				// billingAccounts/<account>/budgets/<id>
+				ID: budget.Name,
+				Attributes: map[string]any{
+					"billing_account": billingAccount,
+					"display_name":    budget.DisplayName,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing budgets of billing account %s: %w", billingAccount, err)
+	}
+	return nil
+}
+
+// ProjectNumber returns the number of a project, which names of workload
+// identity pools and IAM principals refer to it by
+func ProjectNumber(ctx context.Context, creds providers.Credentials, projectID string) (string, error) {
+	opts, err := ClientOptions(ctx, creds, cloudresourcemanager.CloudPlatformReadOnlyScope)
+	if err != nil {
+		return "", err
+	}
+	crm, err := cloudresourcemanager.NewService(ctx, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create resource manager service: %w", err)
+	}
+	project, err := crm.Projects.Get(projectID).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to get project %s: %w", projectID, err)
+	}
+	return strconv.FormatInt(project.ProjectNumber, 10), nil
+}
@@ -3,26 +3,41 @@ package google
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/iterator"
 )
 
+// deletedTopic is the topic of subscriptions whose topic was deleted
+const deletedTopic = "_deleted-topic_"
+
 type pubSub struct {
 	client   *pubsub.Client
 	provider providers.Provider
+	opts     PubSubOptions
 }
 
-func NewPubsub(ctx context.Context, provider providers.Provider) (*pubSub, error) {
-	client, err := pubsub.NewClient(ctx, provider.ProjectID)
+func NewPubsub(ctx context.Context, provider providers.Provider, opts PubSubOptions) (*pubSub, error) {
+	clientOpts, err := grpcClientOptions(ctx, provider, pubsub.ScopePubSub)
+	if err != nil {
+		return nil, err
+	}
+	client, err := pubsub.NewClient(ctx, provider.ProjectID, clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pubsub client: %w", err)
 	}
 	return &pubSub{
 		client:   client,
 		provider: provider,
+		opts:     opts,
 	}, nil
 }
 
@@ -31,68 +46,24 @@ func (ps *pubSub) Close() {
 }
 
 type pubSubIterator struct {
-	ctx           context.Context
-	pubsub        *pubSub
-	topicIter     *pubsub.TopicIterator
-	currentTopic  *pubsub.Topic
-	resourceQueue []Resource // Queue for dependent resources (IAM bindings, subscriptions)
-	err           error
-	isClosed      bool
+	prefetch *prefetcher
+	isClosed bool
 }
 
 func (it *pubSubIterator) Next(ctx context.Context) (*Resource, error) {
-	ctx = it.ctx
 	if it.isClosed {
 		return nil, fmt.Errorf("iterator is closed")
 	}
 
-	if len(it.resourceQueue) > 0 {
-		resource := it.resourceQueue[0]
-		it.resourceQueue = it.resourceQueue[1:]
-		return &resource, nil
-	}
-
-	if it.err != nil {
-		return nil, it.err
-	}
-
-	topic, err := it.topicIter.Next()
-	if err == iterator.Done {
-		return nil, nil
-	}
-	if err != nil {
-		it.err = fmt.Errorf("error iterating topics: %w", err)
-		return nil, it.err
-	}
-
-	topicName := topic.ID()
-	topicResource := Resource{
-		Provider: it.pubsub.provider,
-		Type:     ResourceTypePubSubTopic,
-		Service:  ServicePubSub,
-		Name:     sanitizeName(topicName),
-		ID:       fmt.Sprintf("projects/%s/topics/%s", it.pubsub.provider.ProjectID, topicName),
-	}
-
-	iamBindings, err := it.pubsub.getTopicIAMBindings(it.ctx, topicName)
-	if err != nil {
-		it.err = fmt.Errorf("error getting IAM bindings for topic %s: %w", topicName, err)
-		return nil, it.err
-	}
-	if len(iamBindings) > 0 {
-		topicResource.Dependents = append(topicResource.Dependents, iamBindings...)
-	}
+	return it.prefetch.Next(ctx)
+}
 
-	subscriptions, err := it.pubsub.topicSubscriptions(it.ctx, topicName)
-	if err != nil {
-		it.err = fmt.Errorf("error getting subscriptions for topic %s: %w", topicName, err)
-		return nil, it.err
-	}
-	if len(subscriptions) > 0 {
-		topicResource.Dependents = append(topicResource.Dependents, subscriptions...)
-	}
+func (it *pubSubIterator) Skip() {
+	it.prefetch.Skip()
+}
 
-	return &topicResource, nil
+func (it *pubSubIterator) Err() error {
+	return it.prefetch.Err()
 }
 
 func (it *pubSubIterator) Close() error {
@@ -100,125 +71,305 @@ func (it *pubSubIterator) Close() error {
 		return nil
 	}
 	it.isClosed = true
+	it.prefetch.Close()
 	return nil
 }
 
+// pubSubItem is a topic with its subscriptions, or a subscription whose
+// topic is not one of the project's topics
+type pubSubItem struct {
+	topic         *pubsub.Topic
+	subscriptions []*pubsub.SubscriptionConfig
+	subscription  *pubsub.SubscriptionConfig
+}
+
+// Import yields the topics of the project, each with its subscriptions, and
+// then the subscriptions attached to topics of other projects or to deleted
+// topics. Subscriptions are listed from the project rather than from its
+// topics, so that these are found too.
 func (ps *pubSub) Import(ctx context.Context) (ResourceIterator, error) {
+	var subscriptions map[string][]*pubsub.SubscriptionConfig
+	if ps.opts.IncludeSubscriptions {
+		var err error
+		subscriptions, err = ps.listSubscriptions(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	topicIter := ps.client.Topics(ctx)
+	var orphans []*pubsub.SubscriptionConfig
+	topicsDone := false
+
+	next := func() (pubSubItem, bool, error) {
+		if !topicsDone {
+			topic, err := topicIter.Next()
+			if err != nil && err != iterator.Done {
+				return pubSubItem{}, false, fmt.Errorf("error iterating topics: %w", err)
+			}
+			if err == nil {
+				topicSubscriptions := subscriptions[topic.String()]
+				delete(subscriptions, topic.String())
+				return pubSubItem{topic: topic, subscriptions: topicSubscriptions}, true, nil
+			}
+
+			topicsDone = true
+			for _, subs := range subscriptions {
+				orphans = append(orphans, subs...)
+			}
+			slices.SortFunc(orphans, func(a, b *pubsub.SubscriptionConfig) int {
+				return strings.Compare(a.String(), b.String())
+			})
+		}
+
+		if len(orphans) == 0 {
+			return pubSubItem{}, false, nil
+		}
+		sub := orphans[0]
+		orphans = orphans[1:]
+		return pubSubItem{subscription: sub}, true, nil
+	}
 
 	return &pubSubIterator{
-		ctx:           ctx,
-		pubsub:        ps,
-		topicIter:     topicIter,
-		resourceQueue: make([]Resource, 0),
+		prefetch: newPrefetcher(ctx, ps.opts.Parallelism, next, ps.itemResource, ps.itemStub),
 	}, nil
 }
 
-func (c *pubSub) getTopicIAMBindings(ctx context.Context, topicName string) ([]Resource, error) {
-	var resources []Resource
-
-	topic := c.client.Topic(topicName)
-	policy, err := topic.IAM().Policy(ctx)
-	if err != nil {
-		return []Resource{}, fmt.Errorf("error getting IAM policy for topic %s: %w", topicName, err)
+// listSubscriptions returns the subscriptions of the project by the full name
+// of their topic
+func (ps *pubSub) listSubscriptions(ctx context.Context) (map[string][]*pubsub.SubscriptionConfig, error) {
+	subscriptions := make(map[string][]*pubsub.SubscriptionConfig)
+	it := ps.client.Subscriptions(ctx)
+	for {
+		sub, err := it.NextConfig()
+		if err == iterator.Done {
+			return subscriptions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+		}
+		topic := sub.Topic.String()
+		subscriptions[topic] = append(subscriptions[topic], sub)
 	}
+}
 
-	for _, role := range policy.Roles() {
-		members := policy.Members(role)
-		if len(members) > 0 {
-			roleSuffix := strings.Replace(string(role), "/", "_", -1)
-			roleSuffix = strings.Replace(roleSuffix, ".", "_", -1)
-
-			iamResource := Resource{
-				Provider: c.provider,
-				Type:     ResourceTypePubSubTopicIAMBinding,
-				Name: fmt.Sprintf("%s_%s",
-					sanitizeName(topicName), sanitizeName(roleSuffix)),
-				ID: fmt.Sprintf("projects/%s/topics/%s %s",
-					c.provider.ProjectID, topicName, role),
-				Attributes: map[string]any{
-					"topic":   topicName,
-					"role":    role,
-					"members": members,
-				},
-			}
-			resources = append(resources, iamResource)
+func (ps *pubSub) itemResource(ctx context.Context, item pubSubItem) (*Resource, error) {
+	if item.topic == nil {
+		if item.subscription.Topic.String() == deletedTopic {
+			slog.Warn("Subscription is attached to a deleted topic, terraform can't recreate it",
+				"subscription", item.subscription.String())
+		}
+		r, err := ps.subscriptionResource(ctx, item.subscription)
+		if err != nil {
+			return nil, err
 		}
+		return &r, nil
 	}
-
-	return resources, nil
+	return ps.topicResource(ctx, item.topic, item.subscriptions)
 }
 
-func (c *pubSub) topicSubscriptions(ctx context.Context, topicName string) ([]Resource, error) {
-	var resources []Resource
+// itemStub names the topic or subscription of an item which failed to build
+func (ps *pubSub) itemStub(item pubSubItem) Resource {
+	if item.topic == nil {
+		return Resource{Provider: ps.provider, Type: ResourceTypePubSubSubscription, Service: ServicePubSub,
+			Name: sanitizeName(item.subscription.ID()), ID: item.subscription.String()}
+	}
+	return Resource{Provider: ps.provider, Type: ResourceTypePubSubTopic, Service: ServicePubSub,
+		Name: sanitizeName(item.topic.ID()), ID: fmt.Sprintf("projects/%s/topics/%s", ps.provider.ProjectID, item.topic.ID())}
+}
 
-	topic := c.client.Topic(topicName)
-	subIter := topic.Subscriptions(ctx)
+// topicResource builds the resource of a topic, including its IAM bindings and
+// subscriptions
+func (ps *pubSub) topicResource(ctx context.Context, topic *pubsub.Topic, subscriptions []*pubsub.SubscriptionConfig) (*Resource, error) {
+	topicName := topic.ID()
+	topicResource := Resource{
+		Provider: ps.provider,
+		Type:     ResourceTypePubSubTopic,
+		Service:  ServicePubSub,
+		Name:     sanitizeName(topicName),
+		ID:       fmt.Sprintf("projects/%s/topics/%s", ps.provider.ProjectID, topicName),
+	}
 
-	for {
-		sub, err := subIter.Next()
-		if err == iterator.Done {
-			break
-		}
+	if ps.opts.IncludeIAM {
+		iamBindings, err := ps.getTopicIAMBindings(ctx, topicName)
 		if err != nil {
-			return nil, fmt.Errorf("error iterating subscriptions for topic %s: %w", topicName, err)
+			return nil, fmt.Errorf("error getting IAM bindings for topic %s: %w", topicName, err)
+		}
+		if len(iamBindings) > 0 {
+			topicResource.Dependents = append(topicResource.Dependents, iamBindings...)
 		}
+	}
 
-		subName := sub.ID()
-		subResource := Resource{
-			Provider: c.provider,
-			Type:     ResourceTypePubSubSubscription,
-			Service:  ServicePubSub,
-			Name:     sanitizeName(subName),
-			ID:       fmt.Sprintf("projects/%s/subscriptions/%s", c.provider.ProjectID, subName),
+	for _, sub := range subscriptions {
+		subResource, err := ps.subscriptionResource(ctx, sub)
+		if err != nil {
+			return nil, err
 		}
+		topicResource.Dependents = append(topicResource.Dependents, subResource)
+	}
+
+	return &topicResource, nil
+}
+
+func (c *pubSub) getTopicIAMBindings(ctx context.Context, topicName string) ([]Resource, error) {
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("topic", topicName))
+	defer span.End()
+
+	topic := c.client.Topic(topicName)
+	policy, err := topic.IAM().Policy(ctx)
+	if err != nil {
+		return []Resource{}, fmt.Errorf("error getting IAM policy for topic %s: %w", topicName, err)
+	}
+
+	return iamResources(c.opts.IAMMode, iamParent{
+		provider:  c.provider,
+		service:   ServicePubSub,
+		types:     pubSubTopicIAMTypes,
+		attribute: "topic",
+		name:      topicName,
+		id:        fmt.Sprintf("projects/%s/topics/%s", c.provider.ProjectID, topicName),
+	}, policy), nil
+}
+
+// subscriptionResource builds the resource of a subscription of the project,
+// including its IAM bindings. The topic is kept as an attribute, as it may
+// belong to another project.
+func (ps *pubSub) subscriptionResource(ctx context.Context, sub *pubsub.SubscriptionConfig) (Resource, error) {
+	subName := sub.ID()
+	subResource := Resource{
+		Provider:   ps.provider,
+		Type:       ResourceTypePubSubSubscription,
+		Service:    ServicePubSub,
+		Name:       sanitizeName(subName),
+		ID:         sub.String(),
+		Attributes: subscriptionAttributes(sub),
+	}
 
-		iamBindings, err := c.getSubscriptionIAMBindings(ctx, subName)
+	if ps.opts.IncludeIAM {
+		iamBindings, err := ps.getSubscriptionIAMBindings(ctx, subName)
 		if err != nil {
-			return nil, fmt.Errorf("error getting IAM bindings for subscription %s: %w", subName, err)
+			return Resource{}, fmt.Errorf("error getting IAM bindings for subscription %s: %w", subName, err)
 		}
 		if len(iamBindings) > 0 {
 			subResource.Dependents = append(subResource.Dependents, iamBindings...)
 		}
-
-		resources = append(resources, subResource)
 	}
 
-	return resources, nil
+	return subResource, nil
 }
 
 func (ps *pubSub) getSubscriptionIAMBindings(ctx context.Context, subName string) ([]Resource, error) {
-	var resources []Resource
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("subscription", subName))
+	defer span.End()
 
 	subscription := ps.client.Subscription(subName)
 	policy, err := subscription.IAM().Policy(ctx)
 	if err != nil {
-		return resources, fmt.Errorf("error getting IAM policy for subscription %s: %w", subName, err)
-	}
-
-	for _, role := range policy.Roles() {
-		members := policy.Members(role)
-		if len(members) > 0 {
-			roleSuffix := strings.Replace(string(role), "/", "_", -1)
-			roleSuffix = strings.Replace(roleSuffix, ".", "_", -1)
-
-			iamResource := Resource{
-				Type: ResourceTypePubSubSubscriptionIAMBinding,
-				Name: fmt.Sprintf("%s_%s",
-					sanitizeName(subName), sanitizeName(roleSuffix)),
-				ID: fmt.Sprintf("projects/%s/subscriptions/%s %s",
-					ps.provider.ProjectID, subName, role),
-				Attributes: map[string]any{
-					"subscription": subName,
-					"role":         role,
-					"members":      members,
-				},
-			}
-			resources = append(resources, iamResource)
+		return nil, fmt.Errorf("error getting IAM policy for subscription %s: %w", subName, err)
+	}
+
+	return iamResources(ps.opts.IAMMode, iamParent{
+		provider:  ps.provider,
+		service:   ServicePubSub,
+		types:     pubSubSubscriptionIAMTypes,
+		attribute: "subscription",
+		name:      subName,
+		id:        fmt.Sprintf("projects/%s/subscriptions/%s", ps.provider.ProjectID, subName),
+	}, policy), nil
+}
+
+// subscriptionAttributes returns the attributes of a subscription as the
+// google_pubsub_subscription schema names them. Delivery settings, exports
+// and policies are included only when set, so policies can check them.
+func subscriptionAttributes(sub *pubsub.SubscriptionConfig) map[string]any {
+	attributes := map[string]any{
+		"name":                         sub.ID(),
+		"topic":                        sub.Topic.String(),
+		"ack_deadline_seconds":         int(sub.AckDeadline.Seconds()),
+		"message_retention_duration":   durationString(sub.RetentionDuration),
+		"retain_acked_messages":        sub.RetainAckedMessages,
+		"enable_message_ordering":      sub.EnableMessageOrdering,
+		"enable_exactly_once_delivery": sub.EnableExactlyOnceDelivery,
+		"filter":                       sub.Filter,
+	}
+	if len(sub.Labels) > 0 {
+		attributes["labels"] = sub.Labels
+	}
+
+	// A subscription without a TTL never expires
+	ttl := ""
+	if d, ok := sub.ExpirationPolicy.(time.Duration); ok && d > 0 {
+		ttl = durationString(d)
+	}
+	attributes["expiration_policy"] = []any{map[string]any{"ttl": ttl}}
+
+	if push := sub.PushConfig; push.Endpoint != "" {
+		config := map[string]any{"push_endpoint": push.Endpoint}
+		if len(push.Attributes) > 0 {
+			config["attributes"] = push.Attributes
 		}
+		if token, ok := push.AuthenticationMethod.(*pubsub.OIDCToken); ok {
+			config["oidc_token"] = []any{map[string]any{
+				"service_account_email": token.ServiceAccountEmail,
+				"audience":              token.Audience,
+			}}
+		}
+		if wrapper, ok := push.Wrapper.(*pubsub.NoWrapper); ok {
+			config["no_wrapper"] = []any{map[string]any{"write_metadata": wrapper.WriteMetadata}}
+		}
+		attributes["push_config"] = []any{config}
 	}
 
-	return resources, nil
+	if bq := sub.BigQueryConfig; bq.Table != "" {
+		attributes["bigquery_config"] = []any{map[string]any{
+			"table":               bq.Table,
+			"use_topic_schema":    bq.UseTopicSchema,
+			"write_metadata":      bq.WriteMetadata,
+			"drop_unknown_fields": bq.DropUnknownFields,
+		}}
+	}
+
+	if gcs := sub.CloudStorageConfig; gcs.Bucket != "" {
+		config := map[string]any{
+			"bucket":          gcs.Bucket,
+			"filename_prefix": gcs.FilenamePrefix,
+			"filename_suffix": gcs.FilenameSuffix,
+			"max_bytes":       gcs.MaxBytes,
+		}
+		if d, ok := gcs.MaxDuration.(time.Duration); ok && d > 0 {
+			config["max_duration"] = durationString(d)
+		}
+		if avro, ok := gcs.OutputFormat.(*pubsub.CloudStorageOutputFormatAvroConfig); ok {
+			config["avro_config"] = []any{map[string]any{"write_metadata": avro.WriteMetadata}}
+		}
+		attributes["cloud_storage_config"] = []any{config}
+	}
+
+	if dlp := sub.DeadLetterPolicy; dlp != nil && dlp.DeadLetterTopic != "" {
+		attributes["dead_letter_policy"] = []any{map[string]any{
+			"dead_letter_topic":     dlp.DeadLetterTopic,
+			"max_delivery_attempts": dlp.MaxDeliveryAttempts,
+		}}
+	}
+
+	if retry := sub.RetryPolicy; retry != nil {
+		config := map[string]any{}
+		if d, ok := retry.MinimumBackoff.(time.Duration); ok {
+			config["minimum_backoff"] = durationString(d)
+		}
+		if d, ok := retry.MaximumBackoff.(time.Duration); ok {
+			config["maximum_backoff"] = durationString(d)
+		}
+		attributes["retry_policy"] = []any{config}
+	}
+
+	return attributes
+}
+
+// durationString formats a duration the way the google provider does, e.g.
+// "600s"
+func durationString(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
 }
 
 func sanitizeName(name string) string {
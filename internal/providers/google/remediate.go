@@ -0,0 +1,181 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/storage"
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+// remediableTypes maps the resource types whose drift a Remediator reverts to
+// the attribute naming the resource whose IAM policy holds them. Bindings
+// are restored authoritatively, members are granted again. Whole policies
+// are left alone, as restoring one revokes every grant made since.
+var remediableTypes = map[ResourceType]string{
+	ResourceTypePubSubTopicIAMBinding:        "topic",
+	ResourceTypePubSubTopicIAMMember:         "topic",
+	ResourceTypePubSubSubscriptionIAMBinding: "subscription",
+	ResourceTypePubSubSubscriptionIAMMember:  "subscription",
+	ResourceTypeStorageBucketIAMBinding:      "bucket",
+	ResourceTypeStorageBucketIAMMember:       "bucket",
+}
+
+// RemediableTypes returns the resource types whose drift can be remediated
+func RemediableTypes() []ResourceType {
+	var types []ResourceType
+	for t := range remediableTypes {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Remediation grants a role of the IAM policy of a cloud resource to the
+// members state declares
+type Remediation struct {
+	// Address is the terraform address of the drifted resource
+	Address string
+	Type    ResourceType
+	// Parent is the name of the resource the policy belongs to
+	Parent string
+	Role   iam.RoleName
+	// Members are granted the role. Authoritative remediations also revoke
+	// it from every other member.
+	Members       []string
+	Authoritative bool
+}
+
+func (r Remediation) String() string {
+	verb := "grant"
+	if r.Authoritative {
+		verb = "set"
+	}
+	return fmt.Sprintf("%s %s on %s to %s", verb, r.Role, r.Parent, strings.Join(r.Members, ", "))
+}
+
+// NewRemediation returns the remediation restoring a resource to its
+// attributes in state, or false if resources of its type can't be
+// remediated. Conditional grants can't be restored through the IAM policies
+// of the client libraries and aren't remediated either.
+func NewRemediation(address string, resourceType ResourceType, attributes map[string]any) (Remediation, bool) {
+	parentAttribute, ok := remediableTypes[resourceType]
+	if !ok {
+		return Remediation{}, false
+	}
+	if condition, _ := attributes["condition"].([]any); len(condition) > 0 {
+		return Remediation{}, false
+	}
+
+	parent, _ := attributes[parentAttribute].(string)
+	role, _ := attributes["role"].(string)
+	r := Remediation{
+		Address: address,
+		Type:    resourceType,
+		// State holds qualified names such as projects/p/topics/t or b/bucket
+		Parent: path.Base(parent),
+		Role:   iam.RoleName(role),
+	}
+	if member, ok := attributes["member"].(string); ok {
+		r.Members = []string{member}
+	} else {
+		members, _ := attributes["members"].([]any)
+		for _, m := range members {
+			if s, ok := m.(string); ok {
+				r.Members = append(r.Members, s)
+			}
+		}
+		r.Authoritative = true
+	}
+	if r.Parent == "" || r.Parent == "." || r.Role == "" || (len(r.Members) == 0 && !r.Authoritative) {
+		return Remediation{}, false
+	}
+	return r, true
+}
+
+// Remediator reverts drift of cloud resources to what state declares
+type Remediator struct {
+	pubsub  *pubsub.Client
+	storage *storage.Client
+}
+
+func NewRemediator(ctx context.Context, provider providers.Provider) (*Remediator, error) {
+	pubsubOpts, err := grpcClientOptions(ctx, provider, pubsub.ScopePubSub)
+	if err != nil {
+		return nil, err
+	}
+	storageOpts, err := providerClientOptions(ctx, provider, storage.ScopeFullControl)
+	if err != nil {
+		return nil, err
+	}
+
+	pubsubClient, err := pubsub.NewClient(ctx, provider.ProjectID, pubsubOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub client: %w", err)
+	}
+
+	storageClient, err := storage.NewClient(ctx, storageOpts...)
+	if err != nil {
+		pubsubClient.Close()
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	return &Remediator{pubsub: pubsubClient, storage: storageClient}, nil
+}
+
+func (r *Remediator) Close() {
+	r.pubsub.Close()
+	r.storage.Close()
+}
+
+// Apply makes the remediation. It returns whether the policy was changed,
+// which it isn't if the drift was reverted in the meantime.
+func (r *Remediator) Apply(ctx context.Context, remediation Remediation) (bool, error) {
+	var handle *iam.Handle
+	switch remediableTypes[remediation.Type] {
+	case "topic":
+		handle = r.pubsub.Topic(remediation.Parent).IAM()
+	case "subscription":
+		handle = r.pubsub.Subscription(remediation.Parent).IAM()
+	case "bucket":
+		handle = r.storage.Bucket(remediation.Parent).IAM()
+	default:
+		return false, fmt.Errorf("resource type %s can't be remediated", remediation.Type)
+	}
+
+	policy, err := handle.Policy(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get IAM policy of %s: %w", remediation.Parent, err)
+	}
+
+	var changed bool
+	if remediation.Authoritative {
+		for _, member := range policy.Members(remediation.Role) {
+			if !slices.Contains(remediation.Members, member) {
+				policy.Remove(member, remediation.Role)
+				changed = true
+			}
+		}
+	}
+	for _, member := range remediation.Members {
+		if !policy.HasRole(member, remediation.Role) {
+			policy.Add(member, remediation.Role)
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	// The policy carries the etag it was read with, so concurrent changes
+	// make this fail instead of being overwritten
+	if err := handle.SetPolicy(ctx, policy); err != nil {
+		return false, fmt.Errorf("failed to set IAM policy of %s: %w", remediation.Parent, err)
+	}
+	return true, nil
+}
@@ -1,14 +1,26 @@
 package google
 
-import "github.com/priyanshujain/infrasync/internal/providers"
+import (
+	"slices"
 
-type ResourceType string
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// ResourceType is the type of google resources
+type ResourceType = resource.Type
+
+// Resource is a google resource, discovered by the importers of its service
+type Resource = resource.Resource
 
 var (
 	ResourceTypePubSubTopic                  ResourceType = "google_pubsub_topic"
 	ResourceTypePubSubTopicIAMBinding        ResourceType = "google_pubsub_topic_iam_binding"
 	ResourceTypePubSubSubscription           ResourceType = "google_pubsub_subscription"
 	ResourceTypePubSubSubscriptionIAMBinding ResourceType = "google_pubsub_subscription_iam_binding"
+	ResourceTypePubSubTopicIAMMember         ResourceType = "google_pubsub_topic_iam_member"
+	ResourceTypePubSubTopicIAMPolicy         ResourceType = "google_pubsub_topic_iam_policy"
+	ResourceTypePubSubSubscriptionIAMMember  ResourceType = "google_pubsub_subscription_iam_member"
+	ResourceTypePubSubSubscriptionIAMPolicy  ResourceType = "google_pubsub_subscription_iam_policy"
 	
 	// CloudSQL resource types
 	ResourceTypeSQLInstance                  ResourceType = "google_sql_database_instance"
@@ -18,26 +30,197 @@ var (
 	// Storage resource types
 	ResourceTypeStorageBucket                ResourceType = "google_storage_bucket"
 	ResourceTypeStorageBucketIAMBinding      ResourceType = "google_storage_bucket_iam_binding"
+	ResourceTypeStorageBucketIAMMember       ResourceType = "google_storage_bucket_iam_member"
+	ResourceTypeStorageBucketIAMPolicy       ResourceType = "google_storage_bucket_iam_policy"
+	ResourceTypeStorageBucketAccessControl   ResourceType = "google_storage_bucket_access_control"
+	ResourceTypeStorageDefaultObjectAccessControl ResourceType = "google_storage_default_object_access_control"
+
+	// Compute resource types
+	ResourceTypeComputeSharedVPCHostProject    ResourceType = "google_compute_shared_vpc_host_project"
+	ResourceTypeComputeSharedVPCServiceProject ResourceType = "google_compute_shared_vpc_service_project"
+	ResourceTypeComputeSubnetworkIAMBinding    ResourceType = "google_compute_subnetwork_iam_binding"
+	ResourceTypeComputeSubnetworkIAMMember     ResourceType = "google_compute_subnetwork_iam_member"
+	ResourceTypeComputeSubnetworkIAMPolicy     ResourceType = "google_compute_subnetwork_iam_policy"
+	ResourceTypeComputeAddress                 ResourceType = "google_compute_address"
+	ResourceTypeComputeGlobalAddress           ResourceType = "google_compute_global_address"
+	ResourceTypeComputeManagedSSLCertificate   ResourceType = "google_compute_managed_ssl_certificate"
+	ResourceTypeComputeNetwork                 ResourceType = "google_compute_network"
+
+	// Project resource types
+	ResourceTypeProject                  ResourceType = "google_project"
+	ResourceTypeEssentialContactsContact ResourceType = "google_essential_contacts_contact"
+	ResourceTypeBillingBudget            ResourceType = "google_billing_budget"
+
+	// Filestore resource types
+	ResourceTypeFilestoreInstance ResourceType = "google_filestore_instance"
+	ResourceTypeFilestoreBackup   ResourceType = "google_filestore_backup"
+	ResourceTypeNetAppStoragePool ResourceType = "google_netapp_storage_pool"
+	ResourceTypeNetAppVolume      ResourceType = "google_netapp_volume"
+
+	// API Gateway resource types, only supported by the google-beta provider
+	ResourceTypeAPIGatewayAPI                 ResourceType = "google_api_gateway_api"
+	ResourceTypeAPIGatewayAPIIAMBinding       ResourceType = "google_api_gateway_api_iam_binding"
+	ResourceTypeAPIGatewayAPIIAMMember        ResourceType = "google_api_gateway_api_iam_member"
+	ResourceTypeAPIGatewayAPIIAMPolicy        ResourceType = "google_api_gateway_api_iam_policy"
+	ResourceTypeAPIGatewayAPIConfig           ResourceType = "google_api_gateway_api_config"
+	ResourceTypeAPIGatewayAPIConfigIAMBinding ResourceType = "google_api_gateway_api_config_iam_binding"
+	ResourceTypeAPIGatewayAPIConfigIAMMember  ResourceType = "google_api_gateway_api_config_iam_member"
+	ResourceTypeAPIGatewayAPIConfigIAMPolicy  ResourceType = "google_api_gateway_api_config_iam_policy"
+	ResourceTypeAPIGatewayGateway             ResourceType = "google_api_gateway_gateway"
+	ResourceTypeAPIGatewayGatewayIAMBinding   ResourceType = "google_api_gateway_gateway_iam_binding"
+	ResourceTypeAPIGatewayGatewayIAMMember    ResourceType = "google_api_gateway_gateway_iam_member"
+	ResourceTypeAPIGatewayGatewayIAMPolicy    ResourceType = "google_api_gateway_gateway_iam_policy"
+
+	// Eventarc resource types
+	ResourceTypeEventarcTrigger ResourceType = "google_eventarc_trigger"
+
+	// Workflows resource types
+	ResourceTypeWorkflowsWorkflow ResourceType = "google_workflows_workflow"
+
+	// IAP and Identity Platform resource types
+	ResourceTypeIAPBrand               ResourceType = "google_iap_brand"
+	ResourceTypeIAPClient              ResourceType = "google_iap_client"
+	ResourceTypeIAPWebIAMBinding       ResourceType = "google_iap_web_iam_binding"
+	ResourceTypeIAPWebIAMMember        ResourceType = "google_iap_web_iam_member"
+	ResourceTypeIAPWebIAMPolicy        ResourceType = "google_iap_web_iam_policy"
+	ResourceTypeIAPTunnelIAMBinding    ResourceType = "google_iap_tunnel_iam_binding"
+	ResourceTypeIAPTunnelIAMMember     ResourceType = "google_iap_tunnel_iam_member"
+	ResourceTypeIAPTunnelIAMPolicy     ResourceType = "google_iap_tunnel_iam_policy"
+	ResourceTypeIdentityPlatformTenant ResourceType = "google_identity_platform_tenant"
+
+	// Certificate Manager resource types
+	ResourceTypeCertificateManagerCertificate         ResourceType = "google_certificate_manager_certificate"
+	ResourceTypeCertificateManagerDNSAuthorization    ResourceType = "google_certificate_manager_dns_authorization"
+	ResourceTypeCertificateManagerCertificateMap      ResourceType = "google_certificate_manager_certificate_map"
+	ResourceTypeCertificateManagerCertificateMapEntry ResourceType = "google_certificate_manager_certificate_map_entry"
+
+	// Private CA resource types
+	ResourceTypePrivateCAPool                 ResourceType = "google_privateca_ca_pool"
+	ResourceTypePrivateCAPoolIAMBinding       ResourceType = "google_privateca_ca_pool_iam_binding"
+	ResourceTypePrivateCAPoolIAMMember        ResourceType = "google_privateca_ca_pool_iam_member"
+	ResourceTypePrivateCAPoolIAMPolicy        ResourceType = "google_privateca_ca_pool_iam_policy"
+	ResourceTypePrivateCACertificateAuthority ResourceType = "google_privateca_certificate_authority"
+
+	// Binary Authorization resource types
+	ResourceTypeBinaryAuthorizationPolicy             ResourceType = "google_binary_authorization_policy"
+	ResourceTypeBinaryAuthorizationAttestor           ResourceType = "google_binary_authorization_attestor"
+	ResourceTypeBinaryAuthorizationAttestorIAMBinding ResourceType = "google_binary_authorization_attestor_iam_binding"
+	ResourceTypeBinaryAuthorizationAttestorIAMMember  ResourceType = "google_binary_authorization_attestor_iam_member"
+	ResourceTypeBinaryAuthorizationAttestorIAMPolicy  ResourceType = "google_binary_authorization_attestor_iam_policy"
+	ResourceTypeContainerAnalysisNote                 ResourceType = "google_container_analysis_note"
+
+	// GKE Hub resource types
+	ResourceTypeGKEHubMembership        ResourceType = "google_gke_hub_membership"
+	ResourceTypeGKEHubFeature           ResourceType = "google_gke_hub_feature"
+	ResourceTypeGKEHubFeatureMembership ResourceType = "google_gke_hub_feature_membership"
+
+	// Dataplex and Data Catalog resource types
+	ResourceTypeDataCatalogTaxonomy  ResourceType = "google_data_catalog_taxonomy"
+	ResourceTypeDataCatalogPolicyTag ResourceType = "google_data_catalog_policy_tag"
+	ResourceTypeDataplexLake         ResourceType = "google_dataplex_lake"
+	ResourceTypeDataplexZone         ResourceType = "google_dataplex_zone"
+	ResourceTypeDataplexAsset        ResourceType = "google_dataplex_asset"
+
+	// IAM resource types
+	ResourceTypeProjectIAMCustomRole         ResourceType = "google_project_iam_custom_role"
+	ResourceTypeOrganizationIAMCustomRole    ResourceType = "google_organization_iam_custom_role"
+	ResourceTypeProjectIAMAuditConfig        ResourceType = "google_project_iam_audit_config"
+	ResourceTypeOrganizationIAMAuditConfig   ResourceType = "google_organization_iam_audit_config"
 )
 
-type Service string
+// Service is a google service with an importer
+type Service = resource.Service
 
 var (
-	ServicePubSub   Service = "pubsub"
-	ServiceCloudSQL Service = "cloudsql"
-	ServiceStorage  Service = "storage"
+	ServicePubSub              Service = "pubsub"
+	ServiceCloudSQL            Service = "cloudsql"
+	ServiceStorage             Service = "storage"
+	ServiceIAM                 Service = "iam"
+	ServiceCompute             Service = "compute"
+	ServiceProject             Service = "project"
+	ServiceFilestore           Service = "filestore"
+	ServiceAPIGateway          Service = "apigateway"
+	ServiceEventarc            Service = "eventarc"
+	ServiceWorkflows           Service = "workflows"
+	ServiceIAP                 Service = "iap"
+	ServiceCertificateManager  Service = "certificatemanager"
+	ServicePrivateCA           Service = "privateca"
+	ServiceBinaryAuthorization Service = "binaryauthorization"
+	ServiceGKEHub              Service = "gkehub"
+	ServiceDataplex            Service = "dataplex"
 )
 
-func (s Service) String() string {
-	return string(s)
+// Services lists every service with an importer
+var Services = []Service{ServicePubSub, ServiceCloudSQL, ServiceStorage, ServiceIAM, ServiceCompute, ServiceProject, ServiceFilestore, ServiceAPIGateway, ServiceEventarc,
+	ServiceWorkflows, ServiceIAP, ServiceCertificateManager, ServicePrivateCA, ServiceBinaryAuthorization,
+	ServiceGKEHub, ServiceDataplex}
+
+// ResourceTypes returns the resource types the service's importer discovers
+func ResourceTypes(s Service) []ResourceType {
+	switch s {
+	case ServicePubSub:
+		return []ResourceType{ResourceTypePubSubTopic, ResourceTypePubSubTopicIAMBinding,
+			ResourceTypePubSubSubscription, ResourceTypePubSubSubscriptionIAMBinding,
+			ResourceTypePubSubTopicIAMMember, ResourceTypePubSubTopicIAMPolicy,
+			ResourceTypePubSubSubscriptionIAMMember, ResourceTypePubSubSubscriptionIAMPolicy}
+	case ServiceCloudSQL:
+		return []ResourceType{ResourceTypeSQLInstance, ResourceTypeSQLDatabase, ResourceTypeSQLUser}
+	case ServiceStorage:
+		return []ResourceType{ResourceTypeStorageBucket, ResourceTypeStorageBucketIAMBinding,
+			ResourceTypeStorageBucketIAMMember, ResourceTypeStorageBucketIAMPolicy,
+			ResourceTypeStorageBucketAccessControl, ResourceTypeStorageDefaultObjectAccessControl}
+	case ServiceIAM:
+		return []ResourceType{ResourceTypeProjectIAMCustomRole, ResourceTypeProjectIAMAuditConfig,
+			ResourceTypeOrganizationIAMCustomRole, ResourceTypeOrganizationIAMAuditConfig}
+	case ServiceCompute:
+		return []ResourceType{ResourceTypeComputeSharedVPCHostProject, ResourceTypeComputeSharedVPCServiceProject,
+			ResourceTypeComputeSubnetworkIAMBinding, ResourceTypeComputeSubnetworkIAMMember,
+			ResourceTypeComputeSubnetworkIAMPolicy, ResourceTypeComputeAddress, ResourceTypeComputeGlobalAddress,
+			ResourceTypeComputeManagedSSLCertificate}
+	case ServiceProject:
+		return []ResourceType{ResourceTypeProject, ResourceTypeEssentialContactsContact, ResourceTypeBillingBudget}
+	case ServiceFilestore:
+		return []ResourceType{ResourceTypeFilestoreInstance, ResourceTypeFilestoreBackup,
+			ResourceTypeNetAppStoragePool, ResourceTypeNetAppVolume}
+	case ServiceAPIGateway:
+		return []ResourceType{ResourceTypeAPIGatewayAPI, ResourceTypeAPIGatewayAPIIAMBinding,
+			ResourceTypeAPIGatewayAPIIAMMember, ResourceTypeAPIGatewayAPIIAMPolicy,
+			ResourceTypeAPIGatewayAPIConfig, ResourceTypeAPIGatewayAPIConfigIAMBinding,
+			ResourceTypeAPIGatewayAPIConfigIAMMember, ResourceTypeAPIGatewayAPIConfigIAMPolicy,
+			ResourceTypeAPIGatewayGateway, ResourceTypeAPIGatewayGatewayIAMBinding,
+			ResourceTypeAPIGatewayGatewayIAMMember, ResourceTypeAPIGatewayGatewayIAMPolicy}
+	case ServiceEventarc:
+		return []ResourceType{ResourceTypeEventarcTrigger}
+	case ServiceWorkflows:
+		return []ResourceType{ResourceTypeWorkflowsWorkflow}
+	case ServiceIAP:
+		return []ResourceType{ResourceTypeIAPBrand, ResourceTypeIAPClient,
+			ResourceTypeIAPWebIAMBinding, ResourceTypeIAPWebIAMMember, ResourceTypeIAPWebIAMPolicy,
+			ResourceTypeIAPTunnelIAMBinding, ResourceTypeIAPTunnelIAMMember, ResourceTypeIAPTunnelIAMPolicy,
+			ResourceTypeIdentityPlatformTenant}
+	case ServiceCertificateManager:
+		return []ResourceType{ResourceTypeCertificateManagerCertificate, ResourceTypeCertificateManagerDNSAuthorization,
+			ResourceTypeCertificateManagerCertificateMap, ResourceTypeCertificateManagerCertificateMapEntry}
+	case ServicePrivateCA:
+		return []ResourceType{ResourceTypePrivateCAPool, ResourceTypePrivateCAPoolIAMBinding,
+			ResourceTypePrivateCAPoolIAMMember, ResourceTypePrivateCAPoolIAMPolicy,
+			ResourceTypePrivateCACertificateAuthority}
+	case ServiceBinaryAuthorization:
+		return []ResourceType{ResourceTypeBinaryAuthorizationPolicy, ResourceTypeBinaryAuthorizationAttestor,
+			ResourceTypeBinaryAuthorizationAttestorIAMBinding, ResourceTypeBinaryAuthorizationAttestorIAMMember,
+			ResourceTypeBinaryAuthorizationAttestorIAMPolicy, ResourceTypeContainerAnalysisNote}
+	case ServiceGKEHub:
+		return []ResourceType{ResourceTypeGKEHubMembership, ResourceTypeGKEHubFeature, ResourceTypeGKEHubFeatureMembership}
+	case ServiceDataplex:
+		return []ResourceType{ResourceTypeDataCatalogTaxonomy, ResourceTypeDataCatalogPolicyTag,
+			ResourceTypeDataplexLake, ResourceTypeDataplexZone, ResourceTypeDataplexAsset}
+	default:
+		return nil
+	}
 }
 
-type Resource struct {
-	Provider   providers.Provider
-	Type       ResourceType
-	Service    Service
-	Name       string
-	ID         string
-	Dependents []Resource
-	Attributes map[string]any
+// Beta reports whether the resource type is only supported by the google-beta
+// provider
+func Beta(t ResourceType) bool {
+	return slices.Contains(ResourceTypes(ServiceAPIGateway), t)
 }
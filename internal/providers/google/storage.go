@@ -2,29 +2,43 @@ package google
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
+	"net/http"
 
 	"cloud.google.com/go/storage"
 	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
 )
 
 type gcsStorage struct {
 	client   *storage.Client
 	provider providers.Provider
+	opts     StorageOptions
 }
 
-func NewStorage(ctx context.Context, provider providers.Provider) (*gcsStorage, error) {
-	client, err := storage.NewClient(ctx, option.WithScopes(storage.ScopeReadOnly))
+func NewStorage(ctx context.Context, provider providers.Provider, opts StorageOptions) (*gcsStorage, error) {
+	scope := storage.ScopeReadOnly
+	if opts.IncludeACL {
+		// ACLs can only be listed with full control, though only read here
+		scope = storage.ScopeFullControl
+	}
+	clientOpts, err := providerClientOptions(ctx, provider, scope)
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx, clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage client: %w", err)
 	}
 	return &gcsStorage{
 		client:   client,
 		provider: provider,
+		opts:     opts,
 	}, nil
 }
 
@@ -33,61 +47,134 @@ func (gs *gcsStorage) Close() {
 }
 
 type storageIterator struct {
-	ctx           context.Context
-	storage       *gcsStorage
-	bucketIter    *storage.BucketIterator
-	resourceQueue []Resource
-	err           error
-	isClosed      bool
+	prefetch *prefetcher
+	isClosed bool
 }
 
 func (it *storageIterator) Next(ctx context.Context) (*Resource, error) {
-	it.ctx = ctx
-
 	if it.isClosed {
 		return nil, fmt.Errorf("iterator is closed")
 	}
 
-	if it.err != nil {
-		return nil, it.err
-	}
+	return it.prefetch.Next(ctx)
+}
 
-	// Return resources from the queue if available
-	if len(it.resourceQueue) > 0 {
-		resource := it.resourceQueue[0]
-		it.resourceQueue = it.resourceQueue[1:]
-		return &resource, nil
-	}
+func (it *storageIterator) Skip() {
+	it.prefetch.Skip()
+}
 
-	// Get the next bucket
-	attrs, err := it.bucketIter.Next()
-	if err == iterator.Done {
-		return nil, nil
+func (it *storageIterator) Err() error {
+	return it.prefetch.Err()
+}
+
+func (it *storageIterator) Close() error {
+	if it.isClosed {
+		return nil
 	}
-	if err != nil {
-		it.err = fmt.Errorf("error iterating buckets: %w", err)
-		return nil, it.err
+	it.isClosed = true
+	it.prefetch.Close()
+	return nil
+}
+
+func (gs *gcsStorage) Import(ctx context.Context) (ResourceIterator, error) {
+	// Create a bucket iterator
+	bucketIter := gs.client.Buckets(ctx, gs.provider.ProjectID)
+
+	next := func() (*storage.BucketAttrs, bool, error) {
+		attrs, err := bucketIter.Next()
+		if err == iterator.Done {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error iterating buckets: %w", err)
+		}
+		return attrs, true, nil
 	}
 
+	return &storageIterator{
+		prefetch: newPrefetcher(ctx, gs.opts.Parallelism, next, gs.bucketResource, gs.bucketStub),
+	}, nil
+}
+
+// BucketResourceName returns the name the resource of a bucket is given on
+// import
+func BucketResourceName(bucket string) string {
+	return sanitizeName(bucket)
+}
+
+// bucketStub names a bucket which failed to build
+func (gs *gcsStorage) bucketStub(attrs *storage.BucketAttrs) Resource {
+	return Resource{Provider: gs.provider, Type: ResourceTypeStorageBucket, Service: ServiceStorage,
+		Name: sanitizeName(attrs.Name), ID: attrs.Name}
+}
+
+// bucketResource builds the resource of a bucket, including its IAM bindings
+func (gs *gcsStorage) bucketResource(ctx context.Context, attrs *storage.BucketAttrs) (*Resource, error) {
 	bucketName := attrs.Name
 
 	// Create bucket resource
 	bucketResource := Resource{
-		Provider: it.storage.provider,
+		Provider: gs.provider,
 		Type:     ResourceTypeStorageBucket,
 		Service:  ServiceStorage,
 		Name:     sanitizeName(bucketName),
 		ID:       bucketName, // Import ID for GCS bucket is just the bucket name
 		Attributes: map[string]any{
-			"name":          bucketName,
-			"project":       it.storage.provider.ProjectID,
-			"location":      attrs.Location,
-			"storage_class": attrs.StorageClass,
+			"name":                        bucketName,
+			"project":                     gs.provider.ProjectID,
+			"location":                    attrs.Location,
+			"storage_class":               attrs.StorageClass,
+			"uniform_bucket_level_access": attrs.UniformBucketLevelAccess.Enabled,
+			"versioning":                  []any{map[string]any{"enabled": attrs.VersioningEnabled}},
 		},
 	}
+	if pap := attrs.PublicAccessPrevention.String(); pap != "" {
+		bucketResource.Attributes["public_access_prevention"] = pap
+	}
+	if policy := attrs.RetentionPolicy; policy != nil {
+		bucketResource.Attributes["retention_policy"] = []any{map[string]any{
+			"retention_period": int64(policy.RetentionPeriod.Seconds()),
+			"is_locked":        policy.IsLocked,
+		}}
+		if policy.IsLocked {
+			slog.Warn("Bucket retention policy is locked, terraform can't remove or shorten it",
+				"bucket", bucketName, "retention_period", policy.RetentionPeriod)
+		}
+	}
+	if policy := attrs.SoftDeletePolicy; policy != nil {
+		bucketResource.Attributes["soft_delete_policy"] = []any{map[string]any{
+			"retention_duration_seconds": int64(policy.RetentionDuration.Seconds()),
+		}}
+	}
+
+	if !attrs.UniformBucketLevelAccess.Enabled {
+		if gs.opts.IncludeACL {
+			acls, err := gs.getBucketACLs(ctx, bucketName)
+			if err := isBucketImportable(err); err != nil {
+				slog.Info("Skipping bucket due to terraform pre-check", "bucket", bucketName, "error", err)
+				return nil, nil
+			}
+			if err != nil {
+				// IAM alone doesn't grant what the ACLs do, so don't lose them quietly
+				slog.Warn("Error getting ACLs, its ACL grants are not imported", "bucket", bucketName, "error", err)
+			} else {
+				bucketResource.Dependents = append(bucketResource.Dependents, acls...)
+			}
+		} else {
+			slog.Warn("Bucket uses ACLs, which are not imported", "bucket", bucketName)
+		}
+	}
+
+	if !gs.opts.IncludeIAM {
+		return &bucketResource, nil
+	}
 
 	// Get IAM bindings for this bucket
-	iamBindings, err := it.storage.getBucketIAMBindings(it.ctx, bucketName)
+	iamBindings, err := gs.getBucketIAMBindings(ctx, bucketName)
+	if err := isBucketImportable(err); err != nil {
+		slog.Info("Skipping bucket due to terraform pre-check", "bucket", bucketName, "error", err)
+		return nil, nil
+	}
 	if err != nil {
 		// Log error but continue with the bucket
 		slog.Info("Error getting IAM bindings", "bucket", bucketName, "error", err)
@@ -98,56 +185,79 @@ func (it *storageIterator) Next(ctx context.Context) (*Resource, error) {
 	return &bucketResource, nil
 }
 
-func (it *storageIterator) Close() error {
-	if it.isClosed {
-		return nil
+// isBucketImportable reports why terraform can't import a bucket, given the
+// error of a lookup made after it was listed. Listing only returns live
+// buckets, but one deleted since is soft-deleted and no longer found, which
+// terraform can't import.
+func isBucketImportable(lookupErr error) error {
+	var apiErr *googleapi.Error
+	if errors.Is(lookupErr, storage.ErrBucketNotExist) ||
+		(errors.As(lookupErr, &apiErr) && apiErr.Code == http.StatusNotFound) {
+		return fmt.Errorf("bucket was deleted after it was listed")
 	}
-	it.isClosed = true
 	return nil
 }
 
-func (gs *gcsStorage) Import(ctx context.Context) (ResourceIterator, error) {
-	// Create a bucket iterator
-	bucketIter := gs.client.Buckets(ctx, gs.provider.ProjectID)
+func (gs *gcsStorage) getBucketIAMBindings(ctx context.Context, bucketName string) ([]Resource, error) {
+	ctx, span := telemetry.Start(ctx, "discover.iam_policy", attribute.String("bucket", bucketName))
+	defer span.End()
 
-	return &storageIterator{
-		ctx:           ctx,
-		storage:       gs,
-		bucketIter:    bucketIter,
-		resourceQueue: make([]Resource, 0),
-	}, nil
+	bucket := gs.client.Bucket(bucketName)
+	policy, err := bucket.IAM().Policy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting IAM policy for bucket %s: %w", bucketName, err)
+	}
+
+	return iamResources(gs.opts.IAMMode, iamParent{
+		provider:  gs.provider,
+		service:   ServiceStorage,
+		types:     storageBucketIAMTypes,
+		attribute: "bucket",
+		name:      bucketName,
+		// Import ID for GCS bucket is just the bucket name
+		id: bucketName,
+	}, policy), nil
 }
 
-func (gs *gcsStorage) getBucketIAMBindings(ctx context.Context, bucketName string) ([]Resource, error) {
-	var resources []Resource
+// getBucketACLs returns an access control resource per entity of the ACL and
+// default object ACL of a bucket. Both are only used by buckets without
+// uniform bucket-level access.
+func (gs *gcsStorage) getBucketACLs(ctx context.Context, bucketName string) ([]Resource, error) {
+	ctx, span := telemetry.Start(ctx, "discover.acl", attribute.String("bucket", bucketName))
+	defer span.End()
 
 	bucket := gs.client.Bucket(bucketName)
-	policy, err := bucket.IAM().Policy(ctx)
+	bucketACL, err := bucket.ACL().List(ctx)
 	if err != nil {
-		return resources, fmt.Errorf("error getting IAM policy for bucket %s: %w", bucketName, err)
-	}
-
-	for _, role := range policy.Roles() {
-		members := policy.Members(role)
-		if len(members) > 0 {
-			roleSuffix := strings.Replace(string(role), "/", "_", -1)
-			roleSuffix = strings.Replace(roleSuffix, ".", "_", -1)
-
-			iamResource := Resource{
-				Provider: gs.provider,
-				Type:     ResourceTypeStorageBucketIAMBinding,
-				Service:  ServiceStorage,
-				Name:     fmt.Sprintf("%s_%s", sanitizeName(bucketName), sanitizeName(roleSuffix)),
-				ID:       fmt.Sprintf("%s %s", bucketName, role),
-				Attributes: map[string]any{
-					"bucket":  bucketName,
-					"role":    role,
-					"members": members,
-				},
-			}
-			resources = append(resources, iamResource)
-		}
+		return nil, fmt.Errorf("error getting ACL for bucket %s: %w", bucketName, err)
+	}
+	objectACL, err := bucket.DefaultObjectACL().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting default object ACL for bucket %s: %w", bucketName, err)
 	}
 
+	var resources []Resource
+	for _, rule := range bucketACL {
+		resources = append(resources, gs.aclResource(ResourceTypeStorageBucketAccessControl, bucketName, rule))
+	}
+	for _, rule := range objectACL {
+		resources = append(resources, gs.aclResource(ResourceTypeStorageDefaultObjectAccessControl, bucketName, rule))
+	}
 	return resources, nil
-}
\ No newline at end of file
+}
+
+func (gs *gcsStorage) aclResource(resourceType ResourceType, bucketName string, rule storage.ACLRule) Resource {
+	return Resource{
+		Provider: gs.provider,
+		Type:     resourceType,
+		Service:  ServiceStorage,
+		Name:     fmt.Sprintf("%s_%s", sanitizeName(bucketName), sanitizeMember(string(rule.Entity))),
+		// Import ID for access controls is bucket/entity
+		ID: fmt.Sprintf("%s/%s", bucketName, rule.Entity),
+		Attributes: map[string]any{
+			"bucket": bucketName,
+			"entity": string(rule.Entity),
+			"role":   string(rule.Role),
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	workflows "google.golang.org/api/workflows/v1"
+)
+
+// workflowDefinitions imports the Workflows of every configured region with
+// their source. Executions are runs of a workflow, not config, and are not
+// imported.
+type workflowDefinitions struct {
+	service  *workflows.Service
+	provider providers.Provider
+	opts     WorkflowsOptions
+}
+
+func NewWorkflows(ctx context.Context, provider providers.Provider, opts WorkflowsOptions) (*workflowDefinitions, error) {
+	clientOpts, err := providerClientOptions(ctx, provider, workflows.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := workflows.NewService(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workflows service: %w", err)
+	}
+	return &workflowDefinitions{
+		service:  service,
+		provider: provider,
+		opts:     opts,
+	}, nil
+}
+
+func (wd *workflowDefinitions) Close() {
+	// No close method for the service
+}
+
+func (wd *workflowDefinitions) Import(ctx context.Context) (ResourceIterator, error) {
+	return newQueueIterator(wd.resources), nil
+}
+
+func (wd *workflowDefinitions) resources(ctx context.Context, resources *discovered) error {
+	projectID := wd.provider.ProjectID
+
+	var locations []string
+	err := wd.service.Projects.Locations.List("projects/"+projectID).Pages(ctx, func(page *workflows.ListLocationsResponse) error {
+		for _, location := range page.Locations {
+			if wd.provider.InRegions(location.LocationId) {
+				locations = append(locations, location.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing workflows locations of project %s: %w", projectID, err)
+	}
+
+	for _, location := range locations {
+		err := wd.service.Projects.Locations.Workflows.List(location).Pages(ctx, func(page *workflows.ListWorkflowsResponse) error {
+			for _, workflow := range page.Workflows {
+				resources.append(wd.workflowResource(workflow))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error listing workflows in %s: %w", location, err)
+		}
+	}
+	return nil
+}
+
+// workflowResource builds the resource of a workflow. The import ID is its
+// full name, projects/<project>/locations/<region>/workflows/<name>.
+func (wd *workflowDefinitions) workflowResource(workflow *workflows.Workflow) Resource {
+	name := path.Base(workflow.Name)
+	region := locationOf(workflow.Name)
+
+	attributes := map[string]any{
+		"name":            name,
+		"project":         wd.provider.ProjectID,
+		"region":          region,
+		"source_contents": workflow.SourceContents,
+	}
+	if workflow.Description != "" {
+		attributes["description"] = workflow.Description
+	}
+	if workflow.ServiceAccount != "" {
+		attributes["service_account"] = workflow.ServiceAccount
+	}
+	if workflow.CallLogLevel != "" {
+		attributes["call_log_level"] = workflow.CallLogLevel
+	}
+	if workflow.CryptoKeyName != "" {
+		attributes["crypto_key_name"] = workflow.CryptoKeyName
+	}
+	if len(workflow.UserEnvVars) > 0 {
+		attributes["user_env_vars"] = workflow.UserEnvVars
+	}
+	if len(workflow.Labels) > 0 {
+		attributes["labels"] = workflow.Labels
+	}
+
+	return Resource{
+		Provider: wd.provider,
+		Type:     ResourceTypeWorkflowsWorkflow,
+		Service:  ServiceWorkflows,
+		// Workflows of different regions may share names
+		Name:       sanitizeName(fmt.Sprintf("%s_%s", region, name)),
+		ID:         workflow.Name,
+		Attributes: attributes,
+	}
+}
@@ -1,5 +1,7 @@
 package providers
 
+import "strings"
+
 type ProviderType string
 
 var (
@@ -9,7 +11,9 @@ var (
 type BackendType string
 
 var (
-	BackendTypeGCS BackendType = "gcs"
+	BackendTypeGCS    BackendType = "gcs"
+	BackendTypeRemote BackendType = "remote"
+	BackendTypeHTTP   BackendType = "http"
 )
 
 func (p ProviderType) String() string {
@@ -20,9 +24,68 @@ type Provider struct {
 	Type      ProviderType
 	ProjectID string
 	Region    string
+	// Regions limits discovery of regional services, all regions if empty
+	Regions []string
+	// Credentials the project is accessed with
+	Credentials Credentials
+	// APIEndpoint is the private or restricted VIP, or the name of a Private
+	// Service Connect endpoint, API clients connect to, the public hosts if
+	// empty
+	APIEndpoint string
+}
+
+// Credentials selects how a provider authenticates. Application default
+// credentials are used if neither File nor JSON is set.
+type Credentials struct {
+	// File is the path of a service account key or external account file
+	File string
+	// JSON is the content of such a file
+	JSON string
+	// ImpersonateServiceAccount is the email of a service account which is
+	// impersonated with the other credentials
+	ImpersonateServiceAccount string
+	// QuotaProject is billed for the API calls made with user credentials,
+	// such as those of gcloud auth application-default login, which don't
+	// name a quota project of their own
+	QuotaProject string
+}
+
+// IsZero reports whether application default credentials are used as they are
+func (c Credentials) IsZero() bool {
+	return c.File == "" && c.JSON == "" && c.ImpersonateServiceAccount == ""
+}
+
+// InRegions reports whether resources in region are discovered
+func (p Provider) InRegions(region string) bool {
+	if len(p.Regions) == 0 {
+		return true
+	}
+	for _, r := range p.Regions {
+		if strings.EqualFold(r, region) {
+			return true
+		}
+	}
+	return false
 }
 
 type Backend struct {
 	Type   BackendType
 	Bucket string
+	Prefix string
+	// Credentials the bucket is accessed with, those of the default project
+	Credentials Credentials
+	// APIEndpoint the bucket is accessed through, that of the default project
+	APIEndpoint string
+
+	// Terraform Cloud / HCP Terraform
+	Hostname     string
+	Organization string
+	Workspace    string
+
+	// Generic http backend
+	Address       string
+	LockAddress   string
+	UnlockAddress string
+	LockMethod    string
+	UnlockMethod  string
 }
@@ -0,0 +1,378 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RunKind string
+
+var (
+	RunKindImport RunKind = "import"
+	RunKindSync   RunKind = "sync"
+)
+
+type RunStatus string
+
+var (
+	RunStatusQueued    RunStatus = "queued"
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSucceeded RunStatus = "succeeded"
+	RunStatusFailed    RunStatus = "failed"
+)
+
+type RunRequest struct {
+	Kind     RunKind  `json:"kind"`
+	Project  string   `json:"project"`
+	Services []string `json:"services"`
+}
+
+type Run struct {
+	ID       string     `json:"id"`
+	Request  RunRequest `json:"request"`
+	Status   RunStatus  `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  time.Time  `json:"started,omitempty"`
+	Finished time.Time  `json:"finished,omitempty"`
+	Events   []string   `json:"events"`
+	// Result is what the run returned once it succeeded
+	Result any `json:"result,omitempty"`
+	// EventsDropped counts the oldest events dropped beyond maxEvents
+	EventsDropped int `json:"events_dropped,omitempty"`
+}
+
+// RunFunc executes a requested import or sync and returns its result, e.g. the
+// import result or the drift found, to be served with the run.
+type RunFunc func(context.Context, RunRequest) (any, error)
+
+const (
+	// maxRuns is the number of runs kept, finished runs are evicted oldest
+	// first beyond it
+	maxRuns = 100
+	// maxEvents is the number of log lines kept per run
+	maxEvents = 10000
+)
+
+// Server queues import and sync runs and executes them one at a time, since
+// concurrent terraform runs in the same repository corrupt each other.
+type Server struct {
+	run   RunFunc
+	token string
+	queue chan *Run
+
+	mu      sync.Mutex
+	runs    map[string]*Run
+	order   []string
+	current *Run
+	nextID  int
+}
+
+// New returns a server executing runs with fn. Requests must carry token as a
+// bearer token.
+func New(fn RunFunc, token string) *Server {
+	return &Server{
+		run:   fn,
+		token: token,
+		queue: make(chan *Run, 64),
+		runs:  make(map[string]*Run),
+	}
+}
+
+// LogHandler wraps h so that log records are also added to the events of the
+// run in progress.
+func (s *Server) LogHandler(h slog.Handler) slog.Handler {
+	return &teeHandler{Handler: h, server: s}
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	go s.worker(ctx)
+
+	server := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	slog.Info("Serving API", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/runs", s.authorize(s.createRun))
+	mux.HandleFunc("GET /v1/runs", s.authorize(s.listRuns))
+	mux.HandleFunc("GET /v1/runs/{id}", s.authorize(s.getRun))
+	mux.HandleFunc("GET /v1/runs/{id}/events", s.authorize(s.streamEvents))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	return mux
+}
+
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing bearer token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case run := <-s.queue:
+			s.execute(ctx, run)
+		}
+	}
+}
+
+func (s *Server) execute(ctx context.Context, run *Run) {
+	s.mu.Lock()
+	run.Status = RunStatusRunning
+	run.Started = time.Now()
+	s.current = run
+	s.mu.Unlock()
+
+	result, err := s.run(ctx, run.Request)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = nil
+	run.Finished = time.Now()
+	if err != nil {
+		run.Status = RunStatusFailed
+		run.Error = err.Error()
+		return
+	}
+	run.Status = RunStatusSucceeded
+	run.Result = result
+}
+
+func (s *Server) createRun(w http.ResponseWriter, r *http.Request) {
+	var req RunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if req.Kind != RunKindImport && req.Kind != RunKindSync {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported run kind: %s", req.Kind))
+		return
+	}
+	if req.Project == "" || len(req.Services) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("project and services are required"))
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	run := &Run{
+		ID:      fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102T150405"), s.nextID),
+		Request: req,
+		Status:  RunStatusQueued,
+		Created: time.Now(),
+	}
+	// The run is only kept, and older ones evicted for it, once it is queued
+	select {
+	case s.queue <- run:
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("run queue is full"))
+		return
+	}
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	s.evict()
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, s.snapshot(run))
+}
+
+// evict drops the oldest finished runs beyond maxRuns. Queued and running
+// runs are always kept.
+func (s *Server) evict() {
+	excess := len(s.order) - maxRuns
+	if excess <= 0 {
+		return
+	}
+
+	kept := s.order[:0]
+	for _, id := range s.order {
+		run := s.runs[id]
+		if excess > 0 && (run.Status == RunStatusSucceeded || run.Status == RunStatusFailed) {
+			delete(s.runs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	runs := make([]Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		r := *run
+		r.Events = nil
+		r.Result = nil
+		runs = append(runs, r)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(run))
+}
+
+// streamEvents streams the log of a run as server-sent events until it finishes.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run not found"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	var sent int
+	for {
+		snapshot := s.snapshot(run)
+		start := max(sent-snapshot.EventsDropped, 0)
+		for _, event := range snapshot.Events[start:] {
+			writeEvent(w, "", event)
+		}
+		sent = snapshot.EventsDropped + len(snapshot.Events)
+
+		if snapshot.Status == RunStatusSucceeded || snapshot.Status == RunStatusFailed {
+			writeEvent(w, string(snapshot.Status), snapshot.Error)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) lookup(id string) (*Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	return run, ok
+}
+
+func (s *Server) snapshot(run *Run) Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := *run
+	r.Events = append([]string{}, run.Events...)
+	return r
+}
+
+func (s *Server) appendEvent(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return
+	}
+	if len(s.current.Events) >= maxEvents {
+		s.current.Events = s.current.Events[1:]
+		s.current.EventsDropped++
+	}
+	s.current.Events = append(s.current.Events, event)
+}
+
+// writeEvent writes a server-sent event. Every line of data gets its own
+// data field, since a bare newline would end the event.
+func writeEvent(w io.Writer, name, data string) {
+	if name != "" {
+		fmt.Fprintf(w, "event: %s\n", name)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// teeHandler copies log records into the events of the run in progress.
+type teeHandler struct {
+	slog.Handler
+	server *Server
+	attrs  []slog.Attr
+}
+
+func (h *teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", r.Level, r.Message)
+	for _, a := range h.attrs {
+		fmt.Fprintf(&b, " %s", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s", a)
+		return true
+	})
+	h.server.appendEvent(b.String())
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &teeHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		server:  h.server,
+		attrs:   append(append([]slog.Attr{}, h.attrs...), attrs...),
+	}
+}
+
+func (h *teeHandler) WithGroup(name string) slog.Handler {
+	return &teeHandler{Handler: h.Handler.WithGroup(name), server: h.server, attrs: h.attrs}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
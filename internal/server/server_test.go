@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testToken = "secret"
+
+func request(t *testing.T, server *httptest.Server, method, path, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode[T any](t *testing.T, resp *http.Response) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+const importRequest = `{"kind":"import","project":"acme","services":["pubsub"]}`
+
+func TestAuthorize(t *testing.T) {
+	s := New(func(context.Context, RunRequest) (any, error) { return nil, nil }, testToken)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "missing", want: http.StatusUnauthorized},
+		{name: "wrong", token: "guess", want: http.StatusUnauthorized},
+		{name: "valid", token: testToken, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := request(t, server, http.MethodGet, "/v1/runs", tt.token, "")
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if resp := request(t, server, http.MethodGet, "/healthz", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("healthz status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestCreateRunQueueFull(t *testing.T) {
+	// Without a worker nothing is taken off the queue
+	s := New(func(context.Context, RunRequest) (any, error) { return nil, nil }, testToken)
+	s.queue = make(chan *Run, 1)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	if resp := request(t, server, http.MethodPost, "/v1/runs", testToken, importRequest); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("first run status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	if resp := request(t, server, http.MethodPost, "/v1/runs", testToken, importRequest); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("second run status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	runs := decode[[]Run](t, request(t, server, http.MethodGet, "/v1/runs", testToken, ""))
+	if len(runs) != 1 {
+		t.Errorf("runs = %d, want the queued run only", len(runs))
+	}
+}
+
+func TestCreateRunEvictsFinishedRuns(t *testing.T) {
+	s := New(func(context.Context, RunRequest) (any, error) { return nil, nil }, testToken)
+	for i := range maxRuns {
+		id := fmt.Sprintf("run-%d", i)
+		status := RunStatusSucceeded
+		// Queued runs are never evicted
+		if i == 0 {
+			status = RunStatusQueued
+		}
+		s.runs[id] = &Run{ID: id, Status: status}
+		s.order = append(s.order, id)
+	}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	// A rejected run evicts nothing
+	s.queue = make(chan *Run)
+	if resp := request(t, server, http.MethodPost, "/v1/runs", testToken, importRequest); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if resp := request(t, server, http.MethodGet, "/v1/runs/run-1", testToken, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("run-1 status = %d after a rejected run, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	s.queue = make(chan *Run, 1)
+	if resp := request(t, server, http.MethodPost, "/v1/runs", testToken, importRequest); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{"run-0", http.StatusOK},
+		{"run-1", http.StatusNotFound},
+		{"run-2", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if resp := request(t, server, http.MethodGet, "/v1/runs/"+tt.id, testToken, ""); resp.StatusCode != tt.want {
+			t.Errorf("%s status = %d, want %d", tt.id, resp.StatusCode, tt.want)
+		}
+	}
+	if runs := decode[[]Run](t, request(t, server, http.MethodGet, "/v1/runs", testToken, "")); len(runs) != maxRuns {
+		t.Errorf("runs = %d, want %d", len(runs), maxRuns)
+	}
+}
+
+func TestStreamEventsAndResult(t *testing.T) {
+	var s *Server
+	s = New(func(ctx context.Context, req RunRequest) (any, error) {
+		s.appendEvent("INFO Importing\nproject=acme")
+		return map[string]int{"imported": 3}, nil
+	}, testToken)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.worker(ctx)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	run := decode[Run](t, request(t, server, http.MethodPost, "/v1/runs", testToken, importRequest))
+
+	// The stream ends once the run finished
+	resp := request(t, server, http.MethodGet, "/v1/runs/"+run.ID+"/events", testToken, "")
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "data: INFO Importing\ndata: project=acme\n\nevent: succeeded\ndata: \n\n"
+	if string(body) != want {
+		t.Errorf("events = %q, want %q", body, want)
+	}
+
+	run = decode[Run](t, request(t, server, http.MethodGet, "/v1/runs/"+run.ID, testToken, ""))
+	if run.Status != RunStatusSucceeded {
+		t.Fatalf("status = %s, want %s", run.Status, RunStatusSucceeded)
+	}
+	if result, ok := run.Result.(map[string]any); !ok || result["imported"] != float64(3) {
+		t.Errorf("result = %v, want the returned result", run.Result)
+	}
+}
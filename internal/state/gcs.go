@@ -0,0 +1,111 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+)
+
+const defaultPrefix = "terraform/state"
+
+// readAttempts is how often reading the state is tried when it fails for a
+// transient reason
+const readAttempts = 3
+
+// errTruncated is returned when less of the state was read than the object
+// holds, which retrying may fix
+var errTruncated = fmt.Errorf("state read was truncated: %w", io.ErrUnexpectedEOF)
+
+type gcs struct {
+	bucket      string
+	object      string
+	credentials providers.Credentials
+	apiEndpoint string
+	// generation is the generation of the state object the first read got,
+	// which later reads are pinned to so that a run sees a single state
+	generation int64
+}
+
+func newGCS(backend providers.Backend) *gcs {
+	prefix := backend.Prefix
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	return &gcs{
+		bucket:      backend.Bucket,
+		object:      path.Join(prefix, "default.tfstate"),
+		credentials: backend.Credentials,
+		apiEndpoint: backend.APIEndpoint,
+	}
+}
+
+// Read returns the state, retrying transient failures. The first read pins
+// the generation of the object, so reads with the same backend fail instead
+// of returning state written in between. An empty object fails too, as
+// treating it as empty state would report every resource as unmanaged.
+func (g *gcs) Read(ctx context.Context) ([]byte, error) {
+	opts, err := google.EndpointClientOptions(ctx, g.credentials, g.apiEndpoint, storage.ScopeReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	for attempts := 1; ; attempts++ {
+		data, err := g.read(ctx, client)
+		if err == nil || attempts == readAttempts || !(google.Retryable(err) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			return data, err
+		}
+		slog.Warn("Retrying state read", "bucket", g.bucket, "object", g.object, "error", err)
+		select {
+		case <-time.After(time.Duration(attempts) * time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// read reads the state once, from the pinned generation if there is one
+func (g *gcs) read(ctx context.Context, client *storage.Client) ([]byte, error) {
+	object := client.Bucket(g.bucket).Object(g.object)
+	if g.generation != 0 {
+		object = object.Generation(g.generation)
+	}
+
+	reader, err := object.NewReader(ctx)
+	switch {
+	case errors.Is(err, storage.ErrObjectNotExist) && g.generation != 0:
+		return nil, fmt.Errorf("state gs://%s/%s#%d changed during the run, run again", g.bucket, g.object, g.generation)
+	case errors.Is(err, storage.ErrObjectNotExist):
+		return nil, fmt.Errorf("%w at gs://%s/%s", ErrNoState, g.bucket, g.object)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read state gs://%s/%s: %w", g.bucket, g.object, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state gs://%s/%s: %w", g.bucket, g.object, err)
+	}
+	// Objects stored gzipped are larger once decompressed
+	if !reader.Attrs.Decompressed && int64(len(data)) != reader.Attrs.Size {
+		return nil, fmt.Errorf("%w, got %d of %d bytes of gs://%s/%s", errTruncated, len(data), reader.Attrs.Size, g.bucket, g.object)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("state gs://%s/%s#%d is empty", g.bucket, g.object, reader.Attrs.Generation)
+	}
+	g.generation = reader.Attrs.Generation
+	return data, nil
+}
@@ -0,0 +1,224 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+// LockInfo is the lock payload of terraform's http backend protocol.
+type LockInfo struct {
+	ID        string    `json:"ID"`
+	Operation string    `json:"Operation"`
+	Info      string    `json:"Info"`
+	Who       string    `json:"Who"`
+	Version   string    `json:"Version"`
+	Created   time.Time `json:"Created"`
+	Path      string    `json:"Path"`
+}
+
+var ErrLocked = fmt.Errorf("state_locked")
+
+// LockError is returned by Lock when the state is locked by someone else
+type LockError struct {
+	Holder LockInfo
+}
+
+func (e *LockError) Error() string {
+	return fmt.Sprintf("%s: held by %s since %s (ID %s)", ErrLocked, e.Holder.Who, e.Holder.Created, e.Holder.ID)
+}
+
+func (e *LockError) Unwrap() error {
+	return ErrLocked
+}
+
+// httpBackend implements terraform's generic http backend protocol, as served
+// by GitLab managed terraform state and others. infrasync never writes state,
+// so it reads it with GET and locks it around sync, but never POSTs it.
+type httpBackend struct {
+	address       string
+	lockAddress   string
+	unlockAddress string
+	lockMethod    string
+	unlockMethod  string
+	username      string
+	password      string
+	client        *http.Client
+
+	lock *LockInfo
+}
+
+func newHTTP(backend providers.Backend) (*httpBackend, error) {
+	if backend.Address == "" {
+		return nil, fmt.Errorf("address is required for the http backend")
+	}
+
+	// Credentials use the same environment variables as terraform, and so do
+	// the lock methods' defaults
+	lockMethod, unlockMethod := backend.LockMethod, backend.UnlockMethod
+	if lockMethod == "" {
+		lockMethod = "LOCK"
+	}
+	if unlockMethod == "" {
+		unlockMethod = "UNLOCK"
+	}
+
+	return &httpBackend{
+		address:       backend.Address,
+		lockAddress:   backend.LockAddress,
+		unlockAddress: backend.UnlockAddress,
+		lockMethod:    lockMethod,
+		unlockMethod:  unlockMethod,
+		username:      os.Getenv("TF_HTTP_USERNAME"),
+		password:      os.Getenv("TF_HTTP_PASSWORD"),
+		client:        &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func (h *httpBackend) Read(ctx context.Context) ([]byte, error) {
+	resp, err := h.do(ctx, http.MethodGet, h.address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, fmt.Errorf("%w at %s", ErrNoState, h.address)
+	default:
+		return nil, fmt.Errorf("failed to read state: unexpected status %s", resp.Status)
+	}
+}
+
+func (h *httpBackend) Lock(ctx context.Context, operation string) error {
+	if h.lockAddress == "" {
+		return nil
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate lock ID: %w", err)
+	}
+	who, _ := os.Hostname()
+	lock := &LockInfo{
+		ID:        hex.EncodeToString(id),
+		Operation: operation,
+		Who:       who,
+		Version:   "infrasync",
+		Created:   time.Now().UTC(),
+		Path:      h.address,
+	}
+
+	body, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.do(ctx, h.lockMethod, h.lockAddress, body)
+	if err != nil {
+		return fmt.Errorf("failed to lock state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		h.lock = lock
+		audit.State(audit.ActionLock, "", nil)
+		return nil
+	case http.StatusLocked, http.StatusConflict:
+		var holder LockInfo
+		json.NewDecoder(resp.Body).Decode(&holder)
+		return &LockError{Holder: holder}
+	default:
+		return fmt.Errorf("failed to lock state: unexpected status %s", resp.Status)
+	}
+}
+
+func (h *httpBackend) Unlock(ctx context.Context) error {
+	if h.lock == nil {
+		return nil
+	}
+
+	address := h.unlockAddress
+	if address == "" {
+		address = h.lockAddress
+	}
+
+	body, err := json.Marshal(h.lock)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.do(ctx, h.unlockMethod, address, body)
+	if err != nil {
+		return fmt.Errorf("failed to unlock state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to unlock state: unexpected status %s", resp.Status)
+	}
+	h.lock = nil
+	audit.State(audit.ActionUnlock, "", nil)
+	return nil
+}
+
+// ForceUnlock releases a lock held by someone else, like terraform force-unlock
+func (h *httpBackend) ForceUnlock(ctx context.Context, id string) error {
+	address := h.unlockAddress
+	if address == "" {
+		address = h.lockAddress
+	}
+	if address == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(LockInfo{ID: id})
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.do(ctx, h.unlockMethod, address, body)
+	if err != nil {
+		return fmt.Errorf("failed to force unlock state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to force unlock state: unexpected status %s", resp.Status)
+	}
+	audit.State(audit.ActionUnlock, "", nil)
+	return nil
+}
+
+func (h *httpBackend) do(ctx context.Context, method, address string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, address, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if h.username != "" || h.password != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	return h.client.Do(req)
+}
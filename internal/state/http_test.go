@@ -0,0 +1,218 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+func TestHTTPRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "no content", status: http.StatusNoContent, wantErr: ErrNoState},
+		{name: "not found", status: http.StatusNotFound, wantErr: ErrNoState},
+		{name: "server error", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user, password, _ := r.BasicAuth(); user != "ci" || password != "secret" {
+					t.Errorf("basic auth = %s:%s, want ci:secret", user, password)
+				}
+				w.WriteHeader(tt.status)
+				if tt.status == http.StatusOK {
+					fmt.Fprint(w, `{"version":4}`)
+				}
+			}))
+			defer server.Close()
+
+			t.Setenv("TF_HTTP_USERNAME", "ci")
+			t.Setenv("TF_HTTP_PASSWORD", "secret")
+			backend, err := newHTTP(providers.Backend{Type: providers.BackendTypeHTTP, Address: server.URL + "/state"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := backend.Read(context.Background())
+			switch {
+			case tt.status == http.StatusOK:
+				if err != nil || string(data) != `{"version":4}` {
+					t.Errorf("Read() = %s, %v, want the state", data, err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+				}
+			case err == nil:
+				t.Error("Read() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestHTTPLock(t *testing.T) {
+	holder := LockInfo{ID: "other", Who: "ci@runner", Operation: "OperationTypePlan"}
+
+	tests := []struct {
+		name   string
+		status int
+		locked bool
+	}{
+		{name: "locked", status: http.StatusOK},
+		{name: "held with 423", status: http.StatusLocked, locked: true},
+		{name: "held with 409", status: http.StatusConflict, locked: true},
+		{name: "server error", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock LockInfo
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "LOCK" || r.URL.Path != "/lock" {
+					t.Errorf("request = %s %s, want LOCK /lock", r.Method, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&lock); err != nil {
+					t.Errorf("lock body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				if tt.locked {
+					json.NewEncoder(w).Encode(holder)
+				}
+			}))
+			defer server.Close()
+
+			backend, err := newHTTP(providers.Backend{Type: providers.BackendTypeHTTP,
+				Address: server.URL + "/state", LockAddress: server.URL + "/lock"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = backend.Lock(context.Background(), "infrasync sync")
+			var lockErr *LockError
+			switch {
+			case tt.status == http.StatusOK:
+				if err != nil {
+					t.Fatalf("Lock() = %v", err)
+				}
+				if lock.ID == "" || lock.Operation != "infrasync sync" || lock.Path != server.URL+"/state" {
+					t.Errorf("lock = %+v, want an ID, the operation and the state address", lock)
+				}
+			case tt.locked:
+				if !errors.As(err, &lockErr) || lockErr.Holder.ID != holder.ID || lockErr.Holder.Who != holder.Who {
+					t.Errorf("Lock() error = %v, want a LockError held by %s", err, holder.Who)
+				}
+				if !errors.Is(err, ErrLocked) {
+					t.Errorf("Lock() error = %v, want ErrLocked", err)
+				}
+			default:
+				if err == nil || errors.As(err, &lockErr) {
+					t.Errorf("Lock() error = %v, want an error other than LockError", err)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPLockWithoutLockAddress(t *testing.T) {
+	backend, err := newHTTP(providers.Backend{Type: providers.BackendTypeHTTP, Address: "http://127.0.0.1:0/state"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Lock(context.Background(), "infrasync sync"); err != nil {
+		t.Errorf("Lock() = %v, want nil without a lock address", err)
+	}
+	if err := backend.Unlock(context.Background()); err != nil {
+		t.Errorf("Unlock() = %v, want nil without a lock", err)
+	}
+}
+
+func TestHTTPUnlock(t *testing.T) {
+	var requests []string
+	var locked, unlocked LockInfo
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&locked)
+		case http.MethodDelete:
+			json.NewDecoder(r.Body).Decode(&unlocked)
+		}
+	}))
+	defer server.Close()
+
+	backend, err := newHTTP(providers.Backend{Type: providers.BackendTypeHTTP, Address: server.URL + "/state",
+		LockAddress: server.URL + "/lock", LockMethod: http.MethodPost,
+		UnlockAddress: server.URL + "/unlock", UnlockMethod: http.MethodDelete})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := backend.Lock(ctx, "infrasync sync"); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The lock is released already
+	if err := backend.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"POST /lock", "DELETE /unlock"}
+	if !slices.Equal(requests, want) {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+	if unlocked.ID != locked.ID {
+		t.Errorf("unlocked ID %q, want the lock ID %q", unlocked.ID, locked.ID)
+	}
+}
+
+func TestHTTPForceUnlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "released", status: http.StatusOK},
+		{name: "rejected", status: http.StatusConflict, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unlocked LockInfo
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Without an unlock address the lock address is used
+				if r.Method != "UNLOCK" || r.URL.Path != "/lock" {
+					t.Errorf("request = %s %s, want UNLOCK /lock", r.Method, r.URL.Path)
+				}
+				json.NewDecoder(r.Body).Decode(&unlocked)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			backend, err := newHTTP(providers.Backend{Type: providers.BackendTypeHTTP,
+				Address: server.URL + "/state", LockAddress: server.URL + "/lock"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = backend.ForceUnlock(context.Background(), "other")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ForceUnlock() = %v, want error %t", err, tt.wantErr)
+			}
+			if unlocked.ID != "other" {
+				t.Errorf("unlocked ID %q, want other", unlocked.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+// Backend reads raw terraform state.
+type Backend interface {
+	Read(ctx context.Context) ([]byte, error)
+}
+
+// ErrNoState is returned by Read when the backend holds no state. Treating it
+// as empty state would report every resource as unmanaged.
+var ErrNoState = errors.New("no state found")
+
+// requestTimeout bounds each request to the backends read over HTTP, so an
+// unresponsive server fails the run instead of hanging it
+const requestTimeout = time.Minute
+
+// Locker is implemented by backends supporting state locking.
+type Locker interface {
+	Lock(ctx context.Context, operation string) error
+	Unlock(ctx context.Context) error
+	// ForceUnlock releases the lock with ID id held by someone else
+	ForceUnlock(ctx context.Context, id string) error
+}
+
+// NewBackend returns the state reader matching the configured backend.
+func NewBackend(ctx context.Context, backend providers.Backend) (Backend, error) {
+	switch backend.Type {
+	case providers.BackendTypeGCS:
+		return newGCS(backend), nil
+	case providers.BackendTypeRemote:
+		return newTFC(backend)
+	case providers.BackendTypeHTTP:
+		return newHTTP(backend)
+	default:
+		return nil, fmt.Errorf("unsupported state backend: %s", backend.Type)
+	}
+}
+
+// Resource is a managed resource instance recorded in state.
+type Resource struct {
+	Address    string
+	Type       string
+	Name       string
+	IndexKey   any
+	Attributes map[string]any
+	// Sensitive holds the dotted paths of sensitive attributes
+	Sensitive []string
+}
+
+// ID returns the id attribute of the resource, which matches the import ID
+// of most resource types.
+func (r Resource) ID() string {
+	id, _ := r.Attributes["id"].(string)
+	return id
+}
+
+// Resources returns the managed resource instances of a terraform state
+// document, without deposed objects.
+func Resources(data []byte) ([]Resource, error) {
+	file, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, nil
+	}
+
+	var resources []Resource
+	for _, r := range file.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		for _, instance := range r.Instances {
+			// Deposed objects are pending destruction and replaced by the
+			// current object
+			if instance.Deposed != "" {
+				continue
+			}
+			resources = append(resources, Resource{
+				Address:    r.InstanceAddress(instance),
+				Type:       r.Type,
+				Name:       r.Name,
+				IndexKey:   instance.IndexKey,
+				Attributes: instance.Attributes,
+				Sensitive:  instance.SensitivePaths(),
+			})
+		}
+	}
+
+	return resources, nil
+}
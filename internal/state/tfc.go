@@ -0,0 +1,134 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+const defaultTFCHostname = "app.terraform.io"
+
+// tfc reads state from a Terraform Cloud / HCP Terraform workspace.
+type tfc struct {
+	hostname     string
+	organization string
+	workspace    string
+	token        string
+	client       *http.Client
+}
+
+func newTFC(backend providers.Backend) (*tfc, error) {
+	hostname := backend.Hostname
+	if hostname == "" {
+		hostname = defaultTFCHostname
+	}
+	if backend.Organization == "" || backend.Workspace == "" {
+		return nil, fmt.Errorf("organization and workspace are required for the remote backend")
+	}
+
+	token := tfcToken(hostname)
+	if token == "" {
+		return nil, fmt.Errorf("no API token for %s, set TFE_TOKEN or %s", hostname, tfcTokenEnv(hostname))
+	}
+
+	return &tfc{
+		hostname:     hostname,
+		organization: backend.Organization,
+		workspace:    backend.Workspace,
+		token:        token,
+		client:       &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// tfcToken follows terraform's TF_TOKEN_<host> convention, falling back to TFE_TOKEN.
+func tfcToken(hostname string) string {
+	if token := os.Getenv(tfcTokenEnv(hostname)); token != "" {
+		return token
+	}
+	return os.Getenv("TFE_TOKEN")
+}
+
+func tfcTokenEnv(hostname string) string {
+	return "TF_TOKEN_" + strings.NewReplacer(".", "_", "-", "__").Replace(hostname)
+}
+
+func (t *tfc) Read(ctx context.Context) ([]byte, error) {
+	var workspace struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	url := fmt.Sprintf("https://%s/api/v2/organizations/%s/workspaces/%s", t.hostname, t.organization, t.workspace)
+	if _, err := t.get(ctx, url, &workspace); err != nil {
+		return nil, fmt.Errorf("failed to get workspace %s: %w", t.workspace, err)
+	}
+
+	var version struct {
+		Data struct {
+			Attributes struct {
+				DownloadURL string `json:"hosted-state-download-url"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	url = fmt.Sprintf("https://%s/api/v2/workspaces/%s/current-state-version", t.hostname, workspace.Data.ID)
+	status, err := t.get(ctx, url, &version)
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w in workspace %s", ErrNoState, workspace.Data.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current state version: %w", err)
+	}
+
+	data, err := t.download(ctx, version.Data.Attributes.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download state: %w", err)
+	}
+	return data, nil
+}
+
+func (t *tfc) get(ctx context.Context, url string, out any) (int, error) {
+	data, status, err := t.do(ctx, url, "application/vnd.api+json", true)
+	if err != nil {
+		return status, err
+	}
+	return status, json.Unmarshal(data, out)
+}
+
+// download fetches state from the signed download URL of a state version,
+// which is served by another host and needs no token, so none is sent
+func (t *tfc) download(ctx context.Context, url string) ([]byte, error) {
+	data, _, err := t.do(ctx, url, "application/json", false)
+	return data, err
+}
+
+func (t *tfc) do(ctx context.Context, url, accept string, authorize bool) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if authorize {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return data, resp.StatusCode, nil
+}
@@ -0,0 +1,52 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTFCReadSendsNoTokenToDownloadURL(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/api/v2/organizations/acme/workspaces/prod":
+			if authorization != "Bearer secret" {
+				t.Errorf("workspace request Authorization = %q, want the token", authorization)
+			}
+			fmt.Fprint(w, `{"data":{"id":"ws-1"}}`)
+		case "/api/v2/workspaces/ws-1/current-state-version":
+			if authorization != "Bearer secret" {
+				t.Errorf("state version request Authorization = %q, want the token", authorization)
+			}
+			fmt.Fprintf(w, `{"data":{"attributes":{"hosted-state-download-url":"%s/archivist/v1/object/state"}}}`, server.URL)
+		case "/archivist/v1/object/state":
+			if authorization != "" {
+				t.Errorf("download request Authorization = %q, want none", authorization)
+			}
+			fmt.Fprint(w, `{"version":4}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	backend := &tfc{
+		hostname:     strings.TrimPrefix(server.URL, "https://"),
+		organization: "acme",
+		workspace:    "prod",
+		token:        "secret",
+		client:       server.Client(),
+	}
+	data, err := backend.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"version":4}` {
+		t.Errorf("Read() = %s, want the downloaded state", data)
+	}
+}
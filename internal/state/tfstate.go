@@ -0,0 +1,121 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// File is a terraform state file in format version 4.
+type File struct {
+	Version          int               `json:"version"`
+	TerraformVersion string            `json:"terraform_version"`
+	Serial           int64             `json:"serial"`
+	Lineage          string            `json:"lineage"`
+	Outputs          map[string]Output `json:"outputs"`
+	Resources        []FileResource    `json:"resources"`
+}
+
+type Output struct {
+	Value     any             `json:"value"`
+	Type      json.RawMessage `json:"type"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+}
+
+type FileResource struct {
+	Module    string     `json:"module,omitempty"`
+	Mode      string     `json:"mode"`
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Each      string     `json:"each,omitempty"`
+	Provider  string     `json:"provider"`
+	Instances []Instance `json:"instances"`
+}
+
+type Instance struct {
+	// IndexKey is a float64 for count and a string for for_each instances
+	IndexKey            any            `json:"index_key,omitempty"`
+	SchemaVersion       int            `json:"schema_version"`
+	Attributes          map[string]any `json:"attributes"`
+	SensitiveAttributes [][]PathStep   `json:"sensitive_attributes,omitempty"`
+	Private             string         `json:"private,omitempty"`
+	Dependencies        []string       `json:"dependencies,omitempty"`
+	CreateBeforeDestroy bool           `json:"create_before_destroy,omitempty"`
+	Deposed             string         `json:"deposed,omitempty"`
+	Status              string         `json:"status,omitempty"`
+}
+
+// PathStep is one step of an attribute path, either an attribute name or an index.
+type PathStep struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// Parse decodes a version 4 state document. Empty input means there is no
+// state yet and returns nil.
+func Parse(data []byte) (*File, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	if file.Version != 4 {
+		return nil, fmt.Errorf("unsupported state version %d, only version 4 is supported", file.Version)
+	}
+	return &file, nil
+}
+
+// Address returns the address of the resource, without instance key.
+func (r FileResource) Address() string {
+	address := fmt.Sprintf("%s.%s", r.Type, r.Name)
+	if r.Mode == "data" {
+		address = "data." + address
+	}
+	if r.Module != "" {
+		address = r.Module + "." + address
+	}
+	return address
+}
+
+// InstanceAddress returns the address of a single instance, e.g.
+// google_pubsub_topic.topic[0] or google_pubsub_topic.topic["a"].
+func (r FileResource) InstanceAddress(instance Instance) string {
+	return r.Address() + indexSuffix(instance.IndexKey)
+}
+
+func indexSuffix(key any) string {
+	switch k := key.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("[%d]", int64(k))
+	case string:
+		return fmt.Sprintf("[%s]", strconv.Quote(k))
+	default:
+		return fmt.Sprintf("[%v]", k)
+	}
+}
+
+// SensitivePaths returns the sensitive attribute paths of the instance in
+// dotted form, e.g. "password" or "settings.0.root_password".
+func (i Instance) SensitivePaths() []string {
+	var paths []string
+	for _, path := range i.SensitiveAttributes {
+		var parts []string
+		for _, step := range path {
+			value := step.Value
+			// Index steps hold their key as a typed value, e.g.
+			// {"value": 0, "type": "number"}
+			if typed, ok := value.(map[string]any); ok {
+				value = typed["value"]
+			}
+			parts = append(parts, fmt.Sprint(value))
+		}
+		paths = append(paths, strings.Join(parts, "."))
+	}
+	return paths
+}
@@ -0,0 +1,117 @@
+package state
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResourcesAddresses(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     []string
+	}{
+		{
+			name:     "single",
+			resource: `{"mode":"managed","type":"google_pubsub_topic","name":"t","instances":[{"attributes":{"id":"projects/acme/topics/t"}}]}`,
+			want:     []string{"google_pubsub_topic.t"},
+		},
+		{
+			name:     "count",
+			resource: `{"mode":"managed","type":"google_pubsub_topic","name":"t","instances":[{"index_key":0,"attributes":{}},{"index_key":1,"attributes":{}}]}`,
+			want:     []string{"google_pubsub_topic.t[0]", "google_pubsub_topic.t[1]"},
+		},
+		{
+			name:     "for_each",
+			resource: `{"mode":"managed","type":"google_pubsub_topic","name":"t","each":"map","instances":[{"index_key":"orders.v1","attributes":{}}]}`,
+			want:     []string{`google_pubsub_topic.t["orders.v1"]`},
+		},
+		{
+			name:     "module",
+			resource: `{"module":"module.pubsub[\"prod\"]","mode":"managed","type":"google_pubsub_topic","name":"t","instances":[{"attributes":{}}]}`,
+			want:     []string{`module.pubsub["prod"].google_pubsub_topic.t`},
+		},
+		{
+			name:     "deposed",
+			resource: `{"mode":"managed","type":"google_pubsub_topic","name":"t","instances":[{"attributes":{}},{"deposed":"00000001","attributes":{}}]}`,
+			want:     []string{"google_pubsub_topic.t"},
+		},
+		{
+			name:     "data",
+			resource: `{"mode":"data","type":"google_project","name":"p","instances":[{"attributes":{}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := Resources([]byte(`{"version":4,"resources":[` + tt.resource + `]}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range resources {
+				got = append(got, r.Address)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("addresses = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourcesID(t *testing.T) {
+	resources, err := Resources([]byte(`{"version":4,"resources":[
+		{"mode":"managed","type":"google_pubsub_topic","name":"t","instances":[{"attributes":{"id":"projects/acme/topics/t"}}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resources[0].ID(); got != "projects/acme/topics/t" {
+		t.Errorf("ID() = %q, want projects/acme/topics/t", got)
+	}
+}
+
+func TestResourcesSensitivePaths(t *testing.T) {
+	resources, err := Resources([]byte(`{"version":4,"resources":[
+		{"mode":"managed","type":"google_sql_database_instance","name":"db","instances":[{"attributes":{},
+		 "sensitive_attributes":[[{"type":"get_attr","value":"root_password"}],
+		  [{"type":"get_attr","value":"settings"},{"type":"index","value":{"value":0,"type":"number"}},{"type":"get_attr","value":"password"}],
+		  [{"type":"get_attr","value":"settings"},{"type":"index","value":0},{"type":"get_attr","value":"password"}]]}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"root_password", "settings.0.password", "settings.0.password"}
+	if got := resources[0].Sensitive; !slices.Equal(got, want) {
+		t.Errorf("Sensitive = %q, want %q", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantNil bool
+		wantErr string
+	}{
+		{name: "empty", data: " \n", wantNil: true},
+		{name: "version 4", data: `{"version":4,"serial":3}`},
+		{name: "version 3", data: `{"version":3}`, wantErr: "unsupported state version 3"},
+		{name: "invalid", data: `{"version":`, wantErr: "failed to parse state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (file == nil) != tt.wantNil {
+				t.Errorf("Parse() = %v, want nil %t", file, tt.wantNil)
+			}
+		})
+	}
+}
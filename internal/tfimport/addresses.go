@@ -0,0 +1,258 @@
+package tfimport
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AddressReference is an attribute holding the ID or name of a resource whose
+// config may be in the working directory, e.g. the workflow an Eventarc
+// trigger delivers events to. It is replaced by a reference to the resource's
+// address when the resource is found there.
+type AddressReference struct {
+	ResourceType string
+	// Block is the nested block holding the attribute
+	Block     string
+	Attribute string
+	// Targets are the resource types the attribute may refer to
+	Targets []string
+	// Key returns the key the referenced resource is indexed by, see
+	// addressTargets, from the attribute's value, the other attributes of
+	// its block and the project of the referring resource
+	Key func(value string, block map[string]string, project string) string
+	// Output is the attribute of the referenced resource referred to
+	Output string
+}
+
+// AddressReferences are the attributes replaced by references to resources in
+// the working directory
+var AddressReferences = []AddressReference{
+	{
+		ResourceType: "google_eventarc_trigger",
+		Block:        "cloud_run_service",
+		Attribute:    "service",
+		Targets:      []string{"google_cloud_run_v2_service", "google_cloud_run_service"},
+		Key: func(service string, block map[string]string, project string) string {
+			return fmt.Sprintf("projects/%s/locations/%s/services/%s", project, block["region"], service)
+		},
+		Output: "name",
+	},
+	{
+		ResourceType: "google_eventarc_trigger",
+		Block:        "destination",
+		Attribute:    "cloud_function",
+		Targets:      []string{"google_cloudfunctions2_function", "google_cloudfunctions_function"},
+		Key:          func(id string, _ map[string]string, _ string) string { return id },
+		Output:       "id",
+	},
+	{
+		ResourceType: "google_eventarc_trigger",
+		Block:        "destination",
+		Attribute:    "workflow",
+		Targets:      []string{"google_workflows_workflow"},
+		Key:          func(id string, _ map[string]string, _ string) string { return id },
+		Output:       "id",
+	},
+}
+
+// addressTargets return the key a resource of each type is indexed by from
+// its top-level attributes
+var addressTargets = map[string]func(attributes map[string]string) string{
+	"google_cloud_run_v2_service": func(a map[string]string) string {
+		return fmt.Sprintf("projects/%s/locations/%s/services/%s", a["project"], a["location"], a["name"])
+	},
+	"google_cloud_run_service": func(a map[string]string) string {
+		return fmt.Sprintf("projects/%s/locations/%s/services/%s", a["project"], a["location"], a["name"])
+	},
+	"google_cloudfunctions2_function": func(a map[string]string) string {
+		return fmt.Sprintf("projects/%s/locations/%s/functions/%s", a["project"], a["location"], a["name"])
+	},
+	"google_cloudfunctions_function": func(a map[string]string) string {
+		return fmt.Sprintf("projects/%s/locations/%s/functions/%s", a["project"], a["region"], a["name"])
+	},
+	"google_workflows_workflow": func(a map[string]string) string {
+		return fmt.Sprintf("projects/%s/locations/%s/workflows/%s", a["project"], a["region"], a["name"])
+	},
+}
+
+// hasAddressReferences reports whether content holds a resource with
+// attributes which may refer to other resources by address
+func hasAddressReferences(content string) bool {
+	for _, ref := range AddressReferences {
+		if strings.Contains(content, fmt.Sprintf("resource %q", ref.ResourceType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexAddresses returns the addresses of the resources of the target types
+// configured in the working directory, keyed by the key of their type.
+// Resources without a project are taken to be in project.
+func indexAddresses(workingDir, project string) (map[string]string, error) {
+	index := map[string]string{}
+	// The variables factored out of generated config, by module
+	modules := map[string]map[string]string{}
+	err := filepath.WalkDir(workingDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != workingDir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || filepath.Ext(path) != ".tf" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		variables, ok := modules[filepath.Dir(path)]
+		if !ok {
+			if variables, err = readExtractedVariables(filepath.Dir(path)); err != nil {
+				return err
+			}
+			modules[filepath.Dir(path)] = variables
+		}
+		for address, attributes := range topLevelAttributes(string(content), variables) {
+			resourceType, _, _ := strings.Cut(address, ".")
+			key, ok := addressTargets[resourceType]
+			if !ok {
+				continue
+			}
+			if attributes["project"] == "" {
+				attributes["project"] = project
+			}
+			index[resourceType+" "+key(attributes)] = address
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index resources: %w", err)
+	}
+	return index, nil
+}
+
+// topLevelAttributes returns the quoted top-level attributes of the resources
+// in content, keyed by address. Attributes set to variables, see
+// ExtractVariables, have their values.
+func topLevelAttributes(content string, variables map[string]string) map[string]map[string]string {
+	resources := map[string]map[string]string{}
+	var attributes map[string]string
+	var depth int
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				attributes = map[string]string{}
+				resources[m[1]+"."+m[2]] = attributes
+			}
+		} else if depth == 1 && attributes != nil {
+			if name, value, ok := quotedAttribute(trimmed); ok {
+				attributes[name] = value
+			} else if m := variableRefRe.FindStringSubmatch(trimmed); m != nil {
+				if value, ok := variables[m[2]]; ok {
+					attributes[m[1]] = value
+				}
+			}
+		}
+		depth += bracketDelta(trimmed)
+		if depth == 0 {
+			attributes = nil
+		}
+	}
+	return resources
+}
+
+// quotedAttribute returns the name and unquoted value of an attribute set to
+// a string
+func quotedAttribute(line string) (string, string, bool) {
+	m := attributeRe.FindStringSubmatch(line + " ")
+	if m == nil {
+		return "", "", false
+	}
+	value := quotedValueRe.FindStringSubmatch(line)
+	if value == nil {
+		return "", "", false
+	}
+	s, err := strconv.Unquote(value[1])
+	if err != nil {
+		return "", "", false
+	}
+	return m[1], s, true
+}
+
+// resolveAddresses replaces the attributes in AddressReferences which refer to
+// resources in index with references to their addresses
+func resolveAddresses(content, project string, index map[string]string) string {
+	lines := strings.Split(content, "\n")
+
+	// Attributes of a block may follow the one referring to a resource, so
+	// the blocks are read before rewriting
+	type block struct {
+		name       string
+		attributes map[string]string
+		// depth is the nesting depth of the block's body
+		depth int
+	}
+	blockOf := make([]*block, len(lines))
+	resourceTypes := make([]string, len(lines))
+	var stack []*block
+	var resourceType string
+	var depth int
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				resourceType = m[1]
+			}
+		} else if m := blockRe.FindStringSubmatch(trimmed); m != nil {
+			stack = append(stack, &block{name: m[1], attributes: map[string]string{}, depth: depth + 1})
+		} else if len(stack) > 0 {
+			if name, value, ok := quotedAttribute(trimmed); ok {
+				stack[len(stack)-1].attributes[name] = value
+			}
+		}
+		resourceTypes[i] = resourceType
+		if len(stack) > 0 {
+			blockOf[i] = stack[len(stack)-1]
+		}
+
+		depth += bracketDelta(trimmed)
+		for len(stack) > 0 && depth < stack[len(stack)-1].depth {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	for i, line := range lines {
+		b := blockOf[i]
+		if b == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		name, value, ok := quotedAttribute(trimmed)
+		if !ok {
+			continue
+		}
+		for _, ref := range AddressReferences {
+			if ref.ResourceType != resourceTypes[i] || ref.Block != b.name || ref.Attribute != name {
+				continue
+			}
+			key := ref.Key(value, b.attributes, project)
+			idx := slices.IndexFunc(ref.Targets, func(t string) bool { _, ok := index[t+" "+key]; return ok })
+			if idx < 0 {
+				continue
+			}
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			lines[i] = fmt.Sprintf("%s%s = %s.%s", indent, name, index[ref.Targets[idx]+" "+key], ref.Output)
+			break
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,227 @@
+package tfimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/binary"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+)
+
+// Runner is where terraform runs
+type Runner string
+
+const (
+	// RunnerLocal runs the terraform binary in PATH
+	RunnerLocal Runner = "local"
+	// RunnerDocker runs terraform in a container, so that no local binary is
+	// needed
+	RunnerDocker Runner = "docker"
+)
+
+// DefaultDockerImage is the image terraform runs in with RunnerDocker. Its
+// entrypoint has to be the terraform or tofu binary.
+const DefaultDockerImage = "hashicorp/terraform:1.9.8"
+
+const (
+	containerWorkdir     = "/workspace"
+	containerCredentials = "/credentials.json"
+)
+
+// ParseRunner validates the name of a runner. Empty means RunnerLocal.
+func ParseRunner(name string) (Runner, error) {
+	switch Runner(name) {
+	case "", RunnerLocal:
+		return RunnerLocal, nil
+	case RunnerDocker:
+		return RunnerDocker, nil
+	default:
+		return "", fmt.Errorf("unsupported runner %q, must be %s or %s", name, RunnerLocal, RunnerDocker)
+	}
+}
+
+// dockerTerraform runs terraform in a container with the working directory
+// mounted
+type dockerTerraform struct {
+	workingDir string
+	image      string
+	creds      providers.Credentials
+	// lock is whether plans lock the state
+	lock bool
+}
+
+func newDockerTerraform(workingDir, image string, creds providers.Credentials, lock bool) (*dockerTerraform, error) {
+	if _, err := binary.LookPath(binary.Docker); err != nil {
+		return nil, fmt.Errorf("docker is not installed or not in PATH: %w", err)
+	}
+	if image == "" {
+		image = DefaultDockerImage
+	}
+	return &dockerTerraform{workingDir: workingDir, image: image, creds: creds, lock: lock}, nil
+}
+
+// proxyEnv are the variables which configure proxies, passed to the
+// container when set
+var proxyEnv = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"}
+
+// run runs terraform with args in the container and returns its output. The
+// error includes what terraform printed to stderr.
+func (t *dockerTerraform) run(ctx context.Context, args ...string) ([]byte, error) {
+	run := []string{"run", "--rm",
+		"-v", t.workingDir + ":" + containerWorkdir,
+		"-w", containerWorkdir,
+		// The user may not exist in the image, so HOME is set to a
+		// directory it can write to
+		"-e", "HOME=/tmp",
+		"-e", "TF_IN_AUTOMATION=1",
+	}
+	// Files are created as the local user rather than root
+	if runtime.GOOS != "windows" {
+		run = append(run, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+	// Values are passed through the environment of docker rather than its
+	// arguments, which may hold a key
+	var env []string
+	switch {
+	case t.creds.File != "":
+		run = append(run,
+			"-v", t.creds.File+":"+containerCredentials+":ro",
+			"-e", "GOOGLE_CREDENTIALS="+containerCredentials)
+	case t.creds.JSON != "":
+		run = append(run, "-e", "GOOGLE_CREDENTIALS")
+		env = append(env, "GOOGLE_CREDENTIALS="+t.creds.JSON)
+	default:
+		if path := credentialsFile(); path != "" {
+			run = append(run,
+				"-v", path+":"+containerCredentials+":ro",
+				"-e", "GOOGLE_APPLICATION_CREDENTIALS="+containerCredentials)
+		}
+	}
+	if t.creds.ImpersonateServiceAccount != "" {
+		run = append(run, "-e", "GOOGLE_IMPERSONATE_SERVICE_ACCOUNT="+t.creds.ImpersonateServiceAccount)
+	}
+	if google.NeedsQuotaProject(ctx, t.creds) {
+		run = append(run, "-e", "GOOGLE_BILLING_PROJECT="+t.creds.QuotaProject, "-e", "USER_PROJECT_OVERRIDE=true")
+	}
+	// Proxies are passed by name, as their URLs may hold a password
+	for _, name := range proxyEnv {
+		if _, ok := os.LookupEnv(name); ok {
+			run = append(run, "-e", name)
+		}
+	}
+	run = append(run, t.image)
+
+	cmd := binary.CommandContext(ctx, binary.Docker, append(run, args...)...)
+	cmd.Dir = t.workingDir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := audit.Run(cmd); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%w\n%s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+func (t *dockerTerraform) Version(ctx context.Context) (*version.Version, error) {
+	out, err := t.run(ctx, "version", "-json")
+	if err != nil {
+		return nil, err
+	}
+	// OpenTofu reports its version under the same key
+	var v struct {
+		Version string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse version: %w", err)
+	}
+	return version.NewVersion(v.Version)
+}
+
+func (t *dockerTerraform) Init(ctx context.Context) error {
+	_, err := t.run(ctx, "init", "-input=false", "-no-color")
+	return err
+}
+
+func (t *dockerTerraform) Plan(ctx context.Context, out, generateConfigOut string) (bool, error) {
+	args := []string{"plan", "-input=false", "-no-color", "-detailed-exitcode", "-out=" + out}
+	if !t.lock {
+		args = append(args, "-lock=false")
+	}
+	if generateConfigOut != "" {
+		args = append(args, "-generate-config-out="+generateConfigOut)
+	}
+	_, err := t.run(ctx, args...)
+
+	// Exit code 2 is a successful plan with changes
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 2 {
+		return true, nil
+	}
+	return false, err
+}
+
+func (t *dockerTerraform) ShowPlanFile(ctx context.Context, path string) (*tfjson.Plan, error) {
+	out, err := t.run(ctx, "show", "-json", "-no-color", path)
+	if err != nil {
+		return nil, err
+	}
+	var plan tfjson.Plan
+	if err := json.Unmarshal(out, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	return &plan, nil
+}
+
+func (t *dockerTerraform) ShowState(ctx context.Context) (*tfjson.State, error) {
+	out, err := t.run(ctx, "show", "-json", "-no-color")
+	if err != nil {
+		return nil, err
+	}
+	var state tfjson.State
+	if err := json.Unmarshal(out, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	return &state, nil
+}
+
+func (t *dockerTerraform) StateRm(ctx context.Context, address string) error {
+	_, err := t.run(ctx, "state", "rm", address)
+	return err
+}
+
+// credentialsFile returns the Google credentials to mount into the container:
+// GOOGLE_APPLICATION_CREDENTIALS if set, or else the application default
+// credentials of gcloud. It returns "" if there are none.
+func credentialsFile() string {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path
+	}
+
+	var dir string
+	if runtime.GOOS == "windows" {
+		dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dir = filepath.Join(home, ".config", "gcloud")
+	}
+	path := filepath.Join(dir, "application_default_credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
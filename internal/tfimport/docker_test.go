@@ -0,0 +1,45 @@
+package tfimport
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCredentialsFileOfGCloud(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	// gcloud keeps its config in %APPDATA% on Windows and in ~/.config
+	// elsewhere
+	configDir := filepath.Join(dir, ".config", "gcloud")
+	if runtime.GOOS == "windows" {
+		t.Setenv("APPDATA", dir)
+		configDir = filepath.Join(dir, "gcloud")
+	} else {
+		t.Setenv("HOME", dir)
+	}
+
+	if got := credentialsFile(); got != "" {
+		t.Errorf("credentialsFile() = %q without credentials, want none", got)
+	}
+
+	want := filepath.Join(configDir, "application_default_credentials.json")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(want, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := credentialsFile(); got != want {
+		t.Errorf("credentialsFile() = %q, want %q", got, want)
+	}
+}
+
+func TestCredentialsFileFromEnvironment(t *testing.T) {
+	want := filepath.Join(t.TempDir(), "key.json")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", want)
+	if got := credentialsFile(); got != want {
+		t.Errorf("credentialsFile() = %q, want %q", got, want)
+	}
+}
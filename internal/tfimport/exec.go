@@ -0,0 +1,171 @@
+package tfimport
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/binary"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+)
+
+// minVersion is the first terraform release which generates config for
+// import blocks with -generate-config-out
+var minVersion = version.Must(version.NewVersion("1.5.0"))
+
+// terraform runs the terraform commands the generator needs in its working
+// directory. Paths are relative to the working directory.
+type terraform interface {
+	Version(ctx context.Context) (*version.Version, error)
+	Init(ctx context.Context) error
+	// Plan saves a plan to out, writing config for import blocks to
+	// generateConfigOut unless it is empty, and reports whether the plan
+	// has changes
+	Plan(ctx context.Context, out, generateConfigOut string) (bool, error)
+	ShowPlanFile(ctx context.Context, path string) (*tfjson.Plan, error)
+	ShowState(ctx context.Context) (*tfjson.State, error)
+	StateRm(ctx context.Context, address string) error
+}
+
+// execTerraform runs the terraform binary in PATH with terraform-exec
+type execTerraform struct {
+	tf *tfexec.Terraform
+	// lock is whether plans lock the state
+	lock bool
+}
+
+func newExecTerraform(ctx context.Context, workingDir string, creds providers.Credentials, lock bool) (*execTerraform, error) {
+	execPath, err := binary.LookPath(binary.Terraform)
+	if err != nil {
+		return nil, fmt.Errorf("terraform is not installed or not in PATH: %w", err)
+	}
+	tf, err := tfexec.NewTerraform(workingDir, execPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if env := credentialsEnv(ctx, creds); len(env) > 0 {
+		// SetEnv replaces the environment rather than adding to it
+		merged := tfexec.CleanEnv(environ())
+		maps.Copy(merged, env)
+		if err := tf.SetEnv(merged); err != nil {
+			return nil, err
+		}
+	}
+	return &execTerraform{tf: tf, lock: lock}, nil
+}
+
+// credentialsEnv returns the environment which makes the google provider and
+// the gcs backend use creds, none for application default credentials which
+// name their quota project
+func credentialsEnv(ctx context.Context, creds providers.Credentials) map[string]string {
+	env := make(map[string]string)
+	switch {
+	case creds.File != "":
+		env["GOOGLE_CREDENTIALS"] = creds.File
+	case creds.JSON != "":
+		// The variable takes the content of a credentials file too
+		env["GOOGLE_CREDENTIALS"] = creds.JSON
+	}
+	if creds.ImpersonateServiceAccount != "" {
+		env["GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"] = creds.ImpersonateServiceAccount
+	}
+	if google.NeedsQuotaProject(ctx, creds) {
+		// The provider only bills the billing project with the override
+		env["GOOGLE_BILLING_PROJECT"] = creds.QuotaProject
+		env["USER_PROJECT_OVERRIDE"] = "true"
+	}
+	return env
+}
+
+// environ returns the environment of the process as a map
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	return env
+}
+
+// record records a command in the audit log. terraform-exec adds flags of its
+// own, so args are the ones it was asked for.
+func (t *execTerraform) record(err error, args ...string) {
+	audit.Command(t.tf.ExecPath(), t.tf.WorkingDir(), append([]string{t.tf.ExecPath()}, args...), err)
+}
+
+func (t *execTerraform) Version(ctx context.Context) (*version.Version, error) {
+	v, _, err := t.tf.Version(ctx, true)
+	t.record(err, "version")
+	return v, err
+}
+
+func (t *execTerraform) Init(ctx context.Context) error {
+	err := t.tf.Init(ctx)
+	t.record(err, "init")
+	return err
+}
+
+func (t *execTerraform) Plan(ctx context.Context, out, generateConfigOut string) (bool, error) {
+	opts := []tfexec.PlanOption{tfexec.Out(out)}
+	args := []string{"plan", "-out=" + out}
+	if !t.lock {
+		opts = append(opts, tfexec.Lock(false))
+		args = append(args, "-lock=false")
+	}
+	if generateConfigOut != "" {
+		opts = append(opts, tfexec.GenerateConfigOut(generateConfigOut))
+		args = append(args, "-generate-config-out="+generateConfigOut)
+	}
+	changes, err := t.tf.Plan(ctx, opts...)
+	t.record(err, args...)
+	return changes, err
+}
+
+func (t *execTerraform) ShowPlanFile(ctx context.Context, path string) (*tfjson.Plan, error) {
+	plan, err := t.tf.ShowPlanFile(ctx, path)
+	t.record(err, "show", "-json", path)
+	return plan, err
+}
+
+func (t *execTerraform) ShowState(ctx context.Context) (*tfjson.State, error) {
+	state, err := t.tf.Show(ctx)
+	t.record(err, "show", "-json")
+	return state, err
+}
+
+func (t *execTerraform) StateRm(ctx context.Context, address string) error {
+	err := t.tf.StateRm(ctx, address)
+	t.record(err, "state", "rm", address)
+	return err
+}
+
+// stateAddresses returns the address of every resource in state, including
+// those of child modules
+func stateAddresses(state *tfjson.State) []string {
+	if state == nil || state.Values == nil {
+		return nil
+	}
+	var addresses []string
+	var walk func(module *tfjson.StateModule)
+	walk = func(module *tfjson.StateModule) {
+		if module == nil {
+			return
+		}
+		for _, r := range module.Resources {
+			addresses = append(addresses, r.Address)
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(state.Values.RootModule)
+	return addresses
+}
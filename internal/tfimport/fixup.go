@@ -0,0 +1,137 @@
+package tfimport
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// generatedFix corrects a value the API reports but the provider rejects,
+// which makes terraform fail to plan the config it generated itself
+type generatedFix struct {
+	ResourceType string
+	// Block is the nested block holding the attribute
+	Block     string
+	Attribute string
+	Value     string
+	// Replace is written instead of Value. The whole block is dropped if it
+	// is empty.
+	Replace string
+	Reason  string
+}
+
+// generatedFixes are applied to generated config terraform can't plan
+var generatedFixes = []generatedFix{
+	{
+		// settings.0.maintenance_window.0.day must be in the range (1 - 7)
+		ResourceType: "google_sql_database_instance",
+		Block:        "maintenance_window",
+		Attribute:    "day",
+		Value:        "0",
+		Reason:       "the instance allows maintenance in any window, which the provider can't express",
+	},
+	{
+		// settings.0.insights_config.0.query_string_length must be in the
+		// range (256 - 4500)
+		ResourceType: "google_sql_database_instance",
+		Block:        "insights_config",
+		Attribute:    "query_string_length",
+		Value:        "0",
+		Replace:      "1024",
+		Reason:       "the API reports 0, which the provider rejects, so its default is used",
+	},
+}
+
+// fixComment starts the comments explaining fixes above a resource
+const fixComment = "# infrasync: "
+
+var blockRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*\{$`)
+
+// fixGenerated applies fixes to generated config. Each fix applied is
+// explained in a comment above its resource, and returned.
+func fixGenerated(content string, fixes []generatedFix) (string, []string) {
+	var out, applied []string
+	var resourceType string
+	var header, depth int
+	var block []string
+	var blockDepth int
+	var blockFix *generatedFix
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if block != nil {
+			block = append(block, line)
+			blockDepth += bracketDelta(trimmed)
+			if blockDepth > 0 {
+				continue
+			}
+			fixed, ok := applyFix(block, *blockFix)
+			if ok {
+				note := fmt.Sprintf("%s.%s %s: %s", blockFix.Block, blockFix.Attribute, fixAction(*blockFix), blockFix.Reason)
+				applied = append(applied, note)
+				out = slices.Insert(out, header, fixComment+note)
+				header++
+			}
+			out = append(out, fixed...)
+			block = nil
+			continue
+		}
+
+		if depth == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				resourceType = m[1]
+				header = len(out)
+			}
+		} else if m := blockRe.FindStringSubmatch(trimmed); m != nil {
+			if fix := fixFor(fixes, resourceType, m[1]); fix != nil {
+				block = []string{line}
+				blockDepth = 1
+				blockFix = fix
+				continue
+			}
+		}
+
+		depth += bracketDelta(trimmed)
+		out = append(out, line)
+	}
+	out = append(out, block...)
+
+	return strings.Join(out, "\n"), applied
+}
+
+func fixFor(fixes []generatedFix, resourceType, block string) *generatedFix {
+	for i := range fixes {
+		if fixes[i].ResourceType == resourceType && fixes[i].Block == block {
+			return &fixes[i]
+		}
+	}
+	return nil
+}
+
+// applyFix returns the lines of block with fix applied, and whether it
+// applied
+func applyFix(block []string, fix generatedFix) ([]string, bool) {
+	for i, line := range block {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.TrimSpace(name) != fix.Attribute || strings.TrimSpace(value) != fix.Value {
+			continue
+		}
+		if fix.Replace == "" {
+			return nil, true
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		fixed := append([]string{}, block...)
+		fixed[i] = fmt.Sprintf("%s%s = %s", indent, fix.Attribute, fix.Replace)
+		return fixed, true
+	}
+	return block, false
+}
+
+func fixAction(fix generatedFix) string {
+	if fix.Replace == "" {
+		return fmt.Sprintf("was %s, %s removed", fix.Value, fix.Block)
+	}
+	return fmt.Sprintf("changed from %s to %s", fix.Value, fix.Replace)
+}
@@ -0,0 +1,167 @@
+package tfimport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/version"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+const (
+	// headerTitle starts the header of generated files
+	headerTitle = "# Generated by InfraSync, edits are kept but stop it from updating this file"
+	// headerPrefix starts the machine-readable lines of the header
+	headerPrefix = "# infrasync:"
+)
+
+// Header is the comment infrasync starts generated files with. Besides the
+// build and run which last wrote the file and the resources generated in it,
+// it holds a hash of the rest of the file, which tells generated files apart
+// from edited ones without the manifest, e.g. in fresh clones of repositories
+// which don't commit it.
+type Header struct {
+	Version   string
+	RunID     string
+	Resources []HeaderResource
+	// Hash is the SHA-256 of the file without its header
+	Hash string
+}
+
+// HeaderResource is a resource generated in a file
+type HeaderResource struct {
+	Address string
+	ID      string
+	// Discovered is when the resource was discovered and generated
+	Discovered time.Time
+}
+
+// ParseHeader returns the header content starts with and the content after
+// it, or false if content has no header
+func ParseHeader(content string) (Header, string, bool) {
+	title, rest, _ := strings.Cut(content, "\n")
+	if strings.TrimRight(title, "\r") != headerTitle {
+		return Header{}, content, false
+	}
+
+	var h Header
+	for strings.HasPrefix(rest, headerPrefix) {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		key, value, _ := strings.Cut(strings.TrimRight(strings.TrimPrefix(line, headerPrefix), "\r"), " ")
+		switch key {
+		case "version":
+			h.Version = value
+		case "run":
+			h.RunID = value
+		case "hash":
+			h.Hash = value
+		case "resource":
+			// Addresses and timestamps have no spaces, IDs may
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				continue
+			}
+			discovered, _ := time.Parse(time.RFC3339, fields[len(fields)-1])
+			h.Resources = append(h.Resources, HeaderResource{
+				Address:    fields[0],
+				ID:         strings.Join(fields[1:len(fields)-1], " "),
+				Discovered: discovered,
+			})
+		}
+	}
+	return h, strings.TrimPrefix(rest, "\n"), true
+}
+
+// String returns the header as the comment it is written as, ending in a
+// blank line
+func (h Header) String() string {
+	var b strings.Builder
+	b.WriteString(headerTitle + "\n")
+	fmt.Fprintf(&b, "%sversion %s\n", headerPrefix, h.Version)
+	if h.RunID != "" {
+		fmt.Fprintf(&b, "%srun %s\n", headerPrefix, h.RunID)
+	}
+	for _, r := range h.Resources {
+		fmt.Fprintf(&b, "%sresource %s %s %s\n", headerPrefix, r.Address, r.ID, r.Discovered.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "%shash %s\n\n", headerPrefix, h.Hash)
+	return b.String()
+}
+
+// Unchanged reports whether body, the content after the header, is as
+// infrasync wrote it
+func (h Header) Unchanged(body string) bool {
+	return h.Hash != "" && h.Hash == bodyHash(body)
+}
+
+// unchangedSinceGenerated reports whether content is a generated file nobody
+// changed since, by its header
+func unchangedSinceGenerated(content string) bool {
+	h, body, ok := ParseHeader(content)
+	return ok && h.Unchanged(body)
+}
+
+// stampHeader returns content, a generated file, with its header updated for
+// the resource and its dependents generated in it by this run. Content
+// without a header gets one in place of the comment terraform generates.
+func stampHeader(content string, resource resource.Resource, discovered time.Time) string {
+	h, body, ok := ParseHeader(content)
+	if !ok {
+		body = trimGeneratedHeader(content)
+	}
+
+	h.Version, _ = version.Info()
+	h.RunID = audit.RunID()
+	for _, r := range resource.Flatten() {
+		entry := HeaderResource{Address: r.Address(), ID: r.ID, Discovered: discovered}
+		if i := slices.IndexFunc(h.Resources, func(e HeaderResource) bool { return e.Address == entry.Address }); i >= 0 {
+			h.Resources[i] = entry
+		} else {
+			h.Resources = append(h.Resources, entry)
+		}
+	}
+	h.Hash = bodyHash(body)
+	return h.String() + body
+}
+
+// renameHeader renames the resources in the header of content which moves
+// are from, and updates its hash
+func renameHeader(content string, moves []move) string {
+	h, body, ok := ParseHeader(content)
+	if !ok {
+		return content
+	}
+	for i, r := range h.Resources {
+		for _, mv := range moves {
+			if r.Address == mv.From {
+				h.Resources[i].Address = mv.To
+			}
+		}
+	}
+	h.Hash = bodyHash(body)
+	return h.String() + body
+}
+
+// rehashHeader updates the hash in the header of content after its body was
+// rewritten
+func rehashHeader(content string) string {
+	h, body, ok := ParseHeader(content)
+	if !ok {
+		return content
+	}
+	h.Hash = bodyHash(body)
+	return h.String() + body
+}
+
+// bodyHash returns the hash of the content after a header. Line endings are
+// normalized first, like the manifest does.
+func bodyHash(body string) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(body, "\r\n", "\n")))
+	return hex.EncodeToString(sum[:])
+}
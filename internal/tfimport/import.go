@@ -2,37 +2,41 @@ package tfimport
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 
+	"github.com/priyanshujain/infrasync/internal/audit"
 	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/pkg/resource"
 )
 
 type TerraformImporter interface {
-	SaveImportBlock(google.Resource) error
+	SaveImportBlock(resource.Resource) error
 }
 
-func (i importer) SaveImportBlock(resource google.Resource) error {
-	filePath := filepath.Join(i.outputPath, fmt.Sprintf("%s.tf", resource.Name))
+func (i importer) SaveImportBlock(resource resource.Resource) error {
+	filePath := importBlockPath(i.outputPath, i.layout, resource)
 
 	var content string
 	content = "# Generated by InfraSync"
 	content += generateImportBlockContent(resource)
 
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+	if err := audit.WriteFile(filePath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write import file: %w", err)
 	}
 
 	return nil
 }
 
-func generateImportBlockContent(resource google.Resource) string {
+func generateImportBlockContent(resource resource.Resource) string {
 	var content = "\n"
+	var provider string
+	if google.Beta(resource.Type) {
+		provider = "\n\tprovider = google-beta"
+	}
 	content += fmt.Sprintf(`
 import {
 	to = %s.%s
-	id = "%s"
-}`, resource.Type, resource.Name, resource.ID)
+	id = "%s"%s
+}`, resource.Type, resource.Name, resource.ID, provider)
 
 	if len(resource.Dependents) > 0 {
 		for _, d := range resource.Dependents {
@@ -0,0 +1,135 @@
+package tfimport
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+var (
+	blockHeaderRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*\{$`)
+	mapKeyRe      = regexp.MustCompile(`^"?([^"=\s]+)"?\s*=`)
+)
+
+// injectLabels adds labels to resources whose type supports them, see
+// google.LabelAttributes. Labels already present in the generated config keep
+// their value, since they reflect what is set in the cloud.
+func injectLabels(content string, labels map[string]string) string {
+	var out []string
+
+	// blocks holds the nested blocks of the current resource, so that
+	// blocks[0] is the resource itself
+	var blocks []string
+	var parent, attribute string
+	var found bool
+
+	// skipDepth tracks multi-line attribute values, mapDepth the labels map
+	var skipDepth, mapDepth int
+	existing := make(map[string]bool)
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if len(blocks) == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil && bracketDelta(trimmed) > 0 {
+				blocks = []string{m[1]}
+				parent, attribute, found = "", "", false
+				if target, ok := google.LabelAttributes[resource.Type(m[1])]; ok {
+					if i := strings.LastIndex(target, "."); i >= 0 {
+						parent, attribute = target[:i], target[i+1:]
+					} else {
+						attribute = target
+					}
+				}
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if mapDepth > 0 {
+			if m := mapKeyRe.FindStringSubmatch(trimmed); m != nil && mapDepth == 1 {
+				existing[m[1]] = true
+			}
+			mapDepth += bracketDelta(trimmed)
+			if mapDepth == 0 {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				out = append(out, labelLines(indent+"  ", labels, existing)...)
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if skipDepth > 0 {
+			skipDepth += bracketDelta(trimmed)
+			out = append(out, line)
+			continue
+		}
+
+		path := strings.Join(blocks[1:], ".")
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+		if m := attributeRe.FindStringSubmatch(trimmed + " "); m != nil {
+			if attribute != "" && path == parent && m[1] == attribute {
+				found = true
+				value := strings.TrimSpace(trimmed[strings.Index(trimmed, "=")+1:])
+				switch value {
+				case "{":
+					existing = make(map[string]bool)
+					mapDepth = 1
+					out = append(out, line)
+				case "{}", "null":
+					out = append(out, labelsAttribute(indent, attribute, labels)...)
+				default:
+					out = append(out, line)
+				}
+				continue
+			}
+			skipDepth = bracketDelta(trimmed)
+			out = append(out, line)
+			continue
+		}
+
+		if m := blockHeaderRe.FindStringSubmatch(trimmed); m != nil {
+			blocks = append(blocks, m[1])
+			out = append(out, line)
+			continue
+		}
+
+		if trimmed == "}" {
+			if attribute != "" && !found && path == parent {
+				out = append(out, labelsAttribute(indent+"  ", attribute, labels)...)
+				found = true
+			}
+			blocks = blocks[:len(blocks)-1]
+		}
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func labelsAttribute(indent, attribute string, labels map[string]string) []string {
+	lines := []string{fmt.Sprintf("%s%s = {", indent, attribute)}
+	lines = append(lines, labelLines(indent+"  ", labels, nil)...)
+	return append(lines, indent+"}")
+}
+
+func labelLines(indent string, labels map[string]string, existing map[string]bool) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if !existing[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s%q = %q", indent, k, labels[k]))
+	}
+	return lines
+}
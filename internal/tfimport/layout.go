@@ -0,0 +1,105 @@
+package tfimport
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// DefaultLayout places every resource in a file of its own, grouped by
+// provider, project and service
+const DefaultLayout = "resources/{{provider}}/{{project}}/{{service}}/{{name}}.tf"
+
+// FileLayout selects how many resources share a generated file
+type FileLayout string
+
+const (
+	FileLayoutPerResource FileLayout = "per-resource"
+	FileLayoutPerService  FileLayout = "per-service"
+	FileLayoutPerType     FileLayout = "per-type"
+)
+
+// fileLayouts are the layouts of each file layout
+var fileLayouts = map[FileLayout]string{
+	FileLayoutPerResource: DefaultLayout,
+	FileLayoutPerService:  "resources/{{provider}}/{{project}}/{{service}}.tf",
+	FileLayoutPerType:     "resources/{{provider}}/{{project}}/{{service}}/{{type}}.tf",
+}
+
+// FileLayoutPath returns the layout of a file layout
+func FileLayoutPath(fileLayout FileLayout) (string, error) {
+	layout, ok := fileLayouts[fileLayout]
+	if !ok {
+		return "", fmt.Errorf("unsupported file_layout %q (supported: per-resource, per-service, per-type)", fileLayout)
+	}
+	return layout, nil
+}
+
+var layoutPlaceholderRe = regexp.MustCompile(`{{\s*([a-z]+)\s*}}`)
+
+// layoutPlaceholders are the values a layout can refer to
+var layoutPlaceholders = map[string]func(resource.Resource) string{
+	"provider": func(r resource.Resource) string { return r.Provider.Type.String() },
+	"project":  func(r resource.Resource) string { return r.Provider.ProjectID },
+	"service":  func(r resource.Resource) string { return r.Service.String() },
+	"type":     func(r resource.Resource) string { return string(r.Type) },
+	"name":     func(r resource.Resource) string { return r.Name },
+}
+
+// ValidateLayout checks that a layout only uses known placeholders and names
+// a .tf file inside the working directory. Layouts without {{name}} group
+// several resources into one file.
+func ValidateLayout(layout string) error {
+	for _, m := range layoutPlaceholderRe.FindAllStringSubmatch(layout, -1) {
+		if _, ok := layoutPlaceholders[m[1]]; !ok {
+			return fmt.Errorf("layout %q: unknown placeholder {{%s}}", layout, m[1])
+		}
+	}
+	if filepath.Ext(layout) != ".tf" {
+		return fmt.Errorf("layout %q must name a .tf file", layout)
+	}
+	if !filepath.IsLocal(layoutPlaceholderRe.ReplaceAllString(layout, "x")) {
+		return fmt.Errorf("layout %q must be a relative path inside the project", layout)
+	}
+	return nil
+}
+
+// LayoutPath returns the path, relative to the working directory, the config
+// of a top-level resource is written to
+func LayoutPath(layout string, resource resource.Resource) string {
+	if layout == "" {
+		layout = DefaultLayout
+	}
+	path := layoutPlaceholderRe.ReplaceAllStringFunc(layout, func(placeholder string) string {
+		name := layoutPlaceholderRe.FindStringSubmatch(placeholder)[1]
+		return layoutPlaceholders[name](resource)
+	})
+	return filepath.FromSlash(path)
+}
+
+// importBlockPath returns the file in the working directory the import blocks
+// of a resource are written to while its config is generated. It is named
+// after the file the config goes to, so resources sharing a file also share
+// their import block file.
+func importBlockPath(workingDir, layout string, resource resource.Resource) string {
+	return filepath.Join(workingDir, "import_"+filepath.Base(LayoutPath(layout, resource)))
+}
+
+// generatedHeader is the comment terraform starts generated config with
+const generatedHeader = "# __generated__ by Terraform\n"
+
+// trimGeneratedHeader drops the file-level comment terraform adds to
+// generated config, which would otherwise repeat for every resource appended
+// to a shared file
+func trimGeneratedHeader(content string) string {
+	if !strings.HasPrefix(content, generatedHeader) {
+		return content
+	}
+	if _, rest, ok := strings.Cut(content, "\n\n"); ok {
+		return rest
+	}
+	return content
+}
@@ -0,0 +1,43 @@
+package tfimport
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+func TestLayoutPathUsesPlatformSeparators(t *testing.T) {
+	r := resource.Resource{
+		Provider: providers.Provider{Type: providers.ProviderTypeGoogle, ProjectID: "app-prod"},
+		Service:  "pubsub",
+		Type:     "google_pubsub_topic",
+		Name:     "orders",
+	}
+
+	got := LayoutPath("", r)
+	want := filepath.Join("resources", "google", "app-prod", "pubsub", "orders.tf")
+	if got != want {
+		t.Errorf("LayoutPath() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateLayoutRejectsPathsOutsideProject(t *testing.T) {
+	layouts := []string{"../{{name}}.tf", "resources/../../{{name}}.tf"}
+	if runtime.GOOS == "windows" {
+		layouts = append(layouts, `C:\resources\{{name}}.tf`, `\\server\share\{{name}}.tf`, `..\{{name}}.tf`)
+	} else {
+		layouts = append(layouts, "/resources/{{name}}.tf")
+	}
+	for _, layout := range layouts {
+		if err := ValidateLayout(layout); err == nil {
+			t.Errorf("ValidateLayout(%q) succeeded, want an error", layout)
+		}
+	}
+
+	if err := ValidateLayout(DefaultLayout); err != nil {
+		t.Errorf("ValidateLayout(DefaultLayout) = %v", err)
+	}
+}
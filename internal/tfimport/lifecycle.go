@@ -0,0 +1,206 @@
+package tfimport
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// LifecycleRule injects a lifecycle block into generated resources whose type
+// and name match the given glob patterns. An empty Name matches every resource.
+type LifecycleRule struct {
+	ResourceType   string
+	Name           string
+	PreventDestroy bool
+	IgnoreChanges  []string
+}
+
+// DefaultLifecycleRules keep terraform from acting on settings it can't read
+// back after an import
+var DefaultLifecycleRules = []LifecycleRule{
+	{
+		// The certificate and key of self-managed certificates are write-only
+		ResourceType:  "google_certificate_manager_certificate",
+		IgnoreChanges: []string{"self_managed"},
+	},
+}
+
+func (r LifecycleRule) matches(resourceType, name string) bool {
+	if matched, err := path.Match(r.ResourceType, resourceType); err != nil || !matched {
+		return false
+	}
+	if r.Name == "" {
+		return true
+	}
+	matched, err := path.Match(r.Name, name)
+	return err == nil && matched
+}
+
+func injectLifecycle(content string, rules []LifecycleRule) string {
+	var out []string
+	var depth int
+	var want lifecycleSettings
+	var hasLifecycle, inLifecycle bool
+	var preventSeen, ignoreSeen bool
+	var ignoreLines []string
+	// Heredoc bodies are copied as they are, whatever braces or resource
+	// headers they hold
+	var heredoc string
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if heredoc != "" {
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if depth == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				want = lifecycleFor(rules, m[1], m[2])
+				hasLifecycle = false
+				depth = bracketDelta(trimmed)
+
+				// Empty resources are generated on a single line
+				if depth == 0 && strings.HasSuffix(trimmed, "{}") && !want.empty() {
+					out = append(out, strings.TrimSuffix(strings.TrimRight(line, " \t"), "}"))
+					out = append(out, want.block()...)
+					out = append(out, "}")
+					continue
+				}
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if ignoreLines != nil {
+			ignoreLines = append(ignoreLines, line)
+			depth += bracketDelta(trimmed)
+			if depth == 2 {
+				out = append(out, mergeIgnoreChanges(ignoreLines, want.ignoreChanges)...)
+				ignoreLines = nil
+			}
+			continue
+		}
+
+		if depth == 1 && (strings.HasPrefix(trimmed, "lifecycle ") || strings.HasPrefix(trimmed, "lifecycle{")) {
+			hasLifecycle = true
+			if strings.HasSuffix(trimmed, "{}") {
+				out = append(out, want.block()...)
+				continue
+			}
+			inLifecycle = true
+			preventSeen, ignoreSeen = false, false
+			depth += bracketDelta(trimmed)
+			out = append(out, line)
+			continue
+		}
+
+		if inLifecycle && depth == 2 {
+			if m := attributeRe.FindStringSubmatch(trimmed + " "); m != nil {
+				switch m[1] {
+				case "prevent_destroy":
+					preventSeen = true
+					if want.preventDestroy {
+						indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+						out = append(out, indent+"prevent_destroy = true")
+						depth += bracketDelta(trimmed)
+						continue
+					}
+				case "ignore_changes":
+					ignoreSeen = true
+					ignoreLines = []string{line}
+					depth += bracketDelta(trimmed)
+					if depth == 2 {
+						out = append(out, mergeIgnoreChanges(ignoreLines, want.ignoreChanges)...)
+						ignoreLines = nil
+					}
+					continue
+				}
+			}
+		}
+
+		depth += bracketDelta(trimmed)
+		if inLifecycle && depth == 1 {
+			inLifecycle = false
+			if want.preventDestroy && !preventSeen {
+				out = append(out, "    prevent_destroy = true")
+			}
+			if len(want.ignoreChanges) > 0 && !ignoreSeen {
+				out = append(out, fmt.Sprintf("    ignore_changes  = [%s]", strings.Join(want.ignoreChanges, ", ")))
+			}
+		}
+		if depth == 0 && !hasLifecycle {
+			out = append(out, want.block()...)
+		}
+		if m := heredocRe.FindStringSubmatch(trimmed); m != nil {
+			heredoc = m[1]
+		}
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+type lifecycleSettings struct {
+	preventDestroy bool
+	ignoreChanges  []string
+}
+
+func (s lifecycleSettings) empty() bool {
+	return !s.preventDestroy && len(s.ignoreChanges) == 0
+}
+
+func lifecycleFor(rules []LifecycleRule, resourceType, name string) lifecycleSettings {
+	var settings lifecycleSettings
+	for _, rule := range rules {
+		if !rule.matches(resourceType, name) {
+			continue
+		}
+		settings.preventDestroy = settings.preventDestroy || rule.PreventDestroy
+		settings.ignoreChanges = append(settings.ignoreChanges, rule.IgnoreChanges...)
+	}
+	return settings
+}
+
+func (s lifecycleSettings) block() []string {
+	if s.empty() {
+		return nil
+	}
+
+	lines := []string{"  lifecycle {"}
+	if s.preventDestroy {
+		lines = append(lines, "    prevent_destroy = true")
+	}
+	if len(s.ignoreChanges) > 0 {
+		lines = append(lines, fmt.Sprintf("    ignore_changes  = [%s]", strings.Join(s.ignoreChanges, ", ")))
+	}
+	lines = append(lines, "  }")
+	return lines
+}
+
+// mergeIgnoreChanges adds attributes to an existing, possibly multi-line,
+// ignore_changes list. A list set to "all" already ignores everything.
+func mergeIgnoreChanges(lines []string, attributes []string) []string {
+	value := strings.Join(lines, " ")
+	start, end := strings.Index(value, "["), strings.LastIndex(value, "]")
+	if len(attributes) == 0 || start < 0 || end < start {
+		return lines
+	}
+
+	var merged []string
+	seen := make(map[string]bool)
+	for _, item := range append(strings.Split(value[start+1:end], ","), attributes...) {
+		item = strings.TrimSpace(item)
+		if item != "" && !seen[item] {
+			seen[item] = true
+			merged = append(merged, item)
+		}
+	}
+
+	indent := lines[0][:len(lines[0])-len(strings.TrimLeft(lines[0], " \t"))]
+	return []string{fmt.Sprintf("%signore_changes = [%s]", indent, strings.Join(merged, ", "))}
+}
@@ -0,0 +1,68 @@
+package tfimport
+
+import "testing"
+
+func TestInjectLifecycle(t *testing.T) {
+	rules := []LifecycleRule{{ResourceType: "google_storage_bucket", PreventDestroy: true}}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "adds a lifecycle block",
+			content: `resource "google_storage_bucket" "assets" {
+  name = "assets"
+}`,
+			want: `resource "google_storage_bucket" "assets" {
+  name = "assets"
+  lifecycle {
+    prevent_destroy = true
+  }
+}`,
+		},
+		{
+			name: "adds to an existing lifecycle block",
+			content: `resource "google_storage_bucket" "assets" {
+  lifecycle {
+    ignore_changes = [labels]
+  }
+}`,
+			want: `resource "google_storage_bucket" "assets" {
+  lifecycle {
+    ignore_changes = [labels]
+    prevent_destroy = true
+  }
+}`,
+		},
+		{
+			name: "skips heredoc bodies",
+			content: `resource "google_storage_bucket" "assets" {
+  cors_json = <<EOT
+}
+resource "google_storage_bucket" "other" {
+EOT
+  name = "assets"
+}`,
+			want: `resource "google_storage_bucket" "assets" {
+  cors_json = <<EOT
+}
+resource "google_storage_bucket" "other" {
+EOT
+  name = "assets"
+  lifecycle {
+    prevent_destroy = true
+  }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := injectLifecycle(tt.content, rules); got != tt.want {
+				t.Errorf("injectLifecycle() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package tfimport
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// movedFile declares the moved blocks of renamed resources
+const movedFile = "moved.tf"
+
+// move is a resource whose address changed since it was generated, e.g.
+// because of a changed naming strategy or mapping
+type move struct {
+	From string
+	To   string
+}
+
+// moveRenamed renames the config of a resource, and its dependents, which was
+// generated at other addresses before, and declares moved blocks so that
+// terraform moves them in state instead of destroying and recreating them. It
+// reports whether the resource was moved; its config then stays where it was
+// generated. Resources are only recognized by the IDs in the manifest, and
+// are looked for in the file the manifest records them in, or else in
+// fallback, the file of the resource they depend on.
+func (r *generator) moveRenamed(m *manifest.Manifest, resource resource.Resource, fallback string) (bool, error) {
+	from, ok := m.AddressOf(string(resource.Type), resource.ID)
+	if !ok || from == resource.Address() {
+		return false, nil
+	}
+	path, ok := m.Owner(from)
+	if !ok {
+		if fallback == "" {
+			return false, nil
+		}
+		path = fallback
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read resource file: %w", err)
+	}
+	fromType, fromName, _ := strings.Cut(from, ".")
+	if !strings.Contains(string(content), fmt.Sprintf("resource %q %q", fromType, fromName)) {
+		return false, nil
+	}
+
+	var moves []move
+	for _, d := range resource.Flatten() {
+		if from, ok := m.AddressOf(string(d.Type), d.ID); ok && from != d.Address() {
+			moves = append(moves, move{From: from, To: d.Address()})
+		}
+	}
+
+	if !r.opts.Force && m.Modified(path, content) && !unchangedSinceGenerated(string(content)) {
+		slog.Warn("File was changed since it was generated, not renaming resources in it. Rename them by hand or pass --force",
+			"file", path, "from", from, "to", resource.Address())
+		return false, ErrModified
+	}
+
+	// Generated files may refer to the renamed resources, see
+	// AddressReferences
+	for owner := range m.Files {
+		file := filepath.Join(r.workingDir, filepath.FromSlash(owner))
+		if file == path {
+			continue
+		}
+		existing, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// Only files as infrasync wrote them are changed
+		if m.Modified(file, existing) {
+			continue
+		}
+		if updated := renameHeader(renameReferences(string(existing), moves), moves); updated != string(existing) {
+			if err := audit.WriteFile(file, []byte(updated), 0644); err != nil {
+				return false, err
+			}
+			m.Rewrite(file, []byte(updated))
+		}
+	}
+
+	updated := renameHeader(renameReferences(renameBlocks(string(content), moves), moves), moves)
+	if err := audit.WriteFile(path, []byte(updated), 0644); err != nil {
+		return false, err
+	}
+	for _, mv := range moves {
+		m.Rename(mv.From, mv.To)
+	}
+	m.Record(path, []byte(updated), resource.Address())
+	if err := m.Save(); err != nil {
+		return false, err
+	}
+
+	if err := declareMoved(r.workingDir, moves); err != nil {
+		return false, fmt.Errorf("failed to declare moved resources: %w", err)
+	}
+	for _, mv := range moves {
+		slog.Info("Moved renamed resource", "from", mv.From, "to", mv.To)
+	}
+	return true, nil
+}
+
+// renameBlocks renames the resource blocks in content declared at the
+// addresses moves are from
+func renameBlocks(content string, moves []move) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		m := resourceHeaderRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		for _, mv := range moves {
+			if mv.From != m[1]+"."+m[2] {
+				continue
+			}
+			_, name, _ := strings.Cut(mv.To, ".")
+			lines[i] = strings.Replace(line, fmt.Sprintf("%q %q", m[1], m[2]), fmt.Sprintf("%q %q", m[1], name), 1)
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renameReferences replaces references to the addresses moves are from
+func renameReferences(content string, moves []move) string {
+	for _, mv := range moves {
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(mv.From) + `\b`)
+		content = re.ReplaceAllString(content, mv.To)
+	}
+	return content
+}
+
+// declareMoved appends the moved blocks of moves to the moved file, once
+func declareMoved(workingDir string, moves []move) error {
+	movedPath := filepath.Join(workingDir, movedFile)
+	for _, mv := range moves {
+		block := fmt.Sprintf(`
+moved {
+  from = %s
+  to   = %s
+}
+`, mv.From, mv.To)
+		if err := appendIfMissing(movedPath, fmt.Sprintf("from = %s\n", mv.From), block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
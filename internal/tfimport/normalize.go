@@ -0,0 +1,157 @@
+package tfimport
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// NormalizeRule strips or rewrites attributes of generated resources whose
+// type matches ResourceType. ResourceType supports glob patterns like
+// "google_pubsub_*" or "*".
+type NormalizeRule struct {
+	ResourceType string
+	Remove       []string
+	Rewrite      map[string]string
+}
+
+// DefaultNormalizeRules drop computed attributes which terraform sometimes
+// writes into generated config and which cause perpetual diffs.
+var DefaultNormalizeRules = []NormalizeRule{
+	{
+		ResourceType: "*",
+		Remove:       []string{"self_link", "etag", "creation_timestamp", "create_time", "update_time"},
+	},
+	{
+		ResourceType: "google_storage_bucket",
+		Remove:       []string{"url", "project_number"},
+	},
+	{
+		ResourceType: "google_sql_database_instance",
+		Remove:       []string{"connection_name", "first_ip_address", "public_ip_address", "private_ip_address", "server_ca_cert"},
+	},
+}
+
+var (
+	resourceHeaderRe = regexp.MustCompile(`^resource\s+"([^"]+)"\s+"([^"]+)"\s*\{`)
+	attributeRe      = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*=[^=]`)
+)
+
+func normalizeContent(content string, rules []NormalizeRule) string {
+	var out []string
+	var depth int
+	var resourceType string
+	// Continuation lines of a removed or rewritten multi-line value are
+	// dropped until its brackets balance or its heredoc ends
+	var skipDepth int
+	var heredoc string
+	var skipHeredoc bool
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if heredoc != "" {
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			if !skipHeredoc {
+				out = append(out, line)
+			}
+			continue
+		}
+		if skipDepth > 0 {
+			skipDepth += bracketDelta(trimmed)
+			continue
+		}
+
+		if depth == 0 {
+			resourceType = ""
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				resourceType = m[1]
+			}
+			depth = bracketDelta(trimmed)
+			out = append(out, line)
+			continue
+		}
+
+		var marker string
+		if m := heredocRe.FindStringSubmatch(trimmed); m != nil {
+			marker = m[1]
+		}
+
+		m := attributeRe.FindStringSubmatch(trimmed + " ")
+		if m == nil || resourceType == "" {
+			depth += bracketDelta(trimmed)
+			heredoc, skipHeredoc = marker, false
+			out = append(out, line)
+			continue
+		}
+
+		remove, rewrite, ok := ruleFor(rules, resourceType, m[1])
+		if !ok {
+			depth += bracketDelta(trimmed)
+			heredoc, skipHeredoc = marker, false
+			out = append(out, line)
+			continue
+		}
+
+		// Multi-line values (lists, maps, function calls, heredocs) are
+		// skipped until their brackets balance again or the heredoc ends.
+		if marker != "" {
+			heredoc, skipHeredoc = marker, true
+		} else {
+			skipDepth = bracketDelta(trimmed)
+		}
+		if remove {
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		out = append(out, fmt.Sprintf("%s%s = %s", indent, m[1], rewrite))
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func ruleFor(rules []NormalizeRule, resourceType, attribute string) (remove bool, rewrite string, ok bool) {
+	for _, rule := range rules {
+		matched, err := path.Match(rule.ResourceType, resourceType)
+		if err != nil || !matched {
+			continue
+		}
+		if value, found := rule.Rewrite[attribute]; found {
+			rewrite, ok = value, true
+			remove = false
+		}
+		for _, r := range rule.Remove {
+			if r == attribute {
+				remove, ok = true, true
+			}
+		}
+	}
+	return remove, rewrite, ok
+}
+
+// bracketDelta returns the number of opened minus closed brackets in line,
+// ignoring the ones inside string literals.
+func bracketDelta(line string) int {
+	var delta int
+	var inString, escaped bool
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && inString:
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '{' || r == '[' || r == '(':
+			delta++
+		case r == '}' || r == ']' || r == ')':
+			delta--
+		}
+	}
+	return delta
+}
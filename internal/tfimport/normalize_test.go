@@ -0,0 +1,92 @@
+package tfimport
+
+import "testing"
+
+func TestNormalizeContent(t *testing.T) {
+	rules := []NormalizeRule{
+		{ResourceType: "google_storage_bucket", Remove: []string{"url", "labels"}},
+		{ResourceType: "google_pubsub_topic", Rewrite: map[string]string{"message_retention_duration": `"86400s"`}},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "removes attributes",
+			content: `resource "google_storage_bucket" "assets" {
+  name = "assets"
+  url  = "gs://assets"
+  labels = {
+    env = "prod"
+  }
+}`,
+			want: `resource "google_storage_bucket" "assets" {
+  name = "assets"
+}`,
+		},
+		{
+			name: "rewrites attributes",
+			content: `resource "google_pubsub_topic" "events" {
+  message_retention_duration = "604800s"
+}`,
+			want: `resource "google_pubsub_topic" "events" {
+  message_retention_duration = "86400s"
+}`,
+		},
+		{
+			name: "resets the resource type after its block",
+			content: `resource "google_storage_bucket" "assets" {
+  name = "assets"
+}
+
+output "assets" {
+  url = google_storage_bucket.assets.url
+}`,
+			want: `resource "google_storage_bucket" "assets" {
+  name = "assets"
+}
+
+output "assets" {
+  url = google_storage_bucket.assets.url
+}`,
+		},
+		{
+			name: "leaves heredoc bodies alone",
+			content: `resource "google_storage_bucket" "assets" {
+  cors_json = <<EOT
+url = "gs://assets"
+}
+EOT
+  url = "gs://assets"
+}`,
+			want: `resource "google_storage_bucket" "assets" {
+  cors_json = <<EOT
+url = "gs://assets"
+}
+EOT
+}`,
+		},
+		{
+			name: "removes heredoc values",
+			content: `resource "google_storage_bucket" "assets" {
+  url = <<-EOT
+    {
+  EOT
+  name = "assets"
+}`,
+			want: `resource "google_storage_bucket" "assets" {
+  name = "assets"
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeContent(tt.content, rules); got != tt.want {
+				t.Errorf("normalizeContent() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
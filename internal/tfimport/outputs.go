@@ -0,0 +1,89 @@
+package tfimport
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/version"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// OutputsFile exposes the commonly referenced values of the generated
+// resources, such as bucket URLs and SQL connection names, at the top of the
+// project
+const OutputsFile = "outputs.tf"
+
+// WriteOutputs regenerates the outputs file of the project in workingDir for
+// the generated resources, owners maps their addresses to the files they are
+// in. Only resources in the root module, whose files are in workingDir itself,
+// can be referenced from it, so the others get no outputs. A file changed since
+// it was generated is left alone and the outputs are written next to it with a
+// .new suffix.
+func WriteOutputs(workingDir string, owners map[string]string) error {
+	path := filepath.Join(workingDir, OutputsFile)
+	var addresses []string
+	for address, file := range owners {
+		if filepath.Dir(file) == filepath.Clean(workingDir) {
+			addresses = append(addresses, address)
+		}
+	}
+	body := outputsContent(addresses)
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", OutputsFile, err)
+	}
+	if err != nil && body == "" {
+		return nil
+	}
+	if _, previous, ok := ParseHeader(string(existing)); ok && previous == body {
+		return nil
+	}
+
+	h := Header{RunID: audit.RunID(), Hash: bodyHash(body)}
+	h.Version, _ = version.Info()
+	content := h.String() + body
+
+	if len(existing) > 0 && !unchangedSinceGenerated(string(existing)) {
+		newPath := path + ".new"
+		if err := audit.WriteFile(newPath, []byte(content), 0644); err != nil {
+			return err
+		}
+		slog.Warn("Outputs file was changed since it was generated, not overwriting it. Merge the changes by hand",
+			"file", path, "new", newPath)
+		return nil
+	}
+
+	return audit.WriteFile(path, []byte(content), 0644)
+}
+
+// outputsContent returns an output per attribute of google.OutputAttributes of
+// each resource at addresses, sorted by address
+func outputsContent(addresses []string) string {
+	addresses = slices.Sorted(slices.Values(addresses))
+
+	var b strings.Builder
+	for _, address := range addresses {
+		resourceType, name, ok := strings.Cut(address, ".")
+		if !ok {
+			continue
+		}
+		for _, attribute := range google.OutputAttributes[resource.Type(resourceType)] {
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			// Output names are unique across providers, types and resources
+			fmt.Fprintf(&b, "output %q {\n", fmt.Sprintf("%s_%s_%s", resourceType, name, attribute))
+			fmt.Fprintf(&b, "  description = %q\n", fmt.Sprintf("%s of %s", attribute, address))
+			fmt.Fprintf(&b, "  value       = %s.%s\n", address, attribute)
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
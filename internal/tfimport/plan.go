@@ -0,0 +1,87 @@
+package tfimport
+
+import (
+	"fmt"
+	"slices"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// PlanSummary counts the changes of a plan like terraform does, where a
+// replacement is both an add and a destroy, and lists them per resource
+type PlanSummary struct {
+	Import    int
+	Add       int
+	Change    int
+	Destroy   int
+	Resources []PlannedChange
+}
+
+// PlannedChange is what a plan does to one resource
+type PlannedChange struct {
+	Address string
+	// Actions are the terraform actions, such as create, update and delete
+	Actions []string
+	Import  bool
+}
+
+// Empty reports whether the plan changes nothing
+func (s PlanSummary) Empty() bool {
+	return len(s.Resources) == 0
+}
+
+// Destroyed returns the addresses of the resources the plan destroys, whether
+// or not it creates them again
+func (s PlanSummary) Destroyed() []string {
+	var addresses []string
+	for _, change := range s.Resources {
+		if slices.Contains(change.Actions, string(tfjson.ActionDelete)) {
+			addresses = append(addresses, change.Address)
+		}
+	}
+	return addresses
+}
+
+func (s PlanSummary) String() string {
+	return fmt.Sprintf("%d to import, %d to add, %d to change, %d to destroy",
+		s.Import, s.Add, s.Change, s.Destroy)
+}
+
+// summarizePlan summarizes the resource changes of a plan. Reads of data
+// sources and resources which are left as they are, and not imported, are
+// left out.
+func summarizePlan(plan *tfjson.Plan) PlanSummary {
+	var summary PlanSummary
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil {
+			continue
+		}
+		actions := rc.Change.Actions
+		importing := rc.Change.Importing != nil
+		if (actions.NoOp() || actions.Read()) && !importing {
+			continue
+		}
+
+		switch {
+		case actions.Replace():
+			summary.Add++
+			summary.Destroy++
+		case actions.Create():
+			summary.Add++
+		case actions.Update():
+			summary.Change++
+		case actions.Delete():
+			summary.Destroy++
+		}
+		if importing {
+			summary.Import++
+		}
+
+		change := PlannedChange{Address: rc.Address, Import: importing}
+		for _, action := range actions {
+			change.Actions = append(change.Actions, string(action))
+		}
+		summary.Resources = append(summary.Resources, change)
+	}
+	return summary
+}
@@ -0,0 +1,170 @@
+package tfimport
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CrossProjectReference is an attribute holding the ID of a resource which
+// may live in another project, e.g. the topic of a subscription. Pattern
+// captures the project and name of the referenced resource from the ID.
+type CrossProjectReference struct {
+	ResourceType string
+	Attribute    string
+	DataSource   string
+	Pattern      *regexp.Regexp
+}
+
+// CrossProjectReferences are the attributes whose IDs are replaced by a data
+// source when they point into another configured project
+var CrossProjectReferences = []CrossProjectReference{
+	{
+		ResourceType: "google_pubsub_subscription",
+		Attribute:    "topic",
+		DataSource:   "google_pubsub_topic",
+		Pattern:      regexp.MustCompile(`^projects/([^/]+)/topics/([^/]+)$`),
+	},
+	{
+		ResourceType: "google_pubsub_subscription",
+		Attribute:    "dead_letter_topic",
+		DataSource:   "google_pubsub_topic",
+		Pattern:      regexp.MustCompile(`^projects/([^/]+)/topics/([^/]+)$`),
+	},
+	{
+		// Shared VPC networks live in the host project
+		ResourceType: "google_sql_database_instance",
+		Attribute:    "private_network",
+		DataSource:   "google_compute_network",
+		Pattern:      regexp.MustCompile(`^projects/([^/]+)/global/networks/([^/]+)$`),
+	},
+}
+
+const referencesFile = "references.tf"
+
+var quotedValueRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*\s*=\s*("(?:[^"\\]|\\.)*")$`)
+
+// dataSource is a data source generated config refers to instead of the
+// literal ID of a resource in another project
+type dataSource struct {
+	Type    string
+	Name    string
+	Project string
+	// ResourceName is the name of the referenced resource in its project
+	ResourceName string
+}
+
+func (d dataSource) address() string {
+	return fmt.Sprintf("data.%s.%s", d.Type, d.Name)
+}
+
+// rewriteReferences replaces IDs of resources in the given projects, other
+// than project itself, with references to data sources, which are returned
+// for declaring. IDs in projects infrasync doesn't manage are left as they
+// are, as terraform may not be able to read them.
+func rewriteReferences(content, project string, projects []string) (string, []dataSource) {
+	var out []string
+	var sources []dataSource
+	var resourceType string
+	var depth int
+	// Entries of map and list values, e.g. labels, are not attributes
+	var valueDepth int
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if valueDepth > 0 {
+			valueDepth += bracketDelta(trimmed)
+			depth += bracketDelta(trimmed)
+			out = append(out, line)
+			continue
+		}
+
+		if depth == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				resourceType = m[1]
+			}
+			depth += bracketDelta(trimmed)
+			out = append(out, line)
+			continue
+		}
+		depth += bracketDelta(trimmed)
+		if attributeRe.MatchString(trimmed + " ") {
+			valueDepth = bracketDelta(trimmed)
+		}
+
+		source, ok := crossProjectSource(resourceType, trimmed, project, projects)
+		if !ok {
+			out = append(out, line)
+			continue
+		}
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+
+		attribute, _, _ := strings.Cut(trimmed, "=")
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		out = append(out, fmt.Sprintf("%s%s = %s.id", indent, strings.TrimSpace(attribute), source.address()))
+	}
+
+	return strings.Join(out, "\n"), sources
+}
+
+// crossProjectSource returns the data source of the resource the attribute
+// on line refers to, if it is in another of the given projects
+func crossProjectSource(resourceType, line, project string, projects []string) (dataSource, bool) {
+	m := attributeRe.FindStringSubmatch(line + " ")
+	if m == nil {
+		return dataSource{}, false
+	}
+	value := quotedValueRe.FindStringSubmatch(line)
+	if value == nil {
+		return dataSource{}, false
+	}
+	id, err := strconv.Unquote(value[1])
+	if err != nil {
+		return dataSource{}, false
+	}
+
+	for _, ref := range CrossProjectReferences {
+		if ref.ResourceType != resourceType || ref.Attribute != m[1] {
+			continue
+		}
+		parts := ref.Pattern.FindStringSubmatch(id)
+		if parts == nil || parts[1] == project || !slices.Contains(projects, parts[1]) {
+			continue
+		}
+		return dataSource{
+			Type:         ref.DataSource,
+			Name:         invalidNameChars.ReplaceAllString(parts[1]+"_"+parts[2], "_"),
+			Project:      parts[1],
+			ResourceName: parts[2],
+		}, true
+	}
+	return dataSource{}, false
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// declareReferences declares the data sources generated config refers to in
+// the working directory terraform runs in
+func declareReferences(workingDir string, sources []dataSource) error {
+	referencesPath := filepath.Join(workingDir, referencesFile)
+
+	for _, s := range sources {
+		block := fmt.Sprintf(`
+data "%s" "%s" {
+  project = "%s"
+  name    = "%s"
+}
+`, s.Type, s.Name, s.Project, s.ResourceName)
+		if err := appendIfMissing(referencesPath, fmt.Sprintf(`data "%s" "%s"`, s.Type, s.Name), block); err != nil {
+			return fmt.Errorf("failed to declare data source: %w", err)
+		}
+	}
+
+	return nil
+}
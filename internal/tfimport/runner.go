@@ -1,101 +1,475 @@
 package tfimport
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
+	"strings"
 
-	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// THINK: Should this generator be via docker?
-
 type generator struct {
 	workingDir string
+	opts       Options
+	tf         terraform
+}
+
+// Options tune how the generator post-processes generated config.
+type Options struct {
+	NormalizeRules []NormalizeRule
+	LifecycleRules []LifecycleRule
+	Labels         map[string]string
+	// ManifestPath is the manifest of generated files, which keeps files
+	// changed since they were generated from being overwritten. Files are
+	// not tracked if it is empty.
+	ManifestPath string
+	// Force changes files even if they were changed since they were generated
+	Force bool
+	// Runner is where terraform runs, with DockerImage as the image of
+	// RunnerDocker
+	Runner      Runner
+	DockerImage string
+	// Credentials terraform runs with, application default credentials if
+	// zero. They are passed to terraform alone, not set for the process.
+	Credentials providers.Credentials
+	// Layout is the path template config is written to, see DefaultLayout
+	Layout string
+	// Projects are the IDs of every configured project. References to
+	// resources in one of them other than the resource's own project are
+	// generated as data sources, see CrossProjectReferences.
+	Projects []string
+	// SourceFiles reports whether the sources in the config of a resource,
+	// see SourceAttributes, are written to files of their own rather than
+	// inline
+	SourceFiles func(resource resource.Resource) bool
+	// Templates render the generated blocks of the resources they match, see
+	// TemplateRule
+	Templates []TemplateRule
+	// StateLocked is set when the caller holds the state lock, so terraform
+	// plans without locking the state itself
+	StateLocked bool
+	// Check is called with the planned attributes of every resource in the
+	// plan, keyed by address, before the generated config of a resource is
+	// written.
+	// Returning an error wrapping ErrRejected skips the resource.
+	Check func(ctx context.Context, resource resource.Resource, attributes map[string]map[string]any) error
 }
 
 var ErrAlreadyExists = fmt.Errorf("resource_already_exists")
 
-func New(workingDir string) (*generator, error) {
-	if err := checkIfRunnerInstalled(); err != nil {
+var ErrRejected = fmt.Errorf("resource_rejected")
+
+// ErrModified is returned when the config of a resource would change a file
+// which was changed since infrasync generated it
+var ErrModified = fmt.Errorf("file_modified")
+
+func New(ctx context.Context, workingDir string, opts Options) (*generator, error) {
+	var tf terraform
+	var err error
+	if opts.Runner == RunnerDocker {
+		tf, err = newDockerTerraform(workingDir, opts.DockerImage, opts.Credentials, !opts.StateLocked)
+	} else {
+		tf, err = newExecTerraform(ctx, workingDir, opts.Credentials, !opts.StateLocked)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("generator not installed: %w", err)
 	}
 
 	return &generator{
 		workingDir: workingDir,
+		opts:       opts,
+		tf:         tf,
 	}, nil
 }
 
-func checkIfRunnerInstalled() error {
-	cmd := exec.Command("terraform", "version")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("terraform is not installed or not in PATH: %w", err)
+// Import generates the config of a top-level resource, without its
+// dependents, which are generated into its file by ImportDependent once it
+// is imported. The dependents are renamed along with the resource, see
+// moveRenamed.
+func (r *generator) Import(ctx context.Context, resource resource.Resource) error {
+	m, err := r.manifest()
+	if err != nil {
+		return err
 	}
-	return nil
+
+	resourceFilePath := filepath.Join(r.workingDir, LayoutPath(r.opts.Layout, resource))
+	existing, err := readResourceFile(resourceFilePath, resource)
+	if err != nil {
+		return err
+	}
+
+	// Resources renamed since they were generated are moved rather than
+	// generated again
+	if moved, err := r.moveRenamed(m, resource, ""); err != nil {
+		return err
+	} else if moved {
+		return ErrAlreadyExists
+	}
+	// Resources generated under an earlier layout stay where they are
+	if owner, ok := m.Owner(resource.Address()); ok && owner != resourceFilePath {
+		if _, err := os.Stat(owner); err == nil {
+			return ErrAlreadyExists
+		}
+	}
+
+	resource.Dependents = nil
+	return r.generate(ctx, m, resource, resourceFilePath, existing)
+}
+
+// ImportDependent generates the config of a dependent of root, without its
+// own dependents, into the file root was generated in
+func (r *generator) ImportDependent(ctx context.Context, dependent, root resource.Resource) error {
+	m, err := r.manifest()
+	if err != nil {
+		return err
+	}
+
+	resourceFilePath := filepath.Join(r.workingDir, LayoutPath(r.opts.Layout, root))
+	if owner, ok := m.Owner(root.Address()); ok {
+		if _, err := os.Stat(owner); err == nil {
+			resourceFilePath = owner
+		}
+	}
+	existing, err := readResourceFile(resourceFilePath, dependent)
+	if err != nil {
+		return err
+	}
+
+	if moved, err := r.moveRenamed(m, dependent, resourceFilePath); err != nil {
+		return err
+	} else if moved {
+		return ErrAlreadyExists
+	}
+
+	dependent.Dependents = nil
+	return r.generate(ctx, m, dependent, resourceFilePath, existing)
 }
 
-func (r *generator) Import(ctx context.Context, resource google.Resource) error {
+// readResourceFile returns the content of the file the config of resource
+// goes to, or ErrAlreadyExists if it was generated in it before. Files may
+// hold several resources, so the resource is looked up by its block rather
+// than by file.
+func readResourceFile(path string, resource resource.Resource) ([]byte, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read resource file: %w", err)
+	}
+	if strings.Contains(string(existing), fmt.Sprintf("resource %q %q", resource.Type, resource.Name)) {
+		return nil, ErrAlreadyExists
+	}
+	return existing, nil
+}
+
+// generate generates the config of a resource, whose import block was saved,
+// into the file at resourceFilePath with the existing content
+func (r *generator) generate(ctx context.Context, m *manifest.Manifest, resource resource.Resource, resourceFilePath string, existing []byte) (err error) {
+	ctx, span := telemetry.Start(ctx, "terraform.generate_config",
+		attribute.String("resource.type", string(resource.Type)),
+		attribute.String("resource.id", resource.ID))
+	defer func() { telemetry.End(span, err) }()
+
 	slog.Info("Importing resource",
 		"type", resource.Type,
 		"name", resource.Name,
 		"id", resource.ID)
 
-	resourceDir := filepath.Join(r.workingDir, "resources", resource.Provider.Type.String(), resource.Provider.ProjectID, resource.Service.String())
-	resourceFilePath := filepath.Join(resourceDir, fmt.Sprintf("%s.tf", resource.Name))
+	if resourceFilePath == importBlockPath(r.workingDir, r.opts.Layout, resource) {
+		return fmt.Errorf("layout places %s in its import block file %s", resource.Address(), resourceFilePath)
+	}
 
-	if _, err := os.Stat(resourceFilePath); err == nil {
-		return ErrAlreadyExists
+	if err := os.MkdirAll(filepath.Dir(resourceFilePath), 0755); err != nil {
+		return fmt.Errorf("failed to create resource directory: %w", err)
 	}
 
-	if _, err := os.Stat(resourceDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(resourceDir, 0755); err != nil {
-			return fmt.Errorf("failed to create resource directory: %w", err)
-		}
+	// Config is generated in a staging directory so that it is only written
+	// once it passed the checks
+	stagingDir, cleanup, err := r.stagingDir("import-")
+	if err != nil {
+		return err
 	}
+	defer cleanup()
+
+	generatedPath := path.Join(stagingDir, "generated.tf")
+	planPath := path.Join(stagingDir, "plan")
 
-	cmd := exec.CommandContext(ctx, "terraform", "plan",
-		fmt.Sprintf("-generate-config-out=%s", resourceFilePath))
-	cmd.Dir = r.workingDir
+	if _, err := r.tf.Plan(ctx, planPath, generatedPath); err != nil {
+		fixed, fixErr := r.replanFixed(ctx, resource, generatedPath, planPath)
+		if !fixed {
+			return fmt.Errorf("failed to import resource: %w", err)
+		}
+		if fixErr != nil {
+			return fmt.Errorf("failed to import resource with corrected config: %w", fixErr)
+		}
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var attributes map[string]map[string]any
+	if r.opts.Check != nil || len(r.opts.Templates) > 0 {
+		attributes, err = r.plannedAttributes(ctx, planPath)
+		if err != nil {
+			return err
+		}
+	}
+	if r.opts.Check != nil {
+		if err := r.opts.Check(ctx, resource, attributes); err != nil {
+			return err
+		}
+	}
 
-	err := cmd.Run()
+	generated, err := os.ReadFile(filepath.Join(r.workingDir, filepath.FromSlash(generatedPath)))
 	if err != nil {
-		slog.Error("Import failed",
-			"stderr", stderr.String())
-		return fmt.Errorf("failed to import resource: %w", err)
+		return fmt.Errorf("failed to read generated config: %w", err)
+	}
+
+	content, variables := r.postProcess(string(generated))
+	content, sources := rewriteReferences(content, resource.Provider.ProjectID, r.opts.Projects)
+	if hasAddressReferences(content) {
+		index, err := indexAddresses(r.workingDir, resource.Provider.ProjectID)
+		if err != nil {
+			return err
+		}
+		content = resolveAddresses(content, resource.Provider.ProjectID, index)
+	}
+	var sourceFiles []sourceFile
+	if r.opts.SourceFiles != nil && r.opts.SourceFiles(resource) {
+		content, sourceFiles, err = extractSources(content, filepath.Dir(resourceFilePath), r.workingDir)
+		if err != nil {
+			return fmt.Errorf("failed to move sources to files: %w", err)
+		}
+	}
+	if len(r.opts.Templates) > 0 {
+		content, err = applyTemplates(content, r.opts.Templates, resource, attributes)
+		if err != nil {
+			return err
+		}
+	}
+	if err := r.writeConfig(m, resource, resourceFilePath, existing, content); err != nil {
+		if errors.Is(err, ErrModified) {
+			return err
+		}
+		return fmt.Errorf("failed to write generated config: %w", err)
 	}
 
+	for _, source := range sourceFiles {
+		if err := audit.WriteFile(source.Path, []byte(source.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write source file: %w", err)
+		}
+	}
+
+	// The variables are referenced from the generated file, so they are
+	// declared in its module
+	if err := declareSensitive(filepath.Dir(resourceFilePath), variables); err != nil {
+		return fmt.Errorf("failed to handle sensitive values: %w", err)
+	}
+
+	if err := declareReferences(r.workingDir, sources); err != nil {
+		return fmt.Errorf("failed to handle cross-project references: %w", err)
+	}
+
+	recordImport(resource)
+
 	slog.Info("Import succeeded",
 		"resource", resource.ID)
 
 	return nil
 }
 
-func (r *generator) CleanupImportBlocks(resource google.Resource) error {
-	importBlockPath := filepath.Join(r.workingDir, fmt.Sprintf("%s.tf", resource.Name))
-	if err := os.Remove(importBlockPath); err != nil {
+// replanFixed corrects config terraform generated but failed to plan, see
+// generatedFixes, and plans again with it. It reports whether any fix
+// applied; the corrected config replaces the generated one.
+func (r *generator) replanFixed(ctx context.Context, resource resource.Resource, generatedPath, planPath string) (bool, error) {
+	generatedFile := filepath.Join(r.workingDir, filepath.FromSlash(generatedPath))
+	generated, err := os.ReadFile(generatedFile)
+	if err != nil {
+		return false, nil
+	}
+	content, applied := fixGenerated(string(generated), generatedFixes)
+	if len(applied) == 0 {
+		return false, nil
+	}
+	for _, fix := range applied {
+		slog.Warn("Generated config was corrected", "resource", resource.Address(), "fix", fix)
+	}
+
+	if err := os.WriteFile(generatedFile, []byte(content), 0644); err != nil {
+		return true, err
+	}
+
+	// Config outside the root module isn't planned, so the corrected config
+	// is copied into it for the plan
+	planned := filepath.Join(r.workingDir, "infrasync_"+path.Base(path.Dir(generatedPath))+".tf")
+	if err := os.WriteFile(planned, []byte(content), 0644); err != nil {
+		return true, err
+	}
+	defer os.Remove(planned)
+
+	_, err = r.tf.Plan(ctx, planPath, "")
+	return true, err
+}
+
+// postProcess applies the configured rewrites to generated config and moves
+// sensitive values into variables, which are returned for declaring
+func (r *generator) postProcess(content string) (string, []sensitiveVariable) {
+	if len(r.opts.NormalizeRules) > 0 {
+		content = normalizeContent(content, r.opts.NormalizeRules)
+	}
+	if len(r.opts.LifecycleRules) > 0 {
+		content = injectLifecycle(content, r.opts.LifecycleRules)
+	}
+	if len(r.opts.Labels) > 0 {
+		content = injectLabels(content, r.opts.Labels)
+	}
+	return extractSensitive(content)
+}
+
+// plannedAttributes returns the attributes of every resource in the saved plan,
+// keyed by address
+func (r *generator) plannedAttributes(ctx context.Context, planPath string) (map[string]map[string]any, error) {
+	plan, err := r.tf.ShowPlanFile(ctx, planPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	attributes := make(map[string]map[string]any, len(plan.ResourceChanges))
+	for _, change := range plan.ResourceChanges {
+		if change.Change == nil {
+			continue
+		}
+		after, _ := change.Change.After.(map[string]any)
+		attributes[change.Address] = after
+	}
+	return attributes, nil
+}
+
+// recordImport records the state entries the import blocks of resource add
+// once applied
+func recordImport(resource resource.Resource) {
+	audit.State(audit.ActionImport, fmt.Sprintf("%s.%s", resource.Type, resource.Name), nil)
+	for _, d := range resource.Dependents {
+		recordImport(d)
+	}
+}
+
+func (r *generator) CleanupImportBlocks(resource resource.Resource) error {
+	if err := audit.Remove(importBlockPath(r.workingDir, r.opts.Layout, resource)); err != nil {
 		return fmt.Errorf("failed to remove import block file: %w", err)
 	}
 	return nil
 }
 
-func (r *generator) Initialize(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, "terraform", "init")
-	cmd.Dir = r.workingDir
+// Initialize runs terraform init after checking that terraform is recent
+// enough to generate config
+func (r *generator) Initialize(ctx context.Context) (err error) {
+	ctx, span := telemetry.Start(ctx, "terraform.init")
+	defer func() { telemetry.End(span, err) }()
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	v, err := r.tf.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get terraform version: %w", err)
+	}
+	if v.LessThan(minVersion) {
+		return fmt.Errorf("terraform %s is too old, %s or later is needed to generate config", v, minVersion)
+	}
 
-	if err := cmd.Run(); err != nil {
+	if err := r.tf.Init(ctx); err != nil {
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
 	return nil
 }
+
+// StateList returns the addresses of all resources in state
+func (r *generator) StateList(ctx context.Context) ([]string, error) {
+	state, err := r.tf.ShowState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list state: %w", err)
+	}
+	return stateAddresses(state), nil
+}
+
+// StateRemove removes resources from state without destroying them
+func (r *generator) StateRemove(ctx context.Context, addresses ...string) error {
+	for _, address := range addresses {
+		err := r.tf.StateRm(ctx, address)
+		audit.State(audit.ActionDelete, address, err)
+		if err != nil {
+			return fmt.Errorf("failed to remove %s from state: %w", address, err)
+		}
+	}
+	return nil
+}
+
+var ErrPlanNotEmpty = fmt.Errorf("plan_not_empty")
+
+// Plan runs terraform plan against the working directory and summarizes the
+// changes it would make
+func (r *generator) Plan(ctx context.Context) (summary PlanSummary, err error) {
+	ctx, span := telemetry.Start(ctx, "terraform.plan")
+	defer func() { telemetry.End(span, err) }()
+
+	stagingDir, cleanup, err := r.stagingDir("plan-")
+	if err != nil {
+		return PlanSummary{}, err
+	}
+	defer cleanup()
+
+	planPath := path.Join(stagingDir, "plan")
+	changes, err := r.tf.Plan(ctx, planPath, "")
+	if err != nil {
+		return PlanSummary{}, fmt.Errorf("failed to run plan: %w", err)
+	}
+	if !changes {
+		return PlanSummary{}, nil
+	}
+
+	plan, err := r.tf.ShowPlanFile(ctx, planPath)
+	if err != nil {
+		return PlanSummary{}, fmt.Errorf("failed to read plan: %w", err)
+	}
+	return summarizePlan(plan), nil
+}
+
+// Verify runs terraform plan against the working directory and returns
+// ErrPlanNotEmpty with the summary of the changes if the configuration does
+// not match state
+func (r *generator) Verify(ctx context.Context) (PlanSummary, error) {
+	summary, err := r.Plan(ctx)
+	if err != nil {
+		return PlanSummary{}, err
+	}
+	if !summary.Empty() {
+		return summary, ErrPlanNotEmpty
+	}
+	return summary, nil
+}
+
+// stagingDir creates a directory for plans and generated config in the
+// gitignored .infrasync directory and returns its slash separated path
+// relative to the working directory, which is all the docker runner mounts,
+// and a function removing it
+func (r *generator) stagingDir(prefix string) (string, func(), error) {
+	parent := filepath.Join(r.workingDir, ".infrasync")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, prefix)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	rel, err := filepath.Rel(r.workingDir, dir)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return filepath.ToSlash(rel), cleanup, nil
+}
@@ -0,0 +1,163 @@
+package tfimport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+)
+
+// SensitiveAttributes lists attributes per resource type whose values can't be
+// read back from the cloud and must be supplied as sensitive variables.
+// Attributes terraform marks with a "# sensitive" comment are handled as well.
+var SensitiveAttributes = map[string][]string{
+	"google_sql_user":              {"password"},
+	"google_sql_database_instance": {"root_password"},
+	"google_service_account_key":   {"private_key"},
+	"google_storage_hmac_key":      {"secret"},
+}
+
+const (
+	sensitiveVariablesFile = "sensitive_variables.tf"
+	tfvarsExampleFile      = "terraform.tfvars.example"
+)
+
+var heredocRe = regexp.MustCompile(`=\s*<<-?([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+
+type sensitiveVariable struct {
+	Name         string
+	ResourceType string
+	Attribute    string
+}
+
+// extractSensitive replaces sensitive attribute values in generated config with
+// references to variables and returns the variables which need declaring.
+func extractSensitive(content string) (string, []sensitiveVariable) {
+	var out []string
+	var variables []sensitiveVariable
+	var depth int
+	var resourceType, name string
+	// Continuation lines of a replaced multi-line value are dropped until its
+	// brackets balance or its heredoc ends
+	var skipDepth int
+	var heredoc string
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if heredoc != "" {
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			continue
+		}
+		if skipDepth > 0 {
+			skipDepth += bracketDelta(trimmed)
+			continue
+		}
+
+		if depth == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				resourceType, name = m[1], m[2]
+				depth = bracketDelta(trimmed)
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if m := attributeRe.FindStringSubmatch(trimmed + " "); m != nil && depth == 1 &&
+			isSensitive(resourceType, m[1], trimmed) {
+			variable := sensitiveVariable{
+				Name:         fmt.Sprintf("%s_%s", name, m[1]),
+				ResourceType: resourceType,
+				Attribute:    m[1],
+			}
+			variables = append(variables, variable)
+
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			out = append(out, fmt.Sprintf("%s%s = var.%s", indent, m[1], variable.Name))
+
+			if marker := heredocRe.FindStringSubmatch(trimmed); marker != nil {
+				heredoc = marker[1]
+			} else {
+				skipDepth = bracketDelta(trimmed)
+			}
+			continue
+		}
+
+		depth += bracketDelta(trimmed)
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n"), variables
+}
+
+func isSensitive(resourceType, attribute, line string) bool {
+	if strings.HasSuffix(line, "# sensitive") {
+		return true
+	}
+	for _, a := range SensitiveAttributes[resourceType] {
+		if a == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+// declareSensitive declares the variables extracted from generated config,
+// plus an example tfvars entry, in dir, the module of the config referring to
+// them. Secret material is never written to disk.
+func declareSensitive(dir string, variables []sensitiveVariable) error {
+	variablesPath := filepath.Join(dir, sensitiveVariablesFile)
+	examplePath := filepath.Join(dir, tfvarsExampleFile)
+
+	for _, v := range variables {
+		block := fmt.Sprintf(`
+variable "%s" {
+  description = "%s of %s"
+  type        = string
+  sensitive   = true
+}
+`, v.Name, v.Attribute, v.ResourceType)
+		if err := appendIfMissing(variablesPath, fmt.Sprintf(`variable "%s"`, v.Name), block); err != nil {
+			return fmt.Errorf("failed to declare sensitive variable: %w", err)
+		}
+
+		entry := fmt.Sprintf("%s = \"\"\n", v.Name)
+		if err := appendIfMissing(examplePath, "\n"+v.Name+" =", entry); err != nil {
+			return fmt.Errorf("failed to update %s: %w", tfvarsExampleFile, err)
+		}
+	}
+
+	return nil
+}
+
+func appendIfMissing(filePath, marker, content string) error {
+	existing, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if strings.Contains(string(existing), marker) {
+		return nil
+	}
+	if len(existing) == 0 {
+		content = "# Generated by InfraSync\n" + content
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	action := audit.ActionModify
+	if len(existing) == 0 {
+		action = audit.ActionCreate
+	}
+	audit.File(action, filePath, err)
+	return err
+}
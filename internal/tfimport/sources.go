@@ -0,0 +1,124 @@
+package tfimport
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sourceAttribute is an attribute holding a source, such as the definition of
+// a workflow, which can be written to a file of its own
+type sourceAttribute struct {
+	Attribute string
+	// Extension is the extension of the file the source is written to
+	Extension string
+}
+
+// SourceAttributes are the attributes whose sources are written to files when
+// SourceFiles is set, by resource type
+var SourceAttributes = map[string]sourceAttribute{
+	// Definitions are YAML or JSON, which YAML is a superset of
+	"google_workflows_workflow": {Attribute: "source_contents", Extension: ".yaml"},
+}
+
+// sourceFile is a source moved out of generated config
+type sourceFile struct {
+	Path    string
+	Content string
+}
+
+// extractSources replaces the sources in content with file() calls reading
+// them from files in dir, named after their resource, and returns the files
+// to write. Paths are relative to workingDir, the root module.
+func extractSources(content, dir, workingDir string) (string, []sourceFile, error) {
+	var out []string
+	var files []sourceFile
+	var resourceType, resourceName string
+	var depth int
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if depth == 0 {
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				resourceType, resourceName = m[1], m[2]
+			}
+		}
+		source, ok := SourceAttributes[resourceType]
+		m := attributeRe.FindStringSubmatch(trimmed + " ")
+		if depth != 1 || !ok || m == nil || m[1] != source.Attribute {
+			depth += bracketDelta(trimmed)
+			out = append(out, line)
+			continue
+		}
+
+		_, value, _ := strings.Cut(trimmed, "=")
+		value = strings.TrimSpace(value)
+		var text string
+		if marker := heredocRe.FindStringSubmatch(trimmed); marker != nil {
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != marker[1] {
+				end++
+			}
+			if end == len(lines) {
+				return "", nil, fmt.Errorf("unterminated heredoc in %s.%s", resourceType, resourceName)
+			}
+			body := lines[i+1 : end]
+			if strings.HasPrefix(value, "<<-") {
+				body = dedent(body)
+			}
+			text = strings.Join(body, "\n") + "\n"
+			i = end
+		} else {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				// Not a literal, e.g. already a file() call
+				out = append(out, line)
+				continue
+			}
+			text = unquoted
+		}
+		// Literals escape template sequences, which files don't
+		text = strings.NewReplacer("$${", "${", "%%{", "%{").Replace(text)
+
+		path := filepath.Join(dir, resourceName+source.Extension)
+		rel, err := filepath.Rel(workingDir, path)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to locate source file: %w", err)
+		}
+		files = append(files, sourceFile{Path: path, Content: text})
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		out = append(out, fmt.Sprintf(`%s%s = file("${path.module}/%s")`, indent, source.Attribute, filepath.ToSlash(rel)))
+	}
+
+	return strings.Join(out, "\n"), files, nil
+}
+
+// dedent removes the leading whitespace all non-blank lines share, as
+// terraform does for <<- heredocs
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return lines
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent {
+			out[i] = line[indent:]
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package tfimport
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// TemplateRule renders the generated blocks of resources whose type and name
+// match the given glob patterns with a Go text/template, e.g. to add comments
+// or wrap them in a module call. An empty Name matches every resource. The
+// template is executed with a TemplateData and its output replaces the block.
+type TemplateRule struct {
+	ResourceType string
+	Name         string
+	Template     *template.Template
+}
+
+// TemplateData is the resource a template renders the block of
+type TemplateData struct {
+	Type    string
+	Name    string
+	Address string
+	ID      string
+	Project string
+	Service string
+	// Block is the generated block, after every other rewrite
+	Block string
+	// Body is the block without its first and last line
+	Body string
+	// Attributes are the planned attributes of the resource
+	Attributes map[string]any
+}
+
+// templateFuncs are the functions templates may call besides the built-in
+// ones
+var templateFuncs = template.FuncMap{
+	// indent prefixes every non-empty line of s with n spaces
+	"indent": func(n int, s string) string {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = strings.Repeat(" ", n) + line
+			}
+		}
+		return strings.Join(lines, "\n")
+	},
+	"quote": strconv.Quote,
+}
+
+// ParseTemplate parses a template file. Templates are text/templates
+// whatever their extension, so HCL files with actions work too.
+func ParseTemplate(file string) (*template.Template, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	t, err := template.New(filepath.Base(file)).Funcs(templateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return t, nil
+}
+
+func (r TemplateRule) matches(resourceType, name string) bool {
+	if matched, err := path.Match(r.ResourceType, resourceType); err != nil || !matched {
+		return false
+	}
+	if r.Name == "" {
+		return true
+	}
+	matched, err := path.Match(r.Name, name)
+	return err == nil && matched
+}
+
+// applyTemplates replaces the blocks of root and its dependents in
+// content which a rule matches with their rendered template. The first
+// matching rule applies.
+func applyTemplates(content string, rules []TemplateRule, root resource.Resource, attributes map[string]map[string]any) (string, error) {
+	resources := map[string]resource.Resource{}
+	for _, r := range root.Flatten() {
+		resources[r.Address()] = r
+	}
+
+	var out, block []string
+	var rule *TemplateRule
+	var address string
+	var depth int
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 && block == nil {
+			m := resourceHeaderRe.FindStringSubmatch(trimmed)
+			if m == nil {
+				out = append(out, line)
+				continue
+			}
+			address, rule = m[1]+"."+m[2], nil
+			for i := range rules {
+				if rules[i].matches(m[1], m[2]) {
+					rule = &rules[i]
+					break
+				}
+			}
+		}
+		depth += bracketDelta(trimmed)
+		if rule == nil {
+			out = append(out, line)
+			continue
+		}
+
+		block = append(block, line)
+		if depth > 0 {
+			continue
+		}
+		rendered, err := renderTemplate(rule.Template, address, resources[address], block, attributes[address])
+		if err != nil {
+			return "", err
+		}
+		out = append(out, rendered)
+		block, rule = nil, nil
+	}
+
+	return strings.Join(out, "\n"), nil
+}
+
+func renderTemplate(t *template.Template, address string, resource resource.Resource, block []string, attributes map[string]any) (string, error) {
+	resourceType, name, _ := strings.Cut(address, ".")
+	data := TemplateData{
+		Type:       resourceType,
+		Name:       name,
+		Address:    address,
+		ID:         resource.ID,
+		Project:    resource.Provider.ProjectID,
+		Service:    resource.Service.String(),
+		Block:      strings.Join(block, "\n"),
+		Attributes: attributes,
+	}
+	if len(block) > 2 {
+		data.Body = strings.Join(block[1:len(block)-1], "\n")
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s for %s: %w", t.Name(), address, err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -6,13 +6,17 @@ import (
 
 type importer struct {
 	outputPath string
+	layout     string
 }
 
-func NewImporter(outputPath string) (TerraformImporter, error) {
+// NewImporter writes import blocks to outputPath, in files named after the
+// files layout places generated config in
+func NewImporter(outputPath string, layout string) (TerraformImporter, error) {
 	if outputPath == "" {
 		return nil, fmt.Errorf("output path cannot be empty")
 	}
 	return &importer{
 		outputPath: outputPath,
+		layout:     layout,
 	}, nil
 }
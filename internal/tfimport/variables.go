@@ -0,0 +1,292 @@
+package tfimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+)
+
+// VariableAttributes are the top-level attributes whose values are factored
+// into variables once they repeat across resources, see ExtractVariables.
+// Labels are factored per key.
+var VariableAttributes = []string{"region", "location", "zone", "network", "private_network", "subnetwork"}
+
+const (
+	extractedVariablesFile = "infrasync_variables.tf"
+	// Values are written as JSON, which terraform loads like any
+	// .auto.tfvars file but the *.tfvars pattern of .gitignore doesn't
+	// match, so that they are committed
+	extractedTfvarsFile = "infrasync.auto.tfvars.json"
+)
+
+var (
+	labelsMapRe   = regexp.MustCompile(`^labels\s*=\s*\{$`)
+	variableRefRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*=\s*var\.([A-Za-z0-9_]+)$`)
+)
+
+// literal is a value set for an attribute, or for a label key
+type literal struct {
+	Name  string
+	Label bool
+	Value string
+}
+
+// variable returns the name of the variable the literal is factored into,
+// which only depends on the literal so that it is the same in every run
+func (l literal) variable() string {
+	value := l.Value
+	if !l.Label {
+		// Self links are named after the resource they link to
+		value = value[strings.LastIndex(value, "/")+1:]
+	}
+	name := l.Name + "_" + value
+	if l.Label {
+		name = "label_" + name
+	}
+	return strings.ToLower(invalidNameChars.ReplaceAllString(name, "_"))
+}
+
+// ExtractVariables factors the literals of VariableAttributes, and of labels,
+// which at least minResources generated resources of the project in
+// workingDir share into variables, with their values in a generated tfvars
+// file. The variables are declared in the module, i.e. the directory, of each
+// generated file referencing them. Literals factored by earlier runs are
+// factored in every generated file. Files changed since they were generated
+// are left alone.
+func ExtractVariables(workingDir, manifestPath string, minResources int) error {
+	m, err := manifest.Load(manifestPath, workingDir)
+	if err != nil {
+		return err
+	}
+
+	// The variables declared by earlier runs, in every module and all
+	// together, which keeps a literal factored into the same variable
+	// whichever module it is in
+	variables := map[string]string{}
+	modules := map[string]map[string]string{}
+	files := map[string]string{}
+	resources := map[literal]map[string]bool{}
+	for _, path := range slices.Sorted(maps.Values(m.Addresses())) {
+		if _, ok := files[path]; ok {
+			continue
+		}
+		if dir := filepath.Dir(path); modules[dir] == nil {
+			declared, err := readExtractedVariables(dir)
+			if err != nil {
+				return err
+			}
+			modules[dir] = declared
+			maps.Copy(variables, declared)
+		}
+
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read generated config: %w", err)
+		}
+		if m.Modified(path, content) && !unchangedSinceGenerated(string(content)) {
+			continue
+		}
+		files[path] = string(content)
+		for address, literals := range literals(string(content)) {
+			for _, l := range literals {
+				if resources[l] == nil {
+					resources[l] = map[string]bool{}
+				}
+				resources[l][address] = true
+			}
+		}
+	}
+
+	extracted := map[literal]string{}
+	for l, addresses := range resources {
+		name := l.variable()
+		if value, ok := variables[name]; ok && value == l.Value {
+			extracted[l] = name
+			continue
+		}
+		// A variable of the same name with another value, e.g. from
+		// values which only differ in case, keeps the literals
+		if _, ok := variables[name]; !ok && len(addresses) >= minResources {
+			variables[name] = l.Value
+			extracted[l] = name
+		}
+	}
+	if len(extracted) == 0 {
+		return nil
+	}
+
+	changed := map[string]bool{}
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		content := files[path]
+		updated := replaceLiterals(content, extracted)
+		if updated == content {
+			continue
+		}
+		updated = rehashHeader(updated)
+		if err := audit.WriteFile(path, []byte(updated), 0644); err != nil {
+			return fmt.Errorf("failed to write generated config: %w", err)
+		}
+		m.Rewrite(path, []byte(updated))
+
+		dir := filepath.Dir(path)
+		for _, name := range referencedVariables(updated) {
+			if value, ok := variables[name]; ok {
+				modules[dir][name] = value
+			}
+		}
+		changed[dir] = true
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	for _, dir := range slices.Sorted(maps.Keys(changed)) {
+		if err := writeExtractedVariables(dir, modules[dir]); err != nil {
+			return err
+		}
+	}
+	if manifestPath == "" {
+		return nil
+	}
+	return m.Save()
+}
+
+// referencedVariables returns the names of the variables top-level attributes
+// and labels in content are set to
+func referencedVariables(content string) []string {
+	var names []string
+	for _, line := range strings.Split(content, "\n") {
+		if m := variableRefRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			names = append(names, m[2])
+		}
+	}
+	return names
+}
+
+// literals returns the literals of VariableAttributes and labels set in the
+// resources of content, keyed by address
+func literals(content string) map[string][]literal {
+	out := map[string][]literal{}
+	walkLiterals(content, func(address string, l literal, _ string) string {
+		out[address] = append(out[address], l)
+		return ""
+	})
+	return out
+}
+
+// replaceLiterals replaces the literals in content which were extracted with
+// references to their variables
+func replaceLiterals(content string, extracted map[literal]string) string {
+	return walkLiterals(content, func(_ string, l literal, line string) string {
+		name, ok := extracted[l]
+		if !ok {
+			return line
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		key, _, _ := strings.Cut(strings.TrimSpace(line), "=")
+		return fmt.Sprintf("%s%s= var.%s", indent, key, name)
+	})
+}
+
+// walkLiterals calls visit with the literals of VariableAttributes and labels
+// in the resources of content, and the line each is set on, and returns
+// content with those lines replaced by what visit returns
+func walkLiterals(content string, visit func(address string, l literal, line string) string) string {
+	lines := strings.Split(content, "\n")
+	var address string
+	var depth, labelsDepth int
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case depth == 0:
+			if m := resourceHeaderRe.FindStringSubmatch(trimmed); m != nil {
+				address = m[1] + "." + m[2]
+			}
+		case labelsDepth > 0 && depth == labelsDepth:
+			if m := mapKeyRe.FindStringSubmatch(trimmed); m != nil {
+				if value, ok := quotedValue(trimmed); ok {
+					lines[i] = visit(address, literal{Name: m[1], Label: true, Value: value}, line)
+				}
+			}
+		case depth == 1:
+			if labelsMapRe.MatchString(trimmed) {
+				labelsDepth = 2
+				break
+			}
+			if name, value, ok := quotedAttribute(trimmed); ok && slices.Contains(VariableAttributes, name) {
+				lines[i] = visit(address, literal{Name: name, Value: value}, line)
+			}
+		}
+		depth += bracketDelta(trimmed)
+		if depth < labelsDepth {
+			labelsDepth = 0
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quotedValue returns the unquoted value of a map entry set to a string
+func quotedValue(line string) (string, bool) {
+	_, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", false
+	}
+	s, err := strconv.Unquote(strings.TrimSpace(value))
+	return s, err == nil
+}
+
+// readExtractedVariables returns the values of the variables factored out of
+// the generated config of the module in dir, keyed by name
+func readExtractedVariables(dir string) (map[string]string, error) {
+	variables := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(dir, extractedTfvarsFile))
+	if os.IsNotExist(err) {
+		return variables, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", extractedTfvarsFile, err)
+	}
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", extractedTfvarsFile, err)
+	}
+	return variables, nil
+}
+
+// writeExtractedVariables declares the variables factored out of the
+// generated config of the module in dir and writes their values
+func writeExtractedVariables(dir string, variables map[string]string) error {
+	var b strings.Builder
+	b.WriteString("# Generated by InfraSync\n")
+	for _, name := range slices.Sorted(maps.Keys(variables)) {
+		fmt.Fprintf(&b, `
+variable "%s" {
+  description = "Shared by generated resources, set in %s"
+  type        = string
+}
+`, name, extractedTfvarsFile)
+	}
+	if err := audit.WriteFile(filepath.Join(dir, extractedVariablesFile), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", extractedVariablesFile, err)
+	}
+
+	data, err := json.MarshalIndent(variables, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := audit.WriteFile(filepath.Join(dir, extractedTfvarsFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", extractedTfvarsFile, err)
+	}
+	return nil
+}
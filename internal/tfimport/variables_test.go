@@ -0,0 +1,68 @@
+package tfimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/manifest"
+)
+
+func TestExtractVariablesDeclaresInModule(t *testing.T) {
+	workingDir := t.TempDir()
+	manifestPath := filepath.Join(workingDir, ".infrasync", "manifest.json")
+	module := filepath.Join(workingDir, "resources", "google", "acme", "storage")
+	if err := os.MkdirAll(module, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := manifest.Load(manifestPath, workingDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"assets", "logs"} {
+		path := filepath.Join(module, name+".tf")
+		content := `resource "google_storage_bucket" "` + name + `" {
+  name     = "` + name + `"
+  location = "EU"
+}
+`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m.Record(path, []byte(content), "google_storage_bucket."+name)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ExtractVariables(workingDir, manifestPath, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(module, "assets.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "location = var.location_eu") {
+		t.Errorf("assets.tf doesn't refer to the variable:\n%s", content)
+	}
+	declarations, err := os.ReadFile(filepath.Join(module, extractedVariablesFile))
+	if err != nil {
+		t.Fatalf("variables aren't declared in the module of the references: %v", err)
+	}
+	if !strings.Contains(string(declarations), `variable "location_eu"`) {
+		t.Errorf("%s doesn't declare location_eu:\n%s", extractedVariablesFile, declarations)
+	}
+	variables, err := readExtractedVariables(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variables["location_eu"] != "EU" {
+		t.Errorf("location_eu = %q, want %q", variables["location_eu"], "EU")
+	}
+	if _, err := os.Stat(filepath.Join(workingDir, extractedVariablesFile)); !os.IsNotExist(err) {
+		t.Errorf("%s was written to the root module, which has no references", extractedVariablesFile)
+	}
+}
@@ -0,0 +1,81 @@
+package tfimport
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// manifest loads the manifest of generated files, which is empty if files are
+// not tracked
+func (r *generator) manifest() (*manifest.Manifest, error) {
+	return manifest.Load(r.opts.ManifestPath, r.workingDir)
+}
+
+// writeConfig writes the generated config of resource to path, appending it
+// to the existing content of files shared with other resources, and records
+// it in the manifest. Files which were changed since infrasync last wrote them
+// are left alone unless Force is set: the content they would get is written
+// next to them with a .new suffix and ErrModified is returned.
+func (r *generator) writeConfig(m *manifest.Manifest, resource resource.Resource, path string, existing []byte, content string) error {
+	updated := content
+	if len(existing) > 0 {
+		updated = string(existing) + "\n" + trimGeneratedHeader(content)
+	}
+	updated = stampHeader(updated, resource, time.Now())
+
+	// Generated files the manifest has no record of, e.g. in repositories
+	// which don't commit it, are known by their header
+	if len(existing) > 0 && r.opts.ManifestPath != "" && !r.opts.Force &&
+		m.Modified(path, existing) && !unchangedSinceGenerated(string(existing)) {
+		newPath := path + ".new"
+		if err := audit.WriteFile(newPath, []byte(updated), 0644); err != nil {
+			return err
+		}
+		slog.Warn("File was changed since it was generated, not overwriting it. Merge the changes by hand or pass --force",
+			"file", path, "new", newPath, "diff", lineDiff(string(existing), updated))
+		return ErrModified
+	}
+
+	if err := audit.WriteFile(path, []byte(updated), 0644); err != nil {
+		return err
+	}
+
+	if r.opts.ManifestPath == "" {
+		return nil
+	}
+	m.Record(path, []byte(updated), resource.Address())
+	for _, r := range resource.Flatten() {
+		m.Identify(r.Address(), r.ID)
+	}
+	return m.Save()
+}
+
+// lineDiff returns the lines added to and removed from src to get dst, in the
+// style of a unified diff without context
+func lineDiff(src, dst string) string {
+	var b strings.Builder
+	for _, d := range diff.Do(src, dst) {
+		var prefix string
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		default:
+			continue
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line != "" {
+				b.WriteString(prefix + line)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package tfimport
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+func TestWriteConfigHandEdited(t *testing.T) {
+	orders := resource.Resource{Type: "google_pubsub_topic", Name: "orders", ID: "projects/acme/topics/orders"}
+	events := resource.Resource{Type: "google_pubsub_topic", Name: "events", ID: "projects/acme/topics/events"}
+	const ordersConfig = "resource \"google_pubsub_topic\" \"orders\" {\n  name = \"orders\"\n}\n"
+	const eventsConfig = "resource \"google_pubsub_topic\" \"events\" {\n  name = \"events\"\n}\n"
+	const edit = "# Owned by the payments team\n"
+
+	tests := []struct {
+		name string
+		// edited appends a comment to the file after it was generated
+		edited bool
+		// forgotten loses the manifest, as in repositories which don't commit it
+		forgotten bool
+		force     bool
+		wantErr   error
+	}{
+		{name: "unchanged"},
+		{name: "edited", edited: true, wantErr: ErrModified},
+		{name: "edited with force", edited: true, force: true},
+		{name: "unchanged without a manifest record", forgotten: true},
+		{name: "edited without a manifest record", edited: true, forgotten: true, wantErr: ErrModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			manifestPath := filepath.Join(workingDir, ".infrasync", "manifest.json")
+			path := filepath.Join(workingDir, "topics.tf")
+			m, err := manifest.Load(manifestPath, workingDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := &generator{workingDir: workingDir, opts: Options{ManifestPath: manifestPath, Force: tt.force}}
+			if err := r.writeConfig(m, orders, path, nil, ordersConfig); err != nil {
+				t.Fatal(err)
+			}
+			existing, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.edited {
+				existing = append(existing, edit...)
+				if err := os.WriteFile(path, existing, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.forgotten {
+				m.Forget(path)
+			}
+
+			err = r.writeConfig(m, events, path, existing, eventsConfig)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("writeConfig() = %v, want %v", err, tt.wantErr)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			newContent, newErr := os.ReadFile(path + ".new")
+			if tt.wantErr != nil {
+				if string(content) != string(existing) {
+					t.Errorf("file was overwritten:\n%s", content)
+				}
+				if newErr != nil || !strings.Contains(string(newContent), eventsConfig) {
+					t.Errorf("%s.new = %q, %v, want the regenerated config", path, newContent, newErr)
+				}
+				return
+			}
+
+			if !os.IsNotExist(newErr) {
+				t.Errorf("%s.new exists, want it only for refused writes", path)
+			}
+			for _, want := range []string{ordersConfig, eventsConfig} {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("file doesn't contain %q:\n%s", want, content)
+				}
+			}
+			if tt.edited && !strings.Contains(string(content), edit) {
+				t.Errorf("file lost the hand edit:\n%s", content)
+			}
+			if m.Modified(path, content) {
+				t.Error("manifest doesn't track the written file")
+			}
+			if address, ok := m.AddressOf("google_pubsub_topic", events.ID); !ok || address != events.Address() {
+				t.Errorf("AddressOf() = %s, %t, want %s", address, ok, events.Address())
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/priyanshujain/infrasync/internal/config"
+)
+
+// Repository records generated files in version control.
+type Repository interface {
+	Init() error
+	Commit(message string) error
+	HasChanges() (bool, error)
+	CreateBranch(name string) error
+	Checkout(branch string) error
+	Push(ctx context.Context, branch string) error
+	RemoteURL() (string, error)
+}
+
+// New returns the repository at path, or a no-op repository when git
+// integration is disabled.
+func New(path string, cfg config.GitConfig) Repository {
+	if !cfg.Enabled {
+		return noop{}
+	}
+	return &goGit{path: path, cfg: cfg}
+}
+
+// ErrDisabled is returned by repositories which can't answer because git
+// integration is disabled
+var ErrDisabled = errors.New("git is disabled")
+
+type noop struct{}
+
+func (noop) Init() error                        { return nil }
+func (noop) Commit(string) error                { return nil }
+func (noop) HasChanges() (bool, error)          { return false, ErrDisabled }
+func (noop) CreateBranch(string) error          { return nil }
+func (noop) Checkout(string) error              { return nil }
+func (noop) Push(context.Context, string) error { return nil }
+func (noop) RemoteURL() (string, error)         { return "", ErrDisabled }
+
+// goGit works on the repository in-process with go-git, so no git binary is
+// needed and the process cwd is never changed.
+type goGit struct {
+	path string
+	cfg  config.GitConfig
+}
+
+func (g *goGit) Init() error {
+	opts := &git.PlainInitOptions{}
+	if g.cfg.DefaultBranch != "" {
+		opts.InitOptions.DefaultBranch = plumbing.NewBranchReferenceName(g.cfg.DefaultBranch)
+	}
+	if _, err := git.PlainInitWithOptions(g.path, opts); err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+	return nil
+}
+
+func (g *goGit) Commit(message string) error {
+	_, worktree, err := g.open()
+	if err != nil {
+		return err
+	}
+
+	if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return fmt.Errorf("failed to add files: %w", err)
+	}
+
+	// Without a configured author, go-git falls back to the user of the git
+	// config like the git binary does
+	opts := &git.CommitOptions{}
+	if g.cfg.AuthorName != "" || g.cfg.AuthorEmail != "" {
+		opts.Author = &object.Signature{Name: g.cfg.AuthorName, Email: g.cfg.AuthorEmail, When: time.Now()}
+	}
+
+	if _, err := worktree.Commit(message, opts); err != nil {
+		return fmt.Errorf("failed to commit files: %w", err)
+	}
+	return nil
+}
+
+func (g *goGit) HasChanges() (bool, error) {
+	_, worktree, err := g.open()
+	if err != nil {
+		return false, err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to get git status: %w", err)
+	}
+	return !status.IsClean(), nil
+}
+
+func (g *goGit) CreateBranch(name string) error {
+	_, worktree, err := g.open()
+	if err != nil {
+		return err
+	}
+
+	// Keep carries uncommitted changes over to the new branch
+	err = worktree.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(name),
+		Create: true,
+		Keep:   true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// Checkout switches to branch like git checkout: it refuses while tracked
+// files have uncommitted changes, and else updates the worktree and index to
+// the branch. Untracked files are left alone.
+func (g *goGit) Checkout(branch string) error {
+	_, worktree, err := g.open()
+	if err != nil {
+		return err
+	}
+
+	// go-git moves HEAD before it finds uncommitted changes, so they are
+	// looked for first
+	status, err := worktree.Status()
+	if err != nil {
+		return fmt.Errorf("failed to get git status: %w", err)
+	}
+	for file, s := range status {
+		if s.Worktree != git.Untracked && (s.Worktree != git.Unmodified || s.Staging != git.Unmodified) {
+			return fmt.Errorf("failed to checkout branch %s: %s has uncommitted changes", branch, file)
+		}
+	}
+
+	// Unlike Keep, which only moves HEAD, this updates the files, and unlike
+	// Force it keeps untracked files
+	err = worktree.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to checkout branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+func (g *goGit) Push(ctx context.Context, branch string) error {
+	repo, _, err := g.open()
+	if err != nil {
+		return err
+	}
+
+	remoteURL, err := g.RemoteURL()
+	if err != nil {
+		return err
+	}
+	auth := pushAuth(remoteURL)
+
+	ref := plumbing.NewBranchReferenceName(branch)
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("%s:%s", ref, ref))},
+		Auth:       auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to push branch %s: %w", branch, err)
+	}
+
+	err = repo.CreateBranch(&gitconfig.Branch{Name: branch, Remote: "origin", Merge: ref})
+	if err != nil && !errors.Is(err, git.ErrBranchExists) {
+		return fmt.Errorf("failed to set upstream of branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+func (g *goGit) RemoteURL() (string, error) {
+	repo, _, err := g.open()
+	if err != nil {
+		return "", err
+	}
+
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return "", fmt.Errorf("failed to get origin remote: %w", err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", fmt.Errorf("origin remote has no URL")
+	}
+	return urls[0], nil
+}
+
+func (g *goGit) open() (*git.Repository, *git.Worktree, error) {
+	repo, err := git.PlainOpen(g.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open git worktree: %w", err)
+	}
+	return repo, worktree, nil
+}
+
+// pushAuth authenticates https remotes with the token used for pull requests
+// on their host, since go-git doesn't run credential helpers. ssh remotes use
+// the ssh agent. Remotes on other hosts get no token, so that it isn't sent
+// to them.
+func pushAuth(remoteURL string) transport.AuthMethod {
+	if !strings.HasPrefix(remoteURL, "https://") && !strings.HasPrefix(remoteURL, "http://") {
+		return nil
+	}
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return nil
+	}
+
+	switch remoteHostType(u.Hostname()) {
+	case HostTypeGitHub:
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			return &http.BasicAuth{Username: "x-access-token", Password: token}
+		}
+	case HostTypeGitLab:
+		if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+			return &http.BasicAuth{Username: "oauth2", Password: token}
+		}
+	}
+	return nil
+}
+
+// remoteHostType returns the type of the code hosting service at host: the
+// public services, or the self-managed instances NewHost is pointed to
+func remoteHostType(host string) HostType {
+	switch {
+	case host == "github.com" || isHostOf(host, os.Getenv("GITHUB_API_URL")):
+		return HostTypeGitHub
+	case host == "gitlab.com" || isHostOf(host, os.Getenv("GITLAB_URL")):
+		return HostTypeGitLab
+	default:
+		return ""
+	}
+}
+
+// isHostOf reports whether host is the host of rawURL
+func isHostOf(host, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Hostname() != "" && u.Hostname() == host
+}
@@ -0,0 +1,143 @@
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/priyanshujain/infrasync/internal/config"
+)
+
+// newTestRepository returns a repository on branch main with file main.tf
+// committed, holding content
+func newTestRepository(t *testing.T, content string) *goGit {
+	t.Helper()
+	repo := &goGit{path: t.TempDir(), cfg: config.GitConfig{
+		Enabled:       true,
+		DefaultBranch: "main",
+		AuthorName:    "infrasync",
+		AuthorEmail:   "infrasync@example.com",
+	}}
+	if err := repo.Init(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "main.tf", content)
+	if err := repo.Commit("Initial commit"); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func writeFile(t *testing.T, repo *goGit, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repo.path, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, repo *goGit, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(repo.path, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCheckoutRefusesUncommittedChanges(t *testing.T) {
+	repo := newTestRepository(t, "base\n")
+	if err := repo.CreateBranch("feature"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "main.tf", "changed\n")
+
+	if err := repo.Checkout("main"); err == nil {
+		t.Fatal("Checkout succeeded with uncommitted changes")
+	}
+	if got := readFile(t, repo, "main.tf"); got != "changed\n" {
+		t.Errorf("main.tf = %q after the refused checkout, want the uncommitted change", got)
+	}
+}
+
+func TestCheckoutKeepsUntrackedFiles(t *testing.T) {
+	repo := newTestRepository(t, "base\n")
+	if err := repo.CreateBranch("feature"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "notes.txt", "untracked\n")
+
+	if err := repo.Checkout("main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, repo, "notes.txt"); got != "untracked\n" {
+		t.Errorf("notes.txt = %q, want the untracked file kept", got)
+	}
+}
+
+// TestCheckoutBaseAfterPullRequestBranch follows a sync opening a pull
+// request: the changes are committed to a new branch, then the base branch is
+// checked out again for the next run
+func TestCheckoutBaseAfterPullRequestBranch(t *testing.T) {
+	repo := newTestRepository(t, "base\n")
+	writeFile(t, repo, "main.tf", "changed\n")
+	writeFile(t, repo, "new.tf", "added\n")
+
+	if err := repo.CreateBranch("infrasync/sync-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Commit("Update Terraform configurations to match cloud state"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Checkout("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := repo.HasChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("base branch has changes after the pull request branch was committed")
+	}
+	if got := readFile(t, repo, "main.tf"); got != "base\n" {
+		t.Errorf("main.tf = %q on the base branch, want %q", got, "base\n")
+	}
+	if _, err := os.Stat(filepath.Join(repo.path, "new.tf")); !os.IsNotExist(err) {
+		t.Errorf("new.tf of the pull request branch exists on the base branch")
+	}
+}
+
+func TestPushAuth(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "github-token")
+	t.Setenv("GITLAB_TOKEN", "gitlab-token")
+	t.Setenv("GITHUB_API_URL", "https://github.example.com/api/v3")
+	t.Setenv("GITLAB_URL", "https://gitlab.example.com")
+
+	tests := []struct {
+		name      string
+		remoteURL string
+		want      string
+	}{
+		{name: "github", remoteURL: "https://github.com/acme/infra.git", want: "github-token"},
+		{name: "gitlab", remoteURL: "https://gitlab.com/acme/infra.git", want: "gitlab-token"},
+		{name: "github enterprise", remoteURL: "https://github.example.com/acme/infra.git", want: "github-token"},
+		{name: "self-managed gitlab", remoteURL: "https://gitlab.example.com/acme/infra.git", want: "gitlab-token"},
+		{name: "other host", remoteURL: "https://git.example.com/acme/infra.git"},
+		{name: "ssh", remoteURL: "git@github.com:acme/infra.git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := pushAuth(tt.remoteURL)
+			var got string
+			if basic, ok := auth.(*http.BasicAuth); ok {
+				got = basic.Password
+			} else if auth != nil {
+				t.Fatalf("pushAuth() = %T, want basic auth", auth)
+			}
+			if got != tt.want {
+				t.Errorf("pushAuth() token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
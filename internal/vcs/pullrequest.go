@@ -0,0 +1,173 @@
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type PullRequest struct {
+	Title string
+	Body  string
+	Head  string
+	Base  string
+}
+
+// Host opens pull requests on a code hosting service.
+type Host interface {
+	CreatePullRequest(ctx context.Context, remoteURL string, pr PullRequest) (string, error)
+}
+
+type HostType string
+
+var (
+	HostTypeGitHub HostType = "github"
+	HostTypeGitLab HostType = "gitlab"
+)
+
+// NewHost returns a Host for the given type. Tokens are read from GITHUB_TOKEN
+// and GITLAB_TOKEN; GITLAB_URL points to self-managed GitLab instances.
+func NewHost(hostType HostType) (Host, error) {
+	switch hostType {
+	case HostTypeGitHub:
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("GITHUB_TOKEN is not set")
+		}
+		apiURL := os.Getenv("GITHUB_API_URL")
+		if apiURL == "" {
+			apiURL = "https://api.github.com"
+		}
+		return &github{apiURL: apiURL, token: token}, nil
+	case HostTypeGitLab:
+		token := os.Getenv("GITLAB_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("GITLAB_TOKEN is not set")
+		}
+		baseURL := os.Getenv("GITLAB_URL")
+		if baseURL == "" {
+			baseURL = "https://gitlab.com"
+		}
+		return &gitlab{baseURL: baseURL, token: token}, nil
+	default:
+		return nil, fmt.Errorf("unsupported pull request host: %s", hostType)
+	}
+}
+
+type github struct {
+	apiURL string
+	token  string
+}
+
+func (g *github) CreatePullRequest(ctx context.Context, remoteURL string, pr PullRequest) (string, error) {
+	repo, err := repositoryPath(remoteURL)
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]string{
+		"title": pr.Title,
+		"body":  pr.Body,
+		"head":  pr.Head,
+		"base":  pr.Base,
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer " + g.token,
+		"Accept":        "application/vnd.github+json",
+	}
+
+	var resp struct {
+		HTMLURL string `json:"html_url"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/pulls", g.apiURL, repo)
+	if err := postJSON(ctx, endpoint, headers, body, &resp); err != nil {
+		return "", fmt.Errorf("failed to create GitHub pull request: %w", err)
+	}
+	return resp.HTMLURL, nil
+}
+
+type gitlab struct {
+	baseURL string
+	token   string
+}
+
+func (g *gitlab) CreatePullRequest(ctx context.Context, remoteURL string, pr PullRequest) (string, error) {
+	repo, err := repositoryPath(remoteURL)
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]string{
+		"title":         pr.Title,
+		"description":   pr.Body,
+		"source_branch": pr.Head,
+		"target_branch": pr.Base,
+	}
+	headers := map[string]string{
+		"PRIVATE-TOKEN": g.token,
+	}
+
+	var resp struct {
+		WebURL string `json:"web_url"`
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", g.baseURL, url.PathEscape(repo))
+	if err := postJSON(ctx, endpoint, headers, body, &resp); err != nil {
+		return "", fmt.Errorf("failed to create GitLab merge request: %w", err)
+	}
+	return resp.WebURL, nil
+}
+
+// repositoryPath extracts "owner/repo" from ssh or https remote URLs.
+func repositoryPath(remoteURL string) (string, error) {
+	path := remoteURL
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid remote URL %s: %w", remoteURL, err)
+		}
+		path = u.Path
+	} else if i := strings.Index(remoteURL, ":"); i >= 0 {
+		path = remoteURL[i+1:]
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if !strings.Contains(path, "/") {
+		return "", fmt.Errorf("cannot determine repository from remote URL %s", remoteURL)
+	}
+	return path, nil
+}
+
+func postJSON(ctx context.Context, endpoint string, headers map[string]string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var buf bytes.Buffer
+		buf.ReadFrom(resp.Body)
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, buf.String())
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
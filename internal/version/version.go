@@ -0,0 +1,97 @@
+// Package version reports the build of infrasync and checks GitHub releases
+// for newer ones.
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// Version and Commit are set at build time with
+// -ldflags "-X github.com/priyanshujain/infrasync/internal/version.Version=v1.2.3"
+var (
+	Version = ""
+	Commit  = ""
+)
+
+const releasesURL = "https://api.github.com/repos/priyanshujain/infrasync/releases/latest"
+
+// Info returns the version and commit of the running binary. Builds without
+// ldflags fall back to the module version and VCS revision embedded by go.
+func Info() (version, commit string) {
+	version, commit = Version, Commit
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && commit == "" {
+				commit = setting.Value
+			}
+		}
+	}
+
+	if version == "" {
+		version = "dev"
+	}
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return version, commit
+}
+
+// Latest returns the tag of the latest GitHub release
+func Latest(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch latest release: unexpected status %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to decode latest release: %w", err)
+	}
+	return release.TagName, nil
+}
+
+var semverRe = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+
+// Newer reports whether latest is a higher version than current. Versions
+// which aren't semver, like dev builds, are never considered outdated.
+func Newer(latest, current string) bool {
+	l, c := semverRe.FindStringSubmatch(latest), semverRe.FindStringSubmatch(current)
+	if l == nil || c == nil {
+		return false
+	}
+	for i := 1; i <= 3; i++ {
+		a, _ := strconv.Atoi(l[i])
+		b, _ := strconv.Atoi(c[i])
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
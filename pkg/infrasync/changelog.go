@@ -0,0 +1,103 @@
+package infrasync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/config"
+	"github.com/priyanshujain/infrasync/internal/vcs"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// changelogFile documents when each resource was adopted, newest first
+const changelogFile = "imports.md"
+
+const changelogTitle = "# Imports\n"
+
+// commitImports adds the resources of service imported in this run to the
+// changelog and commits them. An empty service commits every service of the
+// result.
+func (c *Client) commitImports(result *ImportResult, service resource.Service) error {
+	var imported []ResourceResult
+	for _, r := range result.Resources {
+		if r.Status == ResourceImported && (service == "" || r.Service == service) {
+			imported = append(imported, r)
+		}
+	}
+	if len(imported) == 0 {
+		return nil
+	}
+
+	if err := c.updateChangelog(imported); err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Import %d resources", len(imported))
+	if service != "" {
+		subject = fmt.Sprintf("Import %d %s resources", len(imported), service)
+	}
+	message := subject + "\n\n" + resourceList(imported)
+	// The run ID ties the commit to the audit log, and to rollback
+	if runID := audit.RunID(); runID != "" {
+		message += "\nInfraSync-Run: " + runID + "\n"
+	}
+
+	if err := vcs.New(c.Config.ProjectPath(), c.Config.Git).Commit(message); err != nil {
+		return fmt.Errorf("failed to commit imports: %w", err)
+	}
+	return nil
+}
+
+// commitServiceImports commits the imports of a service once it is done, if
+// imports are committed per service
+func (c *Client) commitServiceImports(result *ImportResult, service resource.Service) error {
+	if c.Config.Git.CommitImports != config.CommitPerService {
+		return nil
+	}
+	return c.commitImports(result, service)
+}
+
+// commitRunImports commits the imports of a run once it is done, if imports
+// are committed per run
+func (c *Client) commitRunImports(result *ImportResult) error {
+	if c.Config.Git.CommitImports != config.CommitPerRun {
+		return nil
+	}
+	return c.commitImports(result, "")
+}
+
+// updateChangelog adds an entry for the imported resources at the top of the
+// changelog, below its title
+func (c *Client) updateChangelog(imported []ResourceResult) error {
+	path := filepath.Join(c.Config.ProjectPath(), changelogFile)
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", changelogFile, err)
+	}
+	previous := strings.TrimPrefix(string(existing), changelogTitle)
+
+	heading := time.Now().UTC().Format("2006-01-02 15:04 MST")
+	if runID := audit.RunID(); runID != "" {
+		heading += fmt.Sprintf(" (run %s)", runID)
+	}
+	entry := fmt.Sprintf("\n## %s\n\n%s", heading, resourceList(imported))
+
+	if err := audit.WriteFile(path, []byte(changelogTitle+entry+previous), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", changelogFile, err)
+	}
+	return nil
+}
+
+// resourceList lists the addresses and import IDs of resources as markdown
+func resourceList(resources []ResourceResult) string {
+	var b strings.Builder
+	for _, r := range resources {
+		fmt.Fprintf(&b, "- `%s` (%s)\n", r.Address, r.ID)
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package infrasync
+
+import (
+	"fmt"
+
+	"github.com/priyanshujain/infrasync/internal/config"
+)
+
+// Client represents the InfraSync client. It initializes repositories,
+// imports all or single services and syncs them with the cloud.
+type Client struct {
+	Config config.Config
+	hooks  Hooks
+	// stateLocked is set while the run holds the state lock, so terraform
+	// doesn't try to take it too
+	stateLocked bool
+}
+
+// Option configures a Client created with New
+type Option func(*options)
+
+type options struct {
+	config      *config.Config
+	parallelism int
+	forceUnlock bool
+	force       bool
+	hooks       Hooks
+}
+
+// WithConfig uses cfg instead of the config at the default path
+func WithConfig(cfg config.Config) Option {
+	return func(o *options) {
+		o.config = &cfg
+	}
+}
+
+// WithParallelism overrides the parallelism of every service
+func WithParallelism(parallelism int) Option {
+	return func(o *options) {
+		o.parallelism = parallelism
+	}
+}
+
+// WithForceUnlock breaks locks held by other runs instead of failing
+func WithForceUnlock(force bool) Option {
+	return func(o *options) {
+		o.forceUnlock = force
+	}
+}
+
+// WithForce overwrites generated files even if they were changed since they
+// were generated
+func WithForce(force bool) Option {
+	return func(o *options) {
+		o.force = force
+	}
+}
+
+// New creates a client. Without WithConfig the config is loaded from the
+// default path.
+func New(opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.config == nil {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		o.config = &cfg
+	}
+
+	cfg := *o.config
+	if o.parallelism > 0 {
+		cfg.Parallelism = o.parallelism
+	}
+	if o.forceUnlock {
+		cfg.ForceUnlock = true
+	}
+	if o.force {
+		cfg.Force = true
+	}
+	return &Client{Config: cfg, hooks: o.hooks}, nil
+}
+
+// NewClient creates a new InfraSync client with the provided configuration
+//
+// Deprecated: use New with WithConfig.
+func NewClient(cfg config.Config) *Client {
+	return &Client{
+		Config: cfg,
+	}
+}
+
+// DefaultClient creates a client with configuration loaded from the default path
+//
+// Deprecated: use New.
+func DefaultClient() (*Client, error) {
+	return New()
+}
@@ -0,0 +1,62 @@
+package infrasync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/priyanshujain/infrasync/internal/cost"
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// costReporter estimates the monthly cost of unmanaged resources as they are
+// discovered, to help prioritize what to bring under IaC. Estimation stops at
+// the first error.
+type costReporter struct {
+	credentials providers.Credentials
+	estimator   *cost.Estimator
+	estimates   []cost.Estimate
+	err         error
+}
+
+func (c *costReporter) add(ctx context.Context, r resource.Resource) {
+	if c.err != nil {
+		return
+	}
+
+	if c.estimator == nil {
+		c.estimator, c.err = cost.NewEstimator(ctx, c.credentials)
+		if c.err != nil {
+			return
+		}
+	}
+
+	estimate, err := c.estimator.Estimate(ctx, r)
+	if err != nil {
+		c.err = fmt.Errorf("failed to estimate cost of %s: %w", r.Address(), err)
+		return
+	}
+	c.estimates = append(c.estimates, estimate)
+}
+
+// report logs the estimates, most expensive first
+func (c *costReporter) report() error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.estimates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(c.estimates, func(i, j int) bool { return c.estimates[i].Monthly > c.estimates[j].Monthly })
+
+	var total float64
+	for _, estimate := range c.estimates {
+		total += estimate.Monthly
+		slog.Info("Unmanaged resource cost", "resource", estimate.Address, "estimate", estimate.String())
+	}
+	slog.Info("Estimated monthly cost of unmanaged resources", "usd", fmt.Sprintf("%.2f", total))
+	return nil
+}
@@ -0,0 +1,124 @@
+package infrasync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/state"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// errUnsupportedService is returned when discovering a service without importer
+var errUnsupportedService = errors.New("service is not supported")
+
+// Diff compares the live cloud resource at a terraform address with its state.
+// Only the service owning the resource type is discovered and no config is
+// generated.
+func (c *Client) Diff(ctx context.Context, address string) (drift.ResourceDiff, error) {
+	local := drift.LocalAddress(address)
+	resourceType, _, ok := strings.Cut(local, ".")
+	if !ok {
+		return drift.ResourceDiff{}, fmt.Errorf("invalid resource address: %s", address)
+	}
+
+	provider := c.Config.DefaultProvider()
+
+	var service resource.Service
+	for _, s := range c.Config.GoogleServices(provider) {
+		for _, t := range google.ResourceTypes(s) {
+			if string(t) == resourceType {
+				service = s
+			}
+		}
+	}
+	if service == "" {
+		return drift.ResourceDiff{}, fmt.Errorf("resource type %s is not covered by the configured services", resourceType)
+	}
+
+	var discovered []resource.Resource
+	err := c.discover(ctx, service, c.Config.DependentLimits(), func(r resource.Resource) error {
+		for _, r := range r.Flatten() {
+			if r.Address() == local {
+				discovered = append(discovered, r)
+			}
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return drift.ResourceDiff{}, fmt.Errorf("failed to discover %s resources: %w", service, err)
+	}
+
+	backend, err := state.NewBackend(ctx, c.Config.DefaultBackend())
+	if err != nil {
+		return drift.ResourceDiff{}, err
+	}
+
+	data, err := backend.Read(ctx)
+	if err != nil {
+		return drift.ResourceDiff{}, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	managed, err := state.Resources(data)
+	if err != nil {
+		return drift.ResourceDiff{}, err
+	}
+
+	return drift.DiffResource(address, discovered, managed), nil
+}
+
+// discover passes the resources of a service to visit one at a time, without
+// the excluded ones and the dependents beyond limits and without generating
+// config. The excluded resources, which still exist, are passed to excluded
+// one at a time if it is not nil.
+func (c *Client) discover(ctx context.Context, service resource.Service, limits google.DependentLimits, visit func(resource.Resource) error, excluded func(resource.Resource)) error {
+	s, err := c.newImporter(ctx, service, c.Config.DefaultProvider())
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return fmt.Errorf("%w: %s", errUnsupportedService, service)
+	}
+	defer s.Close()
+
+	resourceIter, err := s.Import(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create resource iterator: %w", err)
+	}
+	defer resourceIter.Close()
+
+	filter := c.Config.ExcludeFilter()
+	mappings := c.Config.Mappings()
+
+	for {
+		// Resources which can't be discovered aren't skipped, as callers
+		// would take them for deleted
+		resource, err := nextResource(ctx, resourceIter, nil)
+		if err != nil {
+			return fmt.Errorf("error getting next resource: %w", err)
+		}
+		if resource == nil {
+			return nil
+		}
+		kept := make(map[string]bool)
+		if filtered, ok := filter.Apply(*resource); ok {
+			for _, r := range mappings.Apply(filtered).Flatten() {
+				kept[r.Address()] = true
+			}
+			if err := visit(mappings.Apply(limits.Apply(filtered))); err != nil {
+				return err
+			}
+		}
+		if excluded == nil {
+			continue
+		}
+		for _, r := range mappings.Apply(*resource).Flatten() {
+			if !kept[r.Address()] {
+				excluded(r)
+			}
+		}
+	}
+}
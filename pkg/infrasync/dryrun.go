@@ -0,0 +1,204 @@
+package infrasync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/priyanshujain/infrasync/internal/state"
+)
+
+// FileDiff is the change a sync would make to a generated file
+type FileDiff struct {
+	// Path is relative to the project, slash separated
+	Path string
+	// Diff is the unified diff of the file, from /dev/null for new files
+	Diff string
+}
+
+// SyncDryRun detects drift and generates config like Sync, but in a scratch
+// copy of the project, and returns the diffs of the generated files Sync
+// would change. Neither the repository nor the drift history is changed and
+// no pull request is opened. The diffs are returned along with
+// ErrDriftDetected and ErrBudgetExceeded.
+func (c *Client) SyncDryRun(ctx context.Context) ([]FileDiff, error) {
+	project := c.Config.ProjectPath()
+	if _, err := os.Stat(project); err != nil {
+		return nil, fmt.Errorf("failed to read project: %w", err)
+	}
+
+	scratch, err := os.MkdirTemp("", "infrasync-dry-run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	dry := *c
+	dry.Config.Path = scratch
+	if err := copyProject(project, dry.Config.ProjectPath()); err != nil {
+		return nil, fmt.Errorf("failed to copy project: %w", err)
+	}
+
+	backend, err := state.NewBackend(ctx, dry.Config.DefaultBackend())
+	if err != nil {
+		return nil, err
+	}
+	report, _, err := dry.detectDrift(ctx, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs, err := diffProjects(project, dry.Config.ProjectPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff generated files: %w", err)
+	}
+	for _, d := range diffs {
+		slog.Info("Generated file would change", "file", d.Path)
+	}
+
+	if len(diffs) == 0 && !report.HasDrift() {
+		slog.Info("No drift detected")
+		return nil, nil
+	}
+
+	slog.Info("Drift detected", "unmanaged", len(report.Unmanaged), "deleted", len(report.Deleted),
+		"modified", len(report.Modified), "files", len(diffs))
+
+	if failOn := c.Config.DriftFailClasses(); report.HasAny(failOn) {
+		return diffs, fmt.Errorf("%w: classes %v", ErrDriftDetected, report.Classes())
+	}
+	return diffs, c.checkBudget(report)
+}
+
+// copyProject copies the project at src to dst, including the terraform
+// working directory so that it needn't be initialized again. Of the
+// .infrasync directory only the manifest is copied; locks, the audit log and
+// drift history belong to the project.
+func copyProject(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == ".git" || (strings.HasPrefix(rel, ".infrasync/") && rel != ".infrasync/manifest.json") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			// Provider plugin caches link to the providers
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// diffProjects returns the diffs of the .tf files of the project at after
+// which differ from those at before. Syncs only add and change generated
+// files, so files missing from after are not looked for.
+func diffProjects(before, after string) ([]FileDiff, error) {
+	var diffs []FileDiff
+	err := filepath.WalkDir(after, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == ".terraform" || name == ".infrasync" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".tf" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(after, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		updated, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		from := "a/" + rel
+		existing, err := os.ReadFile(filepath.Join(before, filepath.FromSlash(rel)))
+		if os.IsNotExist(err) {
+			from = "/dev/null"
+		} else if err != nil {
+			return err
+		}
+		if string(existing) == string(updated) {
+			return nil
+		}
+
+		text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(string(existing)),
+			B:        splitLines(string(updated)),
+			FromFile: from,
+			ToFile:   "b/" + rel,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		diffs = append(diffs, FileDiff{Path: rel, Diff: text})
+		return nil
+	})
+	return diffs, err
+}
+
+// splitLines splits content into lines keeping their line endings, unlike
+// difflib.SplitLines which adds an empty last line
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
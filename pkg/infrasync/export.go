@@ -0,0 +1,35 @@
+package infrasync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/priyanshujain/infrasync/internal/inventory"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// Export discovers the resources of every configured service and returns
+// them as an inventory. No config is generated and terraform is not run.
+func (c *Client) Export(ctx context.Context) ([]inventory.Item, error) {
+	provider := c.Config.DefaultProvider()
+
+	var items []inventory.Item
+	for _, service := range c.Config.GoogleServices(provider) {
+		err := c.discover(ctx, service, c.Config.DependentLimits(), func(r resource.Resource) error {
+			for _, r := range r.Flatten() {
+				items = append(items, inventory.NewItem(provider.ProjectID, service, r))
+			}
+			return nil
+		}, nil)
+		if errors.Is(err, errUnsupportedService) {
+			slog.Info("Service is not supported", "service", service)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover %s resources: %w", service, err)
+		}
+	}
+	return items, nil
+}
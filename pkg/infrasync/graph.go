@@ -0,0 +1,114 @@
+package infrasync
+
+import (
+	"fmt"
+
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// importGraph is the DAG of a discovered resource and its dependents, e.g. a
+// Cloud SQL instance and its databases and users. Every dependent depends on
+// the resource it was discovered under; a dependent discovered under several
+// resources is a single node depending on all of them.
+type importGraph struct {
+	// nodes are the resources without their dependents, the discovered
+	// resource first
+	nodes    []resource.Resource
+	parents  map[int][]int
+	children map[int][]int
+}
+
+func newImportGraph(resource resource.Resource) *importGraph {
+	g := &importGraph{parents: make(map[int][]int), children: make(map[int][]int)}
+	g.add(resource, -1, make(map[string]int))
+	return g
+}
+
+func (g *importGraph) add(resource resource.Resource, parent int, index map[string]int) {
+	i, ok := index[resource.Address()]
+	if !ok {
+		i = len(g.nodes)
+		index[resource.Address()] = i
+		node := resource
+		node.Dependents = nil
+		g.nodes = append(g.nodes, node)
+	}
+	if parent >= 0 {
+		g.parents[i] = append(g.parents[i], parent)
+		g.children[parent] = append(g.children[parent], i)
+	}
+	if ok {
+		return
+	}
+	for _, d := range resource.Dependents {
+		g.add(d, i, index)
+	}
+}
+
+// order returns the nodes in topological order, every resource after the
+// resources it depends on and otherwise in discovery order
+func (g *importGraph) order() ([]int, error) {
+	indegree := make([]int, len(g.nodes))
+	for i := range g.nodes {
+		indegree[i] = len(g.parents[i])
+	}
+
+	var order, ready []int
+	for i := range g.nodes {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, i)
+		for _, child := range g.children[i] {
+			if indegree[child]--; indegree[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	if len(order) != len(g.nodes) {
+		return nil, fmt.Errorf("dependents of %s depend on each other in a cycle", g.nodes[0].Address())
+	}
+	return order, nil
+}
+
+// root returns the top-level resource i was discovered under, whose file its
+// config goes to
+func (g *importGraph) root(i int) resource.Resource {
+	for len(g.parents[i]) > 0 {
+		i = g.parents[i][0]
+	}
+	return g.nodes[i]
+}
+
+// skippedParent reports whether a resource node i depends on was not
+// imported
+func (g *importGraph) skippedParent(i int, skipped map[int]bool) bool {
+	for _, parent := range g.parents[i] {
+		if skipped[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// tree returns the discovered resource with the dependents which weren't
+// skipped
+func (g *importGraph) tree(skipped map[int]bool) resource.Resource {
+	var build func(i int, seen map[int]bool) resource.Resource
+	build = func(i int, seen map[int]bool) resource.Resource {
+		seen[i] = true
+		r := g.nodes[i]
+		for _, child := range g.children[i] {
+			if !skipped[child] && !seen[child] {
+				r.Dependents = append(r.Dependents, build(child, seen))
+			}
+		}
+		return r
+	}
+	return build(0, make(map[int]bool))
+}
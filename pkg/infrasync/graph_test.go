@@ -0,0 +1,157 @@
+package infrasync
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+func node(name string, dependents ...resource.Resource) resource.Resource {
+	return resource.Resource{Type: "google_test", Name: name, Dependents: dependents}
+}
+
+// names returns the names of the nodes at indexes
+func names(g *importGraph, indexes []int) []string {
+	var out []string
+	for _, i := range indexes {
+		out = append(out, g.nodes[i].Name)
+	}
+	return out
+}
+
+func TestImportGraphOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource resource.Resource
+		want     []string
+	}{
+		{name: "single", resource: node("instance"), want: []string{"instance"}},
+		{
+			name:     "dependents in discovery order",
+			resource: node("instance", node("db", node("db_iam")), node("user")),
+			want:     []string{"instance", "db", "user", "db_iam"},
+		},
+		{
+			// The binding is discovered under both, and imported after both
+			name:     "shared dependent",
+			resource: node("instance", node("db", node("binding")), node("user", node("binding"))),
+			want:     []string{"instance", "db", "user", "binding"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newImportGraph(tt.resource)
+			order, err := g.order()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(g, order); !slices.Equal(got, tt.want) {
+				t.Errorf("order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportGraphSharedDependent(t *testing.T) {
+	g := newImportGraph(node("instance", node("db", node("binding")), node("user", node("binding"))))
+	if len(g.nodes) != 4 {
+		t.Fatalf("nodes = %v, want the binding once", names(g, []int{0, 1, 2, 3}))
+	}
+	binding := 2
+	if g.nodes[binding].Name != "binding" {
+		t.Fatalf("node %d = %s, want binding", binding, g.nodes[binding].Name)
+	}
+	if got := names(g, g.parents[binding]); !slices.Equal(got, []string{"db", "user"}) {
+		t.Errorf("parents of binding = %v, want db and user", got)
+	}
+	if root := g.root(binding); root.Name != "instance" {
+		t.Errorf("root(binding) = %s, want instance", root.Name)
+	}
+}
+
+func TestImportGraphCycle(t *testing.T) {
+	// The database lists the instance as its dependent again
+	g := newImportGraph(node("instance", node("db", node("instance"))))
+	if _, err := g.order(); err == nil {
+		t.Error("order() of a cycle succeeded, want an error")
+	}
+}
+
+func TestImportGraphSkippedParent(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource resource.Resource
+		skip     string
+		want     []string
+	}{
+		{
+			name:     "cascades to grandchildren",
+			resource: node("instance", node("db", node("db_iam", node("db_iam_member"))), node("user")),
+			skip:     "db",
+			want:     []string{"db", "db_iam", "db_iam_member"},
+		},
+		{
+			name:     "shared dependent is skipped with either parent",
+			resource: node("instance", node("db", node("binding")), node("user", node("binding"))),
+			skip:     "user",
+			want:     []string{"user", "binding"},
+		},
+		{
+			name:     "skipped root skips everything",
+			resource: node("instance", node("db"), node("user")),
+			skip:     "instance",
+			want:     []string{"instance", "db", "user"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newImportGraph(tt.resource)
+			order, err := g.order()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Like importInOrder, which skips the resources whose import
+			// failed and those depending on them
+			skipped := make(map[int]bool)
+			var got []string
+			for _, i := range order {
+				if g.nodes[i].Name == tt.skip || g.skippedParent(i, skipped) {
+					skipped[i] = true
+					got = append(got, g.nodes[i].Name)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("skipped = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportGraphTree(t *testing.T) {
+	g := newImportGraph(node("instance", node("db", node("db_iam")), node("user", node("db_iam"))))
+
+	flatten := func(r resource.Resource) []string {
+		var out []string
+		for _, r := range r.Flatten() {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	// The shared dependent is in the tree once
+	if got, want := flatten(g.tree(nil)), []string{"instance", "db", "db_iam", "user"}; !slices.Equal(got, want) {
+		t.Errorf("tree(nil) = %v, want %v", got, want)
+	}
+
+	skipped := make(map[int]bool)
+	for i, n := range g.nodes {
+		if n.Name == "user" || n.Name == "db_iam" {
+			skipped[i] = true
+		}
+	}
+	if got, want := flatten(g.tree(skipped)), []string{"instance", "db"}; !slices.Equal(got, want) {
+		t.Errorf("tree() = %v, want %v", got, want)
+	}
+}
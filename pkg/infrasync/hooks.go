@@ -0,0 +1,67 @@
+package infrasync
+
+import (
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// Hooks receives the events of import and sync runs, so that applications
+// embedding the client can render their own progress and collect metrics.
+// Hooks are called synchronously from the run and should return quickly.
+type Hooks interface {
+	// OnResourceDiscovered is called for every resource found in the cloud,
+	// before exclusions are applied. Dependents are passed on their own,
+	// after the resource they were discovered under.
+	OnResourceDiscovered(resource.Resource)
+	// OnResourceImported is called once the config of a resource was
+	// generated. Dependents are imported, and passed, on their own.
+	OnResourceImported(resource.Resource)
+	// OnResourceSkipped is called for resources which are not imported
+	OnResourceSkipped(r resource.Resource, reason string)
+	// OnDrift is called with the drift found by a sync
+	OnDrift(drift.Report)
+}
+
+// NopHooks ignores every event. Embed it to implement only some of Hooks.
+type NopHooks struct{}
+
+func (NopHooks) OnResourceDiscovered(resource.Resource)      {}
+func (NopHooks) OnResourceImported(resource.Resource)        {}
+func (NopHooks) OnResourceSkipped(resource.Resource, string) {}
+func (NopHooks) OnDrift(drift.Report)                        {}
+
+// Reasons passed to OnResourceSkipped
+const (
+	SkipReasonExcluded      = "excluded"
+	SkipReasonAlreadyExists = "config already exists"
+	SkipReasonPolicy        = "rejected by policy"
+	SkipReasonModified      = "file changed since generated"
+	// SkipReasonFailed is passed for resources which failed to import,
+	// before the import fails
+	SkipReasonFailed = "import failed"
+	// SkipReasonDependencySkipped is passed for dependents of resources
+	// which were not imported
+	SkipReasonDependencySkipped = "depends on a resource which was not imported"
+	// SkipReasonDependentLimit is passed for dependents beyond the limits
+	// of the dependents config
+	SkipReasonDependentLimit = "beyond dependent limits"
+	// SkipReasonDiscoveryFailed is passed for resources whose lookups
+	// failed, e.g. reading their IAM policy, which are left out rather than
+	// failing the whole service
+	SkipReasonDiscoveryFailed = "discovery failed"
+)
+
+// WithHooks sends the events of runs to hooks
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// events returns the hooks of the client, which are never nil
+func (c *Client) events() Hooks {
+	if c.hooks == nil {
+		return NopHooks{}
+	}
+	return c.hooks
+}
@@ -7,37 +7,44 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
-	"github.com/priyanshujain/infrasync/internal/config"
+	"github.com/priyanshujain/infrasync/internal/audit"
 	"github.com/priyanshujain/infrasync/internal/initialize"
+	"github.com/priyanshujain/infrasync/internal/manifest"
 	"github.com/priyanshujain/infrasync/internal/providers"
 	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/telemetry"
 	"github.com/priyanshujain/infrasync/internal/tfimport"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// Client represents the InfraSync client
-type Client struct {
-	Config config.Config
+// InitOptions are the choices made when initializing a repository rather
+// than in the config
+type InitOptions struct {
+	// EncryptState adds an OpenTofu encryption block encrypting state and
+	// plans with a Cloud KMS key
+	EncryptState bool
+	// KMSKey is the full name of the key. If it is empty, the only
+	// encryption key of the project is used.
+	KMSKey string
+	// CreateBackend creates the bucket of the gcs backend if it doesn't
+	// exist and generates its config, so that it is managed too
+	CreateBackend bool
+	// GitHubOIDC is the owner/name of the GitHub repository the repository
+	// is pushed to. If it is set, the config of a workload identity pool
+	// trusting its workflow is generated, and the workflow authenticates
+	// with it instead of a service account key.
+	GitHubOIDC string
 }
 
-// NewClient creates a new InfraSync client with the provided configuration
-func NewClient(cfg config.Config) *Client {
-	return &Client{
-		Config: cfg,
-	}
-}
-
-// DefaultClient creates a client with configuration loaded from the default path
-func DefaultClient() (*Client, error) {
-	cfg, err := config.Load()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
-	}
-	return NewClient(cfg), nil
-}
+// githubRepositoryRe matches the owner/name of a GitHub repository
+var githubRepositoryRe = regexp.MustCompile(`^[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
 
 // Initialize creates a new IaC repository with Terraform configurations
-func (c *Client) Initialize(ctx context.Context) error {
+func (c *Client) Initialize(ctx context.Context, opts InitOptions) error {
 	outputPath := c.Config.ProjectPath()
 
 	absOutputPath, err := filepath.Abs(outputPath)
@@ -56,7 +63,36 @@ func (c *Client) Initialize(ctx context.Context) error {
 		}
 	}
 
-	err = initialize.Init(c.Config)
+	var initOpts initialize.Options
+	if opts.EncryptState || opts.KMSKey != "" {
+		provider := c.Config.DefaultProvider()
+		key, err := google.ResolveEncryptionKey(ctx, provider.Credentials, provider.ProjectID, provider.Region, opts.KMSKey)
+		if err != nil {
+			return err
+		}
+		slog.Info("Encrypting state with KMS key", "key", key)
+		initOpts.EncryptionKey = key
+	}
+
+	if err := c.prepareBackend(ctx, opts.CreateBackend); err != nil {
+		return err
+	}
+	initOpts.ManageStateBucket = opts.CreateBackend
+
+	if opts.GitHubOIDC != "" {
+		if !githubRepositoryRe.MatchString(opts.GitHubOIDC) {
+			return fmt.Errorf("invalid GitHub repository %q, expected owner/name", opts.GitHubOIDC)
+		}
+		provider := c.Config.DefaultProvider()
+		number, err := google.ProjectNumber(ctx, provider.Credentials, provider.ProjectID)
+		if err != nil {
+			return err
+		}
+		initOpts.GitHubRepository = opts.GitHubOIDC
+		initOpts.ProjectNumber = number
+	}
+
+	err = initialize.Init(c.Config, initOpts)
 	if err != nil {
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
@@ -65,58 +101,131 @@ func (c *Client) Initialize(ctx context.Context) error {
 	return nil
 }
 
-// Import imports cloud resources and generates Terraform code
-func (c *Client) Import(ctx context.Context) error {
-	absOutputPath := c.Config.ProjectPath()
-	provider := c.Config.DefaultProvider()
+// prepareBackend creates the state bucket of a gcs backend if create is set,
+// or else warns if it doesn't exist, as terraform init would fail
+func (c *Client) prepareBackend(ctx context.Context, create bool) error {
+	backend := c.Config.DefaultBackend()
+	if backend.Type != providers.BackendTypeGCS {
+		if create {
+			return fmt.Errorf("--create-backend needs a gcs backend, not %s", backend.Type)
+		}
+		return nil
+	}
 
-	resourcesDir := filepath.Join(absOutputPath, "resources", provider.Type.String(), provider.ProjectID)
+	if create {
+		provider := c.Config.DefaultProvider()
+		return initialize.CreateBucket(ctx, backend.Credentials, provider.ProjectID, initialize.BucketLocation(provider.Region), backend.Bucket)
+	}
 
-	for _, dir := range []string{resourcesDir} {
-		if _, err := os.Stat(dir); os.IsNotExist(err) {
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
-			}
-		}
+	exists, err := initialize.BucketExists(ctx, backend.Credentials, backend.Bucket)
+	if err != nil {
+		slog.Warn("Failed to check the state bucket", "bucket", backend.Bucket, "error", err)
+	} else if !exists {
+		slog.Warn("State bucket does not exist, run init with --create-backend to create it", "bucket", backend.Bucket)
+	}
+	return nil
+}
+
+// Import imports cloud resources and generates Terraform code. The result is
+// returned even if the import failed part way.
+func (c *Client) Import(ctx context.Context) (result *ImportResult, err error) {
+	unlock, err := c.lockRepository("import")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	audit.StartRun()
+	start := time.Now()
+	defer func() { c.writeRunManifest("import", start, result, nil, err) }()
+
+	result, err = c.importResources(ctx, nil, c.commitServiceImports)
+	writeJobSummary(result, nil)
+	if err != nil {
+		return result, err
 	}
+	return result, c.commitRunImports(result)
+}
 
+// importResources imports every configured service, passing each discovered
+// resource to visit as soon as it is imported and the result to done once a
+// service is imported
+func (c *Client) importResources(ctx context.Context, visit func(resource.Resource), done func(*ImportResult, resource.Service) error) (*ImportResult, error) {
+	start := time.Now()
+	result := &ImportResult{}
+	defer func() { result.Duration = time.Since(start) }()
+
+	provider := c.Config.DefaultProvider()
 	services := c.Config.GoogleServices(provider)
 
+	policies := c.newPolicyChecker(result)
+
+	events := resultRecorder{hooks: c.events(), result: result}
 	for _, service := range services {
-		serviceResourcesDir := filepath.Join(resourcesDir, service.String())
+		result.service(service)
 
-		for _, dir := range []string{serviceResourcesDir} {
-			if _, err := os.Stat(dir); os.IsNotExist(err) {
-				if err := os.MkdirAll(dir, 0755); err != nil {
-					return fmt.Errorf("failed to create service directory: %w", err)
-				}
+		err := c.importService(ctx, service, nil, policies, events, func(r resource.Resource) error {
+			if visit != nil {
+				visit(r)
 			}
+			return nil
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to process service: %w", err)
 		}
 
-		if err := c.ImportService(ctx, service); err != nil {
-			return fmt.Errorf("failed to process service: %w", err)
+		if done != nil {
+			if err := done(result, service); err != nil {
+				return result, err
+			}
 		}
 	}
 
-	return nil
+	return result, policies.finish()
 }
 
 // ImportService imports resources for a specific service
-func (c *Client) ImportService(ctx context.Context, service google.Service) error {
+func (c *Client) ImportService(ctx context.Context, service resource.Service) (*ImportResult, error) {
+	start := time.Now()
+	result := &ImportResult{}
+	defer func() { result.Duration = time.Since(start) }()
+	result.service(service)
+
+	policies := c.newPolicyChecker(result)
+	events := resultRecorder{hooks: c.events(), result: result}
+	if err := c.importService(ctx, service, nil, policies, events, nil); err != nil {
+		return result, err
+	}
+	return result, policies.finish()
+}
+
+// importService imports the resources of a service one at a time, passing
+// each to visit once its config is generated. Resources rejected by policies
+// are skipped. The resources are discovered unless resourceIter is given.
+func (c *Client) importService(ctx context.Context, service resource.Service, resourceIter google.ResourceIterator, policies *policyChecker, events Hooks, visit func(resource.Resource) error) (err error) {
+	ctx, span := telemetry.Start(ctx, "import.service", attribute.String("service", service.String()))
+	defer func() { telemetry.End(span, err) }()
+
 	path := c.Config.ProjectPath()
 	provider := c.Config.DefaultProvider()
 
+	// Checked before terraform is initialized, to fail fast
+	if resourceIter == nil {
+		if err := c.checkPermissions(ctx, service, provider); err != nil {
+			return err
+		}
+	}
+
 	absOutputPath, err := filepath.Abs(path)
 	if err != nil {
 		return fmt.Errorf("failed to get absolute path for output: %w", err)
 	}
 
-	tf, err := tfimport.NewImporter(absOutputPath)
+	tf, err := tfimport.NewImporter(absOutputPath, c.Config.Layout())
 	if err != nil {
 		return fmt.Errorf("failed to create Terraform generator: %w", err)
 	}
 
-	runner, err := tfimport.New(absOutputPath)
+	runner, err := tfimport.New(ctx, absOutputPath, policies.options(c.generatorOptions()))
 	if err != nil {
 		return fmt.Errorf("failed to create runner: %w", err)
 	}
@@ -125,67 +234,390 @@ func (c *Client) ImportService(ctx context.Context, service google.Service) erro
 		return fmt.Errorf("failed to initialize runner: %w", err)
 	}
 
-	var s google.ResourceImporter
-	switch service {
-	case "pubsub":
-		s, err = google.NewPubsub(ctx, providers.Provider{
-			Type: providers.ProviderTypeGoogle, ProjectID: provider.ProjectID})
+	if resourceIter == nil {
+		s, err := c.newImporter(ctx, service, provider)
 		if err != nil {
-			return fmt.Errorf("failed to create PubSub client: %w", err)
+			return err
 		}
-	case "cloudsql":
-		s, err = google.NewCloudSQL(ctx, providers.Provider{
-			Type: providers.ProviderTypeGoogle, ProjectID: provider.ProjectID})
-		if err != nil {
-			return fmt.Errorf("failed to create CloudSQL client: %w", err)
+		if s == nil {
+			slog.Info("Service is not supported", "service", service)
+			return nil
 		}
-	case "storage":
-		s, err = google.NewStorage(ctx, providers.Provider{
-			Type: providers.ProviderTypeGoogle, ProjectID: provider.ProjectID})
+
+		resourceIter, err = s.Import(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to create Storage client: %w", err)
+			return google.QuotaProjectHint(fmt.Errorf("failed to create resource iterator: %w", err))
 		}
-	default:
-		slog.Info("Service is not supported", "service", service)
-		return nil
-	}
-
-	resourceIter, err := s.Import(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create resource iterator: %w", err)
 	}
 	defer resourceIter.Close()
 
+	filter := c.Config.ExcludeFilter()
+	limits := c.Config.DependentLimits()
+	mappings := c.Config.Mappings()
+
 	var count int
 	for {
-		resource, err := resourceIter.Next(ctx)
+		discoverCtx, discoverSpan := telemetry.Start(ctx, "discover")
+		resource, err := nextResource(discoverCtx, resourceIter, func(failed google.ResourceError) {
+			// Reported at the address it would be imported at, as it may
+			// be in state
+			events.OnResourceSkipped(mappings.Apply(failed.Resource), SkipReasonDiscoveryFailed)
+		})
+		telemetry.End(discoverSpan, err)
 		if err != nil {
-			return fmt.Errorf("error getting next resource: %w", err)
+			return google.QuotaProjectHint(fmt.Errorf("error getting next resource: %w", err))
 		}
 
 		if resource == nil {
 			break
 		}
+		discovered := resource.Flatten()
+		for _, r := range discovered {
+			r.Dependents = nil
+			events.OnResourceDiscovered(r)
+		}
+
+		filtered, ok := filter.Apply(*resource)
+		notExcluded := make(map[string]bool)
+		kept := make(map[string]bool)
+		if ok {
+			for _, r := range filtered.Flatten() {
+				notExcluded[r.Address()] = true
+			}
+			filtered = limits.Apply(filtered)
+			for _, r := range filtered.Flatten() {
+				kept[r.Address()] = true
+			}
+		}
+		// Skipped resources are reported at the address they would be
+		// imported at, as they may be in state
+		for _, r := range discovered {
+			r.Dependents = nil
+			switch {
+			case !notExcluded[r.Address()]:
+				slog.Info("Skipping excluded resource", "resource", r.ID)
+				events.OnResourceSkipped(mappings.Apply(r), SkipReasonExcluded)
+			case !kept[r.Address()]:
+				slog.Info("Skipping dependent beyond limits", "resource", r.ID)
+				events.OnResourceSkipped(mappings.Apply(r), SkipReasonDependentLimit)
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		graph := newImportGraph(mappings.Apply(filtered))
+		skipped, err := importInOrder(ctx, tf, runner, graph, events, &count)
+		if err != nil {
+			return err
+		}
+
+		if visit != nil && !skipped[0] {
+			if err := visit(graph.tree(skipped)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if minResources := c.Config.ExtractVariables(); minResources > 0 {
+		if err := tfimport.ExtractVariables(absOutputPath, c.Config.ManifestPath(), minResources); err != nil {
+			return fmt.Errorf("failed to extract variables: %w", err)
+		}
+	}
+
+	return c.writeOutputs(absOutputPath)
+}
+
+// writeOutputs regenerates the outputs of the project in absOutputPath for the
+// resources generated in it
+func (c *Client) writeOutputs(absOutputPath string) error {
+	m, err := manifest.Load(c.Config.ManifestPath(), absOutputPath)
+	if err != nil {
+		return err
+	}
+	if err := tfimport.WriteOutputs(absOutputPath, m.Addresses()); err != nil {
+		return fmt.Errorf("failed to write outputs: %w", err)
+	}
+	return nil
+}
+
+// discoveryAttempts is how often a resource whose discovery failed for a
+// transient reason is looked up before it is skipped
+const discoveryAttempts = 3
+
+// nextResource returns the next resource of it, or nil once there are no
+// more. Resources which can't be discovered are retried if their error is
+// retryable, and else skipped and passed to skipped, so that they don't fail
+// the whole service. Their error is returned instead if skipped is nil.
+func nextResource(ctx context.Context, it google.ResourceIterator, skipped func(google.ResourceError)) (*resource.Resource, error) {
+	attempts := 0
+	for {
+		resource, err := it.Next(ctx)
+		var resourceErr *google.ResourceError
+		if !errors.As(err, &resourceErr) {
+			return resource, err
+		}
 
-		if err := tf.SaveImportBlock(*resource); err != nil {
-			return fmt.Errorf("failed to save import block: %w", err)
+		attempts++
+		if resourceErr.Retryable() && attempts < discoveryAttempts {
+			slog.Warn("Retrying discovery of resource", "resource", resourceErr.Resource.ID, "error", resourceErr.Err)
+			select {
+			case <-time.After(time.Duration(attempts) * time.Second):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
 		}
 
-		if err := runner.Import(ctx, *resource); err != nil {
+		if skipped == nil {
+			return nil, err
+		}
+		slog.Error("Skipping resource which could not be discovered", "resource", resourceErr.Resource.ID, "error", resourceErr.Err)
+		it.Skip()
+		skipped(*resourceErr)
+		attempts = 0
+	}
+}
+
+// configGenerator generates the config of resources whose import blocks were
+// saved, see tfimport.New
+type configGenerator interface {
+	Import(ctx context.Context, resource resource.Resource) error
+	ImportDependent(ctx context.Context, dependent, root resource.Resource) error
+	CleanupImportBlocks(resource resource.Resource) error
+}
+
+// importInOrder imports a discovered resource and its dependents one at a
+// time in topological order, so that dependents are only imported once the
+// resources they depend on are. Resources depending on a resource which was
+// skipped or failed to import are skipped too. It returns the skipped nodes;
+// on failure the rest of the graph is still imported or skipped before the
+// error is returned.
+func importInOrder(ctx context.Context, tf tfimport.TerraformImporter, runner configGenerator, graph *importGraph, events Hooks, count *int) (map[int]bool, error) {
+	order, err := graph.order()
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := make(map[int]bool)
+	var failure error
+	for _, i := range order {
+		resource := graph.nodes[i]
+		if graph.skippedParent(i, skipped) {
+			slog.Warn("Skipping resource depending on a resource which was not imported", "resource", resource.ID)
+			events.OnResourceSkipped(resource, SkipReasonDependencySkipped)
+			skipped[i] = true
+			continue
+		}
+
+		_, saveSpan := telemetry.Start(ctx, "generate.import_block", attribute.String("resource", resource.ID))
+		err = tf.SaveImportBlock(resource)
+		telemetry.End(saveSpan, err)
+		if err != nil {
+			return skipped, fmt.Errorf("failed to save import block: %w", err)
+		}
+
+		var exists bool
+		if i == 0 {
+			// Moves of renamed resources cover the dependents too
+			err = runner.Import(ctx, graph.tree(nil))
+		} else {
+			err = runner.ImportDependent(ctx, resource, graph.root(i))
+		}
+		if err != nil {
 			if errors.Is(err, tfimport.ErrAlreadyExists) {
 				slog.Info("Resource already exists", "resource", resource.ID)
+				events.OnResourceSkipped(resource, SkipReasonAlreadyExists)
+				exists = true
+			} else if errors.Is(err, tfimport.ErrRejected) {
+				slog.Warn("Skipping resource rejected by policy", "resource", resource.ID)
+				events.OnResourceSkipped(resource, SkipReasonPolicy)
+				skipped[i] = true
+			} else if errors.Is(err, tfimport.ErrModified) {
+				slog.Warn("Skipping resource in file changed since it was generated", "resource", resource.ID)
+				events.OnResourceSkipped(resource, SkipReasonModified)
+				skipped[i] = true
 			} else {
-				return fmt.Errorf("failed to import resource: %w", err)
+				slog.Error("Failed to import resource", "resource", resource.ID, "error", err)
+				events.OnResourceSkipped(resource, SkipReasonFailed)
+				skipped[i] = true
+				if failure == nil {
+					failure = fmt.Errorf("failed to import resource: %w", err)
+				}
 			}
 		}
 
-		if err := runner.CleanupImportBlocks(*resource); err != nil {
-			return fmt.Errorf("failed to cleanup import blocks: %w", err)
+		if err := runner.CleanupImportBlocks(resource); err != nil {
+			return skipped, fmt.Errorf("failed to cleanup import blocks: %w", err)
 		}
 
-		count++
-		slog.Info("Imported resource", "count", count, "resource", resource.ID)
+		if skipped[i] {
+			continue
+		}
+
+		*count++
+		slog.Info("Imported resource", "count", *count, "resource", resource.ID)
+		if !exists {
+			events.OnResourceImported(resource)
+		}
 	}
+	return skipped, failure
+}
+
+// newImporter returns the importer of a service, or nil if the service is not supported
+func (c *Client) newImporter(ctx context.Context, service resource.Service, provider providers.Provider) (google.ResourceImporter, error) {
+	p := providers.Provider{
+		Type: providers.ProviderTypeGoogle, ProjectID: provider.ProjectID, Regions: provider.Regions,
+		Credentials: provider.Credentials}
 
+	switch opts := c.Config.ServiceOptions(provider, service).(type) {
+	case *google.PubSubOptions:
+		s, err := google.NewPubsub(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PubSub client: %w", err)
+		}
+		return s, nil
+	case *google.CloudSQLOptions:
+		s, err := google.NewCloudSQL(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CloudSQL client: %w", err)
+		}
+		return s, nil
+	case *google.StorageOptions:
+		s, err := google.NewStorage(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Storage client: %w", err)
+		}
+		return s, nil
+	case *google.IAMOptions:
+		s, err := google.NewIAM(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create IAM client: %w", err)
+		}
+		return s, nil
+	case *google.ComputeOptions:
+		s, err := google.NewCompute(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Compute client: %w", err)
+		}
+		return s, nil
+	case *google.ProjectOptions:
+		s, err := google.NewProject(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Project client: %w", err)
+		}
+		return s, nil
+	case *google.FilestoreOptions:
+		s, err := google.NewFilestore(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Filestore client: %w", err)
+		}
+		return s, nil
+	case *google.APIGatewayOptions:
+		s, err := google.NewAPIGateway(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create API Gateway client: %w", err)
+		}
+		return s, nil
+	case *google.EventarcOptions:
+		s, err := google.NewEventarc(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Eventarc client: %w", err)
+		}
+		return s, nil
+	case *google.WorkflowsOptions:
+		s, err := google.NewWorkflows(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Workflows client: %w", err)
+		}
+		return s, nil
+	case *google.IAPOptions:
+		s, err := google.NewIAP(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create IAP client: %w", err)
+		}
+		return s, nil
+	case *google.CertificateManagerOptions:
+		s, err := google.NewCertificateManager(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Certificate Manager client: %w", err)
+		}
+		return s, nil
+	case *google.PrivateCAOptions:
+		s, err := google.NewPrivateCA(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Private CA client: %w", err)
+		}
+		return s, nil
+	case *google.BinaryAuthorizationOptions:
+		s, err := google.NewBinaryAuthorization(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Binary Authorization client: %w", err)
+		}
+		return s, nil
+	case *google.GKEHubOptions:
+		s, err := google.NewGKEHub(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GKE Hub client: %w", err)
+		}
+		return s, nil
+	case *google.DataplexOptions:
+		s, err := google.NewDataplex(ctx, p, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Dataplex client: %w", err)
+		}
+		return s, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Verify runs a terraform plan over the imported resources and returns an error
+// listing every resource whose generated config does not match state
+func (c *Client) Verify(ctx context.Context) error {
+	absOutputPath, err := filepath.Abs(c.Config.ProjectPath())
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+
+	runner, err := tfimport.New(ctx, absOutputPath, c.generatorOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create runner: %w", err)
+	}
+
+	summary, err := runner.Verify(ctx)
+	if err != nil {
+		if errors.Is(err, tfimport.ErrPlanNotEmpty) {
+			for _, change := range summary.Resources {
+				slog.Error("Generated config does not match state", "resource", change.Address,
+					"actions", change.Actions, "import", change.Import)
+			}
+			return fmt.Errorf("plan is not empty: %s", summary)
+		}
+		return fmt.Errorf("failed to verify: %w", err)
+	}
+
+	slog.Info("Verification succeeded, plan is empty")
 	return nil
-}
\ No newline at end of file
+}
+
+func (c *Client) generatorOptions() tfimport.Options {
+	return tfimport.Options{
+		NormalizeRules: c.Config.NormalizeRules(),
+		LifecycleRules: c.Config.LifecycleRules(),
+		Labels:         c.Config.Labels(),
+		Layout:         c.Config.Layout(),
+		Projects:       c.Config.ProjectIDs(),
+		ManifestPath:   c.Config.ManifestPath(),
+		Force:          c.Config.Force,
+		Runner:         c.Config.Runner(),
+		DockerImage:    c.Config.RunnerImage(),
+		Credentials:    c.Config.DefaultProvider().Credentials,
+		Templates:      c.Config.Templates(),
+		StateLocked:    c.stateLocked,
+		SourceFiles: func(resource resource.Resource) bool {
+			opts, ok := c.Config.ServiceOptions(resource.Provider, resource.Service).(*google.WorkflowsOptions)
+			return ok && opts.SourceFiles
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package infrasync
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"google.golang.org/api/googleapi"
+)
+
+// unavailableIterator fails every lookup for a transient reason
+type unavailableIterator struct{}
+
+func (unavailableIterator) Next(context.Context) (*google.Resource, error) {
+	return nil, &google.ResourceError{
+		Resource: google.Resource{ID: "projects/project/topics/orders"},
+		Err:      &googleapi.Error{Code: http.StatusServiceUnavailable},
+	}
+}
+
+func (unavailableIterator) Skip()        {}
+func (unavailableIterator) Err() error   { return nil }
+func (unavailableIterator) Close() error { return nil }
+
+func TestNextResourceCanceledWhileRetrying(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := nextResource(ctx, unavailableIterator{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("nextResource() = %v, want context.Canceled", err)
+	}
+	// Without cancellation the retries wait 1s and then 2s
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("nextResource() took %s, want it to stop once canceled", elapsed)
+	}
+}
@@ -0,0 +1,98 @@
+package infrasync
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/inventory"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// ImportInventory imports exactly the resources listed in an inventory, as
+// written by Export, without discovering them. Every item must belong to the
+// configured project.
+func (c *Client) ImportInventory(ctx context.Context, items []inventory.Item) (result *ImportResult, err error) {
+	provider := c.Config.DefaultProvider()
+
+	// Resources are imported service by service, in the order services first
+	// appear in the inventory
+	var services []resource.Service
+	resources := map[resource.Service][]resource.Resource{}
+	for _, item := range items {
+		if err := item.Validate(); err != nil {
+			return nil, err
+		}
+		if item.Project != provider.ProjectID {
+			return nil, fmt.Errorf("inventory lists %s of project %s, but project %s is configured",
+				item.ID, item.Project, provider.ProjectID)
+		}
+		if !slices.Contains(services, item.Service) {
+			services = append(services, item.Service)
+		}
+		resources[item.Service] = append(resources[item.Service], item.Resource(provider))
+	}
+
+	unlock, err := c.lockRepository("import")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	audit.StartRun()
+
+	start := time.Now()
+	result = &ImportResult{}
+	// Deferred first to run last, once the duration is set
+	defer func() { c.writeRunManifest("import", start, result, nil, err) }()
+	defer writeJobSummary(result, nil)
+	defer func() { result.Duration = time.Since(start) }()
+
+	policies := c.newPolicyChecker(result)
+
+	events := resultRecorder{hooks: c.events(), result: result}
+	for _, service := range services {
+		result.service(service)
+
+		iter := &sliceIterator{resources: resources[service]}
+		if err := c.importService(ctx, service, iter, policies, events, nil); err != nil {
+			return result, fmt.Errorf("failed to process service: %w", err)
+		}
+		if err := c.commitServiceImports(result, service); err != nil {
+			return result, err
+		}
+	}
+
+	if err := policies.finish(); err != nil {
+		return result, err
+	}
+	return result, c.commitRunImports(result)
+}
+
+// sliceIterator returns resources which are already known
+type sliceIterator struct {
+	resources []resource.Resource
+}
+
+func (it *sliceIterator) Next(ctx context.Context) (*resource.Resource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(it.resources) == 0 {
+		return nil, nil
+	}
+	r := it.resources[0]
+	it.resources = it.resources[1:]
+	return &r, nil
+}
+
+func (it *sliceIterator) Skip() {}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+func (it *sliceIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package infrasync
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// junitFailures are the skip reasons reported as failed test cases rather
+// than skipped ones, as they need someone to look into them
+var junitFailures = []string{SkipReasonFailed, SkipReasonDiscoveryFailed, SkipReasonPolicy}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnitReport writes the result of an import as a JUnit XML report, with
+// a test suite per service and a test case per resource. Imported resources
+// pass, resources which failed to import or were rejected by policy fail and
+// the other skipped resources are skipped. runErr, the error the import failed
+// with if any, is reported as an error of its own test suite.
+func WriteJUnitReport(w io.Writer, result ImportResult, runErr error) error {
+	report := junitTestSuites{
+		Name: "infrasync import",
+		Time: fmt.Sprintf("%.3f", result.Duration.Seconds()),
+	}
+
+	for _, s := range result.Services {
+		suite := junitTestSuite{Name: s.Service.String()}
+		for _, r := range result.Resources {
+			if r.Service != s.Service {
+				continue
+			}
+			suite.Cases = append(suite.Cases, junitCase(r, &suite))
+		}
+		suite.Tests = len(suite.Cases)
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if runErr != nil {
+		report.Suites = append(report.Suites, junitTestSuite{
+			Name:   "infrasync",
+			Tests:  1,
+			Errors: 1,
+			Cases: []junitTestCase{{
+				ClassName: "infrasync",
+				Name:      "import",
+				Error:     &junitMessage{Message: runErr.Error()},
+			}},
+		})
+	}
+
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitCase returns the test case of a resource, counted in suite
+func junitCase(r ResourceResult, suite *junitTestSuite) junitTestCase {
+	tc := junitTestCase{
+		ClassName: fmt.Sprintf("%s.%s", suite.Name, r.Type),
+		Name:      r.Address,
+		SystemOut: r.ID,
+	}
+	switch {
+	case r.Status == ResourceImported:
+	case slices.Contains(junitFailures, r.Reason):
+		tc.Failure = &junitMessage{Message: r.Reason}
+		suite.Failures++
+	default:
+		tc.Skipped = &junitMessage{Message: r.Reason}
+		suite.Skipped++
+	}
+	return tc
+}
@@ -0,0 +1,53 @@
+package infrasync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// ApplyLabels adds the configured labels to the cloud resources of every
+// configured service which support labels. Labels already set on a resource
+// keep their value.
+func (c *Client) ApplyLabels(ctx context.Context) error {
+	labels := c.Config.Labels()
+	if len(labels) == 0 {
+		return fmt.Errorf("no labels configured")
+	}
+
+	provider := c.Config.DefaultProvider()
+	labeler, err := google.NewLabeler(ctx, provider)
+	if err != nil {
+		return err
+	}
+	defer labeler.Close()
+
+	var count int
+	for _, service := range c.Config.GoogleServices(provider) {
+		err := c.discover(ctx, service, c.Config.DependentLimits(), func(resource resource.Resource) error {
+			for _, r := range resource.Flatten() {
+				if _, ok := google.LabelAttributes[r.Type]; !ok {
+					continue
+				}
+				changed, err := labeler.Apply(ctx, r, labels)
+				if err != nil {
+					return err
+				}
+				if changed {
+					count++
+					slog.Info("Labeled resource", "resource", r.Address())
+				}
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to label %s resources: %w", service, err)
+		}
+	}
+
+	slog.Info("Labels applied", "count", count)
+	return nil
+}
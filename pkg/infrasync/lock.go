@@ -0,0 +1,56 @@
+package infrasync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/priyanshujain/infrasync/internal/lock"
+	"github.com/priyanshujain/infrasync/internal/state"
+)
+
+// lockRepository keeps other runs from writing to the project until the
+// returned function is called
+func (c *Client) lockRepository(operation string) (func(), error) {
+	l, err := lock.Acquire(c.Config.LockPath(), operation, c.Config.ForceUnlock)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := l.Release(); err != nil {
+			slog.Error("Failed to release lock", "error", err)
+		}
+	}, nil
+}
+
+// lockState locks the state in backends which support locking until the
+// returned function is called, and reports whether it did. A lock held by
+// another run is broken with --force-unlock.
+func (c *Client) lockState(ctx context.Context, backend state.Backend, operation string) (func(), bool, error) {
+	locker, ok := backend.(state.Locker)
+	if !ok {
+		return func() {}, false, nil
+	}
+
+	err := locker.Lock(ctx, operation)
+	var lockErr *state.LockError
+	if errors.As(err, &lockErr) && c.Config.ForceUnlock {
+		slog.Warn("Breaking state lock", "who", lockErr.Holder.Who, "id", lockErr.Holder.ID)
+		if err := locker.ForceUnlock(ctx, lockErr.Holder.ID); err != nil {
+			return nil, false, err
+		}
+		err = locker.Lock(ctx, operation)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to lock state: %w", err)
+	}
+
+	return func() {
+		// The run's context may be canceled by now, and the lock must
+		// still be released
+		if err := locker.Unlock(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("Failed to unlock state", "error", err)
+		}
+	}, true, nil
+}
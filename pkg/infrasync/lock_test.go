@@ -0,0 +1,59 @@
+package infrasync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/priyanshujain/infrasync/internal/config"
+)
+
+// readOnlyBackend is a backend without locking, like gcs and tfc
+type readOnlyBackend struct{}
+
+func (readOnlyBackend) Read(context.Context) ([]byte, error) { return nil, nil }
+
+// lockingBackend records its lock calls
+type lockingBackend struct {
+	readOnlyBackend
+	locked, unlocked bool
+}
+
+func (b *lockingBackend) Lock(context.Context, string) error {
+	b.locked = true
+	return nil
+}
+
+func (b *lockingBackend) Unlock(context.Context) error {
+	b.unlocked = true
+	return nil
+}
+
+func (b *lockingBackend) ForceUnlock(context.Context, string) error { return nil }
+
+func TestLockStateWithoutLocker(t *testing.T) {
+	c := &Client{Config: config.Config{}}
+	unlock, locked, err := c.lockState(context.Background(), readOnlyBackend{}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if locked {
+		t.Error("lockState() reported a lock for a backend without locking")
+	}
+}
+
+func TestLockStateWithLocker(t *testing.T) {
+	c := &Client{Config: config.Config{}}
+	backend := &lockingBackend{}
+	unlock, locked, err := c.lockState(context.Background(), backend, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked || !backend.locked {
+		t.Fatalf("lockState() = %t, backend locked %t, want both locked", locked, backend.locked)
+	}
+	unlock()
+	if !backend.unlocked {
+		t.Error("state wasn't unlocked")
+	}
+}
@@ -0,0 +1,78 @@
+package infrasync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/priyanshujain/infrasync/internal/policy"
+	"github.com/priyanshujain/infrasync/internal/tfimport"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// ErrPolicyViolation is returned by Import and Sync when policies are enforced
+// and an imported resource violates one of them
+var ErrPolicyViolation = errors.New("policy violation")
+
+// policyChecker evaluates the configured Rego policies against the generated
+// config of each resource before it is written, recording the violations in
+// the result of the import
+type policyChecker struct {
+	path    string
+	enforce bool
+	result  *ImportResult
+}
+
+func (c *Client) newPolicyChecker(result *ImportResult) *policyChecker {
+	return &policyChecker{path: c.Config.PolicyPath(), enforce: c.Config.EnforcePolicy(), result: result}
+}
+
+// check is the tfimport.Options.Check hook. Resources that violate a policy
+// are rejected when policies are enforced.
+func (p *policyChecker) check(ctx context.Context, r resource.Resource, attributes map[string]map[string]any) error {
+	violations, err := policy.Evaluate(ctx, p.path, []resource.Resource{withAttributes(r, attributes)})
+	if err != nil {
+		return err
+	}
+
+	for _, v := range violations {
+		slog.Warn("Policy violation", "resource", v.Address, "message", v.Message)
+		p.result.Violations = append(p.result.Violations, PolicyViolation{Address: v.Address, Message: v.Message})
+	}
+
+	if len(violations) > 0 && p.enforce {
+		return fmt.Errorf("%w: %s violates %d policy rule(s)", tfimport.ErrRejected, r.Address(), len(violations))
+	}
+	return nil
+}
+
+// options adds the check to opts if policies are configured
+func (p *policyChecker) options(opts tfimport.Options) tfimport.Options {
+	if p.path != "" {
+		opts.Check = p.check
+	}
+	return opts
+}
+
+// finish fails if policies are enforced and any were violated
+func (p *policyChecker) finish() error {
+	if len(p.result.Violations) > 0 && p.enforce {
+		return fmt.Errorf("%w: %d violation(s)", ErrPolicyViolation, len(p.result.Violations))
+	}
+	return nil
+}
+
+// withAttributes returns r and its dependents with the generated attributes in
+// place of the discovered ones
+func withAttributes(r resource.Resource, attributes map[string]map[string]any) resource.Resource {
+	if a, ok := attributes[r.Address()]; ok {
+		r.Attributes = a
+	}
+	dependents := make([]resource.Resource, len(r.Dependents))
+	for i, d := range r.Dependents {
+		dependents[i] = withAttributes(d, attributes)
+	}
+	r.Dependents = dependents
+	return r
+}
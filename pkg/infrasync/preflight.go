@@ -0,0 +1,167 @@
+package infrasync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// serviceQuotas are the approximate default per-minute quotas of the APIs
+// discovery calls. Projects may have raised or lowered limits.
+var serviceQuotas = map[resource.Service]int{
+	google.ServicePubSub:   6000, // Pub/Sub administrator operations
+	google.ServiceStorage:  5000, // Cloud Storage bucket metadata reads
+	google.ServiceCloudSQL: 180,  // Cloud SQL Admin API queries per user
+}
+
+const (
+	// apiCallLatency is the typical latency of a single discovery call
+	apiCallLatency = 200 * time.Millisecond
+	// generateDuration is the typical duration of generating the config of a
+	// resource with terraform plan
+	generateDuration = 3 * time.Second
+)
+
+// ServiceEstimate is the preflight estimate of importing a service
+type ServiceEstimate struct {
+	Service     resource.Service
+	Resources   map[resource.Type]int
+	APICalls    int
+	Parallelism int
+	Duration    time.Duration
+	// PeakRate is the highest expected number of API calls per minute
+	PeakRate int
+	Quota    int
+}
+
+// ExceedsQuota reports whether discovery may run into the per-minute quota
+func (e ServiceEstimate) ExceedsQuota() bool {
+	return e.Quota > 0 && e.APICalls > e.Quota && e.PeakRate > e.Quota
+}
+
+// SuggestedParallelism returns the parallelism which keeps discovery within quota
+func (e ServiceEstimate) SuggestedParallelism() int {
+	return max(1, int(float64(e.Quota)*apiCallLatency.Seconds()/60))
+}
+
+type PreflightReport struct {
+	Services []ServiceEstimate
+	Duration time.Duration
+}
+
+// Preflight counts the resources of every configured service with cheap
+// listing calls and estimates the API calls and duration of an import.
+func (c *Client) Preflight(ctx context.Context) (PreflightReport, error) {
+	provider := c.Config.DefaultProvider()
+
+	filter := c.Config.ExcludeFilter()
+
+	var report PreflightReport
+	for _, service := range c.Config.GoogleServices(provider) {
+		if err := c.checkPermissions(ctx, service, provider); err != nil {
+			return PreflightReport{}, err
+		}
+		s, err := c.newImporter(ctx, service, provider)
+		if err != nil {
+			return PreflightReport{}, err
+		}
+		if s == nil {
+			continue
+		}
+
+		counter, ok := s.(google.ResourceCounter)
+		if !ok {
+			s.Close()
+			continue
+		}
+		count, err := counter.Count(ctx, filter)
+		s.Close()
+		if err != nil {
+			return PreflightReport{}, fmt.Errorf("failed to count %s resources: %w", service, err)
+		}
+
+		parallelism := 1
+		switch opts := c.Config.ServiceOptions(provider, service).(type) {
+		case *google.PubSubOptions:
+			parallelism = opts.Parallelism
+		case *google.StorageOptions:
+			parallelism = opts.Parallelism
+		}
+
+		// Config is generated one top-level resource at a time while
+		// discovery runs ahead, so the slower of the two dominates
+		var generated int
+		if types := google.ResourceTypes(service); len(types) > 0 {
+			generated = count.Resources[types[0]]
+		}
+		discovery := time.Duration(count.APICalls) * apiCallLatency / time.Duration(parallelism)
+		duration := max(discovery, time.Duration(generated)*generateDuration)
+
+		estimate := ServiceEstimate{
+			Service:     service,
+			Resources:   count.Resources,
+			APICalls:    count.APICalls,
+			Parallelism: parallelism,
+			Duration:    duration,
+			PeakRate:    int(float64(parallelism) * time.Minute.Seconds() / apiCallLatency.Seconds()),
+			Quota:       serviceQuotas[service],
+		}
+		report.Services = append(report.Services, estimate)
+		report.Duration += duration
+	}
+
+	return report, nil
+}
+
+// checkPermissions fails if the credentials of provider lack permissions the
+// importer of service needs on its project, listing them with the roles which
+// grant them. Permissions which can't be tested are left to the importer to
+// run into.
+func (c *Client) checkPermissions(ctx context.Context, service resource.Service, provider providers.Provider) error {
+	missing, err := google.MissingPermissions(ctx, provider, c.Config.ServiceOptions(provider, service))
+	if err != nil {
+		slog.Warn("Failed to check permissions, importing anyway", "service", service, "error", err)
+		return nil
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var grants []string
+	for _, role := range slices.Sorted(maps.Keys(missing)) {
+		grants = append(grants, fmt.Sprintf("%s (%s)", role, strings.Join(missing[role], ", ")))
+	}
+	return fmt.Errorf("missing permissions to import %s in project %s, grant %s", service, provider.ProjectID,
+		strings.Join(grants, "; "))
+}
+
+// LogPreflight logs the estimates of a preflight report and warns about
+// services which may exceed their API quota
+func LogPreflight(report PreflightReport) {
+	for _, estimate := range report.Services {
+		for resourceType, count := range estimate.Resources {
+			slog.Info("Resources to import", "service", estimate.Service, "type", resourceType, "count", count)
+		}
+		slog.Info("Estimated discovery", "service", estimate.Service, "api_calls", estimate.APICalls,
+			"duration", estimate.Duration.Round(time.Second))
+
+		if estimate.ExceedsQuota() {
+			suggestion := "request a higher quota or import the service in smaller batches"
+			if suggested := estimate.SuggestedParallelism(); suggested < estimate.Parallelism {
+				suggestion = fmt.Sprintf("lower --parallelism to %d or request a higher quota", suggested)
+			}
+			slog.Warn("Discovery may exceed the API quota", "service", estimate.Service,
+				"calls_per_minute", estimate.PeakRate, "quota_per_minute", estimate.Quota,
+				"suggestion", suggestion)
+		}
+	}
+	slog.Info("Estimated import duration", "duration", report.Duration.Round(time.Second))
+}
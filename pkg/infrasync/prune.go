@@ -0,0 +1,159 @@
+package infrasync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/tfimport"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// Orphan is a generated file whose resources no longer exist in the cloud
+type Orphan struct {
+	Path      string
+	Addresses []string
+}
+
+// Orphans discovers the resources of every configured service and returns
+// the generated files, as recorded in the manifest, all of whose resources
+// are gone. Files of services which are not configured or not supported are
+// never orphans, as their resources are not discovered.
+func (c *Client) Orphans(ctx context.Context) ([]Orphan, error) {
+	absOutputPath, err := filepath.Abs(c.Config.ProjectPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+	m, err := manifest.Load(c.Config.ManifestPath(), absOutputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make(map[resource.Type]bool)
+	exists := make(map[string]bool)
+	provider := c.Config.DefaultProvider()
+	for _, service := range c.Config.GoogleServices(provider) {
+		// Dependents beyond the limits and excluded resources still exist
+		// and their files are no orphans
+		err := c.discover(ctx, service, google.NoDependentLimits, func(r resource.Resource) error {
+			for _, r := range r.Flatten() {
+				exists[r.Address()] = true
+			}
+			return nil
+		}, func(r resource.Resource) {
+			exists[r.Address()] = true
+		})
+		if errors.Is(err, errUnsupportedService) {
+			slog.Info("Service is not supported", "service", service)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover %s resources: %w", service, err)
+		}
+		for _, t := range google.ResourceTypes(service) {
+			discovered[t] = true
+		}
+	}
+
+	gone := make(map[string][]string)
+	kept := make(map[string]bool)
+	for address, path := range m.Addresses() {
+		resourceType, _, _ := strings.Cut(address, ".")
+		// Resources outside the configured regions aren't discovered
+		if exists[address] || !discovered[resource.Type(resourceType)] ||
+			google.OutsideRegions(provider, resource.Type(resourceType), m.IDs[address]) {
+			kept[path] = true
+			continue
+		}
+		gone[path] = append(gone[path], address)
+	}
+
+	var orphans []Orphan
+	for path, addresses := range gone {
+		slices.Sort(addresses)
+		if kept[path] {
+			slog.Warn("Resources no longer exist but share a file with existing ones, remove them by hand",
+				"file", path, "resources", addresses)
+			continue
+		}
+		orphans = append(orphans, Orphan{Path: path, Addresses: addresses})
+	}
+	slices.SortFunc(orphans, func(a, b Orphan) int { return strings.Compare(a.Path, b.Path) })
+	return orphans, nil
+}
+
+// Prune deletes orphaned files and removes their resources from state. The
+// resources are gone already, so nothing is destroyed.
+func (c *Client) Prune(ctx context.Context, orphans []Orphan) error {
+	if len(orphans) == 0 {
+		return nil
+	}
+
+	unlock, err := c.lockRepository("prune")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	audit.StartRun()
+
+	absOutputPath, err := filepath.Abs(c.Config.ProjectPath())
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+
+	runner, err := tfimport.New(ctx, absOutputPath, c.generatorOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create runner: %w", err)
+	}
+
+	if err := runner.Initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize runner: %w", err)
+	}
+
+	inState, err := runner.StateList(ctx)
+	if err != nil {
+		return err
+	}
+
+	var remove []string
+	for _, orphan := range orphans {
+		for _, address := range orphan.Addresses {
+			if slices.Contains(inState, address) {
+				remove = append(remove, address)
+			}
+		}
+	}
+	if len(remove) > 0 {
+		if err := runner.StateRemove(ctx, remove...); err != nil {
+			return err
+		}
+	}
+	slog.Info("Removed resources from state", "count", len(remove))
+
+	m, err := manifest.Load(c.Config.ManifestPath(), absOutputPath)
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		if _, err := os.Stat(orphan.Path); err == nil {
+			if err := audit.Remove(orphan.Path); err != nil {
+				return fmt.Errorf("failed to remove orphaned file: %w", err)
+			}
+		}
+		m.Forget(orphan.Path)
+	}
+	if err := m.Save(); err != nil {
+		return err
+	}
+	slog.Info("Removed orphaned files", "count", len(orphans))
+
+	return c.writeOutputs(absOutputPath)
+}
@@ -0,0 +1,120 @@
+package infrasync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/state"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// remediate reverts the drift in report of the resource types selected in
+// drift.remediate to what the state in backend declares, and returns the addresses of the
+// remediated resources. Every remediation is logged as a preview first and
+// none is made unless confirm approves them all.
+func (c *Client) remediate(ctx context.Context, backend state.Backend, report drift.Report, confirm func([]google.Remediation) bool) ([]string, error) {
+	remediations, err := c.remediations(ctx, backend, report)
+	if err != nil {
+		return nil, err
+	}
+	if len(remediations) == 0 {
+		slog.Info("No drift to remediate")
+		return nil, nil
+	}
+
+	for _, r := range remediations {
+		slog.Info("Would remediate drift", "resource", r.Address, "change", r.String())
+	}
+	if confirm == nil || !confirm(remediations) {
+		slog.Info("Nothing was remediated")
+		return nil, nil
+	}
+
+	remediator, err := google.NewRemediator(ctx, c.Config.DefaultProvider())
+	if err != nil {
+		return nil, err
+	}
+	defer remediator.Close()
+
+	var remediated []string
+	for _, r := range remediations {
+		changed, err := remediator.Apply(ctx, r)
+		if err != nil || changed {
+			audit.Cloud(audit.ActionModify, r.Address, err)
+		}
+		if err != nil {
+			return remediated, fmt.Errorf("failed to remediate %s: %w", r.Address, err)
+		}
+		remediated = append(remediated, r.Address)
+		if changed {
+			slog.Info("Remediated drift", "resource", r.Address, "change", r.String())
+		} else {
+			slog.Info("Drift was already reverted", "resource", r.Address)
+		}
+	}
+	return remediated, nil
+}
+
+// remediations returns the remediations of the modified and deleted resources
+// in report whose type is selected, from their attributes in the state in
+// backend
+func (c *Client) remediations(ctx context.Context, backend state.Backend, report drift.Report) ([]google.Remediation, error) {
+	types := c.Config.RemediateTypes()
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no resource types selected in drift.remediate")
+	}
+
+	drifted := make(map[string]bool)
+	for _, change := range report.Modified {
+		drifted[change.Address] = true
+	}
+	for _, r := range report.Deleted {
+		drifted[r.Address] = true
+	}
+	if len(drifted) == 0 {
+		return nil, nil
+	}
+
+	data, err := backend.Read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	managed, err := state.Resources(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var remediations []google.Remediation
+	for _, r := range managed {
+		if !drifted[r.Address] || !slices.Contains(types, resource.Type(r.Type)) {
+			continue
+		}
+		remediation, ok := google.NewRemediation(r.Address, resource.Type(r.Type), r.Attributes)
+		if !ok {
+			slog.Warn("Drift of resource can't be remediated", "resource", r.Address)
+			continue
+		}
+		remediations = append(remediations, remediation)
+	}
+	return remediations, nil
+}
+
+// withoutRemediated returns report without the drift of the remediated
+// resources
+func withoutRemediated(report drift.Report, remediated []string) drift.Report {
+	if len(remediated) == 0 {
+		return report
+	}
+	report.Modified = slices.DeleteFunc(slices.Clone(report.Modified), func(change drift.Change) bool {
+		return slices.Contains(remediated, change.Address)
+	})
+	report.Deleted = slices.DeleteFunc(slices.Clone(report.Deleted), func(r state.Resource) bool {
+		return slices.Contains(remediated, r.Address)
+	})
+	return report
+}
@@ -0,0 +1,138 @@
+package infrasync
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// ResourceStatus is the outcome of importing a resource
+type ResourceStatus string
+
+const (
+	ResourceImported ResourceStatus = "imported"
+	ResourceSkipped  ResourceStatus = "skipped"
+)
+
+// ResourceResult is the outcome of importing a single resource, top-level or
+// dependent
+type ResourceResult struct {
+	Service resource.Service
+	Type    resource.Type
+	Address string
+	ID      string
+	Status  ResourceStatus
+	// Reason explains why a skipped resource was not imported
+	Reason string
+}
+
+// ServiceResult counts the resources of a service by outcome
+type ServiceResult struct {
+	Service    resource.Service
+	Discovered int
+	Imported   int
+	Skipped    int
+}
+
+// PolicyViolation is a policy rule the generated config of a resource
+// violates
+type PolicyViolation struct {
+	Address string
+	Message string
+}
+
+// ImportResult is the outcome of an import
+type ImportResult struct {
+	Services  []ServiceResult
+	Resources []ResourceResult
+	// Violations are the policy violations of the imported resources, and
+	// of the resources rejected for them when policies are enforced
+	Violations []PolicyViolation
+	Duration   time.Duration
+}
+
+// Imported returns the number of imported resources across services
+func (r ImportResult) Imported() int {
+	var n int
+	for _, s := range r.Services {
+		n += s.Imported
+	}
+	return n
+}
+
+// Skipped returns the resources which were not imported
+func (r ImportResult) Skipped() []ResourceResult {
+	var skipped []ResourceResult
+	for _, res := range r.Resources {
+		if res.Status == ResourceSkipped {
+			skipped = append(skipped, res)
+		}
+	}
+	return skipped
+}
+
+// service returns the counts of a service, adding them on first use
+func (r *ImportResult) service(service resource.Service) *ServiceResult {
+	for i := range r.Services {
+		if r.Services[i].Service == service {
+			return &r.Services[i]
+		}
+	}
+	r.Services = append(r.Services, ServiceResult{Service: service})
+	return &r.Services[len(r.Services)-1]
+}
+
+// resultRecorder collects the events of an import into its result and passes
+// them on to the hooks of the client
+type resultRecorder struct {
+	hooks  Hooks
+	result *ImportResult
+}
+
+func (r resultRecorder) OnResourceDiscovered(res resource.Resource) {
+	r.result.service(res.Service).Discovered++
+	r.hooks.OnResourceDiscovered(res)
+}
+
+func (r resultRecorder) OnResourceImported(res resource.Resource) {
+	r.result.service(res.Service).Imported++
+	r.result.Resources = append(r.result.Resources, newResourceResult(res, ResourceImported, ""))
+	r.hooks.OnResourceImported(res)
+}
+
+func (r resultRecorder) OnResourceSkipped(res resource.Resource, reason string) {
+	r.result.service(res.Service).Skipped++
+	r.result.Resources = append(r.result.Resources, newResourceResult(res, ResourceSkipped, reason))
+	r.hooks.OnResourceSkipped(res, reason)
+}
+
+func (r resultRecorder) OnDrift(report drift.Report) {
+	r.hooks.OnDrift(report)
+}
+
+func newResourceResult(r resource.Resource, status ResourceStatus, reason string) ResourceResult {
+	return ResourceResult{
+		Service: r.Service,
+		Type:    r.Type,
+		Address: r.Address(),
+		ID:      r.ID,
+		Status:  status,
+		Reason:  reason,
+	}
+}
+
+// LogImportResult logs the per-service counts of an import and the resources
+// which were skipped
+func LogImportResult(result ImportResult) {
+	for _, res := range result.Skipped() {
+		slog.Info("Skipped resource", "service", res.Service, "resource", res.ID, "reason", res.Reason)
+	}
+	for _, s := range result.Services {
+		slog.Info("Imported service", "service", s.Service, "discovered", s.Discovered,
+			"imported", s.Imported, "skipped", s.Skipped)
+	}
+	slog.Info("Import completed", "imported", result.Imported(), "skipped", len(result.Skipped()),
+		"duration", result.Duration.Round(time.Second))
+}
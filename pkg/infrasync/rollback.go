@@ -0,0 +1,111 @@
+package infrasync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/internal/tfimport"
+)
+
+// Rollback undoes an import run recorded in the audit log. Resources the run
+// imported are removed from state, without destroying them, and the .tf files
+// it created are deleted.
+func (c *Client) Rollback(ctx context.Context, runID string) error {
+	unlock, err := c.lockRepository("rollback")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	audit.StartRun()
+
+	events, err := audit.Read(c.Config.AuditLogPath(), runID)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no audit log entries for run %s", runID)
+	}
+
+	var files, addresses []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if event.Error != "" {
+			continue
+		}
+		switch {
+		case event.Kind == audit.KindFile && event.Action == audit.ActionCreate && filepath.Ext(event.Path) == ".tf":
+			files = append(files, event.Path)
+		case event.Kind == audit.KindState && event.Action == audit.ActionImport && !seen[event.Address]:
+			seen[event.Address] = true
+			addresses = append(addresses, event.Address)
+		}
+	}
+
+	absOutputPath, err := filepath.Abs(c.Config.ProjectPath())
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+
+	runner, err := tfimport.New(ctx, absOutputPath, c.generatorOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create runner: %w", err)
+	}
+
+	if err := runner.Initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize runner: %w", err)
+	}
+
+	// Import blocks only take effect once applied, so some of the resources
+	// may never have reached state
+	inState, err := runner.StateList(ctx)
+	if err != nil {
+		return err
+	}
+	managed := make(map[string]bool, len(inState))
+	for _, address := range inState {
+		managed[address] = true
+	}
+
+	var remove []string
+	for _, address := range addresses {
+		if managed[address] {
+			remove = append(remove, address)
+		}
+	}
+	if len(remove) > 0 {
+		if err := runner.StateRemove(ctx, remove...); err != nil {
+			return err
+		}
+	}
+	slog.Info("Removed resources from state", "run", runID, "count", len(remove))
+
+	m, err := manifest.Load(c.Config.ManifestPath(), absOutputPath)
+	if err != nil {
+		return err
+	}
+
+	var removed int
+	for _, file := range files {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		if err := audit.Remove(file); err != nil {
+			return fmt.Errorf("failed to remove generated file: %w", err)
+		}
+		m.Forget(file)
+		removed++
+	}
+	if removed > 0 {
+		if err := m.Save(); err != nil {
+			return err
+		}
+	}
+	slog.Info("Removed generated files", "run", runID, "count", removed)
+
+	return c.writeOutputs(absOutputPath)
+}
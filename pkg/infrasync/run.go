@@ -0,0 +1,224 @@
+package infrasync
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/internal/version"
+)
+
+// RunManifestSchemaVersion is the version of the RunManifest schema. It is
+// incremented whenever a field is removed, renamed or changes meaning;
+// fields are only added within a version.
+const RunManifestSchemaVersion = 1
+
+// RunManifest describes a finished import or sync run for pipelines to
+// consume. It is written to run.json next to the manifest of generated files,
+// replacing the one of the previous run.
+type RunManifest struct {
+	SchemaVersion int    `json:"schema_version"`
+	RunID         string `json:"run_id"`
+	// Command is import or sync
+	Command string `json:"command"`
+	// Version is the version of infrasync the run was made with
+	Version string `json:"version"`
+	// ConfigHash is the SHA-256 of the effective config
+	ConfigHash string    `json:"config_hash"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// DurationSeconds is the time from starting to finishing the run
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Status is succeeded or failed, with Error the error the run failed
+	// with
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Providers are the provider projects the run covered, with the counts
+	// of each of their services
+	Providers []RunProvider `json:"providers"`
+	// Resources are the resources which were imported or skipped
+	Resources []RunResource `json:"resources"`
+	// Violations are the policy violations of the generated config
+	Violations []RunViolation `json:"violations"`
+	// Drift counts the drift a sync found. It is omitted for imports, and
+	// for syncs which failed before comparing resources with the state.
+	Drift *RunDrift `json:"drift,omitempty"`
+	// Files are the files the run created or changed, relative to the
+	// project path, sorted
+	Files []string `json:"files"`
+}
+
+// RunProvider is a configured provider project and the services a run covered
+type RunProvider struct {
+	Name     string       `json:"name"`
+	Project  string       `json:"project"`
+	Services []RunService `json:"services"`
+}
+
+// RunService counts the resources of a service by outcome
+type RunService struct {
+	Name       string `json:"name"`
+	Discovered int    `json:"discovered"`
+	Imported   int    `json:"imported"`
+	Skipped    int    `json:"skipped"`
+}
+
+// RunResource is the outcome of importing a resource
+type RunResource struct {
+	Service string `json:"service"`
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	ID      string `json:"id"`
+	// Status is imported or skipped, with Reason why it was skipped
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// RunViolation is a policy rule the generated config of a resource violates
+type RunViolation struct {
+	Address string `json:"address"`
+	Message string `json:"message"`
+}
+
+// RunDrift counts the drift a sync found
+type RunDrift struct {
+	Unmanaged int `json:"unmanaged"`
+	Deleted   int `json:"deleted"`
+	Modified  int `json:"modified"`
+}
+
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// RunManifestPath returns the path run.json is written to
+func (c *Client) RunManifestPath() string {
+	return filepath.Join(filepath.Dir(c.Config.ManifestPath()), "run.json")
+}
+
+// writeRunManifest writes the run.json of a run of command which started at
+// start and failed with runErr, if it did. The manifest is informational, so
+// failing to write it is only logged.
+func (c *Client) writeRunManifest(command string, start time.Time, result *ImportResult, report *drift.Report, runErr error) {
+	finished := time.Now().UTC()
+	run := RunManifest{
+		SchemaVersion:   RunManifestSchemaVersion,
+		RunID:           audit.RunID(),
+		Command:         command,
+		ConfigHash:      c.Config.Hash(),
+		StartedAt:       start.UTC(),
+		FinishedAt:      finished,
+		DurationSeconds: finished.Sub(start).Seconds(),
+		Status:          RunSucceeded,
+		Providers:       []RunProvider{},
+		Resources:       []RunResource{},
+		Violations:      []RunViolation{},
+		Files:           []string{},
+	}
+	run.Version, _ = version.Info()
+	if runErr != nil {
+		run.Status = RunFailed
+		run.Error = runErr.Error()
+	}
+
+	provider := c.Config.DefaultProvider()
+	covered := RunProvider{Name: string(provider.Type), Project: provider.ProjectID, Services: []RunService{}}
+	if result != nil {
+		for _, s := range result.Services {
+			covered.Services = append(covered.Services, RunService{
+				Name:       s.Service.String(),
+				Discovered: s.Discovered,
+				Imported:   s.Imported,
+				Skipped:    s.Skipped,
+			})
+		}
+		for _, r := range result.Resources {
+			run.Resources = append(run.Resources, RunResource{
+				Service: r.Service.String(),
+				Type:    r.Type.String(),
+				Address: r.Address,
+				ID:      r.ID,
+				Status:  string(r.Status),
+				Reason:  r.Reason,
+			})
+		}
+		for _, v := range result.Violations {
+			run.Violations = append(run.Violations, RunViolation{Address: v.Address, Message: v.Message})
+		}
+	}
+	run.Providers = append(run.Providers, covered)
+
+	if report != nil {
+		run.Drift = &RunDrift{
+			Unmanaged: len(report.Unmanaged),
+			Deleted:   len(report.Deleted),
+			Modified:  len(report.Modified),
+		}
+	}
+
+	files, err := c.runFiles(run.RunID)
+	if err != nil {
+		slog.Warn("Failed to list the files of the run", "error", err)
+	}
+	run.Files = append(run.Files, files...)
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err == nil {
+		path := c.RunManifestPath()
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = os.WriteFile(path, append(data, '\n'), 0644)
+		}
+	}
+	if err != nil {
+		slog.Warn("Failed to write the run manifest", "error", err)
+	}
+}
+
+// runFiles returns the files the run with runID created or changed according
+// to the audit log, relative to the project path if they are in it
+func (c *Client) runFiles(runID string) ([]string, error) {
+	if runID == "" {
+		return nil, nil
+	}
+	events, err := audit.Read(c.Config.AuditLogPath(), runID)
+	if err != nil {
+		return nil, err
+	}
+	absOutputPath, err := filepath.Abs(c.Config.ProjectPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+
+	// Files created and removed again, such as import blocks, are left out
+	written := map[string]bool{}
+	for _, event := range events {
+		if event.Kind != audit.KindFile || event.Error != "" {
+			continue
+		}
+		path, err := filepath.Abs(event.Path)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(absOutputPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = filepath.ToSlash(rel)
+		}
+		switch event.Action {
+		case audit.ActionCreate, audit.ActionModify:
+			written[path] = true
+		case audit.ActionDelete:
+			delete(written, path)
+		}
+	}
+	files := slices.Collect(maps.Keys(written))
+	slices.Sort(files)
+	return files, nil
+}
@@ -0,0 +1,233 @@
+package infrasync
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/internal/manifest"
+	"github.com/priyanshujain/infrasync/internal/version"
+)
+
+// The rules SARIF results are reported under
+const (
+	sarifRuleUnmanaged = "infrasync/unmanaged"
+	sarifRuleDeleted   = "infrasync/deleted"
+	sarifRuleModified  = "infrasync/modified"
+	sarifRulePolicy    = "infrasync/policy"
+)
+
+var sarifRules = []sarifRule{
+	{ID: sarifRuleUnmanaged, ShortDescription: sarifMessage{Text: "Resource exists in the cloud but not in state"}},
+	{ID: sarifRuleDeleted, ShortDescription: sarifMessage{Text: "Resource is in state but no longer exists in the cloud"}},
+	{ID: sarifRuleModified, ShortDescription: sarifMessage{Text: "Attribute differs between the cloud and state"}},
+	{ID: sarifRulePolicy, ShortDescription: sarifMessage{Text: "Generated config violates a policy"}},
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeSARIF writes the drift in report and the policy violations to path as
+// SARIF, for code scanning to annotate the generated files with. Results
+// point at the line of the resource, or of the modified attribute, in the
+// file it is generated in, relative to the project path. Findings of
+// resources without generated config are left out, as code scanning needs a
+// location for each.
+func (c *Client) writeSARIF(path string, report drift.Report, violations []PolicyViolation) error {
+	absOutputPath, err := filepath.Abs(c.Config.ProjectPath())
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+	m, err := manifest.Load(c.Config.ManifestPath(), absOutputPath)
+	if err != nil {
+		return err
+	}
+	locator := sarifLocator{projectPath: absOutputPath, files: m.Addresses(), contents: map[string][]string{}}
+
+	var results []sarifResult
+	add := func(rule, level, address, attribute, message string) {
+		location, ok := locator.locate(address, attribute)
+		if !ok {
+			slog.Debug("Finding has no generated config, leaving it out of the SARIF report", "resource", address, "rule", rule)
+			return
+		}
+		results = append(results, sarifResult{
+			RuleID:    rule,
+			Level:     level,
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	for _, r := range report.Unmanaged {
+		add(sarifRuleUnmanaged, "note", r.Address, "", fmt.Sprintf("%s (%s) exists in the cloud but not in state", r.Address, r.ID))
+	}
+	for _, r := range report.Deleted {
+		add(sarifRuleDeleted, "warning", r.Address, "", fmt.Sprintf("%s no longer exists in the cloud", r.Address))
+	}
+	for _, change := range report.Modified {
+		add(sarifRuleModified, "warning", change.Address, change.Attribute,
+			fmt.Sprintf("%s of %s differs between the cloud and state", change.Attribute, change.Address))
+	}
+	for _, v := range violations {
+		add(sarifRulePolicy, "error", v.Address, "", fmt.Sprintf("%s: %s", v.Address, v.Message))
+	}
+
+	driver := sarifDriver{
+		Name:           "InfraSync",
+		InformationURI: "https://github.com/priyanshujain/infrasync",
+		Rules:          sarifRules,
+	}
+	driver.Version, _ = version.Info()
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	if log.Runs[0].Results == nil {
+		log.Runs[0].Results = []sarifResult{}
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write SARIF report: %w", err)
+	}
+	slog.Info("Wrote SARIF report", "path", path, "results", len(results))
+	return nil
+}
+
+// sarifLocator finds the lines resources are generated at
+type sarifLocator struct {
+	projectPath string
+	// files are the generated files, keyed by the addresses in them
+	files    map[string]string
+	contents map[string][]string
+}
+
+// locate returns the location of the attribute of the resource at address,
+// or of the resource if attribute is empty or not found
+func (l sarifLocator) locate(address, attribute string) (sarifLocation, bool) {
+	path, ok := l.files[address]
+	if !ok {
+		return sarifLocation{}, false
+	}
+	lines, ok := l.contents[path]
+	if !ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return sarifLocation{}, false
+		}
+		lines = strings.Split(string(data), "\n")
+		l.contents[path] = lines
+	}
+	rel, err := filepath.Rel(l.projectPath, path)
+	if err != nil {
+		return sarifLocation{}, false
+	}
+
+	resourceType, name, _ := strings.Cut(address, ".")
+	// Attributes are flattened, e.g. settings.0.tier, and only their
+	// top-level name is looked up
+	attribute, _, _ = strings.Cut(attribute, ".")
+
+	line := 0
+	var depth int
+	for i, text := range lines {
+		trimmed := strings.TrimSpace(text)
+		if line == 0 {
+			if m := sarifResourceHeader(trimmed); m == resourceType+"."+name {
+				line = i + 1
+				depth = strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+				if attribute == "" {
+					break
+				}
+			}
+			continue
+		}
+		if depth == 1 && attribute != "" && (strings.HasPrefix(trimmed, attribute+" ") || strings.HasPrefix(trimmed, attribute+"=")) {
+			line = i + 1
+			break
+		}
+		depth += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+		if depth <= 0 {
+			break
+		}
+	}
+	if line == 0 {
+		return sarifLocation{}, false
+	}
+
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(rel)},
+		Region:           sarifRegion{StartLine: line},
+	}}, true
+}
+
+// sarifResourceHeader returns the address of the resource block line starts,
+// or "" if it starts none
+func sarifResourceHeader(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "resource" {
+		return ""
+	}
+	return strings.Trim(fields[1], `"`) + "." + strings.Trim(fields[2], `"`)
+}
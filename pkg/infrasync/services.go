@@ -3,21 +3,21 @@ package infrasync
 import (
 	"context"
 
-	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/pkg/resource"
 )
 
 // ImportPubSub imports all PubSub resources for the configured project
-func (c *Client) ImportPubSub(ctx context.Context) error {
+func (c *Client) ImportPubSub(ctx context.Context) (*ImportResult, error) {
 	return c.ImportService(ctx, "pubsub")
 }
 
 // ImportCloudSQL imports all CloudSQL resources for the configured project
-func (c *Client) ImportCloudSQL(ctx context.Context) error {
+func (c *Client) ImportCloudSQL(ctx context.Context) (*ImportResult, error) {
 	return c.ImportService(ctx, "cloudsql")
 }
 
 // ImportStorage imports all Storage resources for the configured project
-func (c *Client) ImportStorage(ctx context.Context) error {
+func (c *Client) ImportStorage(ctx context.Context) (*ImportResult, error) {
 	return c.ImportService(ctx, "storage")
 }
 
@@ -27,7 +27,7 @@ func (c *Client) ImportStorage(ctx context.Context) error {
 // 1. Create a filtered resource iterator that returns only the specified resource
 // 2. Use the terraform importer to import only that specific resource
 // 3. Support proper error handling for non-existent resources
-func (c *Client) ImportSingleResource(ctx context.Context, service google.Service, resourceType string, resourceID string) error {
+func (c *Client) ImportSingleResource(ctx context.Context, service resource.Service, resourceType string, resourceID string) (*ImportResult, error) {
 	// IMPORTANT: This implementation currently ignores resourceType and resourceID
 	// It will be properly implemented in a future update
 	return c.ImportService(ctx, service)
@@ -0,0 +1,95 @@
+package infrasync
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/drift"
+)
+
+// summaryRows is how many resources a table of the job summary lists, which
+// GitHub limits to 1 MiB
+const summaryRows = 100
+
+// writeJobSummary adds the outcome of an import, and the drift found by a
+// sync if report is set, to the summary of the GitHub Actions job the run is
+// part of. Outside GitHub Actions it does nothing. The summary is
+// informational, so failing to write it is only logged.
+func writeJobSummary(result *ImportResult, report *drift.Report) {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" || result == nil {
+		return
+	}
+
+	var b strings.Builder
+	if report != nil {
+		b.WriteString("## InfraSync sync\n\n")
+	} else {
+		b.WriteString("## InfraSync import\n\n")
+	}
+
+	b.WriteString("| Service | Discovered | Imported | Skipped |\n| --- | ---: | ---: | ---: |\n")
+	for _, s := range result.Services {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", s.Service, s.Discovered, s.Imported, s.Skipped)
+	}
+
+	var skipped [][]string
+	for _, r := range result.Skipped() {
+		skipped = append(skipped, []string{code(r.Address), code(r.ID), r.Reason})
+	}
+	summaryTable(&b, "Skipped resources", []string{"Resource", "ID", "Reason"}, skipped)
+
+	if report != nil {
+		var unmanaged, deleted, modified [][]string
+		for _, r := range report.Unmanaged {
+			unmanaged = append(unmanaged, []string{code(r.Address), code(r.ID)})
+		}
+		for _, r := range report.Deleted {
+			deleted = append(deleted, []string{code(r.Address)})
+		}
+		// Values are left out, as summaries are visible to everyone with
+		// read access to the repository
+		for _, c := range report.Modified {
+			modified = append(modified, []string{code(c.Address), code(c.Attribute)})
+		}
+		if !report.HasDrift() {
+			b.WriteString("\nNo drift detected.\n")
+		}
+		summaryTable(&b, "Unmanaged resources", []string{"Resource", "ID"}, unmanaged)
+		summaryTable(&b, "Deleted resources", []string{"Resource"}, deleted)
+		summaryTable(&b, "Modified attributes", []string{"Resource", "Attribute"}, modified)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		defer file.Close()
+		_, err = file.WriteString(b.String() + "\n")
+	}
+	if err != nil {
+		slog.Warn("Failed to write the job summary", "error", err)
+	}
+}
+
+// summaryTable writes a markdown table of rows under heading, unless there
+// are no rows. Rows beyond summaryRows are counted instead of listed.
+func summaryTable(b *strings.Builder, heading string, columns []string, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n### %s (%d)\n\n", heading, len(rows))
+	fmt.Fprintf(b, "| %s |\n|%s\n", strings.Join(columns, " | "), strings.Repeat(" --- |", len(columns)))
+	for i, row := range rows {
+		if i == summaryRows {
+			fmt.Fprintf(b, "\nand %d more\n", len(rows)-summaryRows)
+			break
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(row, " | "))
+	}
+}
+
+// code formats s as inline code in a table cell
+func code(s string) string {
+	return "`" + strings.ReplaceAll(s, "|", `\|`) + "`"
+}
@@ -0,0 +1,393 @@
+package infrasync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/priyanshujain/infrasync/internal/audit"
+	"github.com/priyanshujain/infrasync/internal/drift"
+	"github.com/priyanshujain/infrasync/internal/history"
+	"github.com/priyanshujain/infrasync/internal/providers/google"
+	"github.com/priyanshujain/infrasync/internal/state"
+	"github.com/priyanshujain/infrasync/internal/tfimport"
+	"github.com/priyanshujain/infrasync/internal/vcs"
+	"github.com/priyanshujain/infrasync/pkg/resource"
+)
+
+// ErrDriftDetected is returned by Sync when drift of a class configured to
+// fail was found
+var ErrDriftDetected = errors.New("drift detected")
+
+// ErrBudgetExceeded is returned by Sync when there are more unmanaged
+// resources of a service or type than its budget allows
+var ErrBudgetExceeded = errors.New("unmanaged resource budget exceeded")
+
+// ErrDestroyPlanned is returned by Sync instead of opening a pull request
+// whose changes would destroy resources once applied
+var ErrDestroyPlanned = errors.New("plan would destroy resources")
+
+// SyncOptions controls what Sync does once drift has been detected
+type SyncOptions struct {
+	CreatePR   bool
+	PRHost     vcs.HostType
+	BaseBranch string
+	// AllowDestroy opens pull requests even if planning their changes
+	// destroys or replaces resources
+	AllowDestroy bool
+	// Remediate reverts the drift of the resource types selected in
+	// drift.remediate in the cloud, once ConfirmRemediation approved the
+	// remediations. Without ConfirmRemediation they are only previewed.
+	Remediate          bool
+	ConfirmRemediation func([]google.Remediation) bool
+	// SARIF is the path drift and policy violations are written to as
+	// SARIF, for code scanning to annotate the generated files with. Keep
+	// it out of the project path, which pull requests commit.
+	SARIF string
+}
+
+// Sync imports resources which are not yet codified and reports whether the
+// repository changed. With CreatePR set, the changes are committed to a new
+// branch and a pull request is opened.
+func (c *Client) Sync(ctx context.Context, opts SyncOptions) (err error) {
+	unlock, err := c.lockRepository("sync")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	audit.StartRun()
+	start := time.Now()
+	var result *ImportResult
+	var detected *drift.Report
+	defer func() { c.writeRunManifest("sync", start, result, detected, err) }()
+
+	store, err := c.Config.DriftHistory()
+	if err != nil {
+		return err
+	}
+
+	// Remediation reads the state drift was detected with, as the backend
+	// pins the state to what it first read
+	backend, err := state.NewBackend(ctx, c.Config.DefaultBackend())
+	if err != nil {
+		return err
+	}
+	// The state is locked for the rest of the run, so that nobody applies
+	// changes while drift is detected, remediated and planned. terraform
+	// only skips its own locking while infrasync holds the lock.
+	unlockState, stateLocked, err := c.lockState(ctx, backend, "infrasync sync")
+	if err != nil {
+		return err
+	}
+	defer unlockState()
+	if stateLocked {
+		locked := *c
+		locked.stateLocked = true
+		c = &locked
+	}
+
+	report, result, err := c.detectDrift(ctx, backend)
+	if err != nil {
+		return err
+	}
+	detected = &report
+
+	record := history.NewRecord(c.Config.DefaultProvider().ProjectID, report)
+	if store == nil {
+		slog.Warn("Drift history is not recorded in CI without a gcs backend")
+	} else if err := store.Save(ctx, record); err != nil {
+		return fmt.Errorf("failed to record drift history: %w", err)
+	}
+
+	if opts.Remediate {
+		remediated, err := c.remediate(ctx, backend, report, opts.ConfirmRemediation)
+		if err != nil {
+			return fmt.Errorf("failed to remediate drift: %w", err)
+		}
+		report = withoutRemediated(report, remediated)
+	}
+
+	if opts.SARIF != "" {
+		if err := c.writeSARIF(opts.SARIF, report, result.Violations); err != nil {
+			return err
+		}
+	}
+
+	repo := vcs.New(c.Config.ProjectPath(), c.Config.Git)
+	changed, err := repo.HasChanges()
+	if errors.Is(err, vcs.ErrDisabled) {
+		slog.Warn("Git is disabled, changes to generated files can't be detected")
+	} else if err != nil {
+		return fmt.Errorf("failed to detect changes: %w", err)
+	}
+
+	if !changed && !report.HasDrift() {
+		slog.Info("No drift detected")
+		return nil
+	}
+
+	// Like the cost estimate the plan is informational, so failing to plan
+	// doesn't keep drift from being reported
+	var plan *tfimport.PlanSummary
+	var planErr error
+	if changed {
+		summary, err := c.plan(ctx)
+		if err != nil {
+			slog.Warn("Failed to plan the generated config", "error", err)
+			planErr = err
+		} else {
+			plan = &summary
+			logPlan(summary)
+		}
+	}
+
+	slog.Info("Drift detected", "unmanaged", len(report.Unmanaged), "deleted", len(report.Deleted),
+		"modified", len(report.Modified))
+	if err := exportDriftDetected(); err != nil {
+		return fmt.Errorf("failed to export drift status: %w", err)
+	}
+
+	if opts.CreatePR && changed && report.HasAny(c.Config.DriftPRClasses()) {
+		if plan != nil && plan.Destroy > 0 && !opts.AllowDestroy {
+			return fmt.Errorf("%w, not opening a pull request without --allow-destroy: %s",
+				ErrDestroyPlanned, strings.Join(plan.Destroyed(), ", "))
+		}
+		if err := c.createPullRequest(ctx, repo, opts, plan, planErr); err != nil {
+			return err
+		}
+	}
+
+	if failOn := c.Config.DriftFailClasses(); report.HasAny(failOn) {
+		return fmt.Errorf("%w: classes %v", ErrDriftDetected, report.Classes())
+	}
+
+	return c.checkBudget(report)
+}
+
+// checkBudget logs the unmanaged resources counted against each configured
+// budget and fails if any is exceeded
+func (c *Client) checkBudget(report drift.Report) error {
+	budget := c.Config.UnmanagedBudget()
+	if len(budget) == 0 {
+		return nil
+	}
+
+	counts := drift.UnmanagedCounts(report)
+	for _, key := range slices.Sorted(maps.Keys(budget)) {
+		slog.Info("Unmanaged resource budget", "budget", key, "unmanaged", counts[key], "limit", budget[key])
+	}
+
+	overruns := budget.Check(report)
+	if len(overruns) == 0 {
+		return nil
+	}
+	var exceeded []string
+	for _, o := range overruns {
+		slog.Error("Unmanaged resource budget exceeded", "budget", o.Key, "unmanaged", o.Unmanaged, "limit", o.Limit)
+		exceeded = append(exceeded, o.String())
+	}
+	return fmt.Errorf("%w: %s", ErrBudgetExceeded, strings.Join(exceeded, "; "))
+}
+
+// detectDrift imports the resources which are not yet codified and compares
+// every discovered resource with the state read from backend. The result of
+// the import is returned along with the drift.
+func (c *Client) detectDrift(ctx context.Context, backend state.Backend) (drift.Report, *ImportResult, error) {
+	detector, err := c.newDetector(ctx, backend)
+	if err != nil {
+		return drift.Report{}, nil, fmt.Errorf("failed to detect drift: %w", err)
+	}
+
+	var costs *costReporter
+	if c.Config.EstimateCost() {
+		costs = &costReporter{credentials: c.Config.DefaultProvider().Credentials}
+		detector.OnUnmanaged(func(r resource.Resource) { costs.add(ctx, r) })
+	}
+
+	result, err := c.importResources(ctx, detector.Observe, nil)
+	if err != nil {
+		return drift.Report{}, nil, fmt.Errorf("failed to import resources: %w", err)
+	}
+	// Excluded resources and dependents left out by the dependent limits
+	// still exist, and resources which couldn't be discovered may
+	for _, r := range result.Skipped() {
+		switch r.Reason {
+		case SkipReasonExcluded, SkipReasonDependentLimit, SkipReasonDiscoveryFailed:
+			detector.Disregard(r.Type, r.ID, r.Address)
+		}
+	}
+
+	report := detector.Report()
+	logDrift(report)
+	c.events().OnDrift(report)
+	writeJobSummary(result, &report)
+
+	if costs != nil {
+		// The estimate is informational and must not keep drift from being
+		// recorded and reported
+		if err := costs.report(); err != nil {
+			slog.Warn("Failed to estimate costs", "error", err)
+		}
+	}
+	return report, result, nil
+}
+
+// newDetector reads the state from backend to compare discovered resources
+// with
+func (c *Client) newDetector(ctx context.Context, backend state.Backend) (*drift.Detector, error) {
+	data, err := backend.Read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	managed, err := state.Resources(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []resource.Type
+	for _, service := range c.Config.GoogleServices(c.Config.DefaultProvider()) {
+		types = append(types, google.ResourceTypes(service)...)
+	}
+
+	return drift.NewDetector(managed, types, c.Config.DriftIgnoreRules()), nil
+}
+
+func logDrift(report drift.Report) {
+	for _, r := range report.Unmanaged {
+		slog.Info("Unmanaged resource", "resource", r.Address, "id", r.ID)
+	}
+	for _, r := range report.Deleted {
+		slog.Info("Resource deleted from cloud", "resource", r.Address)
+	}
+	for _, change := range report.Modified {
+		slog.Info("Resource modified", "resource", change.Address, "attribute", change.Attribute,
+			"cloud", change.Cloud, "state", change.State)
+	}
+}
+
+// plan summarizes the changes applying the project would make
+func (c *Client) plan(ctx context.Context) (tfimport.PlanSummary, error) {
+	absOutputPath, err := filepath.Abs(c.Config.ProjectPath())
+	if err != nil {
+		return tfimport.PlanSummary{}, fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+
+	runner, err := tfimport.New(ctx, absOutputPath, c.generatorOptions())
+	if err != nil {
+		return tfimport.PlanSummary{}, fmt.Errorf("failed to create runner: %w", err)
+	}
+	return runner.Plan(ctx)
+}
+
+func logPlan(summary tfimport.PlanSummary) {
+	for _, change := range summary.Resources {
+		slog.Info("Planned change", "resource", change.Address, "actions", change.Actions, "import", change.Import)
+	}
+	slog.Info("Plan: " + summary.String())
+}
+
+func (c *Client) createPullRequest(ctx context.Context, repo vcs.Repository, opts SyncOptions, plan *tfimport.PlanSummary, planErr error) (err error) {
+	host, err := vcs.NewHost(opts.PRHost)
+	if err != nil {
+		return fmt.Errorf("failed to create pull request host: %w", err)
+	}
+
+	remoteURL, err := repo.RemoteURL()
+	if err != nil {
+		return err
+	}
+
+	branch := fmt.Sprintf("infrasync/sync-%s", time.Now().UTC().Format("20060102150405"))
+	if err := repo.CreateBranch(branch); err != nil {
+		return err
+	}
+
+	// Return to the base branch, whether or not the pull request was created,
+	// so that later runs start from it. Once the changes are committed to the
+	// new branch, the base branch's files are checked out again, so they
+	// don't count as changes of the next run.
+	defer func() {
+		if checkoutErr := repo.Checkout(opts.BaseBranch); checkoutErr != nil && err == nil {
+			err = checkoutErr
+		}
+	}()
+
+	if err := repo.Commit("Update Terraform configurations to match cloud state"); err != nil {
+		return err
+	}
+
+	if err := repo.Push(ctx, branch); err != nil {
+		return err
+	}
+
+	body := "This pull request was created by `infrasync sync`.\n\n" +
+		"Infrastructure drift was detected between the Terraform configuration and actual cloud resources. " +
+		"Please review the changes carefully before merging."
+	switch {
+	case plan != nil:
+		body += "\n\n" + planMarkdown(*plan)
+	case planErr != nil:
+		body += "\n\nThe generated configuration could not be planned, check it before merging:\n\n" +
+			"```\n" + planErr.Error() + "\n```"
+	}
+
+	url, err := host.CreatePullRequest(ctx, remoteURL, vcs.PullRequest{
+		Title: "Infrastructure drift detected",
+		Body:  body,
+		Head:  branch,
+		Base:  opts.BaseBranch,
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Pull request created", "url", url)
+	return nil
+}
+
+// planMarkdown describes the changes of a plan for a pull request body
+func planMarkdown(plan tfimport.PlanSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan: %s.", plan)
+	if plan.Destroy > 0 {
+		b.WriteString("\n\n**Applying this plan destroys or replaces resources.**")
+	}
+	if plan.Empty() {
+		return b.String()
+	}
+
+	b.WriteString("\n\n| Resource | Actions |\n| --- | --- |\n")
+	for _, change := range plan.Resources {
+		actions := slices.DeleteFunc(slices.Clone(change.Actions), func(a string) bool { return a == "no-op" })
+		if change.Import {
+			actions = append([]string{"import"}, actions...)
+		}
+		fmt.Fprintf(&b, "| `%s` | %s |\n", change.Address, strings.Join(actions, ", "))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// exportDriftDetected sets DRIFT_DETECTED for later GitHub Actions steps
+func exportDriftDetected() error {
+	path := os.Getenv("GITHUB_ENV")
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString("DRIFT_DETECTED=true\n")
+	return err
+}
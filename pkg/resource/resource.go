@@ -0,0 +1,73 @@
+// Package resource is the provider-agnostic model of the cloud resources
+// infrasync discovers, imports and compares with state. Providers define
+// their resource types and services as values of Type and Service, along
+// with what else is specific to them.
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/priyanshujain/infrasync/internal/providers"
+)
+
+// Type is the terraform type of a resource, e.g. google_storage_bucket
+type Type string
+
+func (t Type) String() string {
+	return string(t)
+}
+
+// Provider returns the name of the terraform provider the type belongs to,
+// which prefixes it, e.g. google
+func (t Type) Provider() string {
+	provider, _, _ := strings.Cut(string(t), "_")
+	return provider
+}
+
+// IAM reports whether the resource type grants IAM roles on another resource,
+// i.e. is an IAM binding, member or policy
+func (t Type) IAM() bool {
+	for _, suffix := range []string{"_iam_binding", "_iam_member", "_iam_policy"} {
+		if strings.HasSuffix(string(t), suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Service is a group of resource types discovered by the same importer, e.g.
+// pubsub
+type Service string
+
+func (s Service) String() string {
+	return string(s)
+}
+
+// Resource is a cloud resource and the resources which depend on it, such as
+// its IAM bindings
+type Resource struct {
+	// Provider is the provider config the resource was discovered with
+	Provider   providers.Provider
+	Type       Type
+	Service    Service
+	Name       string
+	ID         string
+	Dependents []Resource
+	// Attributes are named as in the terraform schema of the type
+	Attributes map[string]any
+}
+
+// Address returns the terraform address the resource is generated at
+func (r Resource) Address() string {
+	return fmt.Sprintf("%s.%s", r.Type, r.Name)
+}
+
+// Flatten returns the resource followed by all of its dependents, depth first
+func (r Resource) Flatten() []Resource {
+	out := []Resource{r}
+	for _, d := range r.Dependents {
+		out = append(out, d.Flatten()...)
+	}
+	return out
+}